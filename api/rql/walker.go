@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 )
 
@@ -90,6 +91,12 @@ func (w *walkerImpl) walk(ctx context.Context, e *Entry, depth int) ([]Entry, er
 			sort.Slice(children, func(i, j int) bool {
 				return children[i].CName < children[j].CName
 			})
+			if w.opts.Fullmeta && childDepth >= w.opts.Mindepth {
+				// Fetch the children's full metadata in bulk (if supported) so that
+				// visit doesn't need to fetch it one child at a time. visit falls back
+				// to fetching each child's metadata if the bulk fetch fails.
+				w.prefetchMetadata(ctx, e, children)
+			}
 			// Now walk the children
 			for _, child := range children {
 				descendants, err := w.walk(ctx, &child, childDepth)
@@ -104,6 +111,21 @@ func (w *walkerImpl) walk(ctx context.Context, e *Entry, depth int) ([]Entry, er
 	return entries, nil
 }
 
+func (w *walkerImpl) prefetchMetadata(ctx context.Context, parent *Entry, children []Entry) {
+	toPrefetch := []plugin.Entry{}
+	for _, child := range children {
+		if child.SchemaKnown() && !w.q.EvalEntrySchema(child.Schema) {
+			// visit won't fetch this child's metadata
+			continue
+		}
+		toPrefetch = append(toPrefetch, child.pluginEntry)
+	}
+	err := plugin.PrefetchChildMetadata(ctx, parent.pluginEntry.(plugin.Parent), toPrefetch)
+	if err != nil {
+		activity.Warnf(ctx, "could not get full metadata of the children of %v in bulk, fetching it one child at a time: %v", parent.Path, err)
+	}
+}
+
 func (w *walkerImpl) visit(ctx context.Context, e *Entry, depth int) (bool, error) {
 	if depth < w.opts.Mindepth {
 		return false, nil
@@ -178,6 +178,38 @@ func (s *WalkerTestSuite) TestWalk_VisitErrors() {
 	s.Regexp(`full.*metadata.*failed.*metadata`, err)
 }
 
+func (s *WalkerTestSuite) TestWalk_FullmetaSet_PrefetchesChildMetadata() {
+	tree := s.setupDefaultMocksForWalk()
+	s.walker.opts.Fullmeta = true
+	for _, entry := range tree {
+		entry.On("Metadata", mock.Anything).Return(plugin.JSONObject{}, nil)
+	}
+	tree["./foo"].prefetchesChildMetadata = true
+	children := []plugin.Entry{tree["./foo/bar"], tree["./foo/baz"]}
+	tree["./foo"].On("PrefetchChildMetadata", mock.Anything, children).Return(nil).Once()
+
+	entries := s.mustWalk(context.Background(), tree["."])
+	s.Len(entries, 5)
+	tree["./foo"].AssertExpectations(s.T())
+}
+
+func (s *WalkerTestSuite) TestWalk_FullmetaSet_PrefetchChildMetadataErrors_FallsBackToMetadata() {
+	tree := s.setupDefaultMocksForWalk()
+	s.walker.opts.Fullmeta = true
+	for _, entry := range tree {
+		entry.On("Metadata", mock.Anything).Return(plugin.JSONObject{}, nil)
+	}
+	tree["./foo"].prefetchesChildMetadata = true
+	expectedErr := fmt.Errorf("failed to prefetch metadata")
+	tree["./foo"].On("PrefetchChildMetadata", mock.Anything, mock.Anything).Return(expectedErr).Once()
+
+	entries := s.mustWalk(context.Background(), tree["."])
+	s.Len(entries, 5)
+	tree["./foo"].AssertExpectations(s.T())
+	tree["./foo/bar"].AssertCalled(s.T(), "Metadata", mock.Anything)
+	tree["./foo/baz"].AssertCalled(s.T(), "Metadata", mock.Anything)
+}
+
 func (s *WalkerTestSuite) TestVisit_MindepthSet() {
 	s.walker.opts.Mindepth = 1
 	e := newMockEntryForVisit()
@@ -358,8 +390,9 @@ var _ = EntrySchemaPredicate(&mockQuery{})
 type mockPluginEntry struct {
 	plugin.EntryBase
 	mock.Mock
-	rawTypeID   string
-	isNotParent bool
+	rawTypeID               string
+	isNotParent             bool
+	prefetchesChildMetadata bool
 }
 
 func newMockPluginEntry(name string) *mockPluginEntry {
@@ -391,6 +424,14 @@ func (m *mockPluginEntry) Metadata(ctx context.Context) (plugin.JSONObject, erro
 	return args.Get(0).(plugin.JSONObject), args.Error(1)
 }
 
+func (m *mockPluginEntry) PrefetchChildMetadata(ctx context.Context, children []plugin.Entry) error {
+	if !m.prefetchesChildMetadata {
+		return nil
+	}
+	args := m.Called(ctx, children)
+	return args.Error(0)
+}
+
 // Mock the external plugin interface so that we have the ability
 // to mock schemas, type IDs, supported methods, etc.
 //
@@ -266,6 +266,12 @@ func (s *EntrySchema) SetMetadataSchema(schema *plugin.JSONSchema) {
 	s.EntrySchema.MetadataSchema = schema
 }
 
+// ConfigSchema returns the plugin's config schema. It is only set
+// for core plugin roots that declare their config's schema.
+func (s *EntrySchema) ConfigSchema() *plugin.JSONSchema {
+	return s.EntrySchema.ConfigSchema
+}
+
 // Children returns the entry's child schemas
 func (s *EntrySchema) Children() []*EntrySchema {
 	return s.children
@@ -57,6 +57,16 @@ func docsMain(cmd *cobra.Command, args []string) exitCode {
 		}
 	}
 
+	// Print the config options (if there are any). This part is printed as
+	//   CONFIG OPTIONS
+	//     * <option> (<type>[, required])
+	//         <description>
+	//
+	//   <note about where to set them>
+	if schema != nil && schema.ConfigSchema() != nil && len(schema.ConfigSchema().Properties) > 0 {
+		addSection(docs, stringifyConfigOptions(schema.Label(), schema.ConfigSchema()))
+	}
+
 	// Print the supported attributes. This part is printed as
 	//   SUPPORTED ATTRIBUTES
 	//     * <attribute> (<full_name_of_attribute>)
@@ -236,6 +246,38 @@ func stringifySupportedActions(path string, entry apitypes.Entry) string {
 	return supportedActions.String()
 }
 
+func stringifyConfigOptions(pluginName string, configSchema *plugin.JSONSchema) string {
+	var configOptions strings.Builder
+	configOptions.WriteString("CONFIG OPTIONS\n")
+	required := make(map[string]bool)
+	for _, option := range configSchema.Required {
+		required[option] = true
+	}
+	var options []string
+	for option := range configSchema.Properties {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	for _, option := range options {
+		property := configSchema.Properties[option]
+		optionType := property.Type
+		if optionType == "array" && property.Items != nil && len(property.Items.Type) > 0 {
+			optionType = fmt.Sprintf("array of %vs", property.Items.Type)
+		}
+		if required[option] {
+			optionType += ", required"
+		}
+		configOptions.WriteString(fmt.Sprintf("* %v (%v)\n", option, optionType))
+		if len(property.Description) > 0 {
+			for _, line := range strings.Split(strings.Trim(property.Description, "\n"), "\n") {
+				configOptions.WriteString(fmt.Sprintf("    %v\n", line))
+			}
+		}
+	}
+	configOptions.WriteString(fmt.Sprintf("\nSet these under the '%v' key in your wash.yaml file.", pluginName))
+	return configOptions.String()
+}
+
 func stringifySignalSet(setName string, signals []apitypes.SignalSchema) string {
 	var signalSet strings.Builder
 	signalSet.WriteString(fmt.Sprintf("%v\n", setName))
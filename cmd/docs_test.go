@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"testing"
@@ -104,6 +105,35 @@ func (suite *DocsTestSuite) TestStringifySignalSet() {
 	suite.Regexp(".*bar.*\n.*bar signal", supportedSignals)
 }
 
+func (suite *DocsTestSuite) TestStringifyConfigOptions() {
+	rawSchema := `{
+	"aws::root": {
+		"label": "aws",
+		"singleton": true,
+		"actions": ["list"],
+		"config_schema": {
+			"type": "object",
+			"properties": {
+				"profiles": {"type": "array", "items": {"type": "string"}, "description": "The profiles\nto list"},
+				"region": {"type": "string"}
+			},
+			"required": ["region"]
+		}
+	}
+}`
+	var schema apitypes.EntrySchema
+	if err := json.Unmarshal([]byte(rawSchema), &schema); err != nil {
+		suite.FailNow(fmt.Sprintf("failed to unmarshal the schema: %v", err))
+	}
+	suite.Require().NotNil(schema.ConfigSchema())
+
+	configOptions := stringifyConfigOptions(schema.Label(), schema.ConfigSchema())
+
+	suite.Regexp("^CONFIG OPTIONS", configOptions)
+	suite.Regexp(`\* profiles \(array of strings\)\n    The profiles\n    to list\n\* region \(string, required\)\n`, configOptions)
+	suite.Regexp("'aws' key in your wash.yaml", configOptions)
+}
+
 func TestDocs(t *testing.T) {
 	suite.Run(t, new(DocsTestSuite))
 }
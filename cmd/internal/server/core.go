@@ -17,8 +17,11 @@ import (
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/plugin/aws"
 	"github.com/puppetlabs/wash/plugin/docker"
+	"github.com/puppetlabs/wash/plugin/fixture"
 	"github.com/puppetlabs/wash/plugin/gcp"
 	"github.com/puppetlabs/wash/plugin/kubernetes"
+	"github.com/puppetlabs/wash/plugin/replay"
+	"github.com/puppetlabs/wash/plugin/ssh"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -27,8 +30,11 @@ import (
 var InternalPlugins = map[string]plugin.Root{
 	"aws":        &aws.Root{},
 	"docker":     &docker.Root{},
+	"fixture":    &fixture.Root{},
 	"gcp":        &gcp.Root{},
 	"kubernetes": &kubernetes.Root{},
+	"replay":     &replay.Root{},
+	"ssh":        &ssh.Root{},
 }
 
 // Opts exposes additional configuration for server operation.
@@ -38,6 +44,10 @@ type Opts struct {
 	// LogLevel can be "warn", "info", "debug", or "trace".
 	LogLevel     string
 	PluginConfig map[string]map[string]interface{}
+	// RecordPath is the path (rooted at the mountpoint) of the subtree whose
+	// plugin method results are recorded into the RecordCassette file.
+	RecordPath     string
+	RecordCassette string
 }
 
 // SetupLogging configures log level and output file according to configured options.
@@ -121,6 +131,13 @@ func (s *Server) Start() (bool, error) {
 
 		plugin.InitCache()
 
+		if s.opts.RecordPath != "" {
+			if err := plugin.StartRecording(s.opts.RecordPath, s.opts.RecordCassette); err != nil {
+				return successfullyLoadedPlugins, fmt.Errorf("could not start recording %v: %v", s.opts.RecordPath, err)
+			}
+			log.Infof("Recording %v to %v", s.opts.RecordPath, s.opts.RecordCassette)
+		}
+
 		analyticsConfig, err := analytics.GetConfig()
 		if err != nil {
 			return successfullyLoadedPlugins, err
@@ -198,6 +215,11 @@ func (s *Server) shutdown() {
 	// Close any open journals on shutdown to ensure remaining entries are flushed to disk.
 	activity.CloseAll()
 
+	// Write the cassette if we were recording.
+	if err := plugin.StopRecording(); err != nil {
+		log.Warnf("Failed to write the recorded cassette: %v", err)
+	}
+
 	// Flush any outstanding analytics hits. We do this asynchronously
 	// so that the server process isn't blocked on its cleanup (in case
 	// the network is slow).
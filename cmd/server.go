@@ -183,6 +183,8 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 		LogFile:        viper.GetString("logfile"),
 		LogLevel:       viper.GetString("loglevel"),
 		PluginConfig:   pluginConfig,
+		RecordPath:     viper.GetString("record.path"),
+		RecordCassette: viper.GetString("record.cassette"),
 	}, nil
 }
 
@@ -7,22 +7,27 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
+	ssmClient "github.com/aws/aws-sdk-go/service/ssm"
 )
 
-// ec2Dir represents the resources/ec2 directory
+// ec2Dir represents the regions/<region>/ec2 directory
 type ec2Dir struct {
 	plugin.EntryBase
 	session *session.Session
 	client  *ec2Client.EC2
+	ssm     *ssmClient.SSM
+	opts    profileOptions
 }
 
-func newEC2Dir(session *session.Session) *ec2Dir {
+func newEC2Dir(session *session.Session, opts profileOptions) *ec2Dir {
 	ec2Dir := &ec2Dir{
 		EntryBase: plugin.NewEntry("ec2"),
 	}
 	ec2Dir.DisableDefaultCaching()
 	ec2Dir.session = session
 	ec2Dir.client = ec2Client.New(session)
+	ec2Dir.ssm = ssmClient.New(session)
+	ec2Dir.opts = opts
 	return ec2Dir
 }
 
@@ -37,5 +42,5 @@ func (e *ec2Dir) ChildSchemas() []*plugin.EntrySchema {
 }
 
 func (e *ec2Dir) List(ctx context.Context) ([]plugin.Entry, error) {
-	return []plugin.Entry{newEC2InstancesDir(ctx, e.session, e.client)}, nil
+	return []plugin.Entry{newEC2InstancesDir(ctx, e)}, nil
 }
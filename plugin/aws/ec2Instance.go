@@ -15,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
+	ssmClient "github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/transport"
@@ -24,9 +25,13 @@ import (
 // ec2Instance represents an EC2 instance
 type ec2Instance struct {
 	plugin.EntryBase
-	id                      string
-	session                 *session.Session
-	client                  *ec2Client.EC2
+	id      string
+	session *session.Session
+	client  *ec2Client.EC2
+	ssm     *ssmClient.SSM
+	opts    profileOptions
+	// ssmManaged is true if the instance's SSM agent is online
+	ssmManaged              bool
 	latestConsoleOutputOnce sync.Once
 	hasLatestConsoleOutput  bool
 }
@@ -43,7 +48,7 @@ const (
 	EC2InstanceStopped           = 80
 )
 
-func newEC2Instance(ctx context.Context, inst *ec2Client.Instance, session *session.Session, client *ec2Client.EC2) *ec2Instance {
+func newEC2Instance(ctx context.Context, inst *ec2Client.Instance, is *ec2InstancesDir, ssmManaged bool) *ec2Instance {
 	id := awsSDK.StringValue(inst.InstanceId)
 	name := id
 	// AWS has a practice of using a tag with the key 'Name' as the display name in the console, so
@@ -60,8 +65,11 @@ func newEC2Instance(ctx context.Context, inst *ec2Client.Instance, session *sess
 		EntryBase: plugin.NewEntry(name),
 	}
 	ec2Instance.id = id
-	ec2Instance.session = session
-	ec2Instance.client = client
+	ec2Instance.session = is.session
+	ec2Instance.client = is.client
+	ec2Instance.ssm = is.ssm
+	ec2Instance.opts = is.opts
+	ec2Instance.ssmManaged = ssmManaged
 
 	attributes, metadata := getAttributesAndMetadata(inst)
 	ec2Instance.
@@ -216,7 +224,7 @@ func (inst *ec2Instance) List(ctx context.Context) ([]plugin.Entry, error) {
 	}
 
 	// Include a view of the remote filesystem using volume.FS. Use a small maxdepth because
-	// VMs can have lots of files and SSH is fast.
+	// VMs can have lots of files and exec is fast.
 	entries = append(entries, volume.NewFS(ctx, "fs", inst, 3))
 
 	return entries, nil
@@ -272,7 +280,31 @@ func (inst *ec2Instance) Delete(ctx context.Context) (bool, error) {
 	return false, inst.Signal(ctx, "terminate")
 }
 
+// usesSSM returns true if commands are run on the instance with SSM instead of SSH.
+func (inst *ec2Instance) usesSSM() bool {
+	switch inst.opts.execMode() {
+	case execSSM:
+		return true
+	case execSSH:
+		return false
+	default:
+		return inst.ssmManaged
+	}
+}
+
 func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	if inst.usesSSM() {
+		activity.Record(ctx, "Exec'ing on %v with SSM", inst)
+		executor := ssmExecutor{
+			session:      inst.session,
+			client:       inst.ssm,
+			instanceID:   inst.id,
+			windows:      inst.Attributes().OS().LoginShell == plugin.PowerShell,
+			outputBucket: inst.opts.SSMOutputBucket,
+		}
+		return executor.exec(ctx, cmd, args, opts)
+	}
+
 	// TBD: how to get WinRM connection info. Only work with Kerberos? Require a mini-inventory from wash.yaml?
 
 	meta, err := inst.Metadata(ctx)
@@ -353,7 +385,13 @@ func (inst *ec2Instance) Signal(ctx context.Context, signal string) error {
 }
 
 const ec2InstanceDescription = `
-This is an EC2 instance. Its Exec action uses SSH. It will look up port, user,
+This is an EC2 instance. Its Exec action uses SSM if the instance is managed by
+SSM, and SSH otherwise; set the profile's exec option to always use one or the
+other. SSM runs commands with Run Command, or in a Session Manager session if
+they need input or a TTY. Session Manager cannot close a command's input, and
+only recent SSM agents report the exit codes of session commands.
+
+SSH will look up port, user,
 and other configuration by exact hostname match from default SSH config files.
 If present, a local SSH agent will be used for authentication. Lots of SSH
 configuration is currently omitted, such as global known hosts files, finding
@@ -6,6 +6,7 @@ import (
 	awsSDK "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
+	ssmClient "github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 )
@@ -19,14 +20,18 @@ type ec2InstancesDir struct {
 	plugin.EntryBase
 	session *session.Session
 	client  *ec2Client.EC2
+	ssm     *ssmClient.SSM
+	opts    profileOptions
 }
 
-func newEC2InstancesDir(ctx context.Context, session *session.Session, client *ec2Client.EC2) *ec2InstancesDir {
+func newEC2InstancesDir(ctx context.Context, e *ec2Dir) *ec2InstancesDir {
 	ec2InstancesDir := &ec2InstancesDir{
 		EntryBase: plugin.NewEntry("instances"),
 	}
-	ec2InstancesDir.session = session
-	ec2InstancesDir.client = client
+	ec2InstancesDir.session = e.session
+	ec2InstancesDir.client = e.client
+	ec2InstancesDir.ssm = e.ssm
+	ec2InstancesDir.opts = e.opts
 	if _, err := plugin.List(ctx, ec2InstancesDir); err != nil {
 		ec2InstancesDir.MarkInaccessible(ctx, err)
 	}
@@ -51,6 +56,14 @@ func (is *ec2InstancesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 
 	activity.Record(ctx, "Listing %v EC2 reservations", len(resp.Reservations))
 
+	// Find the instances that can be exec'ed on with SSM
+	var managed map[string]bool
+	if is.opts.execMode() == execAuto {
+		if managed, err = ssmManagedInstances(ctx, is.ssm); err != nil {
+			activity.Record(ctx, "Could not list the SSM-managed instances, so SSH will be used for exec: %v", err)
+		}
+	}
+
 	var entries []plugin.Entry
 	for _, reservation := range resp.Reservations {
 		activity.Record(
@@ -65,8 +78,8 @@ func (is *ec2InstancesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 			instances[i] = newEC2Instance(
 				ctx,
 				instance,
-				is.session,
-				is.client,
+				is,
+				managed[awsSDK.StringValue(instance.InstanceId)],
 			)
 		}
 
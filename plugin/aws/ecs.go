@@ -0,0 +1,543 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
+	ecsClient "github.com/aws/aws-sdk-go/service/ecs"
+	ssmClient "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/kballard/go-shellquote"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// These are the most resources that the ECS Describe* APIs accept at once.
+const (
+	ecsDescribeClustersLimit = 100
+	ecsDescribeServicesLimit = 10
+	ecsDescribeTasksLimit    = 100
+)
+
+// arnResource returns the last part of the ARN's resource, which is the resource's
+// ID or name.
+func arnResource(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// batches splits the ARNs into batches of at most size ARNs.
+func batches(arns []*string, size int) [][]*string {
+	var batches [][]*string
+	for len(arns) > size {
+		batches = append(batches, arns[:size])
+		arns = arns[size:]
+	}
+	if len(arns) > 0 {
+		batches = append(batches, arns)
+	}
+	return batches
+}
+
+// ecsDir represents the regions/<region>/ecs directory
+type ecsDir struct {
+	plugin.EntryBase
+	session *session.Session
+	client  *ecsClient.ECS
+}
+
+func newECSDir(ctx context.Context, session *session.Session) *ecsDir {
+	ecsDir := &ecsDir{
+		EntryBase: plugin.NewEntry("ecs"),
+	}
+	ecsDir.session = session
+	ecsDir.client = ecsClient.New(session)
+	if _, err := plugin.List(ctx, ecsDir); err != nil {
+		ecsDir.MarkInaccessible(ctx, err)
+	}
+	return ecsDir
+}
+
+func (e *ecsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(e, "ecs").IsSingleton()
+}
+
+func (e *ecsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&ecsCluster{}).Schema(),
+	}
+}
+
+// List lists the ECS clusters.
+func (e *ecsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var arns []*string
+	err := e.client.ListClustersPagesWithContext(
+		ctx,
+		&ecsClient.ListClustersInput{},
+		func(page *ecsClient.ListClustersOutput, _ bool) bool {
+			arns = append(arns, page.ClusterArns...)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []plugin.Entry
+	for _, batch := range batches(arns, ecsDescribeClustersLimit) {
+		resp, err := e.client.DescribeClustersWithContext(ctx, &ecsClient.DescribeClustersInput{
+			Clusters: batch,
+			Include:  awsSDK.StringSlice([]string{ecsClient.ClusterFieldTags}),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range resp.Clusters {
+			entries = append(entries, newECSCluster(cluster, e))
+		}
+	}
+	activity.Record(ctx, "Listing %v ECS clusters", len(entries))
+	return entries, nil
+}
+
+// ecsCluster represents an ECS cluster
+type ecsCluster struct {
+	plugin.EntryBase
+	arn     string
+	session *session.Session
+	client  *ecsClient.ECS
+}
+
+func newECSCluster(cluster *ecsClient.Cluster, e *ecsDir) *ecsCluster {
+	ecsCluster := &ecsCluster{
+		EntryBase: plugin.NewEntry(awsSDK.StringValue(cluster.ClusterName)),
+	}
+	ecsCluster.DisableDefaultCaching()
+	ecsCluster.arn = awsSDK.StringValue(cluster.ClusterArn)
+	ecsCluster.session = e.session
+	ecsCluster.client = e.client
+	ecsCluster.SetPartialMetadata(cluster)
+	return ecsCluster
+}
+
+func (c *ecsCluster) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(c, "cluster").
+		SetDescription(ecsClusterDescription).
+		SetPartialMetadataSchema(ecsClient.Cluster{})
+}
+
+func (c *ecsCluster) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&ecsServicesDir{}).Schema(),
+		(&ecsTasksDir{}).Schema(),
+	}
+}
+
+func (c *ecsCluster) List(ctx context.Context) ([]plugin.Entry, error) {
+	return []plugin.Entry{
+		newECSServicesDir(c),
+		newECSTasksDir(c),
+	}, nil
+}
+
+// tasks returns the cluster's running tasks. If service is not empty, then it only
+// returns the service's tasks.
+func (c *ecsCluster) tasks(ctx context.Context, service string) ([]plugin.Entry, error) {
+	input := &ecsClient.ListTasksInput{
+		Cluster: awsSDK.String(c.arn),
+	}
+	if service != "" {
+		input.ServiceName = awsSDK.String(service)
+	}
+	var arns []*string
+	err := c.client.ListTasksPagesWithContext(ctx, input, func(page *ecsClient.ListTasksOutput, _ bool) bool {
+		arns = append(arns, page.TaskArns...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []plugin.Entry
+	for _, batch := range batches(arns, ecsDescribeTasksLimit) {
+		resp, err := c.client.DescribeTasksWithContext(ctx, &ecsClient.DescribeTasksInput{
+			Cluster: awsSDK.String(c.arn),
+			Tasks:   batch,
+			Include: awsSDK.StringSlice([]string{ecsClient.TaskFieldTags}),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range resp.Tasks {
+			entries = append(entries, newECSTask(task, c))
+		}
+	}
+	activity.Record(ctx, "Listing %v tasks in the %v cluster", len(entries), c.Name())
+	return entries, nil
+}
+
+// ecsServicesDir represents the <cluster>/services directory
+type ecsServicesDir struct {
+	plugin.EntryBase
+	cluster *ecsCluster
+}
+
+func newECSServicesDir(cluster *ecsCluster) *ecsServicesDir {
+	servicesDir := &ecsServicesDir{
+		EntryBase: plugin.NewEntry("services"),
+	}
+	servicesDir.cluster = cluster
+	return servicesDir
+}
+
+func (s *ecsServicesDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(s, "services").IsSingleton()
+}
+
+func (s *ecsServicesDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&ecsService{}).Schema(),
+	}
+}
+
+func (s *ecsServicesDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var arns []*string
+	err := s.cluster.client.ListServicesPagesWithContext(
+		ctx,
+		&ecsClient.ListServicesInput{Cluster: awsSDK.String(s.cluster.arn)},
+		func(page *ecsClient.ListServicesOutput, _ bool) bool {
+			arns = append(arns, page.ServiceArns...)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []plugin.Entry
+	for _, batch := range batches(arns, ecsDescribeServicesLimit) {
+		resp, err := s.cluster.client.DescribeServicesWithContext(ctx, &ecsClient.DescribeServicesInput{
+			Cluster:  awsSDK.String(s.cluster.arn),
+			Services: batch,
+			Include:  awsSDK.StringSlice([]string{ecsClient.ServiceFieldTags}),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range resp.Services {
+			entries = append(entries, newECSService(service, s.cluster))
+		}
+	}
+	activity.Record(ctx, "Listing %v services in the %v cluster", len(entries), s.cluster.Name())
+	return entries, nil
+}
+
+// ecsService represents an ECS service. It contains the service's tasks.
+type ecsService struct {
+	plugin.EntryBase
+	cluster *ecsCluster
+}
+
+func newECSService(service *ecsClient.Service, cluster *ecsCluster) *ecsService {
+	ecsService := &ecsService{
+		EntryBase: plugin.NewEntry(awsSDK.StringValue(service.ServiceName)),
+	}
+	ecsService.cluster = cluster
+	crtime := awsSDK.TimeValue(service.CreatedAt)
+	mtime := crtime
+	for _, deployment := range service.Deployments {
+		if updatedAt := awsSDK.TimeValue(deployment.UpdatedAt); updatedAt.After(mtime) {
+			mtime = updatedAt
+		}
+	}
+	ecsService.
+		SetPartialMetadata(service).
+		Attributes().
+		SetCrtime(crtime).
+		SetMtime(mtime).
+		SetCtime(mtime).
+		SetAtime(mtime)
+	return ecsService
+}
+
+func (s *ecsService) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "service").
+		SetDescription(ecsServiceDescription).
+		SetPartialMetadataSchema(ecsClient.Service{})
+}
+
+func (s *ecsService) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&ecsTask{}).Schema(),
+	}
+}
+
+func (s *ecsService) List(ctx context.Context) ([]plugin.Entry, error) {
+	return s.cluster.tasks(ctx, s.Name())
+}
+
+// ecsTasksDir represents the <cluster>/tasks directory
+type ecsTasksDir struct {
+	plugin.EntryBase
+	cluster *ecsCluster
+}
+
+func newECSTasksDir(cluster *ecsCluster) *ecsTasksDir {
+	tasksDir := &ecsTasksDir{
+		EntryBase: plugin.NewEntry("tasks"),
+	}
+	tasksDir.cluster = cluster
+	return tasksDir
+}
+
+func (t *ecsTasksDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(t, "tasks").IsSingleton()
+}
+
+func (t *ecsTasksDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&ecsTask{}).Schema(),
+	}
+}
+
+func (t *ecsTasksDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	return t.cluster.tasks(ctx, "")
+}
+
+// ecsTask represents an ECS task
+type ecsTask struct {
+	plugin.EntryBase
+	task    *ecsClient.Task
+	session *session.Session
+	client  *ecsClient.ECS
+}
+
+func newECSTask(task *ecsClient.Task, cluster *ecsCluster) *ecsTask {
+	ecsTask := &ecsTask{
+		EntryBase: plugin.NewEntry(arnResource(awsSDK.StringValue(task.TaskArn))),
+	}
+	ecsTask.task = task
+	ecsTask.session = cluster.session
+	ecsTask.client = cluster.client
+
+	// The mtime is the task's last state transition.
+	crtime := awsSDK.TimeValue(task.CreatedAt)
+	mtime := crtime
+	for _, t := range []*time.Time{task.StartedAt, task.StoppingAt, task.StoppedAt} {
+		if awsSDK.TimeValue(t).After(mtime) {
+			mtime = awsSDK.TimeValue(t)
+		}
+	}
+	ecsTask.
+		SetTTLOf(plugin.ListOp, 30*time.Second).
+		SetPartialMetadata(task).
+		Attributes().
+		SetCrtime(crtime).
+		SetMtime(mtime).
+		SetCtime(mtime).
+		SetAtime(mtime).
+		SetOS(plugin.OS{LoginShell: plugin.POSIXShell})
+	return ecsTask
+}
+
+func (t *ecsTask) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(t, "task").
+		SetDescription(ecsTaskDescription).
+		SetPartialMetadataSchema(ecsClient.Task{}).
+		AddSignal("stop", "Stops the ECS task").
+		AddSignal("restart", "Stops the ECS task and starts a new one with the same task definition")
+}
+
+func (t *ecsTask) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+	}
+}
+
+func (t *ecsTask) List(ctx context.Context) ([]plugin.Entry, error) {
+	metadataJSON, err := plugin.NewMetadataJSONFile(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	return []plugin.Entry{metadataJSON}, nil
+}
+
+// execContainer returns the first of the task's containers that ECS Exec can run
+// commands in.
+func (t *ecsTask) execContainer() (string, error) {
+	for _, container := range t.task.Containers {
+		for _, agent := range container.ManagedAgents {
+			if awsSDK.StringValue(agent.Name) == ecsClient.ManagedAgentNameExecuteCommandAgent &&
+				awsSDK.StringValue(agent.LastStatus) == "RUNNING" {
+				return awsSDK.StringValue(container.Name), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("ECS Exec is not enabled for the %v task. Run the task with enableExecuteCommand to enable it", t.Name())
+}
+
+// Exec runs the command with ECS Exec in the task's first container that supports it.
+// ECS Exec only supports interactive commands, so the command always gets a TTY.
+func (t *ecsTask) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	container, err := t.execContainer()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.ExecuteCommandWithContext(ctx, &ecsClient.ExecuteCommandInput{
+		Cluster:     t.task.ClusterArn,
+		Task:        t.task.TaskArn,
+		Container:   awsSDK.String(container),
+		Command:     awsSDK.String(shellquote.Join(append([]string{cmd}, args...)...)),
+		Interactive: awsSDK.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not exec on the %v task with ECS Exec: %v", t.Name(), err)
+	}
+	sessionID := awsSDK.StringValue(resp.Session.SessionId)
+	activity.Record(ctx, "Started SSM session %v on the %v container of task %v", sessionID, container, t.Name())
+
+	opts.Tty = true
+	return execInSSMSession(
+		ctx,
+		ssmClient.New(t.session),
+		t.Name(),
+		sessionID,
+		awsSDK.StringValue(resp.Session.StreamUrl),
+		awsSDK.StringValue(resp.Session.TokenValue),
+		opts,
+	)
+}
+
+func (t *ecsTask) Delete(ctx context.Context) (bool, error) {
+	return false, t.Signal(ctx, "stop")
+}
+
+func (t *ecsTask) Signal(ctx context.Context, signal string) error {
+	switch signal {
+	case "stop":
+		return t.stop(ctx, "Stopped by Wash")
+	case "restart":
+		// A service replaces its stopped tasks.
+		if strings.HasPrefix(awsSDK.StringValue(t.task.Group), "service:") {
+			return t.stop(ctx, "Restarted by Wash")
+		}
+		return t.restart(ctx)
+	default:
+		return fmt.Errorf("unknown signal %v", signal)
+	}
+}
+
+func (t *ecsTask) stop(ctx context.Context, reason string) error {
+	_, err := t.client.StopTaskWithContext(ctx, &ecsClient.StopTaskInput{
+		Cluster: t.task.ClusterArn,
+		Task:    t.task.TaskArn,
+		Reason:  awsSDK.String(reason),
+	})
+	return err
+}
+
+// restart runs a new task like a standalone task, then stops it.
+func (t *ecsTask) restart(ctx context.Context) error {
+	input := &ecsClient.RunTaskInput{
+		Cluster:              t.task.ClusterArn,
+		TaskDefinition:       t.task.TaskDefinitionArn,
+		Group:                t.task.Group,
+		Overrides:            t.task.Overrides,
+		EnableExecuteCommand: t.task.EnableExecuteCommand,
+	}
+	if len(t.task.Tags) > 0 {
+		input.Tags = t.task.Tags
+	}
+	if t.task.CapacityProviderName != nil {
+		input.CapacityProviderStrategy = []*ecsClient.CapacityProviderStrategyItem{
+			{CapacityProvider: t.task.CapacityProviderName},
+		}
+	} else {
+		input.LaunchType = t.task.LaunchType
+	}
+	if awsSDK.StringValue(t.task.LaunchType) == ecsClient.LaunchTypeFargate {
+		input.PlatformVersion = t.task.PlatformVersion
+	}
+	networkConfiguration, err := t.networkConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	input.NetworkConfiguration = networkConfiguration
+
+	// Run the new task first so that the task isn't stopped if it can't be replaced.
+	resp, err := t.client.RunTaskWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("could not run a task to replace the %v task: %v", t.Name(), err)
+	}
+	if len(resp.Failures) > 0 {
+		return fmt.Errorf("could not run a task to replace the %v task: %v", t.Name(), awsSDK.StringValue(resp.Failures[0].Reason))
+	}
+	for _, task := range resp.Tasks {
+		activity.Record(ctx, "Replacing %v with %v", t.Name(), arnResource(awsSDK.StringValue(task.TaskArn)))
+	}
+	return t.stop(ctx, "Restarted by Wash")
+}
+
+// networkConfiguration returns the network configuration of a task that uses the awsvpc
+// network mode, which is needed to run a task like it. It returns nil for the other
+// network modes.
+func (t *ecsTask) networkConfiguration(ctx context.Context) (*ecsClient.NetworkConfiguration, error) {
+	for _, attachment := range t.task.Attachments {
+		if awsSDK.StringValue(attachment.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		details := make(map[string]string)
+		for _, detail := range attachment.Details {
+			details[awsSDK.StringValue(detail.Name)] = awsSDK.StringValue(detail.Value)
+		}
+
+		// The security groups and public IP are only described by the network interface.
+		resp, err := ec2Client.New(t.session).DescribeNetworkInterfacesWithContext(ctx, &ec2Client.DescribeNetworkInterfacesInput{
+			NetworkInterfaceIds: awsSDK.StringSlice([]string{details["networkInterfaceId"]}),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not get the %v task's network configuration: %v", t.Name(), err)
+		}
+		if len(resp.NetworkInterfaces) == 0 {
+			return nil, fmt.Errorf("could not get the %v task's network configuration: its network interface was deleted", t.Name())
+		}
+		networkInterface := resp.NetworkInterfaces[0]
+		config := &ecsClient.AwsVpcConfiguration{
+			Subnets:        awsSDK.StringSlice([]string{details["subnetId"]}),
+			AssignPublicIp: awsSDK.String(ecsClient.AssignPublicIpDisabled),
+		}
+		for _, group := range networkInterface.Groups {
+			config.SecurityGroups = append(config.SecurityGroups, group.GroupId)
+		}
+		if networkInterface.Association != nil && networkInterface.Association.PublicIp != nil {
+			config.AssignPublicIp = awsSDK.String(ecsClient.AssignPublicIpEnabled)
+		}
+		return &ecsClient.NetworkConfiguration{AwsvpcConfiguration: config}, nil
+	}
+	return nil, nil
+}
+
+const ecsClusterDescription = `
+This is an ECS cluster. It contains its services and its running tasks.
+`
+
+const ecsServiceDescription = `
+This is an ECS service. It contains the service's running tasks.
+`
+
+const ecsTaskDescription = `
+This is an ECS task. Its Exec action uses ECS Exec, which runs commands in the
+task's first container that has the ECS Exec agent. The task must be run with
+enableExecuteCommand. ECS Exec only supports interactive commands, so commands
+always run with a TTY and their stderr is merged into their stdout.
+
+The restart signal stops the task. If the task belongs to a service, then the
+service replaces it. Otherwise, it's replaced by a new task with the same task
+definition, launch type, overrides and network configuration.
+`
@@ -0,0 +1,235 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	ecsClient "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecsTarget = "AmazonEC2ContainerServiceV20141113."
+
+// jsonBodies records the bodies of the JSON protocol requests that a recordingServer
+// receives, keyed by their target.
+type jsonBodies struct {
+	mux    sync.Mutex
+	bodies map[string][]map[string]interface{}
+}
+
+func (b *jsonBodies) record(r *http.Request) map[string]interface{} {
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.bodies == nil {
+		b.bodies = make(map[string][]map[string]interface{})
+	}
+	target := r.Header.Get("X-Amz-Target")
+	b.bodies[target] = append(b.bodies[target], body)
+	return body
+}
+
+func (b *jsonBodies) of(target string) []map[string]interface{} {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.bodies[target]
+}
+
+func TestBatches(t *testing.T) {
+	arns := awsSDK.StringSlice([]string{"a", "b", "c", "d", "e"})
+	assert.Equal(t, [][]*string{arns[:2], arns[2:4], arns[4:]}, batches(arns, 2))
+	assert.Equal(t, [][]*string{arns}, batches(arns, 5))
+	assert.Empty(t, batches(nil, 2))
+}
+
+func TestECSDir_DescribesClustersInBatches(t *testing.T) {
+	var bodies jsonBodies
+	server, targets := recordingServer(func(w http.ResponseWriter, r *http.Request) {
+		body := bodies.record(r)
+		switch r.Header.Get("X-Amz-Target") {
+		case ecsTarget + "ListClusters":
+			var arns []string
+			for i := 0; i < 150; i++ {
+				arns = append(arns, fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:cluster/c%v", i))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusterArns": arns})
+		case ecsTarget + "DescribeClusters":
+			var clusters []map[string]interface{}
+			for _, arn := range body["clusters"].([]interface{}) {
+				clusters = append(clusters, map[string]interface{}{
+					"clusterArn":  arn,
+					"clusterName": arnResource(arn.(string)),
+				})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusters": clusters})
+		default:
+			http.Error(w, `{"__type": "InvalidParameterException"}`, http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	ecs := newECSDir(ctx, newTestSession(t, server.URL))
+	ecs.SetTestID("/aws/default/regions/us-east-1/ecs")
+	clusters, err := ecs.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, clusters, 150)
+	assert.Equal(t, "c0", plugin.Name(clusters[0]))
+	assert.Equal(t, "c149", plugin.Name(clusters[149]))
+
+	assert.Contains(t, targets(), ecsTarget+"DescribeClusters")
+	var sizes []int
+	for _, body := range bodies.of(ecsTarget + "DescribeClusters") {
+		sizes = append(sizes, len(body["clusters"].([]interface{})))
+	}
+	// newECSDir lists the clusters once, then they're listed again.
+	assert.Equal(t, []int{100, 50, 100, 50}, sizes)
+}
+
+func newTestECSTask(t *testing.T, url string, task *ecsClient.Task) *ecsTask {
+	e := &ecsDir{}
+	e.session = newTestSession(t, url)
+	e.client = ecsClient.New(e.session)
+	cluster := newECSCluster(&ecsClient.Cluster{
+		ClusterArn:  task.ClusterArn,
+		ClusterName: awsSDK.String("cluster"),
+	}, e)
+	return newECSTask(task, cluster)
+}
+
+func awsvpcTask(group string) *ecsClient.Task {
+	return &ecsClient.Task{
+		ClusterArn:        awsSDK.String("arn:aws:ecs:us-east-1:123456789012:cluster/cluster"),
+		TaskArn:           awsSDK.String("arn:aws:ecs:us-east-1:123456789012:task/cluster/old"),
+		TaskDefinitionArn: awsSDK.String("arn:aws:ecs:us-east-1:123456789012:task-definition/web:3"),
+		Group:             awsSDK.String(group),
+		LaunchType:        awsSDK.String(ecsClient.LaunchTypeFargate),
+		PlatformVersion:   awsSDK.String("1.4.0"),
+		Attachments: []*ecsClient.Attachment{{
+			Type: awsSDK.String("ElasticNetworkInterface"),
+			Details: []*ecsClient.KeyValuePair{
+				{Name: awsSDK.String("subnetId"), Value: awsSDK.String("subnet-1")},
+				{Name: awsSDK.String("networkInterfaceId"), Value: awsSDK.String("eni-1")},
+			},
+		}},
+	}
+}
+
+// restartServer responds to the requests that restarting a task makes. RunTask fails
+// if runTaskFailure isn't empty.
+func restartServer(bodies *jsonBodies, runTaskFailure string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") == "" {
+			// EC2's query protocol
+			if r.Form.Get("Action") != "DescribeNetworkInterfaces" || r.Form.Get("NetworkInterfaceId.1") != "eni-1" {
+				http.Error(w, "<Response><Errors><Error><Code>InvalidAction</Code></Error></Errors></Response>", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `<DescribeNetworkInterfacesResponse><networkInterfaceSet><item>
+<networkInterfaceId>eni-1</networkInterfaceId>
+<groupSet><item><groupId>sg-1</groupId></item><item><groupId>sg-2</groupId></item></groupSet>
+<association><publicIp>203.0.113.1</publicIp></association>
+</item></networkInterfaceSet></DescribeNetworkInterfacesResponse>`)
+			return
+		}
+		bodies.record(r)
+		switch r.Header.Get("X-Amz-Target") {
+		case ecsTarget + "RunTask":
+			if runTaskFailure != "" {
+				fmt.Fprintf(w, `{"failures": [{"reason": %q}]}`, runTaskFailure)
+				return
+			}
+			fmt.Fprint(w, `{"tasks": [{"taskArn": "arn:aws:ecs:us-east-1:123456789012:task/cluster/new"}]}`)
+		case ecsTarget + "StopTask":
+			fmt.Fprint(w, `{}`)
+		default:
+			http.Error(w, `{"__type": "InvalidParameterException"}`, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestECSTask_RestartStandalone(t *testing.T) {
+	var bodies jsonBodies
+	server, targets := recordingServer(restartServer(&bodies, ""))
+	defer server.Close()
+
+	task := newTestECSTask(t, server.URL, awsvpcTask("family:web"))
+	require.NoError(t, task.Signal(context.Background(), "restart"))
+	assert.Equal(t, []string{"DescribeNetworkInterfaces", ecsTarget + "RunTask", ecsTarget + "StopTask"}, targets())
+
+	runTask := bodies.of(ecsTarget + "RunTask")
+	require.Len(t, runTask, 1)
+	assert.Equal(t, "family:web", runTask[0]["group"])
+	assert.Equal(t, "FARGATE", runTask[0]["launchType"])
+	assert.Equal(t, "1.4.0", runTask[0]["platformVersion"])
+	assert.Equal(t, map[string]interface{}{
+		"awsvpcConfiguration": map[string]interface{}{
+			"subnets":        []interface{}{"subnet-1"},
+			"securityGroups": []interface{}{"sg-1", "sg-2"},
+			"assignPublicIp": "ENABLED",
+		},
+	}, runTask[0]["networkConfiguration"])
+
+	stopTask := bodies.of(ecsTarget + "StopTask")
+	require.Len(t, stopTask, 1)
+	assert.Equal(t, "arn:aws:ecs:us-east-1:123456789012:task/cluster/old", stopTask[0]["task"])
+}
+
+func TestECSTask_RestartStandalone_KeepsTaskIfItCannotBeReplaced(t *testing.T) {
+	var bodies jsonBodies
+	server, targets := recordingServer(restartServer(&bodies, "RESOURCE:MEMORY"))
+	defer server.Close()
+
+	task := newTestECSTask(t, server.URL, awsvpcTask("family:web"))
+	err := task.Signal(context.Background(), "restart")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "RESOURCE:MEMORY")
+	}
+	assert.NotContains(t, targets(), ecsTarget+"StopTask")
+}
+
+func TestECSTask_RestartServiceTask(t *testing.T) {
+	var bodies jsonBodies
+	server, targets := recordingServer(restartServer(&bodies, ""))
+	defer server.Close()
+
+	task := newTestECSTask(t, server.URL, awsvpcTask("service:web"))
+	require.NoError(t, task.Signal(context.Background(), "restart"))
+	// The service replaces the task.
+	assert.Equal(t, []string{ecsTarget + "StopTask"}, targets())
+	assert.Equal(t, "Restarted by Wash", bodies.of(ecsTarget + "StopTask")[0]["reason"])
+}
+
+func TestECSTask_ExecContainer(t *testing.T) {
+	agent := func(name, status string) *ecsClient.ManagedAgent {
+		return &ecsClient.ManagedAgent{Name: awsSDK.String(name), LastStatus: awsSDK.String(status)}
+	}
+	task := newTestECSTask(t, "http://localhost", &ecsClient.Task{
+		TaskArn: awsSDK.String("arn:aws:ecs:us-east-1:123456789012:task/cluster/abc"),
+		Containers: []*ecsClient.Container{
+			{Name: awsSDK.String("sidecar")},
+			{Name: awsSDK.String("pending"), ManagedAgents: []*ecsClient.ManagedAgent{agent(ecsClient.ManagedAgentNameExecuteCommandAgent, "PENDING")}},
+			{Name: awsSDK.String("app"), ManagedAgents: []*ecsClient.ManagedAgent{agent(ecsClient.ManagedAgentNameExecuteCommandAgent, "RUNNING")}},
+			{Name: awsSDK.String("other"), ManagedAgents: []*ecsClient.ManagedAgent{agent(ecsClient.ManagedAgentNameExecuteCommandAgent, "RUNNING")}},
+		},
+	})
+	container, err := task.execContainer()
+	require.NoError(t, err)
+	assert.Equal(t, "app", container)
+
+	task.task.Containers = task.task.Containers[:2]
+	_, err = task.execContainer()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ECS Exec is not enabled for the abc task")
+	}
+}
@@ -0,0 +1,38 @@
+package aws
+
+import (
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// defaultEndpoint is the endpoints key whose URL is used for the services that don't
+// have their own endpoint, like an emulator that serves every service.
+const defaultEndpoint = "default"
+
+// sdkConfig returns the SDK config that profiles' sessions are created with. Services
+// whose endpoints are configured are sent to those endpoints instead of AWS, which is
+// useful for emulators like LocalStack and MinIO.
+func (c config) sdkConfig() *awsSDK.Config {
+	cfg := awsSDK.NewConfig()
+	if c.S3ForcePathStyle {
+		cfg = cfg.WithS3ForcePathStyle(true)
+	}
+	if len(c.Endpoints) == 0 {
+		return cfg
+	}
+
+	resolver := endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		url, ok := c.Endpoints[service]
+		if !ok {
+			url, ok = c.Endpoints[defaultEndpoint]
+		}
+		if !ok {
+			return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+		}
+		return endpoints.ResolvedEndpoint{
+			URL:           url,
+			SigningRegion: region,
+		}, nil
+	})
+	return cfg.WithEndpointResolver(resolver)
+}
@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a stand-in for S3 that serves a bucket with one object using path-style
+// addressing. It records the paths of the requests it receives.
+func fakeS3(content string) (*httptest.Server, func() []string) {
+	var mux sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		paths = append(paths, r.URL.Path)
+		mux.Unlock()
+
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprint(w, `<ListAllMyBucketsResult><Buckets><Bucket><Name>bucket</Name><CreationDate>2020-01-01T00:00:00.000Z</CreationDate></Bucket></Buckets></ListAllMyBucketsResult>`)
+		case r.URL.Path == "/bucket" && query["location"] != nil:
+			fmt.Fprint(w, `<LocationConstraint>us-west-2</LocationConstraint>`)
+		case r.URL.Path == "/bucket" && query["versioning"] != nil:
+			fmt.Fprint(w, `<VersioningConfiguration></VersioningConfiguration>`)
+		case r.URL.Path == "/bucket":
+			fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><Contents><Key>hello.txt</Key><Size>%v</Size><LastModified>2020-01-01T00:00:00.000Z</LastModified></Contents></ListBucketResult>`, len(content))
+		case r.URL.Path == "/bucket/hello.txt" && r.Method == http.MethodGet:
+			var start, end int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %v-%v/%v", start, end, len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, content[start:end+1])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]string{}, paths...)
+	}
+}
+
+// setenv sets the environment variables and returns a function that restores them.
+func setenv(t *testing.T, vars map[string]string) func() {
+	old := make(map[string]*string)
+	for name, value := range vars {
+		if v, ok := os.LookupEnv(name); ok {
+			old[name] = &v
+		} else {
+			old[name] = nil
+		}
+		require.NoError(t, os.Setenv(name, value))
+	}
+	return func() {
+		for name, value := range old {
+			if value == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *value)
+			}
+		}
+	}
+}
+
+func TestEndpointsSendServicesToEmulators(t *testing.T) {
+	server, requests := fakeS3("hello world")
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "wash-aws")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	credentials := filepath.Join(dir, "credentials")
+	require.NoError(t, ioutil.WriteFile(credentials, []byte("[emulator]\naws_access_key_id = AKID\naws_secret_access_key = SECRET\nregion = us-east-1\n"), 0600))
+	defer setenv(t, map[string]string{
+		"AWS_SHARED_CREDENTIALS_FILE": credentials,
+		"AWS_CONFIG_FILE":             filepath.Join(dir, "config"),
+		"XDG_CACHE_HOME":              dir,
+	})()
+
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	root := &Root{}
+	require.NoError(t, root.Init(map[string]interface{}{
+		"profiles":            []interface{}{"emulator"},
+		"endpoints":           map[string]interface{}{"s3": server.URL},
+		"s3_force_path_style": true,
+	}))
+	root.SetTestID("/aws")
+
+	entry := plugin.Entry(root)
+	for _, name := range []string{"emulator", "resources", "s3", "bucket", "hello.txt"} {
+		entries, err := plugin.List(ctx, entry.(plugin.Parent))
+		require.NoError(t, err)
+		child, ok := entries.Load(name)
+		require.True(t, ok, "%v was not listed", name)
+		entry = child
+	}
+
+	data, err := plugin.Read(ctx, entry, 5, 6)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+
+	// Path-style requests name the bucket in the path, even from the bucket's region.
+	for _, path := range requests() {
+		assert.True(t, path == "/" || strings.HasPrefix(path, "/bucket"), "unexpected request for %v", path)
+	}
+	assert.Contains(t, requests(), "/bucket/hello.txt")
+}
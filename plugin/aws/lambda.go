@@ -0,0 +1,361 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	logsClient "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	lambdaClient "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// lambdaTimeLayout is the layout of a Lambda function's LastModified timestamp.
+const lambdaTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// lambdaDir represents the regions/<region>/lambda directory
+type lambdaDir struct {
+	plugin.EntryBase
+	client    *lambdaClient.Lambda
+	logs      *logsClient.CloudWatchLogs
+	responses *lambdaResponses
+}
+
+func newLambdaDir(ctx context.Context, session *session.Session, responses *lambdaResponses) *lambdaDir {
+	lambdaDir := &lambdaDir{
+		EntryBase: plugin.NewEntry("lambda"),
+	}
+	lambdaDir.client = lambdaClient.New(session)
+	lambdaDir.logs = logsClient.New(session)
+	lambdaDir.responses = responses
+	if _, err := plugin.List(ctx, lambdaDir); err != nil {
+		lambdaDir.MarkInaccessible(ctx, err)
+	}
+	return lambdaDir
+}
+
+func (l *lambdaDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(l, "lambda").IsSingleton()
+}
+
+func (l *lambdaDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&lambdaFunction{}).Schema(),
+	}
+}
+
+// List lists the Lambda functions.
+func (l *lambdaDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	err := l.client.ListFunctionsPagesWithContext(
+		ctx,
+		&lambdaClient.ListFunctionsInput{},
+		func(page *lambdaClient.ListFunctionsOutput, _ bool) bool {
+			for _, fn := range page.Functions {
+				entries = append(entries, newLambdaFunction(fn, l))
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v Lambda functions", len(entries))
+	return entries, nil
+}
+
+// lambdaFunction represents a Lambda function.
+type lambdaFunction struct {
+	plugin.EntryBase
+	config    *lambdaClient.FunctionConfiguration
+	client    *lambdaClient.Lambda
+	logs      *logsClient.CloudWatchLogs
+	responses *lambdaResponses
+}
+
+func newLambdaFunction(config *lambdaClient.FunctionConfiguration, l *lambdaDir) *lambdaFunction {
+	fn := &lambdaFunction{
+		EntryBase: plugin.NewEntry(awsSDK.StringValue(config.FunctionName)),
+	}
+	fn.config = config
+	fn.client = l.client
+	fn.logs = l.logs
+	fn.responses = l.responses
+
+	fn.SetPartialMetadata(config)
+	if mtime, err := time.Parse(lambdaTimeLayout, awsSDK.StringValue(config.LastModified)); err == nil {
+		fn.
+			Attributes().
+			SetMtime(mtime).
+			SetCtime(mtime).
+			SetAtime(mtime)
+	}
+	return fn
+}
+
+// lambdaFunctionMetadata is a Lambda function's full metadata
+type lambdaFunctionMetadata struct {
+	*lambdaClient.FunctionConfiguration
+	Tags                         map[string]*string
+	ReservedConcurrentExecutions *int64
+}
+
+func (fn *lambdaFunction) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(fn, "function").
+		SetDescription(lambdaFunctionDescription).
+		SetPartialMetadataSchema(lambdaClient.FunctionConfiguration{}).
+		SetMetadataSchema(lambdaFunctionMetadata{})
+}
+
+func (fn *lambdaFunction) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+		(&lambdaFunctionConfiguration{}).Schema(),
+		(&lambdaFunctionCode{}).Schema(),
+		(&lambdaFunctionInvoke{}).Schema(),
+		(&logGroup{}).Schema(),
+	}
+}
+
+// Metadata returns the function's configuration, tags and reserved concurrency.
+func (fn *lambdaFunction) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	resp, err := fn.client.GetFunctionWithContext(ctx, &lambdaClient.GetFunctionInput{
+		FunctionName: fn.config.FunctionArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	metadata := lambdaFunctionMetadata{
+		FunctionConfiguration: resp.Configuration,
+		Tags:                  resp.Tags,
+	}
+	if resp.Concurrency != nil {
+		metadata.ReservedConcurrentExecutions = resp.Concurrency.ReservedConcurrentExecutions
+	}
+	return plugin.ToJSONObject(metadata), nil
+}
+
+func (fn *lambdaFunction) List(ctx context.Context) ([]plugin.Entry, error) {
+	metadataJSON, err := plugin.NewMetadataJSONFile(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	entries := []plugin.Entry{
+		metadataJSON,
+		newLambdaFunctionConfiguration(fn),
+		newLambdaFunctionInvoke(fn),
+	}
+	// Functions that are deployed as container images don't have a zip file.
+	if awsSDK.StringValue(fn.config.PackageType) != lambdaClient.PackageTypeImage {
+		entries = append(entries, newLambdaFunctionCode(fn))
+	}
+
+	// The function's log group is created the first time that it's invoked.
+	groupName := "/aws/lambda/" + awsSDK.StringValue(fn.config.FunctionName)
+	resp, err := fn.logs.DescribeLogGroupsWithContext(ctx, &logsClient.DescribeLogGroupsInput{
+		LogGroupNamePrefix: awsSDK.String(groupName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not find the log group of the %v function: %v", fn.Name(), err)
+	}
+	for _, group := range resp.LogGroups {
+		if awsSDK.StringValue(group.LogGroupName) == groupName {
+			entries = append(entries, newLogGroup("logs", group, fn.logs))
+			break
+		}
+	}
+	return entries, nil
+}
+
+// lambdaFunctionConfiguration represents a Lambda function's configuration.json file
+type lambdaFunctionConfiguration struct {
+	plugin.EntryBase
+	fn *lambdaFunction
+}
+
+func newLambdaFunctionConfiguration(fn *lambdaFunction) *lambdaFunctionConfiguration {
+	cfg := &lambdaFunctionConfiguration{
+		EntryBase: plugin.NewEntry("configuration.json"),
+	}
+	cfg.fn = fn
+	return cfg
+}
+
+func (cfg *lambdaFunctionConfiguration) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(cfg, "configuration.json").
+		IsSingleton()
+}
+
+func (cfg *lambdaFunctionConfiguration) Read(ctx context.Context) ([]byte, error) {
+	resp, err := cfg.fn.client.GetFunctionConfigurationWithContext(ctx, &lambdaClient.GetFunctionConfigurationInput{
+		FunctionName: cfg.fn.config.FunctionArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(resp, "", "  ")
+}
+
+// lambdaFunctionCode represents a Lambda function's deployment package.
+type lambdaFunctionCode struct {
+	plugin.EntryBase
+	fn *lambdaFunction
+}
+
+func newLambdaFunctionCode(fn *lambdaFunction) *lambdaFunctionCode {
+	code := &lambdaFunctionCode{
+		EntryBase: plugin.NewEntry("code.zip"),
+	}
+	code.fn = fn
+	code.
+		Attributes().
+		SetMtime(fn.Attributes().Mtime()).
+		SetSize(uint64(awsSDK.Int64Value(fn.config.CodeSize)))
+	return code
+}
+
+func (code *lambdaFunctionCode) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(code, "code.zip").
+		IsSingleton()
+}
+
+// Read downloads the deployment package from the presigned URL that GetFunction returns.
+func (code *lambdaFunctionCode) Read(ctx context.Context) ([]byte, error) {
+	resp, err := code.fn.client.GetFunctionWithContext(ctx, &lambdaClient.GetFunctionInput{
+		FunctionName: code.fn.config.FunctionArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code == nil || resp.Code.Location == nil {
+		return nil, fmt.Errorf("the %v function's code cannot be downloaded", code.fn.Name())
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, awsSDK.StringValue(resp.Code.Location), nil)
+	if err != nil {
+		return nil, err
+	}
+	download, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not download the %v function's code: %v", code.fn.Name(), err)
+	}
+	defer download.Body.Close()
+	if download.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download the %v function's code: %v", code.fn.Name(), download.Status)
+	}
+	return ioutil.ReadAll(download.Body)
+}
+
+// maxLambdaResponses is the number of functions whose last response is kept.
+const maxLambdaResponses = 100
+
+// lambdaResponses stores the last response of each function that was invoked, keyed by
+// the function's ARN. The root keeps it because entries are recreated whenever their
+// parent is listed. Only the responses of the most recently invoked functions are kept.
+type lambdaResponses struct {
+	mux       sync.Mutex
+	responses map[string][]byte
+	// arns are the ARNs of the functions, from the least to the most recently invoked
+	arns []string
+}
+
+func newLambdaResponses() *lambdaResponses {
+	return &lambdaResponses{responses: make(map[string][]byte)}
+}
+
+func (l *lambdaResponses) load(arn string) []byte {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.responses[arn]
+}
+
+func (l *lambdaResponses) store(arn string, response []byte) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for i, a := range l.arns {
+		if a == arn {
+			l.arns = append(l.arns[:i], l.arns[i+1:]...)
+			break
+		}
+	}
+	l.arns = append(l.arns, arn)
+	l.responses[arn] = response
+	if len(l.arns) > maxLambdaResponses {
+		delete(l.responses, l.arns[0])
+		l.arns = l.arns[1:]
+	}
+}
+
+// lambdaFunctionInvoke represents a Lambda function's invoke file. Writing to it invokes
+// the function and reading it returns the function's last response.
+type lambdaFunctionInvoke struct {
+	plugin.EntryBase
+	fn *lambdaFunction
+}
+
+func newLambdaFunctionInvoke(fn *lambdaFunction) *lambdaFunctionInvoke {
+	invoke := &lambdaFunctionInvoke{
+		EntryBase: plugin.NewEntry("invoke"),
+	}
+	invoke.fn = fn
+	invoke.DisableCachingFor(plugin.ReadOp)
+	return invoke
+}
+
+func (invoke *lambdaFunctionInvoke) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(invoke, "invoke").
+		SetDescription(lambdaFunctionInvokeDescription).
+		IsSingleton()
+}
+
+// Read returns the response of the last invocation that was made through Wash.
+func (invoke *lambdaFunctionInvoke) Read(ctx context.Context) ([]byte, error) {
+	return invoke.fn.responses.load(awsSDK.StringValue(invoke.fn.config.FunctionArn)), nil
+}
+
+// Write synchronously invokes the function with the payload and stores its response.
+func (invoke *lambdaFunctionInvoke) Write(ctx context.Context, payload []byte) error {
+	resp, err := invoke.fn.client.InvokeWithContext(ctx, &lambdaClient.InvokeInput{
+		FunctionName:   invoke.fn.config.FunctionArn,
+		InvocationType: awsSDK.String(lambdaClient.InvocationTypeRequestResponse),
+		Payload:        payload,
+	})
+	if err != nil {
+		return err
+	}
+	activity.Record(ctx, "Invoked %v, which executed version %v", invoke.fn.Name(), awsSDK.StringValue(resp.ExecutedVersion))
+
+	invoke.fn.responses.store(awsSDK.StringValue(invoke.fn.config.FunctionArn), resp.Payload)
+	if resp.FunctionError != nil {
+		return fmt.Errorf("the %v function returned an error (%v). Read its invoke file for details", invoke.fn.Name(), awsSDK.StringValue(resp.FunctionError))
+	}
+	return nil
+}
+
+const lambdaFunctionDescription = `
+This is a Lambda function. Its metadata includes its configuration, tags and
+reserved concurrency. It contains its configuration, its deployment package as
+code.zip (unless it's deployed as a container image), an invoke file, and its
+CloudWatch Logs log group once it's been invoked.
+`
+
+const lambdaFunctionInvokeDescription = `
+This is a Lambda function's invoke file. Writing a JSON payload to it invokes
+the function synchronously, and reading it returns the response of the last
+invocation that was made through Wash. Writes fail if the function returns an
+error; the error's details are in the response. For example
+
+echo '{"key": "value"}' > invoke
+cat invoke
+`
@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testFunctionArn      = "arn:aws:lambda:us-east-1:123456789012:function:hello"
+	testImageFunctionArn = "arn:aws:lambda:us-east-1:123456789012:function:image"
+)
+
+// fakeLambda is a stand-in for Lambda with a zip-packaged hello function and an
+// image-packaged image function. The hello function echoes its payload, unless it's
+// "fail". Its code is served at /code.zip.
+func fakeLambda() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Amz-Target") == "Logs_20140328.DescribeLogGroups":
+			fmt.Fprint(w, `{"logGroups": [{"logGroupName": "/aws/lambda/hello-world"}, {"logGroupName": "/aws/lambda/hello"}]}`)
+		case r.URL.Path == "/2015-03-31/functions/":
+			fmt.Fprintf(w, `{"Functions": [
+{"FunctionName": "hello", "FunctionArn": %q, "PackageType": "Zip", "CodeSize": 9, "LastModified": "2020-01-01T00:00:00.000+0000"},
+{"FunctionName": "image", "FunctionArn": %q, "PackageType": "Image"}
+]}`, testFunctionArn, testImageFunctionArn)
+		case r.URL.Path == "/2015-03-31/functions/"+testFunctionArn:
+			fmt.Fprintf(w, `{"Configuration": {"FunctionName": "hello"}, "Code": {"Location": "%v/code.zip"}}`, server.URL)
+		case r.URL.Path == "/2015-03-31/functions/"+testFunctionArn+"/invocations":
+			payload, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("X-Amz-Executed-Version", "$LATEST")
+			if string(payload) == `"fail"` {
+				w.Header().Set("X-Amz-Function-Error", "Unhandled")
+				fmt.Fprint(w, `{"errorMessage": "failed"}`)
+				return
+			}
+			_, _ = w.Write(payload)
+		case r.URL.Path == "/code.zip":
+			fmt.Fprint(w, "zip bytes")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+// lambdaFunctions returns the functions of the fake Lambda, keyed by their name.
+func lambdaFunctions(ctx context.Context, t *testing.T, url string, responses *lambdaResponses) map[string]*lambdaFunction {
+	lambda := newLambdaDir(ctx, newTestSession(t, url), responses)
+	lambda.SetTestID("/aws/default/regions/us-east-1/lambda")
+	entries, err := lambda.List(ctx)
+	require.NoError(t, err)
+	functions := make(map[string]*lambdaFunction)
+	for _, entry := range entries {
+		functions[plugin.Name(entry)] = entry.(*lambdaFunction)
+	}
+	return functions
+}
+
+func TestLambdaFunction_List(t *testing.T) {
+	server := fakeLambda()
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	functions := lambdaFunctions(ctx, t, server.URL, newLambdaResponses())
+	require.Len(t, functions, 2)
+
+	entries, err := functions["hello"].List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metadata.json", "configuration.json", "invoke", "code.zip", "logs"}, regionNames(entries))
+
+	// Functions that are deployed as container images don't have a zip file. The image
+	// function hasn't been invoked, so it doesn't have a log group either.
+	entries, err = functions["image"].List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metadata.json", "configuration.json", "invoke"}, regionNames(entries))
+}
+
+func TestLambdaFunctionCode_Read(t *testing.T) {
+	server := fakeLambda()
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	code := newLambdaFunctionCode(lambdaFunctions(ctx, t, server.URL, newLambdaResponses())["hello"])
+	assert.Equal(t, uint64(9), code.Attributes().Size())
+	content, err := code.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "zip bytes", string(content))
+}
+
+func TestLambdaFunctionInvoke_WriteThenRead(t *testing.T) {
+	server := fakeLambda()
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	responses := newLambdaResponses()
+	invoke := newLambdaFunctionInvoke(lambdaFunctions(ctx, t, server.URL, responses)["hello"])
+	content, err := invoke.Read(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+
+	require.NoError(t, invoke.Write(ctx, []byte(`{"key": "value"}`)))
+	content, err = invoke.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"key": "value"}`, string(content))
+
+	// The response outlives the entries, which are recreated whenever they're listed.
+	invoke = newLambdaFunctionInvoke(lambdaFunctions(ctx, t, server.URL, responses)["hello"])
+	content, err = invoke.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"key": "value"}`, string(content))
+
+	// Other roots don't share it.
+	invoke = newLambdaFunctionInvoke(lambdaFunctions(ctx, t, server.URL, newLambdaResponses())["hello"])
+	content, err = invoke.Read(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestLambdaFunctionInvoke_FunctionError(t *testing.T) {
+	server := fakeLambda()
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	invoke := newLambdaFunctionInvoke(lambdaFunctions(ctx, t, server.URL, newLambdaResponses())["hello"])
+	err := invoke.Write(ctx, []byte(`"fail"`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the hello function returned an error (Unhandled)")
+	}
+	// The error's details are in the response.
+	content, err := invoke.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"errorMessage": "failed"}`, string(content))
+}
+
+func TestLambdaResponses_KeepsMostRecentlyInvoked(t *testing.T) {
+	responses := newLambdaResponses()
+	arn := func(i int) string {
+		return fmt.Sprintf("arn:aws:lambda:us-east-1:123456789012:function:fn%v", i)
+	}
+	for i := 0; i < maxLambdaResponses; i++ {
+		responses.store(arn(i), []byte(arn(i)))
+	}
+	// Invoking fn0 again makes fn1 the least recently invoked function.
+	responses.store(arn(0), []byte("again"))
+	responses.store(arn(maxLambdaResponses), []byte("new"))
+
+	assert.Len(t, responses.responses, maxLambdaResponses)
+	assert.Equal(t, "again", string(responses.load(arn(0))))
+	assert.Nil(t, responses.load(arn(1)))
+	assert.Equal(t, "new", string(responses.load(arn(maxLambdaResponses))))
+	assert.Equal(t, arn(2), string(responses.load(arn(2))))
+}
@@ -0,0 +1,275 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	logsClient "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// maxLogStreams is the number of log streams that are listed in a log group. Groups
+// can have lots of streams, so only the most recently written ones are listed.
+const maxLogStreams = 1000
+
+// logsPollInterval is how often a streamed log stream is checked for new events.
+var logsPollInterval = 2 * time.Second
+
+// msToTime converts the milliseconds since the epoch that CloudWatch Logs uses to a time.
+func msToTime(ms *int64) time.Time {
+	return time.Unix(0, awsSDK.Int64Value(ms)*int64(time.Millisecond))
+}
+
+// logsDir represents the regions/<region>/logs directory
+type logsDir struct {
+	plugin.EntryBase
+	client *logsClient.CloudWatchLogs
+}
+
+func newLogsDir(ctx context.Context, session *session.Session) *logsDir {
+	logsDir := &logsDir{
+		EntryBase: plugin.NewEntry("logs"),
+	}
+	logsDir.client = logsClient.New(session)
+	if _, err := plugin.List(ctx, logsDir); err != nil {
+		logsDir.MarkInaccessible(ctx, err)
+	}
+	return logsDir
+}
+
+func (l *logsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(l, "logs").IsSingleton()
+}
+
+func (l *logsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&logGroup{}).Schema(),
+	}
+}
+
+// List lists the log groups.
+func (l *logsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	err := l.client.DescribeLogGroupsPagesWithContext(
+		ctx,
+		&logsClient.DescribeLogGroupsInput{},
+		func(page *logsClient.DescribeLogGroupsOutput, _ bool) bool {
+			for _, group := range page.LogGroups {
+				entries = append(entries, newLogGroup(awsSDK.StringValue(group.LogGroupName), group, l.client))
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v log groups", len(entries))
+	return entries, nil
+}
+
+// logGroup represents a CloudWatch Logs log group. Group names often contain
+// slashes, like /aws/lambda/<function>.
+type logGroup struct {
+	plugin.EntryBase
+	group  string
+	client *logsClient.CloudWatchLogs
+}
+
+// newLogGroup returns the log group as an entry with the given name. Other resources
+// use it to include their log group, like a Lambda function's logs.
+func newLogGroup(name string, group *logsClient.LogGroup, client *logsClient.CloudWatchLogs) *logGroup {
+	logGroup := &logGroup{
+		EntryBase: plugin.NewEntry(name),
+	}
+	logGroup.group = awsSDK.StringValue(group.LogGroupName)
+	logGroup.client = client
+	crtime := msToTime(group.CreationTime)
+	logGroup.
+		SetPartialMetadata(group).
+		Attributes().
+		SetCrtime(crtime).
+		SetMtime(crtime).
+		SetCtime(crtime).
+		SetAtime(crtime)
+	return logGroup
+}
+
+func (g *logGroup) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(g, "group").
+		SetDescription(logGroupDescription).
+		SetPartialMetadataSchema(logsClient.LogGroup{})
+}
+
+func (g *logGroup) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&logStream{}).Schema(),
+	}
+}
+
+// List lists the group's most recently written log streams.
+func (g *logGroup) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	err := g.client.DescribeLogStreamsPagesWithContext(
+		ctx,
+		&logsClient.DescribeLogStreamsInput{
+			LogGroupName: awsSDK.String(g.group),
+			OrderBy:      awsSDK.String(logsClient.OrderByLastEventTime),
+			Descending:   awsSDK.Bool(true),
+		},
+		func(page *logsClient.DescribeLogStreamsOutput, _ bool) bool {
+			for _, stream := range page.LogStreams {
+				entries = append(entries, newLogStream(g.group, stream, g.client))
+			}
+			return len(entries) < maxLogStreams
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > maxLogStreams {
+		entries = entries[:maxLogStreams]
+	}
+	activity.Record(ctx, "Listing %v log streams in %v", len(entries), g.group)
+	return entries, nil
+}
+
+// logStream represents a CloudWatch Logs log stream.
+type logStream struct {
+	plugin.EntryBase
+	group  string
+	client *logsClient.CloudWatchLogs
+}
+
+func newLogStream(group string, stream *logsClient.LogStream, client *logsClient.CloudWatchLogs) *logStream {
+	logStream := &logStream{
+		EntryBase: plugin.NewEntry(awsSDK.StringValue(stream.LogStreamName)),
+	}
+	logStream.group = group
+	logStream.client = client
+	// Log streams are written to constantly.
+	logStream.DisableCachingFor(plugin.ReadOp)
+
+	crtime := msToTime(stream.CreationTime)
+	mtime := crtime
+	if stream.LastEventTimestamp != nil {
+		mtime = msToTime(stream.LastEventTimestamp)
+	}
+	logStream.
+		SetPartialMetadata(stream).
+		Attributes().
+		SetCrtime(crtime).
+		SetMtime(mtime).
+		SetCtime(mtime).
+		SetAtime(mtime)
+	return logStream
+}
+
+func (s *logStream) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "stream").
+		SetDescription(logStreamDescription).
+		SetPartialMetadataSchema(logsClient.LogStream{})
+}
+
+// events returns the stream's events. If token is empty, then it returns the last
+// limit events (or as many events as fit in 1 MB if limit is 0). Otherwise, it returns
+// the events after the token. The returned token is the cursor for the next events.
+func (s *logStream) events(ctx context.Context, token string, limit int64) ([]*logsClient.OutputLogEvent, string, error) {
+	input := &logsClient.GetLogEventsInput{
+		LogGroupName:  awsSDK.String(s.group),
+		LogStreamName: awsSDK.String(s.Name()),
+	}
+	if token != "" {
+		input.NextToken = awsSDK.String(token)
+		input.StartFromHead = awsSDK.Bool(true)
+	}
+	if limit > 0 {
+		input.Limit = awsSDK.Int64(limit)
+	}
+	resp, err := s.client.GetLogEventsWithContext(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Events, awsSDK.StringValue(resp.NextForwardToken), nil
+}
+
+func formatLogEvents(events []*logsClient.OutputLogEvent) []byte {
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.WriteString(msToTime(event.Timestamp).UTC().Format(time.RFC3339Nano))
+		buf.WriteString(" ")
+		buf.WriteString(strings.TrimRight(awsSDK.StringValue(event.Message), "\n"))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// Read returns the stream's recent events, up to 1 MB of them.
+func (s *logStream) Read(ctx context.Context) ([]byte, error) {
+	events, _, err := s.events(ctx, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return formatLogEvents(events), nil
+}
+
+// Stream returns the last 10 events, followed by new events as they're written. New
+// events are found by polling from the last event that was seen.
+func (s *logStream) Stream(ctx context.Context) (io.ReadCloser, error) {
+	events, token, err := s.events(ctx, "", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r, w := io.Pipe()
+	go func() {
+		ticker := time.NewTicker(logsPollInterval)
+		defer ticker.Stop()
+		for {
+			if len(events) > 0 {
+				if _, err := w.Write(formatLogEvents(events)); err != nil {
+					// The reader was closed
+					cancel()
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				activity.Record(ctx, "Closing log stream %v in %v: %v", s.Name(), s.group, w.Close())
+				return
+			case <-ticker.C:
+			}
+			events, token, err = s.events(ctx, token, 0)
+			if err != nil {
+				if ctx.Err() != nil {
+					activity.Record(ctx, "Closing log stream %v in %v: %v", s.Name(), s.group, w.Close())
+				} else {
+					activity.Record(ctx, "Closing log stream %v in %v: %v", s.Name(), s.group, err)
+					w.CloseWithError(err)
+				}
+				return
+			}
+		}
+	}()
+	return plugin.CleanupReader{ReadCloser: r, Cleanup: cancel}, nil
+}
+
+const logGroupDescription = `
+This is a CloudWatch Logs log group. It contains its most recently written log
+streams, up to 1000 of them. Its metadata includes its retention in days and
+the bytes that it stores. Slashes in the group's name are replaced with #.
+`
+
+const logStreamDescription = `
+This is a CloudWatch Logs log stream. Reading it returns its recent events, up
+to 1 MB of them, prefixed by their timestamps. Streaming it returns the last 10
+events and polls for new events every couple of seconds, so 'tail -f' works.
+`
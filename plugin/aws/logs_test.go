@@ -0,0 +1,225 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	logsClient "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLogStream is a CloudWatch Logs endpoint that serves one log stream's events. Its
+// pages are keyed by the request's nextToken, and a page without events is returned
+// for unknown tokens. A page with an error field fails the request.
+type fakeLogStream struct {
+	mux      sync.Mutex
+	pages    map[string]fakeLogPage
+	requests []logsClient.GetLogEventsInput
+}
+
+type fakeLogPage struct {
+	events    []string
+	nextToken string
+	err       bool
+}
+
+func (f *fakeLogStream) start(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Logs_20140328.GetLogEvents", r.Header.Get("X-Amz-Target"))
+		var input logsClient.GetLogEventsInput
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &input))
+
+		f.mux.Lock()
+		f.requests = append(f.requests, input)
+		token := ""
+		if input.NextToken != nil {
+			token = *input.NextToken
+		}
+		page, ok := f.pages[token]
+		f.mux.Unlock()
+		if !ok {
+			page = fakeLogPage{nextToken: token}
+		}
+		if page.err {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "ResourceNotFoundException", "message": "The specified log stream does not exist."}`)
+			return
+		}
+
+		events := make([]map[string]interface{}, len(page.events))
+		for i, message := range page.events {
+			// Events are a second apart, starting at 2020-01-01T00:00:00Z.
+			events[i] = map[string]interface{}{"timestamp": 1577836800000 + int64(i)*1000, "message": message}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"events":           events,
+			"nextForwardToken": page.nextToken,
+		}))
+	}))
+}
+
+func (f *fakeLogStream) setPage(token string, page fakeLogPage) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.pages[token] = page
+}
+
+func (f *fakeLogStream) received() []logsClient.GetLogEventsInput {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return append([]logsClient.GetLogEventsInput{}, f.requests...)
+}
+
+func newTestLogStream(t *testing.T, url string) *logStream {
+	client := logsClient.New(newTestSession(t, url))
+	return newLogStream("/aws/lambda/hello", &logsClient.LogStream{LogStreamName: awsSDK.String("2020/01/01/[$LATEST]abc")}, client)
+}
+
+// setLogsPollInterval makes streams poll quickly. It returns a function that restores
+// the interval.
+func setLogsPollInterval(interval time.Duration) func() {
+	old := logsPollInterval
+	logsPollInterval = interval
+	return func() { logsPollInterval = old }
+}
+
+func TestLogStreamRead(t *testing.T) {
+	fake := &fakeLogStream{pages: map[string]fakeLogPage{
+		"": {events: []string{"START\n", "END"}, nextToken: "f/1"},
+	}}
+	server := fake.start(t)
+	defer server.Close()
+
+	content, err := newTestLogStream(t, server.URL).Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "2020-01-01T00:00:00Z START\n2020-01-01T00:00:01Z END\n", string(content))
+
+	// Read returns the recent events, so it doesn't start from the head or set a limit.
+	requests := fake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/aws/lambda/hello", *requests[0].LogGroupName)
+	assert.Equal(t, "2020/01/01/[$LATEST]abc", *requests[0].LogStreamName)
+	assert.Nil(t, requests[0].NextToken)
+	assert.Nil(t, requests[0].StartFromHead)
+	assert.Nil(t, requests[0].Limit)
+}
+
+func TestLogStreamStream_FollowsTheCursor(t *testing.T) {
+	defer setLogsPollInterval(time.Millisecond)()
+	fake := &fakeLogStream{pages: map[string]fakeLogPage{
+		"":    {events: []string{"one", "two"}, nextToken: "f/1"},
+		"f/1": {events: []string{"three"}, nextToken: "f/2"},
+	}}
+	server := fake.start(t)
+	defer server.Close()
+
+	rdr, err := newTestLogStream(t, server.URL).Stream(context.Background())
+	require.NoError(t, err)
+	lines := bufio.NewScanner(rdr)
+	for _, expected := range []string{
+		"2020-01-01T00:00:00Z one",
+		"2020-01-01T00:00:01Z two",
+		"2020-01-01T00:00:00Z three",
+	} {
+		require.True(t, lines.Scan())
+		assert.Equal(t, expected, lines.Text())
+	}
+
+	// The stream keeps polling from the last token, and emits new events once they're
+	// written.
+	fake.setPage("f/2", fakeLogPage{events: []string{"four"}, nextToken: "f/3"})
+	require.True(t, lines.Scan())
+	assert.Equal(t, "2020-01-01T00:00:00Z four", lines.Text())
+	require.NoError(t, rdr.Close())
+
+	requests := fake.received()
+	assert.EqualValues(t, 10, *requests[0].Limit)
+	assert.Nil(t, requests[0].NextToken)
+	for _, request := range requests[1:] {
+		assert.True(t, *request.StartFromHead)
+		assert.Nil(t, request.Limit)
+	}
+	assert.Equal(t, "f/1", *requests[1].NextToken)
+	assert.Equal(t, "f/2", *requests[2].NextToken)
+}
+
+func TestLogStreamStream_Close(t *testing.T) {
+	defer setLogsPollInterval(time.Millisecond)()
+	fake := &fakeLogStream{pages: map[string]fakeLogPage{
+		"": {events: []string{"one"}, nextToken: "f/1"},
+	}}
+	server := fake.start(t)
+	defer server.Close()
+
+	rdr, err := newTestLogStream(t, server.URL).Stream(context.Background())
+	require.NoError(t, err)
+	lines := bufio.NewScanner(rdr)
+	require.True(t, lines.Scan())
+	require.NoError(t, rdr.Close())
+
+	// Closing the reader stops the polling.
+	time.Sleep(20 * time.Millisecond)
+	polled := len(fake.received())
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, polled, len(fake.received()))
+}
+
+func TestLogStreamStream_CancelledContext(t *testing.T) {
+	defer setLogsPollInterval(time.Millisecond)()
+	fake := &fakeLogStream{pages: map[string]fakeLogPage{
+		"": {events: []string{"one"}, nextToken: "f/1"},
+	}}
+	server := fake.start(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rdr, err := newTestLogStream(t, server.URL).Stream(ctx)
+	require.NoError(t, err)
+	lines := bufio.NewScanner(rdr)
+	require.True(t, lines.Scan())
+
+	// Cancelling the context ends the stream without an error.
+	cancel()
+	assert.False(t, lines.Scan())
+	assert.NoError(t, lines.Err())
+}
+
+func TestLogStreamStream_Errors(t *testing.T) {
+	defer setLogsPollInterval(time.Millisecond)()
+	fake := &fakeLogStream{pages: map[string]fakeLogPage{
+		"":    {events: []string{"one"}, nextToken: "f/1"},
+		"f/1": {err: true},
+	}}
+	server := fake.start(t)
+	defer server.Close()
+
+	rdr, err := newTestLogStream(t, server.URL).Stream(context.Background())
+	require.NoError(t, err)
+	lines := bufio.NewScanner(rdr)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "2020-01-01T00:00:00Z one", lines.Text())
+
+	// A failed poll ends the stream with the error.
+	assert.False(t, lines.Scan())
+	if assert.Error(t, lines.Err()) {
+		assert.Contains(t, lines.Err().Error(), "The specified log stream does not exist")
+	}
+	assert.NoError(t, rdr.Close())
+
+	// Failing to get the first events fails Stream.
+	fake.setPage("", fakeLogPage{err: true})
+	_, err = newTestLogStream(t, server.URL).Stream(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	ssmClient "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// redact removes a secret value from an error. AWS echoes invalid values in some
+// validation errors, and errors are written to the activity journal.
+func redact(err error, value []byte) error {
+	if err == nil || len(value) == 0 {
+		return err
+	}
+	return errors.New(strings.Replace(err.Error(), string(value), "<redacted>", -1))
+}
+
+// parametersDir represents the regions/<region>/parameters directory, which contains
+// the region's Parameter Store parameters.
+type parametersDir struct {
+	plugin.EntryBase
+	client *ssmClient.SSM
+}
+
+func newParametersDir(ctx context.Context, session *session.Session) *parametersDir {
+	parametersDir := &parametersDir{
+		EntryBase: plugin.NewEntry("parameters"),
+	}
+	parametersDir.client = ssmClient.New(session)
+	if _, err := plugin.List(ctx, parametersDir); err != nil {
+		parametersDir.MarkInaccessible(ctx, err)
+	}
+	return parametersDir
+}
+
+func (p *parametersDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(p, "parameters").
+		SetDescription(parametersDirDescription).
+		IsSingleton()
+}
+
+func (p *parametersDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&parameterPath{}).Schema(),
+		(&parameter{}).Schema(),
+	}
+}
+
+func (p *parametersDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	return listParameters(ctx, p.client, "")
+}
+
+// listParameters lists the parameters and paths that are directly under the path. The
+// root path is "".
+func listParameters(ctx context.Context, client *ssmClient.SSM, path string) ([]plugin.Entry, error) {
+	input := &ssmClient.DescribeParametersInput{}
+	if path != "" {
+		input.ParameterFilters = []*ssmClient.ParameterStringFilter{
+			{
+				Key:    awsSDK.String("Path"),
+				Option: awsSDK.String("Recursive"),
+				Values: awsSDK.StringSlice([]string{path}),
+			},
+		}
+	}
+	var parameters []*ssmClient.ParameterMetadata
+	err := client.DescribeParametersPagesWithContext(ctx, input, func(page *ssmClient.DescribeParametersOutput, _ bool) bool {
+		parameters = append(parameters, page.Parameters...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A path can have the same name as a parameter, like /app and /app/port. The path
+	// takes precedence because entries can't have the same name.
+	paths := make(map[string]bool)
+	for _, param := range parameters {
+		rel := strings.TrimPrefix(strings.TrimPrefix(awsSDK.StringValue(param.Name), path), "/")
+		if i := strings.Index(rel, "/"); i >= 0 {
+			paths[rel[:i]] = true
+		}
+	}
+	var entries []plugin.Entry
+	for name := range paths {
+		entries = append(entries, newParameterPath(name, path+"/"+name, client))
+	}
+	for _, param := range parameters {
+		rel := strings.TrimPrefix(strings.TrimPrefix(awsSDK.StringValue(param.Name), path), "/")
+		if strings.Contains(rel, "/") {
+			continue
+		}
+		if paths[rel] {
+			activity.Record(ctx, "Omitting the %v parameter because a path has the same name", awsSDK.StringValue(param.Name))
+			continue
+		}
+		entries = append(entries, newParameter(rel, param, client))
+	}
+	activity.Record(ctx, "Listing %v parameters and paths under %q", len(entries), path)
+	return entries, nil
+}
+
+// parameterPath represents a level of the Parameter Store hierarchy.
+type parameterPath struct {
+	plugin.EntryBase
+	path   string
+	client *ssmClient.SSM
+}
+
+func newParameterPath(name string, path string, client *ssmClient.SSM) *parameterPath {
+	parameterPath := &parameterPath{
+		EntryBase: plugin.NewEntry(name),
+	}
+	parameterPath.path = path
+	parameterPath.client = client
+	return parameterPath
+}
+
+func (p *parameterPath) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(p, "path")
+}
+
+func (p *parameterPath) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&parameterPath{}).Schema(),
+		(&parameter{}).Schema(),
+	}
+}
+
+func (p *parameterPath) List(ctx context.Context) ([]plugin.Entry, error) {
+	return listParameters(ctx, p.client, p.path)
+}
+
+// parameter represents a Parameter Store parameter. Its value is never cached.
+type parameter struct {
+	plugin.EntryBase
+	param  *ssmClient.ParameterMetadata
+	client *ssmClient.SSM
+}
+
+func newParameter(name string, param *ssmClient.ParameterMetadata, client *ssmClient.SSM) *parameter {
+	parameter := &parameter{
+		EntryBase: plugin.NewEntry(name),
+	}
+	parameter.param = param
+	parameter.client = client
+	parameter.DisableCachingFor(plugin.ReadOp)
+
+	mtime := awsSDK.TimeValue(param.LastModifiedDate)
+	parameter.
+		SetPartialMetadata(param).
+		Attributes().
+		SetMtime(mtime).
+		SetCtime(mtime).
+		SetAtime(mtime)
+	return parameter
+}
+
+func (p *parameter) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(p, "parameter").
+		SetDescription(parameterDescription).
+		SetPartialMetadataSchema(ssmClient.ParameterMetadata{})
+}
+
+// Read returns the parameter's value. SecureString values are decrypted.
+func (p *parameter) Read(ctx context.Context) ([]byte, error) {
+	resp, err := p.client.GetParameterWithContext(ctx, &ssmClient.GetParameterInput{
+		Name:           p.param.Name,
+		WithDecryption: awsSDK.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(awsSDK.StringValue(resp.Parameter.Value)), nil
+}
+
+// Write overwrites the parameter's value, which creates a new version of it.
+func (p *parameter) Write(ctx context.Context, b []byte) error {
+	if !utf8.Valid(b) {
+		return errors.New("parameter values must be UTF-8 text")
+	}
+	input := &ssmClient.PutParameterInput{
+		Name:      p.param.Name,
+		Value:     awsSDK.String(string(b)),
+		Type:      p.param.Type,
+		Overwrite: awsSDK.Bool(true),
+	}
+	if awsSDK.StringValue(p.param.Type) == ssmClient.ParameterTypeSecureString {
+		input.KeyId = p.param.KeyId
+	}
+	resp, err := p.client.PutParameterWithContext(ctx, input)
+	if err != nil {
+		return redact(err, b)
+	}
+	activity.Record(ctx, "Wrote version %v of the %v parameter", awsSDK.Int64Value(resp.Version), awsSDK.StringValue(p.param.Name))
+	return nil
+}
+
+func (p *parameter) Delete(ctx context.Context) (bool, error) {
+	_, err := p.client.DeleteParameterWithContext(ctx, &ssmClient.DeleteParameterInput{
+		Name: p.param.Name,
+	})
+	return true, err
+}
+
+const parametersDirDescription = `
+This directory contains the region's SSM Parameter Store parameters. Parameter
+names are split on '/' into a hierarchy of paths, so /app/db/port is
+parameters/app/db/port. If a parameter has the same name as a path, like /app
+and /app/db/port, then only the path is listed.
+`
+
+const parameterDescription = `
+This is an SSM Parameter Store parameter. Reading it returns its value, and
+SecureString values are decrypted. Writing to it overwrites its value, which
+creates a new version of the parameter. Values are never cached or written to
+the activity journal.
+`
@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSSM is a stand-in for Parameter Store with the parameters, keyed by their name.
+// It rejects values that contain "invalid", echoing them in the error like SSM does.
+func fakeSSM(parameters map[string]string) http.HandlerFunc {
+	var mux sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name             string
+			Value            string
+			ParameterFilters []struct {
+				Key    string
+				Values []string
+			}
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mux.Lock()
+		defer mux.Unlock()
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.DescribeParameters":
+			prefix := ""
+			for _, filter := range body.ParameterFilters {
+				if filter.Key == "Path" {
+					prefix = filter.Values[0] + "/"
+				}
+			}
+			var metadata []map[string]string
+			for name := range parameters {
+				if strings.HasPrefix(name, prefix) {
+					metadata = append(metadata, map[string]string{"Name": name, "Type": "String"})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"Parameters": metadata})
+		case "AmazonSSM.GetParameter":
+			fmt.Fprintf(w, `{"Parameter": {"Name": %q, "Value": %q}}`, body.Name, parameters[body.Name])
+		case "AmazonSSM.PutParameter":
+			if strings.Contains(body.Value, "invalid") {
+				http.Error(w, fmt.Sprintf(`{"__type": "ValidationException", "message": "Value '%v' failed to satisfy the parameter's pattern"}`, body.Value), http.StatusBadRequest)
+				return
+			}
+			parameters[body.Name] = body.Value
+			fmt.Fprint(w, `{"Version": 2}`)
+		default:
+			http.Error(w, `{"__type": "InvalidAction"}`, http.StatusBadRequest)
+		}
+	}
+}
+
+func entryNames(entries []plugin.Entry) []string {
+	names := regionNames(entries)
+	sort.Strings(names)
+	return names
+}
+
+func TestRedact(t *testing.T) {
+	assert.NoError(t, redact(nil, []byte("value")))
+	err := errors.New("Value 'value' is invalid")
+	assert.Equal(t, err, redact(err, nil))
+	assert.EqualError(t, redact(err, []byte("value")), "Value '<redacted>' is invalid")
+}
+
+func TestListParameters(t *testing.T) {
+	server, _ := recordingServer(fakeSSM(map[string]string{
+		"plain":        "1",
+		"/top":         "2",
+		"/app":         "3",
+		"/app/port":    "4",
+		"/app/db/host": "5",
+		"/application": "6",
+	}))
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	parameters := newParametersDir(ctx, newTestSession(t, server.URL))
+	parameters.SetTestID("/aws/default/regions/us-east-1/parameters")
+	entries, err := parameters.List(ctx)
+	require.NoError(t, err)
+	// The /app parameter is omitted because the /app path has the same name.
+	assert.Equal(t, []string{"app", "application", "plain", "top"}, entryNames(entries))
+	for _, entry := range entries {
+		if plugin.Name(entry) == "app" {
+			require.IsType(t, &parameterPath{}, entry)
+			assert.Equal(t, "/app", entry.(*parameterPath).path)
+		} else {
+			assert.IsType(t, &parameter{}, entry)
+		}
+	}
+
+	app := newParameterPath("app", "/app", parameters.client)
+	entries, err = app.List(ctx)
+	require.NoError(t, err)
+	// /application isn't under /app.
+	assert.Equal(t, []string{"db", "port"}, entryNames(entries))
+
+	db := newParameterPath("db", "/app/db", parameters.client)
+	entries, err = db.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"host"}, entryNames(entries))
+	content, err := entries[0].(*parameter).Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "5", string(content))
+}
+
+func TestParameter_ReadsAreNotCached(t *testing.T) {
+	server, targets := recordingServer(fakeSSM(map[string]string{"/app/port": "80"}))
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	entries, err := newParameterPath("app", "/app", newParametersDir(ctx, newTestSession(t, server.URL)).client).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	param := entries[0].(*parameter)
+	param.SetTestID("/aws/default/regions/us-east-1/parameters/app/port")
+
+	for i := 0; i < 2; i++ {
+		// Reads return io.EOF because the content is shorter than the requested size.
+		content, err := plugin.Read(ctx, param, 100, 0)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, "80", string(content))
+	}
+
+	var reads int
+	for _, target := range targets() {
+		if target == "AmazonSSM.GetParameter" {
+			reads++
+		}
+	}
+	assert.Equal(t, 2, reads)
+}
+
+func TestParameter_WriteRedactsValue(t *testing.T) {
+	server, _ := recordingServer(fakeSSM(map[string]string{"/app/port": "80"}))
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	entries, err := newParametersDir(ctx, newTestSession(t, server.URL)).List(ctx)
+	require.NoError(t, err)
+	entries, err = entries[0].(*parameterPath).List(ctx)
+	require.NoError(t, err)
+	err = entries[0].(*parameter).Write(ctx, []byte("invalid-s3cr3t"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Value '<redacted>' failed")
+		assert.NotContains(t, err.Error(), "s3cr3t")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	awsSDK "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	stsClient "github.com/aws/aws-sdk-go/service/sts"
@@ -15,11 +16,11 @@ import (
 // profile represents an AWS profile
 type profile struct {
 	plugin.EntryBase
-	session      *session.Session
-	resourcesDir []plugin.Entry
+	session *session.Session
+	entries []plugin.Entry
 }
 
-func newProfile(ctx context.Context, name string) (*profile, error) {
+func newProfile(ctx context.Context, name string, opts profileOptions, sdkConfig *awsSDK.Config, lambdaResponses *lambdaResponses) (*profile, error) {
 	profile := &profile{
 		EntryBase: plugin.NewEntry(name),
 	}
@@ -35,6 +36,7 @@ func newProfile(ctx context.Context, name string) (*profile, error) {
 	// Create the session. SharedConfigEnable tells AWS to load the profile
 	// config from the ~/.aws/credentials and ~/.aws/config files
 	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:                  *sdkConfig,
 		Profile:                 name,
 		AssumeRoleTokenProvider: tokenProvider,
 		// TODO: make this configurable. Different IAM configs may allow different durations.
@@ -58,7 +60,10 @@ func newProfile(ctx context.Context, name string) (*profile, error) {
 	}
 
 	profile.session = sess
-	profile.resourcesDir = []plugin.Entry{newResourcesDir(sess)}
+	profile.entries = []plugin.Entry{
+		newResourcesDir(sess, opts.S3),
+		newRegionsDir(sess, opts, lambdaResponses),
+	}
 
 	return profile, nil
 }
@@ -72,12 +77,13 @@ func (p *profile) Schema() *plugin.EntrySchema {
 func (p *profile) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&resourcesDir{}).Schema(),
+		(&regionsDir{}).Schema(),
 	}
 }
 
-// List lists the resources directory
+// List lists the resources and regions directories
 func (p *profile) List(ctx context.Context) ([]plugin.Entry, error) {
-	return p.resourcesDir, nil
+	return p.entries, nil
 }
 
 type profileMetadata struct {
@@ -118,5 +124,6 @@ func (p *profile) Metadata(ctx context.Context) (plugin.JSONObject, error) {
 }
 
 const profileDescription = `
-This is an AWS profile.
+This is an AWS profile. Its resources directory contains global resources like
+S3 buckets, and its regions directory contains each region's resources.
 `
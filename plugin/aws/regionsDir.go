@@ -0,0 +1,170 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// defaultRegion is used to discover the enabled regions if the profile doesn't
+// configure a region.
+const defaultRegion = "us-east-1"
+
+// maxConcurrentRegions is the most regions whose resources are listed at once.
+const maxConcurrentRegions = 8
+
+// regionsDir represents the <profile>/regions directory
+type regionsDir struct {
+	plugin.EntryBase
+	session         *session.Session
+	opts            profileOptions
+	lambdaResponses *lambdaResponses
+}
+
+func newRegionsDir(session *session.Session, opts profileOptions, lambdaResponses *lambdaResponses) *regionsDir {
+	regionsDir := &regionsDir{
+		EntryBase: plugin.NewEntry("regions"),
+	}
+	regionsDir.session = session
+	regionsDir.opts = opts
+	regionsDir.lambdaResponses = lambdaResponses
+	return regionsDir
+}
+
+func (r *regionsDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(r, "regions").
+		SetDescription(regionsDirDescription).
+		IsSingleton()
+}
+
+func (r *regionsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&region{}).Schema(),
+	}
+}
+
+// regionNames returns the configured regions, or the regions that are enabled for the
+// account if none are configured.
+func (r *regionsDir) regionNames(ctx context.Context) ([]string, error) {
+	if r.opts.Regions != nil {
+		return r.opts.Regions, nil
+	}
+
+	config := awsSDK.NewConfig()
+	if awsSDK.StringValue(r.session.Config.Region) == "" {
+		config = config.WithRegion(defaultRegion)
+	}
+	resp, err := ec2Client.New(r.session, config).DescribeRegionsWithContext(ctx, &ec2Client.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(resp.Regions))
+	for i, region := range resp.Regions {
+		names[i] = awsSDK.StringValue(region.RegionName)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// List lists the regions. Their resources are also listed, up to maxConcurrentRegions
+// regions at a time, so that walking the regions doesn't list them one by one.
+func (r *regionsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	names, err := r.regionNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v regions", len(names))
+
+	entries := make([]plugin.Entry, len(names))
+	sem := make(chan struct{}, maxConcurrentRegions)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		region := newRegion(name, r.session, r.opts, r.lambdaResponses)
+		entries[i] = region
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// plugin.List caches the region's resources under the region's ID.
+			if _, err := plugin.List(ctx, region); err != nil {
+				activity.Record(ctx, "Unable to list the %v region's resources: %v", region.Name(), err)
+			}
+		}()
+	}
+	wg.Wait()
+	return entries, nil
+}
+
+// region represents a <profile>/regions/<region> directory, which contains the
+// region's resources.
+type region struct {
+	plugin.EntryBase
+	session         *session.Session
+	opts            profileOptions
+	lambdaResponses *lambdaResponses
+}
+
+func newRegion(name string, session *session.Session, opts profileOptions, lambdaResponses *lambdaResponses) *region {
+	region := &region{
+		EntryBase: plugin.NewEntry(name),
+	}
+	region.session = session.Copy(awsSDK.NewConfig().WithRegion(name))
+	region.opts = opts
+	region.lambdaResponses = lambdaResponses
+	return region
+}
+
+func (r *region) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(r, "region")
+}
+
+func (r *region) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&ec2Dir{}).Schema(),
+		(&logsDir{}).Schema(),
+		(&lambdaDir{}).Schema(),
+		(&ecsDir{}).Schema(),
+		(&parametersDir{}).Schema(),
+		(&secretsDir{}).Schema(),
+	}
+}
+
+// List lists the region's resources. Most of them list their contents when they're
+// created, so they're created concurrently.
+func (r *region) List(ctx context.Context) ([]plugin.Entry, error) {
+	newResources := []func() plugin.Entry{
+		func() plugin.Entry { return newEC2Dir(r.session, r.opts) },
+		func() plugin.Entry { return newLogsDir(ctx, r.session) },
+		func() plugin.Entry { return newLambdaDir(ctx, r.session, r.lambdaResponses) },
+		func() plugin.Entry { return newECSDir(ctx, r.session) },
+		func() plugin.Entry { return newParametersDir(ctx, r.session) },
+		func() plugin.Entry { return newSecretsDir(ctx, r.session) },
+	}
+	resources := make([]plugin.Entry, len(newResources))
+	var wg sync.WaitGroup
+	for i, newResource := range newResources {
+		wg.Add(1)
+		go func(i int, newResource func() plugin.Entry) {
+			defer wg.Done()
+			resources[i] = newResource()
+		}(i, newResource)
+	}
+	wg.Wait()
+	return resources, nil
+}
+
+const regionsDirDescription = `
+This directory contains the AWS regions that are enabled for the profile's
+account, or the regions that are configured with the regions option. Each
+region contains its regional resources, like EC2 instances, CloudWatch Logs,
+Lambda functions, ECS clusters, Parameter Store parameters and Secrets Manager
+secrets.
+`
@@ -0,0 +1,191 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSession returns a session whose requests are all sent to the URL.
+func newTestSession(t *testing.T, url string) *session.Session {
+	cfg := config{Endpoints: map[string]string{defaultEndpoint: url}}.sdkConfig().
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("AKID", "SECRET", "")).
+		WithMaxRetries(0)
+	sess, err := session.NewSession(cfg)
+	require.NoError(t, err)
+	return sess
+}
+
+// recordingServer is an AWS endpoint that records the targets (the JSON protocol's
+// X-Amz-Target header or the query protocol's Action) of the requests it receives.
+// It responds with the handler, or with a 400 if the handler's nil.
+func recordingServer(handler http.HandlerFunc) (*httptest.Server, func() []string) {
+	var mux sync.Mutex
+	var targets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		if target == "" {
+			_ = r.ParseForm()
+			target = r.Form.Get("Action")
+		}
+		mux.Lock()
+		targets = append(targets, target)
+		mux.Unlock()
+		if handler == nil {
+			http.Error(w, `{"__type": "AccessDeniedException", "message": "denied"}`, http.StatusBadRequest)
+			return
+		}
+		handler(w, r)
+	}))
+	return server, func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]string{}, targets...)
+	}
+}
+
+func regionNames(entries []plugin.Entry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = plugin.Name(entry)
+	}
+	return names
+}
+
+// requestRegion returns the region that the request was signed for.
+func requestRegion(r *http.Request) string {
+	// The credential's scope is <key ID>/<date>/<region>/<service>/aws4_request.
+	auth := r.Header.Get("Authorization")
+	if i := strings.Index(auth, "Credential="); i >= 0 {
+		if scope := strings.Split(auth[i:], "/"); len(scope) > 2 {
+			return scope[2]
+		}
+	}
+	return ""
+}
+
+func TestRegionsDir_ListsEnabledRegions(t *testing.T) {
+	server, targets := recordingServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("Action") != "DescribeRegions" {
+			http.Error(w, `{"__type": "AccessDeniedException", "message": "denied"}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `<DescribeRegionsResponse><regionInfo>
+<item><regionName>us-west-2</regionName></item>
+<item><regionName>eu-west-1</regionName></item>
+</regionInfo></DescribeRegionsResponse>`)
+	})
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	regions, err := newRegionsDir(newTestSession(t, server.URL), profileOptions{}, newLambdaResponses()).List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eu-west-1", "us-west-2"}, regionNames(regions))
+	assert.Equal(t, "DescribeRegions", targets()[0])
+}
+
+func TestRegionsDir_RegionsOverride(t *testing.T) {
+	server, targets := recordingServer(nil)
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	opts := profileOptions{Regions: []string{"us-west-2", "ap-south-1"}}
+	regions, err := newRegionsDir(newTestSession(t, server.URL), opts, newLambdaResponses()).List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"us-west-2", "ap-south-1"}, regionNames(regions))
+	assert.NotContains(t, targets(), "DescribeRegions")
+	assert.Equal(t, "ap-south-1", awsSDK.StringValue(regions[1].(*region).session.Config.Region))
+}
+
+func TestRegionsDir_ListsRegionsConcurrently(t *testing.T) {
+	// Each region's log groups are only returned once another region has started
+	// listing them, or after a timeout.
+	var mux sync.Mutex
+	started := make(map[string]bool)
+	inFlight, maxInFlight := 0, 0
+	server, _ := recordingServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "Logs_20140328.DescribeLogGroups" {
+			http.Error(w, `{"__type": "AccessDeniedException", "message": "denied"}`, http.StatusBadRequest)
+			return
+		}
+		mux.Lock()
+		started[requestRegion(r)] = true
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mux.Unlock()
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			mux.Lock()
+			n := len(started)
+			mux.Unlock()
+			if n > 1 {
+				break
+			}
+		}
+		mux.Lock()
+		inFlight--
+		mux.Unlock()
+		fmt.Fprint(w, `{"logGroups": []}`)
+	})
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	opts := profileOptions{Regions: []string{"us-west-2", "eu-west-1", "ap-south-1"}}
+	regions, err := newRegionsDir(newTestSession(t, server.URL), opts, newLambdaResponses()).List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, regions, 3)
+	assert.Equal(t, map[string]bool{"us-west-2": true, "eu-west-1": true, "ap-south-1": true}, started)
+	assert.True(t, maxInFlight > 1, "the regions were listed one at a time")
+}
+
+func TestRegion_List(t *testing.T) {
+	server, targets := recordingServer(nil)
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	reg := newRegion("us-west-2", newTestSession(t, server.URL), profileOptions{}, newLambdaResponses())
+	reg.SetTestID("/aws/default/regions/us-west-2")
+	resources, err := reg.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ec2", "logs", "lambda", "ecs", "parameters", "secrets"}, regionNames(resources))
+	// The services that can't be listed are still included. ec2 isn't listed until it's
+	// listed itself.
+	assert.NotContains(t, targets(), "DescribeInstances")
+	assert.Contains(t, targets(), "Logs_20140328.DescribeLogGroups")
+}
+
+func TestS3Dir_AnnotatesBucketRegions(t *testing.T) {
+	server, _ := fakeS3("hello world")
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	sess := newTestSession(t, server.URL)
+	sess.Config.WithS3ForcePathStyle(true)
+	s3 := newS3Dir(ctx, sess, s3Options{})
+	s3.SetTestID("/aws/default/resources/s3")
+	buckets, err := s3.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	bucket := buckets[0].(*s3Bucket)
+	assert.Equal(t, "us-west-2", bucket.region)
+	assert.Equal(t, "us-west-2", plugin.PartialMetadata(bucket)["Region"])
+}
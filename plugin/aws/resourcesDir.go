@@ -7,36 +7,45 @@ import (
 	"github.com/puppetlabs/wash/plugin"
 )
 
-// resourcesDir represents the <profile>/resources directory
+// resourcesDir represents the <profile>/resources directory, which contains the
+// resources that aren't specific to a region.
 type resourcesDir struct {
 	plugin.EntryBase
 	session *session.Session
+	s3Opts  s3Options
 }
 
-func newResourcesDir(session *session.Session) *resourcesDir {
+func newResourcesDir(session *session.Session, s3Opts s3Options) *resourcesDir {
 	resourcesDir := &resourcesDir{
 		EntryBase: plugin.NewEntry("resources"),
 	}
 	resourcesDir.DisableDefaultCaching()
 	resourcesDir.session = session
+	resourcesDir.s3Opts = s3Opts
 	return resourcesDir
 }
 
 func (r *resourcesDir) Schema() *plugin.EntrySchema {
-	return plugin.NewEntrySchema(r, "resources").IsSingleton()
+	return plugin.
+		NewEntrySchema(r, "resources").
+		SetDescription(resourcesDirDescription).
+		IsSingleton()
 }
 
 func (r *resourcesDir) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&s3Dir{}).Schema(),
-		(&ec2Dir{}).Schema(),
 	}
 }
 
-// List lists the available AWS resources
+// List lists the available global AWS resources
 func (r *resourcesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 	return []plugin.Entry{
-		newS3Dir(ctx, r.session),
-		newEC2Dir(r.session),
+		newS3Dir(ctx, r.session, r.s3Opts),
 	}, nil
 }
+
+const resourcesDirDescription = `
+This directory contains the profile's global resources, like S3 buckets.
+Regional resources are in the regions directory.
+`
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	s3Client "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	"gopkg.in/go-ini/ini.v1"
@@ -20,7 +22,12 @@ import (
 // Root of the AWS plugin
 type Root struct {
 	plugin.EntryBase
-	profs map[string]struct{}
+	profs       map[string]struct{}
+	regions     []string
+	profileOpts map[string]profileOptions
+	sdkConfig   *awsSDK.Config
+	// lambdaResponses are kept by the root so that they outlive the profiles' entries
+	lambdaResponses *lambdaResponses
 }
 
 func awsCredentialsFile() (string, error) {
@@ -60,26 +67,92 @@ func exists(path string) (bool, error) {
 	return true, nil
 }
 
+type config struct {
+	Profiles         []string                  `json:"profiles" jsonschema_description:"The AWS profiles to list. If omitted, all profiles are listed."`
+	Regions          []string                  `json:"regions" jsonschema_description:"The regions to list for each profile. If omitted, the regions that are enabled for the profile's account are listed."`
+	ProfileOptions   map[string]profileOptions `json:"profile_options" jsonschema_description:"Options for individual profiles, keyed by the profile's name."`
+	Endpoints        map[string]string         `json:"endpoints" jsonschema_description:"URLs that services are sent to instead of AWS, keyed by the service's endpoint ID, like s3, ec2 or logs. The default key's URL is used for the other services. This is useful for emulators like LocalStack."`
+	S3ForcePathStyle bool                      `json:"s3_force_path_style" jsonschema_description:"Use path-style S3 addressing (<endpoint>/<bucket>/<key>) instead of virtual-hosted-style addressing. Emulators like MinIO need it."`
+}
+
+type profileOptions struct {
+	Regions         []string  `json:"regions" jsonschema_description:"The regions to list for the profile. Overrides the regions option."`
+	Exec            string    `json:"exec" jsonschema_description:"How to exec on the profile's EC2 instances. Use 'ssh', 'ssm' (SSM Run Command and Session Manager), or 'auto' to use SSM for the instances that are managed by it and SSH for the others. Defaults to 'auto'."`
+	SSMOutputBucket string    `json:"ssm_output_bucket" jsonschema_description:"An S3 bucket that SSM writes the output of commands to. Without it, the output of commands that are run with SSM Run Command is truncated to 24000 characters."`
+	S3              s3Options `json:"s3" jsonschema_description:"Options for writing the profile's S3 objects."`
+}
+
+type s3Options struct {
+	ServerSideEncryption string `json:"server_side_encryption" jsonschema_description:"The server-side encryption that objects are written with, AES256 or aws:kms. If omitted, the bucket's default encryption is used."`
+	SSEKMSKeyID          string `json:"sse_kms_key_id" jsonschema_description:"The KMS key that objects are encrypted with when server_side_encryption is aws:kms. If omitted, the AWS managed key is used."`
+	StorageClass         string `json:"storage_class" jsonschema_description:"The storage class that objects are written with, like STANDARD_IA. If omitted, objects keep their storage class."`
+}
+
+// validate checks the options' values, which aren't checked by the config schema.
+func (o s3Options) validate() error {
+	if o.ServerSideEncryption != "" && !contains(s3Client.ServerSideEncryption_Values(), o.ServerSideEncryption) {
+		return fmt.Errorf("server_side_encryption: must be one of %v, not %v", strings.Join(s3Client.ServerSideEncryption_Values(), ", "), o.ServerSideEncryption)
+	}
+	if o.SSEKMSKeyID != "" && o.ServerSideEncryption != s3Client.ServerSideEncryptionAwsKms {
+		return fmt.Errorf("sse_kms_key_id: requires server_side_encryption to be %v", s3Client.ServerSideEncryptionAwsKms)
+	}
+	if o.StorageClass != "" && !contains(s3Client.StorageClass_Values(), o.StorageClass) {
+		return fmt.Errorf("storage_class: must be one of %v, not %v", strings.Join(s3Client.StorageClass_Values(), ", "), o.StorageClass)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// execMode returns the profile's exec mode, which defaults to execAuto.
+func (o profileOptions) execMode() string {
+	if o.Exec == "" {
+		return execAuto
+	}
+	return o.Exec
+}
+
+// ConfigSchema returns the root's config schema
+func (r *Root) ConfigSchema() interface{} {
+	return config{}
+}
+
 // Init for root
 func (r *Root) Init(cfg map[string]interface{}) error {
 	r.EntryBase = plugin.NewEntry("aws")
 	r.SetTTLOf(plugin.ListOp, 1*time.Minute)
 
-	if profsI, ok := cfg["profiles"]; ok {
-		profs, ok := profsI.([]interface{})
-		if !ok {
-			return fmt.Errorf("aws.profiles config must be an array of strings, not %s", profsI)
-		}
-
+	var c config
+	if err := plugin.DecodeConfig(cfg, &c); err != nil {
+		return err
+	}
+	if c.Profiles != nil {
 		r.profs = make(map[string]struct{})
-		for _, elem := range profs {
-			prof, ok := elem.(string)
-			if !ok {
-				return fmt.Errorf("aws.profiles config must be an array of strings, not %s", profs)
-			}
+		for _, prof := range c.Profiles {
 			r.profs[prof] = struct{}{}
 		}
 	}
+	for name, opts := range c.ProfileOptions {
+		switch opts.execMode() {
+		case execAuto, execSSH, execSSM:
+		default:
+			return fmt.Errorf("invalid config: aws.profile_options.%v.exec: must be one of auto, ssh or ssm, not %v", name, opts.Exec)
+		}
+		if err := opts.S3.validate(); err != nil {
+			return fmt.Errorf("invalid config: aws.profile_options.%v.s3.%v", name, err)
+		}
+	}
+	r.regions = c.Regions
+	r.profileOpts = c.ProfileOptions
+	r.sdkConfig = c.sdkConfig()
+	r.lambdaResponses = newLambdaResponses()
 
 	// Force authorizing profiles on startup
 	_, err := r.List(context.Background())
@@ -171,7 +244,11 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 			continue
 		}
 
-		profile, err := newProfile(ctx, name)
+		opts := r.profileOpts[name]
+		if opts.Regions == nil {
+			opts.Regions = r.regions
+		}
+		profile, err := newProfile(ctx, name, opts, r.sdkConfig, r.lambdaResponses)
 		if err != nil {
 			activity.Warnf(ctx, err.Error())
 			continue
@@ -197,9 +274,51 @@ aws:
 
 to Wash’s config file.
 
-The AWS plugin currently supports EC2 and S3. IAM roles are supported when configured
-as described here. Note that currently region will also need to be specified with the
-profile.
+The AWS plugin currently supports EC2, S3, CloudWatch Logs, Lambda, ECS, SSM
+Parameter Store and Secrets Manager. IAM roles are supported when configured as
+described here.
+
+EC2 instances are exec'ed on with SSM if they're managed by it, and with SSH
+otherwise. You can pick the executor for each profile with
+
+aws:
+  profile_options:
+    profile_1:
+      exec: ssm
+      ssm_output_bucket: my-bucket
+
+where exec is one of auto (the default), ssh or ssm. SSM Run Command truncates
+the output of commands to 24000 characters unless ssm_output_bucket is set.
+
+Writes to S3 objects use the bucket's default encryption and keep the object's
+storage class. You can override them for each profile with
+
+aws:
+  profile_options:
+    profile_1:
+      s3:
+        server_side_encryption: aws:kms
+        sse_kms_key_id: alias/my-key
+        storage_class: STANDARD_IA
+
+Each profile lists the regions that are enabled for its account. You can limit
+the listed regions with
+
+aws:
+  regions: [us-east-1, eu-west-1]
+
+or per profile with profile_options.<profile>.regions.
+
+To use emulators like LocalStack or MinIO, send services to them with
+
+aws:
+  endpoints:
+    s3: http://localhost:9000
+    default: http://localhost:4566
+  s3_force_path_style: true
+
+where the keys are the services' endpoint IDs, like s3, ec2, logs or ssm, and
+the default key's URL is used for the other services.
 
 If using MFA, Wash will prompt for it on standard input. Credentials are valid for 1 hour.
 They are cached under wash/aws-credentials in your user cache directory so they can be
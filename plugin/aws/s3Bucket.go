@@ -27,7 +27,7 @@ import (
 // to pass-around an entire object just to access only one of its methods and (2),
 // it makes it difficult to refresh the shared s3Bucket object when the original object
 // is evicted from the cache.
-func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix string) ([]plugin.Entry, error) {
+func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix string, opts s3Options) ([]plugin.Entry, error) {
 	// TODO: Clarify this a bit more later. For now, this should be enough.
 	//
 	// Everything's an object in S3. There is no such thing as a "hierarchy", meaning
@@ -131,7 +131,7 @@ func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix
 			name = strings.TrimSuffix(name, "/")
 		}
 
-		entries = append(entries, newS3ObjectPrefix(name, bucket, commonPrefix, client))
+		entries = append(entries, newS3ObjectPrefix(name, bucket, commonPrefix, client, opts))
 	}
 
 	for _, o := range resp.Contents {
@@ -141,7 +141,7 @@ func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix
 			// key == <prefix> so skip it. This is what the AWS console does.
 			continue
 		}
-		entries = append(entries, newS3Object(o, name, bucket, key, client))
+		entries = append(entries, newS3Object(o, name, bucket, key, client, opts))
 	}
 
 	return entries, nil
@@ -159,21 +159,36 @@ func deleteObjects(ctx context.Context, client *s3Client.S3, bucket string, pref
 // s3Bucket represents an S3 bucket.
 type s3Bucket struct {
 	plugin.EntryBase
-	crtime  time.Time
+	crtime time.Time
+	// region is empty if it couldn't be found when the bucket was listed
+	region  string
 	client  *s3Client.S3
 	cwcli   *cloudwatch.CloudWatch
 	session *session.Session
+	opts    s3Options
 }
 
-func newS3Bucket(name string, crtime time.Time, session *session.Session) *s3Bucket {
+type bucketPartialMetadata struct {
+	*s3Client.Bucket
+	Region string
+}
+
+func newS3Bucket(b *s3Client.Bucket, region string, session *session.Session, opts s3Options) *s3Bucket {
 	bucket := &s3Bucket{
-		EntryBase: plugin.NewEntry(name),
+		EntryBase: plugin.NewEntry(awsSDK.StringValue(b.Name)),
+	}
+	bucket.crtime = awsSDK.TimeValue(b.CreationDate)
+	bucket.region = region
+	if region != "" {
+		bucket.client = s3Client.New(session, aws.NewConfig().WithRegion(region))
+	} else {
+		bucket.client = s3Client.New(session)
 	}
-	bucket.crtime = crtime
-	bucket.client = s3Client.New(session)
 	bucket.cwcli = cloudwatch.New(session)
 	bucket.session = session
+	bucket.opts = opts
 	bucket.
+		SetPartialMetadata(bucketPartialMetadata{Bucket: b, Region: region}).
 		Attributes().
 		SetCrtime(bucket.crtime).
 		SetMtime(bucket.crtime).
@@ -186,19 +201,41 @@ func newS3Bucket(name string, crtime time.Time, session *session.Session) *s3Buc
 func (b *s3Bucket) Schema() *plugin.EntrySchema {
 	return plugin.
 		NewEntrySchema(b, "bucket").
+		SetPartialMetadataSchema(bucketPartialMetadata{}).
 		SetMetadataSchema(bucketMetadata{}).
 		SetDescription(s3BucketDescription)
 }
 
 func (b *s3Bucket) ChildSchemas() []*plugin.EntrySchema {
-	return (&s3ObjectPrefix{}).ChildSchemas()
+	return append((&s3ObjectPrefix{}).ChildSchemas(), (&s3VersionsDir{}).Schema())
 }
 
+// List lists the bucket's objects and prefixes. Versioned buckets also include a
+// versions directory.
 func (b *s3Bucket) List(ctx context.Context) ([]plugin.Entry, error) {
 	if _, err := b.getRegion(ctx); err != nil {
 		return nil, err
 	}
-	return listObjects(ctx, b.client, b.Name(), "")
+	entries, err := listObjects(ctx, b.client, b.Name(), "", b.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	versioned, err := b.isVersioned(ctx)
+	if err != nil {
+		activity.Record(ctx, "Could not get the versioning status of bucket %v: %v", b.Name(), err)
+		return entries, nil
+	}
+	if !versioned {
+		return entries, nil
+	}
+	for _, entry := range entries {
+		if plugin.Name(entry) == "versions" {
+			activity.Warnf(ctx, "Omitting the versions directory of bucket %v because it has an object or prefix named versions", b.Name())
+			return entries, nil
+		}
+	}
+	return append(entries, newS3VersionsDir("versions", b.Name(), "", b.client)), nil
 }
 
 func (b *s3Bucket) Delete(ctx context.Context) (bool, error) {
@@ -288,17 +325,16 @@ func (b *s3Bucket) Metadata(ctx context.Context) (plugin.JSONObject, error) {
 }
 
 func (b *s3Bucket) getRegion(ctx context.Context) (string, error) {
+	if b.region != "" {
+		// The region was found when the bucket was listed
+		return b.region, nil
+	}
+
 	// Note that the callback to CachedOp also creates a new client for that region.
 	// We use CachedOp with a long expiration to ensure region is fetched infrequently.
 	// You can force a retry by deleting the cache entry if there was an error.
 	resp, err := plugin.CachedOp(ctx, "Region", b, 24*time.Hour, func() (interface{}, error) {
-		locRequest := &s3Client.GetBucketLocationInput{Bucket: awsSDK.String(b.Name())}
-		// Normalize bucket location so empty region responses are interpreted as Amazon's default (us-east-1)
-		resp, err := b.client.GetBucketLocationWithContext(ctx, locRequest, s3Client.WithNormalizeBucketLocation)
-		if err != nil {
-			return nil, fmt.Errorf("could not get the region of bucket %v: %w", b.Name(), err)
-		}
-		return awsSDK.StringValue(resp.LocationConstraint), nil
+		return bucketRegion(ctx, b.client, b.Name())
 	})
 
 	if err != nil {
@@ -315,10 +351,13 @@ func (b *s3Bucket) getRegion(ctx context.Context) (string, error) {
 }
 
 const s3BucketDescription = `
-This is an S3 bucket. For convenience, we impose some hierarchical structure
+This is an S3 bucket. Buckets are global, but their metadata includes the
+region that they're stored in. For convenience, we impose some hierarchical structure
 on its objects by grouping keys with common prefixes into a specific directory.
 For example, the objects 'foo/bar' and 'foo/baz' are represented as files with
 path 'foo/bar' and path 'foo/baz', where 'foo' is represented as a 'directory'.
 Thus, if you ls this bucket, then everything you'll see is either an S3 object
-prefix ('directory') or an S3 object ('file').
+prefix ('directory') or an S3 object ('file'). Versioned buckets also contain a
+versions directory with the versions of their objects, unless the bucket has an
+object or prefix named versions.
 `
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
@@ -13,18 +14,20 @@ import (
 	s3Client "github.com/aws/aws-sdk-go/service/s3"
 )
 
-// s3Dir represents the resources/s3 directory
+// s3Dir represents the <profile>/resources/s3 directory
 type s3Dir struct {
 	plugin.EntryBase
 	session *session.Session
 	client  *s3Client.S3
+	opts    s3Options
 }
 
-func newS3Dir(ctx context.Context, session *session.Session) *s3Dir {
+func newS3Dir(ctx context.Context, session *session.Session, opts s3Options) *s3Dir {
 	s3Dir := &s3Dir{
 		EntryBase: plugin.NewEntry("s3"),
 	}
 	s3Dir.session = session
+	s3Dir.opts = opts
 
 	// All S3 buckets can be listed from any region. Normalize the configured region so we can still
 	// list buckets if region is unspecified.
@@ -56,14 +59,40 @@ func (s *s3Dir) List(ctx context.Context) ([]plugin.Entry, error) {
 
 	activity.Record(ctx, "Listing %v S3 buckets", len(resp.Buckets))
 
+	// Buckets are global, but each bucket lives in a region. Look up the regions
+	// concurrently, a few at a time.
 	buckets := make([]plugin.Entry, len(resp.Buckets))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bucketRegionConcurrency)
 	for i, bucket := range resp.Buckets {
-		buckets[i] = newS3Bucket(
-			awsSDK.StringValue(bucket.Name),
-			awsSDK.TimeValue(bucket.CreationDate),
-			s.session,
-		)
+		wg.Add(1)
+		go func(i int, bucket *s3Client.Bucket) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			region, err := bucketRegion(ctx, s.client, awsSDK.StringValue(bucket.Name))
+			if err != nil {
+				activity.Record(ctx, "%v", err)
+			}
+			buckets[i] = newS3Bucket(bucket, region, s.session, s.opts)
+		}(i, bucket)
 	}
+	wg.Wait()
 
 	return buckets, nil
 }
+
+// bucketRegionConcurrency is the number of bucket regions that are looked up at once
+const bucketRegionConcurrency = 10
+
+// bucketRegion returns the bucket's region.
+func bucketRegion(ctx context.Context, client *s3Client.S3, bucket string) (string, error) {
+	locRequest := &s3Client.GetBucketLocationInput{Bucket: awsSDK.String(bucket)}
+	// Normalize bucket location so empty region responses are interpreted as Amazon's default (us-east-1)
+	resp, err := client.GetBucketLocationWithContext(ctx, locRequest, s3Client.WithNormalizeBucketLocation)
+	if err != nil {
+		return "", fmt.Errorf("could not get the region of bucket %v: %w", bucket, err)
+	}
+	return awsSDK.StringValue(resp.LocationConstraint), nil
+}
@@ -3,8 +3,6 @@ package aws
 import (
 	"bytes"
 	"context"
-	"io/ioutil"
-	"strconv"
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
@@ -20,15 +18,22 @@ type s3Object struct {
 	bucket string
 	key    string
 	client *s3Client.S3
+	reader *s3Reader
+	// storageClass is the object's storage class when it was listed
+	storageClass string
+	opts         s3Options
 }
 
-func newS3Object(o *s3Client.Object, name string, bucket string, key string, client *s3Client.S3) *s3Object {
+func newS3Object(o *s3Client.Object, name string, bucket string, key string, client *s3Client.S3, opts s3Options) *s3Object {
 	s3Obj := &s3Object{
 		EntryBase: plugin.NewEntry(name),
 	}
 	s3Obj.bucket = bucket
 	s3Obj.key = key
 	s3Obj.client = client
+	s3Obj.reader = newS3Reader(client, bucket, key, "", awsSDK.StringValue(o.ETag), awsSDK.Int64Value(o.Size))
+	s3Obj.storageClass = awsSDK.StringValue(o.StorageClass)
+	s3Obj.opts = opts
 
 	// S3 objects do not have a "creation time"; they're treated as atomic
 	// blobs that get replaced whenever the user uploads new data. Thus, we
@@ -55,56 +60,76 @@ func (o *s3Object) Schema() *plugin.EntrySchema {
 		NewEntrySchema(o, "object").
 		SetDescription(s3ObjectDescription).
 		SetPartialMetadataSchema(s3Client.Object{}).
-		SetMetadataSchema(s3Client.HeadObjectOutput{})
+		SetMetadataSchema(s3ObjectMetadata{})
+}
+
+// s3ObjectMetadata is an S3 object's full metadata. HeadObjectOutput's Metadata field
+// is the object's user metadata.
+type s3ObjectMetadata struct {
+	*s3Client.HeadObjectOutput
+	TagSet []*s3Client.Tag
 }
 
 func (o *s3Object) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	return objectMetadata(ctx, o.client, o.bucket, o.key, "")
+}
+
+// objectMetadata returns the metadata of a version of an object. The latest version's
+// metadata is returned if versionID is empty.
+func objectMetadata(ctx context.Context, client *s3Client.S3, bucket, key, versionID string) (plugin.JSONObject, error) {
 	request := &s3Client.HeadObjectInput{
-		Bucket: awsSDK.String(o.bucket),
-		Key:    awsSDK.String(o.key),
+		Bucket: awsSDK.String(bucket),
+		Key:    awsSDK.String(key),
+	}
+	tagsRequest := &s3Client.GetObjectTaggingInput{
+		Bucket: awsSDK.String(bucket),
+		Key:    awsSDK.String(key),
+	}
+	if versionID != "" {
+		request.VersionId = awsSDK.String(versionID)
+		tagsRequest.VersionId = awsSDK.String(versionID)
 	}
 
-	metadata, err := o.client.HeadObjectWithContext(ctx, request)
+	head, err := client.HeadObjectWithContext(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-
+	metadata := s3ObjectMetadata{HeadObjectOutput: head}
+	if tags, err := client.GetObjectTaggingWithContext(ctx, tagsRequest); err == nil {
+		metadata.TagSet = tags.TagSet
+	} else {
+		// Reading tags needs a separate permission, so don't fail if it's missing.
+		activity.Record(ctx, "Could not get the tags of S3 object %v in bucket %v: %v", key, bucket, err)
+	}
 	return plugin.ToJSONObject(metadata), nil
 }
 
+// Read reads part of the object. Large objects are read ahead in parallel when they're read
+// sequentially.
 func (o *s3Object) Read(ctx context.Context, size int64, offset int64) ([]byte, error) {
-	// Because bytes request is inclusive, short-circuit requests for 0 bytes.
-	if size <= 0 || offset < 0 {
-		return []byte{}, nil
-	}
-
-	// Bytes range is inclusive, so for N bytes get byte range 0-(N-1).
-	request := &s3Client.GetObjectInput{
-		Bucket: awsSDK.String(o.bucket),
-		Key:    awsSDK.String(o.key),
-		Range:  awsSDK.String("bytes=" + strconv.FormatInt(offset, 10) + "-" + strconv.FormatInt(offset+size-1, 10)),
-	}
-
-	resp, err := o.client.GetObjectWithContext(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			activity.Record(ctx, "Error closing S3 GetObject response body: %v", err)
-		}
-	}()
-
-	activity.Record(ctx, "S3 object read response: %+v", *resp)
-	return ioutil.ReadAll(resp.Body)
+	return o.reader.read(ctx, size, offset)
 }
 
+// Write replaces the object. It's written with the configured server-side encryption
+// and storage class.
 func (o *s3Object) Write(ctx context.Context, p []byte) error {
 	request := &s3Client.PutObjectInput{
 		Bucket: awsSDK.String(o.bucket),
 		Key:    awsSDK.String(o.key),
 		Body:   bytes.NewReader(p),
 	}
+	if o.opts.ServerSideEncryption != "" {
+		request.ServerSideEncryption = awsSDK.String(o.opts.ServerSideEncryption)
+	}
+	if o.opts.SSEKMSKeyID != "" {
+		request.SSEKMSKeyId = awsSDK.String(o.opts.SSEKMSKeyID)
+	}
+	if o.opts.StorageClass != "" {
+		request.StorageClass = awsSDK.String(o.opts.StorageClass)
+	} else if o.storageClass != "" {
+		// Writes would otherwise change the object's storage class to STANDARD.
+		request.StorageClass = awsSDK.String(o.storageClass)
+	}
 
 	resp, err := o.client.PutObjectWithContext(ctx, request)
 	if err != nil {
@@ -125,5 +150,7 @@ func (o *s3Object) Delete(ctx context.Context) (bool, error) {
 
 const s3ObjectDescription = `
 This is an S3 object. See the bucket's docs for more details on
-why we have this kind of entry.
+why we have this kind of entry. Its metadata includes its user
+metadata and tags. Large objects are read ahead in parallel, so
+they're fastest to read sequentially.
 `
@@ -17,15 +17,17 @@ type s3ObjectPrefix struct {
 	bucket string
 	prefix string
 	client *s3Client.S3
+	opts   s3Options
 }
 
-func newS3ObjectPrefix(name string, bucket string, prefix string, client *s3Client.S3) *s3ObjectPrefix {
+func newS3ObjectPrefix(name string, bucket string, prefix string, client *s3Client.S3, opts s3Options) *s3ObjectPrefix {
 	objPrefix := &s3ObjectPrefix{
 		EntryBase: plugin.NewEntry(name),
 	}
 	objPrefix.bucket = bucket
 	objPrefix.prefix = prefix
 	objPrefix.client = client
+	objPrefix.opts = opts
 	return objPrefix
 }
 
@@ -43,7 +45,7 @@ func (d *s3ObjectPrefix) ChildSchemas() []*plugin.EntrySchema {
 // List lists all S3 objects and S3 object prefixes that are
 // prefixed by the current S3 object prefix
 func (d *s3ObjectPrefix) List(ctx context.Context) ([]plugin.Entry, error) {
-	return listObjects(ctx, d.client, d.bucket, d.prefix)
+	return listObjects(ctx, d.client, d.bucket, d.prefix, d.opts)
 }
 
 func (d *s3ObjectPrefix) Delete(ctx context.Context) (bool, error) {
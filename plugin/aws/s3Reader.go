@@ -0,0 +1,288 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	s3Client "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/puppetlabs/wash/activity"
+)
+
+// These control how large objects are read. FUSE reads files in small blocks, so
+// objects that are larger than s3ReadAheadThreshold are fetched in s3PartSize parts
+// instead. Once a read continues where an earlier read ended, the next
+// s3ReadAheadParts parts are fetched in parallel in the background. Other reads only
+// fetch what they read, so that a single small read doesn't fetch the parts after it.
+//
+// An object keeps at most s3MaxParts parts, forgetting the least recently used ones
+// first. Parts are also forgotten once they've been read to their end, and when they
+// haven't been used for s3PartTTL. Reads remember where the last s3MaxStreams
+// sequential reads ended so that concurrent readers of the same object each read
+// ahead.
+var (
+	s3ReadAheadThreshold int64 = 8 * 1024 * 1024
+	s3PartSize           int64 = 1024 * 1024
+	s3ReadAheadParts     int64 = 8
+	s3MaxParts                 = 2 * (s3ReadAheadParts + 1)
+	s3MaxStreams               = 4
+	s3PartTTL                  = time.Minute
+)
+
+// s3Part is a part of an object that's being fetched. done is closed once data or
+// err is set. cancel cancels the fetch, and evicted is set when the part's forgotten
+// before it's read. used is when a read last used the part.
+type s3Part struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	evicted bool
+	used    time.Time
+	data    []byte
+	err     error
+}
+
+// s3Reader reads ranges of an S3 object, reading ahead for large objects.
+type s3Reader struct {
+	size int64
+	// fetch returns the inclusive byte range [start, end] of the object
+	fetch func(ctx context.Context, start, end int64) ([]byte, error)
+	mux   sync.Mutex
+	parts map[int64]*s3Part
+	// streams maps the offsets where recent reads ended to when they were read.
+	streams map[int64]time.Time
+	// expiry forgets the parts that haven't been used for s3PartTTL.
+	expiry *time.Timer
+}
+
+func newS3RangeReader(size int64, fetch func(ctx context.Context, start, end int64) ([]byte, error)) *s3Reader {
+	return &s3Reader{
+		size:    size,
+		fetch:   fetch,
+		parts:   make(map[int64]*s3Part),
+		streams: make(map[int64]time.Time),
+	}
+}
+
+// newS3Reader returns a reader of the object. Reads fail if the object's ETag no longer
+// matches etag, so that parts of different objects aren't mixed when the object is
+// overwritten.
+func newS3Reader(client *s3Client.S3, bucket, key, versionID, etag string, size int64) *s3Reader {
+	return newS3RangeReader(size, func(ctx context.Context, start, end int64) ([]byte, error) {
+		// Bytes range is inclusive, so for N bytes get byte range 0-(N-1).
+		request := &s3Client.GetObjectInput{
+			Bucket: awsSDK.String(bucket),
+			Key:    awsSDK.String(key),
+			Range:  awsSDK.String("bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10)),
+		}
+		if versionID != "" {
+			request.VersionId = awsSDK.String(versionID)
+		}
+		if etag != "" {
+			request.IfMatch = awsSDK.String(etag)
+		}
+		resp, err := client.GetObjectWithContext(ctx, request)
+		if err != nil {
+			if awserr, ok := err.(awserr.Error); ok && awserr.Code() == "PreconditionFailed" {
+				return nil, fmt.Errorf("the %v object changed since it was listed. List its bucket again to read it", key)
+			}
+			return nil, err
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				activity.Record(ctx, "Error closing S3 GetObject response body: %v", err)
+			}
+		}()
+		return ioutil.ReadAll(resp.Body)
+	})
+}
+
+// read returns size bytes of the object starting at offset.
+func (r *s3Reader) read(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	// Because bytes request is inclusive, short-circuit requests for 0 bytes.
+	if size <= 0 || offset < 0 || offset >= r.size {
+		return []byte{}, nil
+	}
+	if offset+size > r.size {
+		size = r.size - offset
+	}
+	if r.size <= s3ReadAheadThreshold || !r.continues(offset, offset+size) {
+		return r.fetch(ctx, offset, offset+size-1)
+	}
+
+	first, last := offset/s3PartSize, (offset+size-1)/s3PartSize
+	parts := r.startFetching(first, last)
+	data := make([]byte, 0, size)
+	for i, part := range parts {
+		select {
+		case <-part.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		index := first + int64(i)
+		partStart := index * s3PartSize
+		partSize := s3PartSize
+		if partStart+partSize > r.size {
+			partSize = r.size - partStart
+		}
+		start, end := int64(0), partSize
+		if i == 0 {
+			start = offset - partStart
+		}
+		if i == len(parts)-1 {
+			end = offset + size - partStart
+		}
+
+		if part.err != nil {
+			if !r.evicted(part) {
+				return nil, part.err
+			}
+			// A concurrent read forgot the part before it was fetched, so fetch what's
+			// needed from it directly rather than competing for it again.
+			piece, err := r.fetch(ctx, partStart+start, partStart+end-1)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, piece...)
+			continue
+		}
+		data = append(data, part.data[start:end]...)
+		if end == partSize {
+			r.release(index, part)
+		}
+	}
+	return data, nil
+}
+
+// continues returns whether a read from offset to end continues where a recent read
+// ended, and remembers where it ends.
+func (r *s3Reader) continues(offset, end int64) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := time.Now()
+	_, ok := r.streams[offset]
+	delete(r.streams, offset)
+	r.streams[end] = now
+	oldest := end
+	for streamEnd, read := range r.streams {
+		if now.Sub(read) > s3PartTTL {
+			delete(r.streams, streamEnd)
+		} else if read.Before(r.streams[oldest]) {
+			oldest = streamEnd
+		}
+	}
+	if len(r.streams) > s3MaxStreams {
+		delete(r.streams, oldest)
+	}
+	return ok
+}
+
+// startFetching starts fetching the parts from first to last and the parts after them
+// that are read ahead. It returns the parts from first to last. If that's more than
+// s3MaxParts parts, the least recently used other parts are forgotten and their
+// fetches are cancelled.
+func (r *s3Reader) startFetching(first, last int64) []*s3Part {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for i, part := range r.parts {
+		select {
+		case <-part.done:
+			if part.err != nil {
+				// Retry the part
+				delete(r.parts, i)
+			}
+		default:
+		}
+	}
+
+	now := time.Now()
+	numParts := (r.size + s3PartSize - 1) / s3PartSize
+	var parts []*s3Part
+	for i := first; i <= last+s3ReadAheadParts && i < numParts; i++ {
+		part, ok := r.parts[i]
+		if !ok {
+			// Parts outlive the read that started them, so they're fetched without its
+			// context.
+			ctx, cancel := context.WithCancel(context.Background())
+			part = &s3Part{done: make(chan struct{}), cancel: cancel}
+			r.parts[i] = part
+			start := i * s3PartSize
+			end := start + s3PartSize - 1
+			if end >= r.size {
+				end = r.size - 1
+			}
+			go func() {
+				defer cancel()
+				part.data, part.err = r.fetch(ctx, start, end)
+				close(part.done)
+			}()
+		}
+		part.used = now
+		if i <= last {
+			parts = append(parts, part)
+		}
+	}
+
+	for int64(len(r.parts)) > s3MaxParts {
+		oldest := int64(-1)
+		for i, part := range r.parts {
+			if part.used.Before(now) && (oldest < 0 || part.used.Before(r.parts[oldest].used)) {
+				oldest = i
+			}
+		}
+		if oldest < 0 {
+			// The other parts are all being read.
+			break
+		}
+		r.evict(oldest)
+	}
+	if r.expiry == nil {
+		r.expiry = time.AfterFunc(s3PartTTL, r.expire)
+	}
+	return parts
+}
+
+// evict forgets the part and cancels its fetch. r.mux must be locked.
+func (r *s3Reader) evict(i int64) {
+	part := r.parts[i]
+	part.evicted = true
+	part.cancel()
+	delete(r.parts, i)
+}
+
+// release forgets the part once it's been read to its end, unless it's been replaced.
+func (r *s3Reader) release(i int64, part *s3Part) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.parts[i] == part {
+		delete(r.parts, i)
+	}
+}
+
+// expire forgets the parts that haven't been used for s3PartTTL.
+func (r *s3Reader) expire() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	now := time.Now()
+	for i, part := range r.parts {
+		if now.Sub(part.used) >= s3PartTTL {
+			r.evict(i)
+		}
+	}
+	r.expiry = nil
+	if len(r.parts) > 0 {
+		r.expiry = time.AfterFunc(s3PartTTL, r.expire)
+	}
+}
+
+func (r *s3Reader) evicted(part *s3Part) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return part.evicted
+}
@@ -0,0 +1,272 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	s3Client "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3Object returns a reader of size bytes that records the ranges it fetches.
+func fakeS3Object(size int64) (*s3Reader, []byte, func() []string) {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	var mux sync.Mutex
+	var fetched []string
+	r := newS3RangeReader(size, func(ctx context.Context, start, end int64) ([]byte, error) {
+		mux.Lock()
+		fetched = append(fetched, fmt.Sprintf("%v-%v", start, end))
+		mux.Unlock()
+		return content[start : end+1], nil
+	})
+	return r, content, func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]string{}, fetched...)
+	}
+}
+
+// withSmallParts makes objects over 20 bytes read ahead 2 parts of 10 bytes, and keep
+// at most maxParts parts. It returns a function that restores the defaults.
+func withSmallParts(maxParts int64) func() {
+	threshold, partSize, readAhead, max := s3ReadAheadThreshold, s3PartSize, s3ReadAheadParts, s3MaxParts
+	s3ReadAheadThreshold, s3PartSize, s3ReadAheadParts, s3MaxParts = 20, 10, 2, maxParts
+	return func() {
+		s3ReadAheadThreshold, s3PartSize, s3ReadAheadParts, s3MaxParts = threshold, partSize, readAhead, max
+	}
+}
+
+func numParts(r *s3Reader) int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return len(r.parts)
+}
+
+func TestS3ReaderSmallObject(t *testing.T) {
+	defer withSmallParts(6)()
+	r, content, fetched := fakeS3Object(15)
+
+	data, err := r.read(context.Background(), 10, 2)
+	require.NoError(t, err)
+	assert.Equal(t, content[2:12], data)
+	// Reads past the end are truncated
+	data, err = r.read(context.Background(), 10, 10)
+	require.NoError(t, err)
+	assert.Equal(t, content[10:], data)
+	data, err = r.read(context.Background(), 10, 15)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	assert.Equal(t, []string{"2-11", "10-14"}, fetched())
+}
+
+func TestS3ReaderReadsAhead(t *testing.T) {
+	defer withSmallParts(6)()
+	r, content, fetched := fakeS3Object(55)
+	ctx := context.Background()
+
+	// The first read only fetches what it reads
+	data, err := r.read(ctx, 6, 7)
+	require.NoError(t, err)
+	assert.Equal(t, content[7:13], data)
+	assert.Equal(t, []string{"7-12"}, fetched())
+	assert.Equal(t, 0, numParts(r))
+
+	// Continuing it fetches part 1 and reads parts 2 and 3 ahead
+	data, err = r.read(ctx, 5, 13)
+	require.NoError(t, err)
+	assert.Equal(t, content[13:18], data)
+	require.Eventually(t, func() bool { return len(fetched()) == 4 }, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []string{"7-12", "10-19", "20-29", "30-39"}, fetched())
+
+	// Continuing to part 3 uses the parts that were read ahead, forgets parts 1 and 2
+	// because they've been read, and reads the last parts ahead
+	data, err = r.read(ctx, 20, 18)
+	require.NoError(t, err)
+	assert.Equal(t, content[18:38], data)
+	require.Eventually(t, func() bool { return len(fetched()) == 6 }, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []string{"7-12", "10-19", "20-29", "30-39", "40-49", "50-54"}, fetched())
+	r.mux.Lock()
+	assert.Len(t, r.parts, 3)
+	assert.Contains(t, r.parts, int64(3))
+	r.mux.Unlock()
+}
+
+func TestS3ReaderReadsAheadForEachReader(t *testing.T) {
+	defer withSmallParts(6)()
+	r, _, fetched := fakeS3Object(100)
+	ctx := context.Background()
+
+	// Interleaved sequential readers each continue their own read
+	for _, offset := range []int64{0, 50, 5, 55} {
+		_, err := r.read(ctx, 5, offset)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return len(fetched()) == 8 }, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []string{"0-4", "50-54", "0-9", "10-19", "20-29", "50-59", "60-69", "70-79"}, fetched())
+}
+
+func TestS3ReaderRetriesFailedParts(t *testing.T) {
+	defer withSmallParts(6)()
+	r, content, _ := fakeS3Object(30)
+	fetch := r.fetch
+	fail := int32(1)
+	r.fetch = func(ctx context.Context, start, end int64) ([]byte, error) {
+		if start == 10 && atomic.LoadInt32(&fail) == 1 {
+			return nil, fmt.Errorf("failed")
+		}
+		return fetch(ctx, start, end)
+	}
+	ctx := context.Background()
+
+	_, err := r.read(ctx, 5, 0)
+	require.NoError(t, err)
+	_, err = r.read(ctx, 10, 5)
+	assert.EqualError(t, err, "failed")
+	atomic.StoreInt32(&fail, 0)
+	data, err := r.read(ctx, 10, 15)
+	require.NoError(t, err)
+	assert.Equal(t, content[15:25], data)
+}
+
+// hangingFetch makes the reader's fetches of ranges that start at the offsets hang until
+// they're cancelled. It returns the offsets of the cancelled fetches.
+func hangingFetch(r *s3Reader, offsets ...int64) func() []int64 {
+	fetch := r.fetch
+	var mux sync.Mutex
+	hang := make(map[int64]bool)
+	for _, offset := range offsets {
+		hang[offset] = true
+	}
+	var cancelled []int64
+	r.fetch = func(ctx context.Context, start, end int64) ([]byte, error) {
+		mux.Lock()
+		hung := hang[start]
+		// Only hang once
+		delete(hang, start)
+		mux.Unlock()
+		if !hung {
+			return fetch(ctx, start, end)
+		}
+		<-ctx.Done()
+		mux.Lock()
+		cancelled = append(cancelled, start)
+		mux.Unlock()
+		return nil, ctx.Err()
+	}
+	return func() []int64 {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]int64{}, cancelled...)
+	}
+}
+
+func TestS3ReaderCancelsEvictedParts(t *testing.T) {
+	defer withSmallParts(3)()
+	r, _, _ := fakeS3Object(200)
+	cancelled := hangingFetch(r, 10, 20)
+	ctx := context.Background()
+
+	// The first reader reads part 0 to its end, which forgets it, and reads parts 1 and
+	// 2 ahead
+	for _, offset := range []int64{0, 5} {
+		_, err := r.read(ctx, 5, offset)
+		require.NoError(t, err)
+	}
+	// The second reader needs room for parts 10 to 12, which forgets the least recently
+	// used parts 1 and 2 and cancels fetching them
+	for _, offset := range []int64{100, 105} {
+		_, err := r.read(ctx, 5, offset)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return len(cancelled()) == 2 }, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []int64{10, 20}, cancelled())
+	assert.True(t, numParts(r) <= 3)
+}
+
+func TestS3ReaderFetchesEvictedPartsDirectly(t *testing.T) {
+	defer withSmallParts(3)()
+	r, content, fetched := fakeS3Object(200)
+	cancelled := hangingFetch(r, 10)
+	ctx := context.Background()
+
+	_, err := r.read(ctx, 5, 5)
+	require.NoError(t, err)
+	// The first reader waits for part 1
+	result := make(chan []byte)
+	go func() {
+		data, err := r.read(ctx, 10, 10)
+		assert.NoError(t, err)
+		result <- data
+	}()
+	require.Eventually(t, func() bool { return numParts(r) == 3 }, time.Second, time.Millisecond)
+
+	// The second reader forgets part 1, so the first reader fetches its range directly
+	for _, offset := range []int64{100, 105} {
+		_, err := r.read(ctx, 5, offset)
+		require.NoError(t, err)
+	}
+	select {
+	case data := <-result:
+		assert.Equal(t, content[10:20], data)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the first read didn't finish")
+	}
+	assert.Equal(t, []int64{10}, cancelled())
+	var fetchedPart1 int
+	for _, rng := range fetched() {
+		if rng == "10-19" {
+			fetchedPart1++
+		}
+	}
+	assert.Equal(t, 1, fetchedPart1)
+}
+
+func TestS3ReaderExpiresParts(t *testing.T) {
+	defer withSmallParts(6)()
+	defer func(ttl time.Duration) { s3PartTTL = ttl }(s3PartTTL)
+	s3PartTTL = 50 * time.Millisecond
+	r, _, _ := fakeS3Object(55)
+	ctx := context.Background()
+
+	for _, offset := range []int64{0, 3} {
+		_, err := r.read(ctx, 3, offset)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, numParts(r))
+	require.Eventually(t, func() bool { return numParts(r) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestS3ReaderPinsETag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") != `"v2"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-4/5")
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+	sess := newTestSession(t, server.URL)
+	sess.Config.WithS3ForcePathStyle(true)
+	client := s3Client.New(sess)
+
+	data, err := newS3Reader(client, "bucket", "hello.txt", "", `"v2"`, 5).read(context.Background(), 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = newS3Reader(client, "bucket", "hello.txt", "", `"v1"`, 5).read(context.Background(), 5, 0)
+	assert.EqualError(t, err, "the hello.txt object changed since it was listed. List its bucket again to read it")
+}
@@ -0,0 +1,228 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	s3Client "github.com/aws/aws-sdk-go/service/s3"
+)
+
+// isVersioned returns true if versioning was ever enabled for the bucket. Buckets
+// whose versioning was suspended keep their versions.
+func (b *s3Bucket) isVersioned(ctx context.Context) (bool, error) {
+	status, err := plugin.CachedOp(ctx, "Versioning", b, 1*time.Minute, func() (interface{}, error) {
+		resp, err := b.client.GetBucketVersioningWithContext(ctx, &s3Client.GetBucketVersioningInput{
+			Bucket: awsSDK.String(b.Name()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return awsSDK.StringValue(resp.Status), nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return status.(string) != "", nil
+}
+
+// s3VersionsDir represents the versions of the objects under a prefix of a versioned
+// bucket. The bucket's versions directory is the root prefix's s3VersionsDir. Like
+// the bucket, it groups the keys with common prefixes into directories.
+type s3VersionsDir struct {
+	plugin.EntryBase
+	bucket string
+	prefix string
+	client *s3Client.S3
+}
+
+func newS3VersionsDir(name string, bucket string, prefix string, client *s3Client.S3) *s3VersionsDir {
+	versionsDir := &s3VersionsDir{
+		EntryBase: plugin.NewEntry(name),
+	}
+	versionsDir.bucket = bucket
+	versionsDir.prefix = prefix
+	versionsDir.client = client
+	return versionsDir
+}
+
+func (d *s3VersionsDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "versions").
+		SetDescription(s3VersionsDirDescription)
+}
+
+func (d *s3VersionsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&s3VersionsDir{}).Schema(),
+		(&s3ObjectVersions{}).Schema(),
+	}
+}
+
+// List lists the prefixes and the keys under the prefix. Each key is a directory of
+// its versions.
+func (d *s3VersionsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var prefixes []string
+	var keys []string
+	versions := make(map[string][]*s3Client.ObjectVersion)
+	err := d.client.ListObjectVersionsPagesWithContext(
+		ctx,
+		&s3Client.ListObjectVersionsInput{
+			Bucket:    awsSDK.String(d.bucket),
+			Prefix:    awsSDK.String(d.prefix),
+			Delimiter: awsSDK.String("/"),
+		},
+		func(page *s3Client.ListObjectVersionsOutput, _ bool) bool {
+			for _, p := range page.CommonPrefixes {
+				prefixes = append(prefixes, awsSDK.StringValue(p.Prefix))
+			}
+			for _, v := range page.Versions {
+				key := awsSDK.StringValue(v.Key)
+				if _, ok := versions[key]; !ok {
+					keys = append(keys, key)
+				}
+				versions[key] = append(versions[key], v)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	activity.Record(
+		ctx,
+		"(Bucket %v, Prefix %v): Retrieved %v prefixes and %v versioned objects",
+		d.bucket,
+		d.prefix,
+		len(prefixes),
+		len(keys),
+	)
+
+	// The names follow listObjects.
+	entries := make([]plugin.Entry, 0, len(prefixes)+len(keys))
+	for _, commonPrefix := range prefixes {
+		name := strings.TrimPrefix(commonPrefix, d.prefix)
+		if name != "/" {
+			name = strings.TrimSuffix(name, "/")
+		}
+		entries = append(entries, newS3VersionsDir(name, d.bucket, commonPrefix, d.client))
+	}
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, d.prefix)
+		if name == "" {
+			continue
+		}
+		entries = append(entries, newS3ObjectVersions(name, d.bucket, key, versions[key], d.client))
+	}
+	return entries, nil
+}
+
+// s3ObjectVersions represents the versions of an object.
+type s3ObjectVersions struct {
+	plugin.EntryBase
+	versions []plugin.Entry
+}
+
+func newS3ObjectVersions(name string, bucket string, key string, versions []*s3Client.ObjectVersion, client *s3Client.S3) *s3ObjectVersions {
+	objVersions := &s3ObjectVersions{
+		EntryBase: plugin.NewEntry(name),
+	}
+	objVersions.DisableDefaultCaching()
+	for _, version := range versions {
+		objVersions.versions = append(objVersions.versions, newS3ObjectVersion(version, bucket, key, client))
+	}
+	return objVersions
+}
+
+func (o *s3ObjectVersions) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(o, "object")
+}
+
+func (o *s3ObjectVersions) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&s3ObjectVersion{}).Schema(),
+	}
+}
+
+func (o *s3ObjectVersions) List(ctx context.Context) ([]plugin.Entry, error) {
+	return o.versions, nil
+}
+
+// s3ObjectVersion represents a version of an S3 object. It's named after its version
+// ID.
+type s3ObjectVersion struct {
+	plugin.EntryBase
+	bucket    string
+	key       string
+	versionID string
+	client    *s3Client.S3
+	reader    *s3Reader
+}
+
+func newS3ObjectVersion(version *s3Client.ObjectVersion, bucket string, key string, client *s3Client.S3) *s3ObjectVersion {
+	versionID := awsSDK.StringValue(version.VersionId)
+	objVersion := &s3ObjectVersion{
+		EntryBase: plugin.NewEntry(versionID),
+	}
+	objVersion.bucket = bucket
+	objVersion.key = key
+	objVersion.versionID = versionID
+	objVersion.client = client
+	objVersion.reader = newS3Reader(client, bucket, key, versionID, awsSDK.StringValue(version.ETag), awsSDK.Int64Value(version.Size))
+
+	// Versions are immutable, so their mtime is when they were written.
+	mtime := awsSDK.TimeValue(version.LastModified)
+	objVersion.
+		SetPartialMetadata(version).
+		Attributes().
+		SetCrtime(mtime).
+		SetMtime(mtime).
+		SetCtime(mtime).
+		SetAtime(mtime).
+		SetSize(uint64(awsSDK.Int64Value(version.Size)))
+	return objVersion
+}
+
+func (v *s3ObjectVersion) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(v, "version").
+		SetDescription(s3ObjectVersionDescription).
+		SetPartialMetadataSchema(s3Client.ObjectVersion{}).
+		SetMetadataSchema(s3ObjectMetadata{})
+}
+
+func (v *s3ObjectVersion) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	return objectMetadata(ctx, v.client, v.bucket, v.key, v.versionID)
+}
+
+func (v *s3ObjectVersion) Read(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return v.reader.read(ctx, size, offset)
+}
+
+// Delete permanently deletes the version.
+func (v *s3ObjectVersion) Delete(ctx context.Context) (bool, error) {
+	_, err := v.client.DeleteObjectWithContext(ctx, &s3Client.DeleteObjectInput{
+		Bucket:    awsSDK.String(v.bucket),
+		Key:       awsSDK.String(v.key),
+		VersionId: awsSDK.String(v.versionID),
+	})
+	return true, err
+}
+
+const s3VersionsDirDescription = `
+This contains the versions of a versioned bucket's objects. It groups keys with
+common prefixes into directories like the bucket does, and each object is a
+directory of its versions, including its latest version. Deleted objects are
+listed if they have versions. Delete markers aren't listed.
+`
+
+const s3ObjectVersionDescription = `
+This is a version of an S3 object, named after its version ID. Its metadata
+includes whether it's the latest version. Deleting it permanently deletes the
+version.
+`
@@ -0,0 +1,141 @@
+package aws
+
+import (
+	"context"
+	"unicode/utf8"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	secretsClient "github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// secretsDir represents the regions/<region>/secrets directory
+type secretsDir struct {
+	plugin.EntryBase
+	client *secretsClient.SecretsManager
+}
+
+func newSecretsDir(ctx context.Context, session *session.Session) *secretsDir {
+	secretsDir := &secretsDir{
+		EntryBase: plugin.NewEntry("secrets"),
+	}
+	secretsDir.client = secretsClient.New(session)
+	if _, err := plugin.List(ctx, secretsDir); err != nil {
+		secretsDir.MarkInaccessible(ctx, err)
+	}
+	return secretsDir
+}
+
+func (s *secretsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(s, "secrets").IsSingleton()
+}
+
+func (s *secretsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&secret{}).Schema(),
+	}
+}
+
+// List lists the Secrets Manager secrets.
+func (s *secretsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	err := s.client.ListSecretsPagesWithContext(
+		ctx,
+		&secretsClient.ListSecretsInput{},
+		func(page *secretsClient.ListSecretsOutput, _ bool) bool {
+			for _, entry := range page.SecretList {
+				entries = append(entries, newSecret(entry, s.client))
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v secrets", len(entries))
+	return entries, nil
+}
+
+// secret represents a Secrets Manager secret. Its value is never cached.
+type secret struct {
+	plugin.EntryBase
+	id     *string
+	client *secretsClient.SecretsManager
+}
+
+func newSecret(entry *secretsClient.SecretListEntry, client *secretsClient.SecretsManager) *secret {
+	secret := &secret{
+		EntryBase: plugin.NewEntry(awsSDK.StringValue(entry.Name)),
+	}
+	secret.id = entry.ARN
+	secret.client = client
+	secret.DisableCachingFor(plugin.ReadOp)
+
+	crtime := awsSDK.TimeValue(entry.CreatedDate)
+	mtime := crtime
+	if entry.LastChangedDate != nil {
+		mtime = awsSDK.TimeValue(entry.LastChangedDate)
+	}
+	atime := mtime
+	if entry.LastAccessedDate != nil {
+		atime = awsSDK.TimeValue(entry.LastAccessedDate)
+	}
+	secret.
+		SetPartialMetadata(entry).
+		Attributes().
+		SetCrtime(crtime).
+		SetMtime(mtime).
+		SetCtime(mtime).
+		SetAtime(atime).
+		SetMode(0600)
+	return secret
+}
+
+func (s *secret) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "secret").
+		SetDescription(secretDescription).
+		SetPartialMetadataSchema(secretsClient.SecretListEntry{})
+}
+
+// Read returns the current version of the secret's value.
+func (s *secret) Read(ctx context.Context) ([]byte, error) {
+	resp, err := s.client.GetSecretValueWithContext(ctx, &secretsClient.GetSecretValueInput{
+		SecretId: s.id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.SecretString != nil {
+		return []byte(awsSDK.StringValue(resp.SecretString)), nil
+	}
+	return resp.SecretBinary, nil
+}
+
+// Write stores the value as a new version of the secret, which becomes the current
+// version. Values that aren't UTF-8 text are stored as binary secrets.
+func (s *secret) Write(ctx context.Context, b []byte) error {
+	input := &secretsClient.PutSecretValueInput{
+		SecretId: s.id,
+	}
+	if utf8.Valid(b) {
+		input.SecretString = awsSDK.String(string(b))
+	} else {
+		input.SecretBinary = b
+	}
+	resp, err := s.client.PutSecretValueWithContext(ctx, input)
+	if err != nil {
+		return redact(err, b)
+	}
+	activity.Record(ctx, "Wrote version %v of the %v secret", awsSDK.StringValue(resp.VersionId), s.Name())
+	return nil
+}
+
+const secretDescription = `
+This is a Secrets Manager secret. Reading it returns the current version of its
+value. Writing to it creates a new version, which becomes the current version.
+Values are never cached or written to the activity journal. Slashes in the
+secret's name are replaced with #.
+`
@@ -0,0 +1,107 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSecretsManager is a stand-in for Secrets Manager with a db-password secret. It
+// rejects values that contain "invalid", echoing them in the error.
+func fakeSecretsManager() http.HandlerFunc {
+	var mux sync.Mutex
+	value := "hunter2"
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SecretString string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mux.Lock()
+		defer mux.Unlock()
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.ListSecrets":
+			fmt.Fprint(w, `{"SecretList": [{"Name": "db-password", "ARN": "arn:aws:secretsmanager:us-east-1:123456789012:secret:db-password-abc"}]}`)
+		case "secretsmanager.GetSecretValue":
+			fmt.Fprintf(w, `{"SecretString": %q}`, value)
+		case "secretsmanager.PutSecretValue":
+			if strings.Contains(body.SecretString, "invalid") {
+				http.Error(w, fmt.Sprintf(`{"__type": "InvalidParameterException", "message": "Invalid value %v"}`, body.SecretString), http.StatusBadRequest)
+				return
+			}
+			value = body.SecretString
+			fmt.Fprint(w, `{"VersionId": "v2"}`)
+		default:
+			http.Error(w, `{"__type": "InvalidAction"}`, http.StatusBadRequest)
+		}
+	}
+}
+
+func testSecret(ctx context.Context, t *testing.T, url string) *secret {
+	secrets := newSecretsDir(ctx, newTestSession(t, url))
+	entries, err := secrets.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	secret := entries[0].(*secret)
+	secret.SetTestID("/aws/default/regions/us-east-1/secrets/db-password")
+	return secret
+}
+
+func TestSecret_ReadsAreNotCached(t *testing.T) {
+	server, targets := recordingServer(fakeSecretsManager())
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	secret := testSecret(ctx, t, server.URL)
+	for i := 0; i < 2; i++ {
+		// Reads return io.EOF because the content is shorter than the requested size.
+		content, err := plugin.Read(ctx, secret, 100, 0)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, "hunter2", string(content))
+	}
+
+	var reads int
+	for _, target := range targets() {
+		if target == "secretsmanager.GetSecretValue" {
+			reads++
+		}
+	}
+	assert.Equal(t, 2, reads)
+}
+
+func TestSecret_WriteThenRead(t *testing.T) {
+	server, _ := recordingServer(fakeSecretsManager())
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	secret := testSecret(ctx, t, server.URL)
+	require.NoError(t, secret.Write(ctx, []byte("correct horse")))
+	content, err := secret.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "correct horse", string(content))
+}
+
+func TestSecret_WriteRedactsValue(t *testing.T) {
+	server, _ := recordingServer(fakeSecretsManager())
+	defer server.Close()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	secret := testSecret(ctx, t, server.URL)
+	err := secret.Write(ctx, []byte("invalid-s3cr3t"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Invalid value <redacted>")
+		assert.NotContains(t, err.Error(), "s3cr3t")
+	}
+}
@@ -0,0 +1,311 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	s3Client "github.com/aws/aws-sdk-go/service/s3"
+	ssmClient "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/kballard/go-shellquote"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// These are the ways to exec on EC2 instances, which are configured per profile.
+const (
+	// execAuto uses SSM for the instances that are managed by it and SSH otherwise.
+	execAuto = "auto"
+	execSSH  = "ssh"
+	execSSM  = "ssm"
+)
+
+// ssmOutputLimit is the number of characters of a command's stdout that SSM returns
+// when its output isn't written to S3.
+const ssmOutputLimit = 24000
+
+// ssmPollInterval is how often a command's status is checked.
+var ssmPollInterval = 1 * time.Second
+
+// ssmManagedInstances returns the IDs of the instances whose SSM agent is online.
+func ssmManagedInstances(ctx context.Context, client *ssmClient.SSM) (map[string]bool, error) {
+	managed := make(map[string]bool)
+	err := client.DescribeInstanceInformationPagesWithContext(
+		ctx,
+		&ssmClient.DescribeInstanceInformationInput{},
+		func(page *ssmClient.DescribeInstanceInformationOutput, _ bool) bool {
+			for _, info := range page.InstanceInformationList {
+				if awsSDK.StringValue(info.PingStatus) == ssmClient.PingStatusOnline {
+					managed[awsSDK.StringValue(info.InstanceId)] = true
+				}
+			}
+			return true
+		},
+	)
+	return managed, err
+}
+
+// ssmExecutor runs commands on an instance with SSM. Commands without input run with
+// Run Command (SendCommand) as root. Commands with input or a TTY run in a Session
+// Manager session as the ssm-user.
+type ssmExecutor struct {
+	session      *session.Session
+	client       *ssmClient.SSM
+	instanceID   string
+	windows      bool
+	outputBucket string
+}
+
+func (e ssmExecutor) exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	if opts.Tty || opts.Stdin != nil {
+		return e.startSession(ctx, cmd, args, opts)
+	}
+	return e.sendCommand(ctx, cmd, args)
+}
+
+// commandLine joins the command and its arguments into a command line for the
+// instance's shell.
+func (e ssmExecutor) commandLine(cmd string, args []string) string {
+	if !e.windows {
+		return shellquote.Join(append([]string{cmd}, args...)...)
+	}
+	words := []string{"&", powershellQuote(cmd)}
+	for _, arg := range args {
+		words = append(words, powershellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+func powershellQuote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n'\"`$;&|(){}<>,@#") {
+		return word
+	}
+	return "'" + strings.Replace(word, "'", "''", -1) + "'"
+}
+
+// document returns the Run Command document that runs the command and the name of its
+// plugin, which is part of the output's S3 key.
+func (e ssmExecutor) document() (document string, pluginName string) {
+	if e.windows {
+		return "AWS-RunPowerShellScript", "awsrunPowerShellScript"
+	}
+	return "AWS-RunShellScript", "awsrunShellScript"
+}
+
+func (e ssmExecutor) sendCommand(ctx context.Context, cmd string, args []string) (plugin.ExecCommand, error) {
+	document, pluginName := e.document()
+	input := &ssmClient.SendCommandInput{
+		DocumentName: awsSDK.String(document),
+		InstanceIds:  awsSDK.StringSlice([]string{e.instanceID}),
+		Parameters: map[string][]*string{
+			"commands": awsSDK.StringSlice([]string{e.commandLine(cmd, args)}),
+		},
+	}
+	if e.outputBucket != "" {
+		input.OutputS3BucketName = awsSDK.String(e.outputBucket)
+		input.OutputS3KeyPrefix = awsSDK.String("wash")
+	}
+	resp, err := e.client.SendCommandWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("could not send the command to %v with SSM: %v", e.instanceID, err)
+	}
+	commandID := awsSDK.StringValue(resp.Command.CommandId)
+	activity.Record(ctx, "Sent SSM command %v to %v", commandID, e.instanceID)
+
+	execCmd := plugin.NewExecCommand(ctx)
+	execCmd.SetStopFunc(func() {
+		_, err := e.client.CancelCommandWithContext(context.Background(), &ssmClient.CancelCommandInput{
+			CommandId:   awsSDK.String(commandID),
+			InstanceIds: awsSDK.StringSlice([]string{e.instanceID}),
+		})
+		activity.Record(ctx, "Cancelled SSM command %v on context termination: %v", commandID, err)
+	})
+
+	go func() {
+		invocation, err := e.waitForCommand(ctx, commandID)
+		if err != nil {
+			execCmd.CloseStreamsWithError(err)
+			execCmd.SetExitCodeErr(err)
+			return
+		}
+
+		stdout := awsSDK.StringValue(invocation.StandardOutputContent)
+		stderr := awsSDK.StringValue(invocation.StandardErrorContent)
+		if e.outputBucket != "" {
+			// The invocation only includes the start of the output.
+			key := path.Join("wash", commandID, e.instanceID, pluginName, "0."+pluginName)
+			if stdout, err = e.readOutput(ctx, key+"/stdout"); err == nil {
+				stderr, err = e.readOutput(ctx, key+"/stderr")
+			}
+			if err != nil {
+				err = fmt.Errorf("could not read the output of SSM command %v from the %v bucket: %v", commandID, e.outputBucket, err)
+				execCmd.CloseStreamsWithError(err)
+				execCmd.SetExitCodeErr(err)
+				return
+			}
+		} else if len(stdout) >= ssmOutputLimit {
+			activity.Warnf(ctx, "The output of SSM command %v was truncated to %v characters. Set the profile's ssm_output_bucket to get the full output.", commandID, ssmOutputLimit)
+		}
+		if _, err := execCmd.Stdout().Write([]byte(stdout)); err != nil {
+			activity.Record(ctx, "Errored writing the output of SSM command %v: %v", commandID, err)
+		}
+		if _, err := execCmd.Stderr().Write([]byte(stderr)); err != nil {
+			activity.Record(ctx, "Errored writing the output of SSM command %v: %v", commandID, err)
+		}
+		execCmd.CloseStreamsWithError(nil)
+
+		switch status := awsSDK.StringValue(invocation.Status); status {
+		case ssmClient.CommandInvocationStatusSuccess, ssmClient.CommandInvocationStatusFailed:
+			execCmd.SetExitCode(int(awsSDK.Int64Value(invocation.ResponseCode)))
+		default:
+			execCmd.SetExitCodeErr(fmt.Errorf("SSM command %v ended with status %v: %v", commandID, status, awsSDK.StringValue(invocation.StatusDetails)))
+		}
+	}()
+	return execCmd, nil
+}
+
+// waitForCommand waits for the command to finish and returns its final invocation.
+func (e ssmExecutor) waitForCommand(ctx context.Context, commandID string) (*ssmClient.GetCommandInvocationOutput, error) {
+	ticker := time.NewTicker(ssmPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		invocation, err := e.client.GetCommandInvocationWithContext(ctx, &ssmClient.GetCommandInvocationInput{
+			CommandId:  awsSDK.String(commandID),
+			InstanceId: awsSDK.String(e.instanceID),
+		})
+		if err != nil {
+			if awserr, ok := err.(awserr.Error); ok && awserr.Code() == ssmClient.ErrCodeInvocationDoesNotExist {
+				// The invocation isn't created right away
+				continue
+			}
+			return nil, fmt.Errorf("could not get the status of SSM command %v: %v", commandID, err)
+		}
+
+		switch awsSDK.StringValue(invocation.Status) {
+		case ssmClient.CommandInvocationStatusPending,
+			ssmClient.CommandInvocationStatusInProgress,
+			ssmClient.CommandInvocationStatusDelayed,
+			ssmClient.CommandInvocationStatusCancelling:
+			continue
+		}
+		return invocation, nil
+	}
+}
+
+// readOutput reads a command's output from the output bucket. SSM doesn't write empty
+// output.
+func (e ssmExecutor) readOutput(ctx context.Context, key string) (string, error) {
+	resp, err := s3Client.New(e.session).GetObjectWithContext(ctx, &s3Client.GetObjectInput{
+		Bucket: awsSDK.String(e.outputBucket),
+		Key:    awsSDK.String(key),
+	})
+	if err != nil {
+		if awserr, ok := err.(awserr.Error); ok && awserr.Code() == s3Client.ErrCodeNoSuchKey {
+			return "", nil
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	return string(content), err
+}
+
+// startSession runs the command in a Session Manager session. Interactive commands get
+// a pseudo-terminal.
+func (e ssmExecutor) startSession(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	commandLine := e.commandLine(cmd, args)
+	if opts.Elevate && !e.windows {
+		// Sessions run as the ssm-user, which can sudo by default.
+		commandLine = "sudo " + commandLine
+	}
+	document := "AWS-StartNonInteractiveCommand"
+	if opts.Tty {
+		document = "AWS-StartInteractiveCommand"
+	}
+	resp, err := e.client.StartSessionWithContext(ctx, &ssmClient.StartSessionInput{
+		Target:       awsSDK.String(e.instanceID),
+		DocumentName: awsSDK.String(document),
+		Parameters: map[string][]*string{
+			"command": awsSDK.StringSlice([]string{commandLine}),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not start an SSM session on %v: %v", e.instanceID, err)
+	}
+	sessionID := awsSDK.StringValue(resp.SessionId)
+	activity.Record(ctx, "Started SSM session %v on %v", sessionID, e.instanceID)
+	return execInSSMSession(ctx, e.client, e.instanceID, sessionID, awsSDK.StringValue(resp.StreamUrl), awsSDK.StringValue(resp.TokenValue), opts)
+}
+
+// execInSSMSession runs a command in a Session Manager session that was started on the
+// target. ECS Exec uses Session Manager sessions too. The session is terminated when the
+// command finishes.
+func execInSSMSession(
+	ctx context.Context,
+	client *ssmClient.SSM,
+	target string,
+	sessionID, streamURL, token string,
+	opts plugin.ExecOptions,
+) (plugin.ExecCommand, error) {
+	terminate := func() {
+		_, err := client.TerminateSessionWithContext(context.Background(), &ssmClient.TerminateSessionInput{
+			SessionId: awsSDK.String(sessionID),
+		})
+		activity.Record(ctx, "Terminated SSM session %v: %v", sessionID, err)
+	}
+
+	execCmd := plugin.NewExecCommand(ctx)
+	sess, err := openSSMSession(ctx, streamURL, token, execCmd.Stdout(), execCmd.Stderr())
+	if err != nil {
+		terminate()
+		return nil, err
+	}
+	execCmd.SetStopFunc(func() {
+		if opts.Tty {
+			// Send Ctrl-C like ExecSSH does
+			activity.Record(ctx, "Sent SIGINT on context termination: %v", sess.send(ssmOutputPayload, []byte{0x03}))
+		}
+		terminate()
+		activity.Record(ctx, "Closing SSM session %v on context termination: %v", sessionID, sess.close())
+	})
+
+	if opts.Tty {
+		go func() {
+			select {
+			case <-sess.ready:
+				activity.Record(ctx, "Set the size of SSM session %v's terminal: %v", sessionID, sess.resize(80, 40))
+			case <-sess.closed:
+			}
+		}()
+	}
+	if opts.Stdin != nil {
+		go sess.copyStdin(ctx, opts.Stdin)
+	}
+	go func() {
+		err := sess.run(ctx)
+		terminate()
+		activity.Record(ctx, "Closing SSM session %v: %v", sessionID, sess.close())
+		execCmd.CloseStreamsWithError(err)
+		switch {
+		case err != nil:
+			execCmd.SetExitCodeErr(err)
+		case sess.exitCode != nil:
+			execCmd.SetExitCode(*sess.exitCode)
+		default:
+			execCmd.SetExitCodeErr(fmt.Errorf("the SSM agent on %v did not report the command's exit code", target))
+		}
+	}()
+	return execCmd, nil
+}
@@ -0,0 +1,378 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/puppetlabs/wash/activity"
+)
+
+// This file implements the client side of Session Manager's websocket protocol, which
+// is what the session-manager-plugin uses to talk to the SSM agent. The message format
+// is described in https://github.com/aws/session-manager-plugin/tree/mainline/src/message.
+
+// Message types
+const (
+	ssmInputStreamMessage   = "input_stream_data"
+	ssmOutputStreamMessage  = "output_stream_data"
+	ssmAcknowledgeMessage   = "acknowledge"
+	ssmChannelClosedMessage = "channel_closed"
+)
+
+// Payload types
+const (
+	ssmOutputPayload            = 1
+	ssmErrorPayload             = 2
+	ssmSizePayload              = 3
+	ssmHandshakeRequestPayload  = 5
+	ssmHandshakeResponsePayload = 6
+	ssmHandshakeCompletePayload = 7
+	ssmStdErrPayload            = 11
+	ssmExitCodePayload          = 12
+)
+
+// Handshake action statuses
+const (
+	ssmActionSucceeded   = 1
+	ssmActionFailed      = 2
+	ssmActionUnsupported = 3
+)
+
+// ssmHeaderLength is the length of the message header, which is everything up to the
+// payload's length.
+const ssmHeaderLength = 116
+
+// ssmClientVersion is the session-manager-plugin version that we claim to be in the
+// handshake.
+const ssmClientVersion = "1.2.0.0"
+
+const ssmAcknowledgeFlag = 3
+
+type ssmMessage struct {
+	messageType    string
+	schemaVersion  uint32
+	createdDate    uint64
+	sequenceNumber int64
+	flags          uint64
+	messageID      uuid.UUID
+	payloadType    uint32
+	payload        []byte
+}
+
+// marshal encodes the message. Integers are big-endian. The message ID is stored with
+// its least significant half first.
+func (m *ssmMessage) marshal() []byte {
+	b := make([]byte, ssmHeaderLength+4+len(m.payload))
+	binary.BigEndian.PutUint32(b[0:4], ssmHeaderLength)
+	copy(b[4:36], fmt.Sprintf("%-32v", m.messageType))
+	binary.BigEndian.PutUint32(b[36:40], m.schemaVersion)
+	binary.BigEndian.PutUint64(b[40:48], m.createdDate)
+	binary.BigEndian.PutUint64(b[48:56], uint64(m.sequenceNumber))
+	binary.BigEndian.PutUint64(b[56:64], m.flags)
+	copy(b[64:72], m.messageID[8:])
+	copy(b[72:80], m.messageID[:8])
+	digest := sha256.Sum256(m.payload)
+	copy(b[80:112], digest[:])
+	binary.BigEndian.PutUint32(b[112:116], m.payloadType)
+	binary.BigEndian.PutUint32(b[116:120], uint32(len(m.payload)))
+	copy(b[120:], m.payload)
+	return b
+}
+
+func unmarshalSSMMessage(b []byte) (*ssmMessage, error) {
+	if len(b) < ssmHeaderLength+4 {
+		return nil, fmt.Errorf("the SSM message is too short (%v bytes)", len(b))
+	}
+	headerLength := int(binary.BigEndian.Uint32(b[0:4]))
+	if headerLength < ssmHeaderLength || len(b) < headerLength+4 {
+		return nil, fmt.Errorf("the SSM message has an invalid header length of %v", headerLength)
+	}
+	payloadLength := int(binary.BigEndian.Uint32(b[headerLength : headerLength+4]))
+	if len(b) < headerLength+4+payloadLength {
+		return nil, fmt.Errorf("the SSM message's payload is truncated")
+	}
+
+	m := &ssmMessage{
+		messageType:    strings.TrimRight(string(b[4:36]), " \x00"),
+		schemaVersion:  binary.BigEndian.Uint32(b[36:40]),
+		createdDate:    binary.BigEndian.Uint64(b[40:48]),
+		sequenceNumber: int64(binary.BigEndian.Uint64(b[48:56])),
+		flags:          binary.BigEndian.Uint64(b[56:64]),
+		payloadType:    binary.BigEndian.Uint32(b[112:116]),
+		payload:        b[headerLength+4 : headerLength+4+payloadLength],
+	}
+	copy(m.messageID[8:], b[64:72])
+	copy(m.messageID[:8], b[72:80])
+	return m, nil
+}
+
+type ssmHandshakeRequest struct {
+	AgentVersion           string
+	RequestedClientActions []struct {
+		ActionType string
+	}
+}
+
+type ssmProcessedClientAction struct {
+	ActionType   string
+	ActionStatus int
+	Error        string `json:",omitempty"`
+}
+
+type ssmHandshakeResponse struct {
+	ClientVersion          string
+	ProcessedClientActions []ssmProcessedClientAction
+	Errors                 []string
+}
+
+// ssmSession is a Session Manager session's data channel.
+type ssmSession struct {
+	conn *websocket.Conn
+	// writeMux guards writes to conn and the sequence number of our next message.
+	writeMux sync.Mutex
+	seq      int64
+	// The agent's messages are processed in order. Messages that arrive early are
+	// kept in pending until it's their turn.
+	expected int64
+	pending  map[int64]*ssmMessage
+	// ready is closed when the agent is ready for input, and closed is closed when
+	// the session is closed.
+	ready     chan struct{}
+	readyOnce sync.Once
+	closed    chan struct{}
+	closeOnce sync.Once
+	// The command's output is written to stdout and stderr. Agents that don't
+	// separate the command's output streams write everything to stdout.
+	stdout   io.Writer
+	stderr   io.Writer
+	exitCode *int
+	err      error
+}
+
+// openSSMSession connects to a session's stream URL. The token authenticates the
+// connection.
+func openSSMSession(ctx context.Context, streamURL string, token string, stdout io.Writer, stderr io.Writer) (*ssmSession, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, streamURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the SSM session: %v", err)
+	}
+	open := map[string]string{
+		"MessageSchemaVersion": "1.0",
+		"RequestId":            uuid.New().String(),
+		"TokenValue":           token,
+	}
+	if err := conn.WriteJSON(open); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not open the SSM session's data channel: %v", err)
+	}
+	return &ssmSession{
+		conn:    conn,
+		pending: make(map[int64]*ssmMessage),
+		ready:   make(chan struct{}),
+		closed:  make(chan struct{}),
+		stdout:  stdout,
+		stderr:  stderr,
+	}, nil
+}
+
+func (s *ssmSession) write(m *ssmMessage) error {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	if m.messageType == ssmInputStreamMessage {
+		m.sequenceNumber = s.seq
+		s.seq++
+	}
+	return s.conn.WriteMessage(websocket.BinaryMessage, m.marshal())
+}
+
+// send sends input to the agent.
+func (s *ssmSession) send(payloadType uint32, payload []byte) error {
+	return s.write(&ssmMessage{
+		messageType:   ssmInputStreamMessage,
+		schemaVersion: 1,
+		createdDate:   uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+		messageID:     uuid.New(),
+		payloadType:   payloadType,
+		payload:       payload,
+	})
+}
+
+func (s *ssmSession) acknowledge(m *ssmMessage) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"AcknowledgedMessageType":           m.messageType,
+		"AcknowledgedMessageId":             m.messageID.String(),
+		"AcknowledgedMessageSequenceNumber": m.sequenceNumber,
+		"IsSequentialMessage":               true,
+	})
+	if err != nil {
+		return err
+	}
+	return s.write(&ssmMessage{
+		messageType:   ssmAcknowledgeMessage,
+		schemaVersion: 1,
+		createdDate:   uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+		flags:         ssmAcknowledgeFlag,
+		messageID:     uuid.New(),
+		payload:       payload,
+	})
+}
+
+func (s *ssmSession) markReady() {
+	s.readyOnce.Do(func() {
+		close(s.ready)
+	})
+}
+
+// resize sets the size of the session's terminal.
+func (s *ssmSession) resize(cols int, rows int) error {
+	return s.send(ssmSizePayload, []byte(fmt.Sprintf(`{"cols":%v,"rows":%v}`, cols, rows)))
+}
+
+// copyStdin sends stdin to the agent once it's ready. The protocol has no way to
+// close the command's stdin, so the command won't see the end of the input.
+func (s *ssmSession) copyStdin(ctx context.Context, stdin io.Reader) {
+	select {
+	case <-s.ready:
+	case <-s.closed:
+		return
+	}
+	buf := make([]byte, 1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if err := s.send(ssmOutputPayload, append([]byte{}, buf[:n]...)); err != nil {
+				activity.Record(ctx, "Stopped sending stdin to the SSM session: %v", err)
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				activity.Record(ctx, "Stopped sending stdin to the SSM session: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// run processes the agent's messages until the session's channel is closed.
+func (s *ssmSession) run(ctx context.Context) error {
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return s.err
+			}
+			return fmt.Errorf("the SSM session's data channel failed: %v", err)
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		m, err := unmarshalSSMMessage(data)
+		if err != nil {
+			return err
+		}
+
+		switch m.messageType {
+		case ssmChannelClosedMessage:
+			var closed struct {
+				Output string
+			}
+			if err := json.Unmarshal(m.payload, &closed); err == nil && closed.Output != "" {
+				activity.Record(ctx, "The SSM session was closed: %v", closed.Output)
+			}
+			return s.err
+		case ssmOutputStreamMessage:
+			if err := s.acknowledge(m); err != nil {
+				return err
+			}
+			if m.sequenceNumber < s.expected {
+				// The agent resent a message that we already processed
+				continue
+			}
+			s.pending[m.sequenceNumber] = m
+			for next, ok := s.pending[s.expected]; ok; next, ok = s.pending[s.expected] {
+				delete(s.pending, s.expected)
+				s.expected++
+				if err := s.handle(ctx, next); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (s *ssmSession) handle(ctx context.Context, m *ssmMessage) error {
+	switch m.payloadType {
+	case ssmHandshakeRequestPayload:
+		return s.handshake(m.payload)
+	case ssmHandshakeCompletePayload:
+		s.markReady()
+	case ssmOutputPayload:
+		// Agents that don't support the handshake start with the command's output.
+		s.markReady()
+		_, err := s.stdout.Write(m.payload)
+		return err
+	case ssmStdErrPayload, ssmErrorPayload:
+		_, err := s.stderr.Write(m.payload)
+		return err
+	case ssmExitCodePayload:
+		exitCode, err := strconv.Atoi(strings.TrimSpace(string(m.payload)))
+		if err != nil {
+			return fmt.Errorf("could not parse the command's exit code %q: %v", m.payload, err)
+		}
+		s.exitCode = &exitCode
+	default:
+		activity.Record(ctx, "Ignoring SSM message with payload type %v", m.payloadType)
+	}
+	return nil
+}
+
+// handshake responds to the agent's handshake request. We don't support encrypting
+// the session with KMS, which is the only other action that agents request.
+func (s *ssmSession) handshake(payload []byte) error {
+	var request ssmHandshakeRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return fmt.Errorf("could not parse the SSM agent's handshake request: %v", err)
+	}
+	response := ssmHandshakeResponse{ClientVersion: ssmClientVersion, Errors: []string{}}
+	for _, action := range request.RequestedClientActions {
+		processed := ssmProcessedClientAction{ActionType: action.ActionType}
+		switch action.ActionType {
+		case "SessionType":
+			processed.ActionStatus = ssmActionSucceeded
+		case "KMSEncryption":
+			processed.ActionStatus = ssmActionFailed
+			processed.Error = "Wash does not support KMS-encrypted sessions"
+			s.err = fmt.Errorf("the SSM session requires KMS encryption, which Wash does not support")
+		default:
+			processed.ActionStatus = ssmActionUnsupported
+			processed.Error = fmt.Sprintf("unsupported action %v", action.ActionType)
+		}
+		response.ProcessedClientActions = append(response.ProcessedClientActions, processed)
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return s.send(ssmHandshakeResponsePayload, data)
+}
+
+// close closes the data channel. It's safe to call more than once.
+func (s *ssmSession) close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		err = s.conn.Close()
+	})
+	return err
+}
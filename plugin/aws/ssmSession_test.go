@@ -0,0 +1,198 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSMMessageRoundTrip(t *testing.T) {
+	m := &ssmMessage{
+		messageType:    ssmOutputStreamMessage,
+		schemaVersion:  1,
+		createdDate:    1585000000000,
+		sequenceNumber: 42,
+		flags:          1,
+		messageID:      uuid.New(),
+		payloadType:    ssmOutputPayload,
+		payload:        []byte("hello"),
+	}
+	b := m.marshal()
+	assert.Equal(t, "output_stream_data              ", string(b[4:36]))
+
+	decoded, err := unmarshalSSMMessage(b)
+	require.NoError(t, err)
+	assert.Equal(t, m, decoded)
+
+	_, err = unmarshalSSMMessage(b[:len(b)-1])
+	assert.EqualError(t, err, "the SSM message's payload is truncated")
+	_, err = unmarshalSSMMessage(b[:100])
+	assert.Error(t, err)
+}
+
+// fakeAgent plays the SSM agent's side of a session. It handshakes, echoes the first
+// input to stdout and stderr, then reports an exit code and closes the channel. It sends
+// the output out of order to check that the client reorders it. The client's messages
+// are sent to received, which is closed when the client disconnects.
+func fakeAgent(t *testing.T, received chan<- *ssmMessage) http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(received)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var open map[string]string
+		require.NoError(t, conn.ReadJSON(&open))
+		assert.Equal(t, "token", open["TokenValue"])
+
+		send := func(seq int64, payloadType uint32, payload string) {
+			m := &ssmMessage{
+				messageType:    ssmOutputStreamMessage,
+				sequenceNumber: seq,
+				messageID:      uuid.New(),
+				payloadType:    payloadType,
+				payload:        []byte(payload),
+			}
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, m.marshal()))
+		}
+		// receive returns the next input message, recording the acknowledgements
+		receive := func() *ssmMessage {
+			for {
+				_, data, err := conn.ReadMessage()
+				require.NoError(t, err)
+				m, err := unmarshalSSMMessage(data)
+				require.NoError(t, err)
+				received <- m
+				if m.messageType == ssmInputStreamMessage {
+					return m
+				}
+			}
+		}
+
+		send(0, ssmHandshakeRequestPayload, `{"AgentVersion":"3.0.0","RequestedClientActions":[{"ActionType":"SessionType"}]}`)
+		response := receive()
+		assert.Equal(t, uint32(ssmHandshakeResponsePayload), response.payloadType)
+		send(1, ssmHandshakeCompletePayload, `{}`)
+
+		input := receive()
+		assert.Equal(t, uint32(ssmOutputPayload), input.payloadType)
+		send(3, ssmStdErrPayload, "err: "+string(input.payload))
+		send(2, ssmOutputPayload, "out: "+string(input.payload))
+		send(4, ssmExitCodePayload, "3")
+		closed := &ssmMessage{
+			messageType: ssmChannelClosedMessage,
+			messageID:   uuid.New(),
+			payload:     []byte(`{"Output":"done"}`),
+		}
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, closed.marshal()))
+		// Record the remaining acknowledgements until the client closes the connection
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			m, err := unmarshalSSMMessage(data)
+			require.NoError(t, err)
+			received <- m
+		}
+	}
+}
+
+func TestSSMSession(t *testing.T) {
+	received := make(chan *ssmMessage, 100)
+	server := httptest.NewServer(fakeAgent(t, received))
+	defer server.Close()
+
+	ctx := context.Background()
+	var stdout, stderr bytes.Buffer
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	sess, err := openSSMSession(ctx, url, "token", &stdout, &stderr)
+	require.NoError(t, err)
+	go sess.copyStdin(ctx, strings.NewReader("hello"))
+
+	require.NoError(t, sess.run(ctx))
+	require.NoError(t, sess.close())
+	assert.Equal(t, "out: hello", stdout.String())
+	assert.Equal(t, "err: hello", stderr.String())
+	if assert.NotNil(t, sess.exitCode) {
+		assert.Equal(t, 3, *sess.exitCode)
+	}
+
+	// Check the handshake response and that every message was acknowledged
+	var acknowledged []int64
+	var inputs []*ssmMessage
+	for m := range received {
+		switch m.messageType {
+		case ssmAcknowledgeMessage:
+			var ack map[string]interface{}
+			require.NoError(t, json.Unmarshal(m.payload, &ack))
+			acknowledged = append(acknowledged, int64(ack["AcknowledgedMessageSequenceNumber"].(float64)))
+		case ssmInputStreamMessage:
+			inputs = append(inputs, m)
+		}
+	}
+	assert.ElementsMatch(t, []int64{0, 1, 2, 3, 4}, acknowledged)
+	if assert.Len(t, inputs, 2) {
+		var response ssmHandshakeResponse
+		require.NoError(t, json.Unmarshal(inputs[0].payload, &response))
+		assert.Equal(t, []ssmProcessedClientAction{{ActionType: "SessionType", ActionStatus: ssmActionSucceeded}}, response.ProcessedClientActions)
+		assert.Equal(t, []int64{0, 1}, []int64{inputs[0].sequenceNumber, inputs[1].sequenceNumber})
+	}
+}
+
+func TestSSMSessionRejectsKMSEncryption(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	responses := make(chan ssmHandshakeResponse, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var open map[string]string
+		require.NoError(t, conn.ReadJSON(&open))
+
+		m := &ssmMessage{
+			messageType: ssmOutputStreamMessage,
+			messageID:   uuid.New(),
+			payloadType: ssmHandshakeRequestPayload,
+			payload:     []byte(`{"RequestedClientActions":[{"ActionType":"KMSEncryption"}]}`),
+		}
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, m.marshal()))
+		for {
+			_, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+			m, err := unmarshalSSMMessage(data)
+			require.NoError(t, err)
+			if m.payloadType == ssmHandshakeResponsePayload {
+				var response ssmHandshakeResponse
+				require.NoError(t, json.Unmarshal(m.payload, &response))
+				responses <- response
+				break
+			}
+		}
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		require.NoError(t, conn.WriteMessage(websocket.CloseMessage, closeMsg))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	sess, err := openSSMSession(ctx, url, "token", &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	defer sess.close()
+
+	assert.EqualError(t, sess.run(ctx), "the SSM session requires KMS encryption, which Wash does not support")
+	response := <-responses
+	if assert.Len(t, response.ProcessedClientActions, 1) {
+		assert.Equal(t, ssmActionFailed, response.ProcessedClientActions[0].ActionStatus)
+	}
+}
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ekinanp/jsonschema"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// configSchemaOf returns the JSON schema of the root's config. Config options
+// are optional unless they're tagged with `jsonschema:"required"`. Unknown
+// options are not allowed so that typos in the config are caught early.
+// configSchemaOf will panic if ConfigSchema does not return a struct.
+func configSchemaOf(root HasConfigSchema) *JSONSchema {
+	obj := root.ConfigSchema()
+	if t := reflect.TypeOf(obj); t == nil || t.Kind() != reflect.Struct {
+		msg := fmt.Sprintf("configSchemaOf: the %v plugin's ConfigSchema must return a struct, not %T", pluginNameOf(root), obj)
+		panic(msg)
+	}
+	r := jsonschema.Reflector{
+		AllowAdditionalProperties:  false,
+		RequiredFromJSONSchemaTags: true,
+		// See EntrySchema#schemaOf for why this option is set.
+		ExpandedStruct: true,
+	}
+	return r.Reflect(obj)
+}
+
+// validateConfig validates the plugin's config against its config schema. The
+// returned error includes every violation, with each violation prefixed by the
+// option's full key (e.g. "aws.profiles").
+func validateConfig(root HasConfigSchema, config map[string]interface{}) error {
+	schema := configSchemaOf(root)
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(normalizeConfig(config)))
+	if err != nil {
+		return fmt.Errorf("could not validate the config: %v", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	var violations []string
+	for _, resultErr := range result.Errors() {
+		key := pluginNameOf(root)
+		if field := resultErr.Field(); field != gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			key += "." + field
+		}
+		violations = append(violations, fmt.Sprintf("%v: %v", key, resultErr.Description()))
+	}
+	return fmt.Errorf("invalid config: %v", strings.Join(violations, "; "))
+}
+
+// DecodeConfig decodes the plugin's config into obj, which should be a pointer
+// to the struct returned by the root's ConfigSchema method. Plugins can assume
+// that the config satisfies their config schema because the registry validates
+// it before calling Init.
+func DecodeConfig(config map[string]interface{}, obj interface{}) error {
+	if config == nil {
+		return nil
+	}
+	data, err := json.Marshal(normalizeConfig(config))
+	if err != nil {
+		return fmt.Errorf("could not decode the config: %v", err)
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("could not decode the config: %v", err)
+	}
+	return nil
+}
+
+// normalizeConfig converts any map[interface{}]interface{} values (which can
+// come from YAML config files) into map[string]interface{} values so that the
+// config can be serialized to JSON.
+func normalizeConfig(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			normalized[key] = normalizeConfig(val)
+		}
+		return normalized
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			normalized[fmt.Sprintf("%v", key)] = normalizeConfig(val)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, val := range v {
+			normalized[i] = normalizeConfig(val)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ConfigTestSuite) TestDecodeConfig() {
+	var c mockConfig
+	suite.NoError(DecodeConfig(nil, &c))
+	suite.Equal(mockConfig{}, c)
+
+	cfg := map[string]interface{}{"names": []interface{}{"foo", "bar"}, "enabled": true}
+	suite.NoError(DecodeConfig(cfg, &c))
+	suite.Equal(mockConfig{Names: []string{"foo", "bar"}, Enabled: true}, c)
+
+	suite.Regexp("could not decode the config", DecodeConfig(map[string]interface{}{"enabled": "foo"}, &c))
+}
+
+func (suite *ConfigTestSuite) TestNormalizeConfig() {
+	cfg := map[string]interface{}{
+		"foo": map[interface{}]interface{}{
+			"bar": []interface{}{map[interface{}]interface{}{1: "baz"}},
+		},
+	}
+	expected := map[string]interface{}{
+		"foo": map[string]interface{}{
+			"bar": []interface{}{map[string]interface{}{"1": "baz"}},
+		},
+	}
+	suite.Equal(expected, normalizeConfig(cfg))
+}
+
+func (suite *ConfigTestSuite) TestConfigSchemaOf_PanicsIfNotAStruct() {
+	root := &mockRootWithStringConfigSchema{&mockRoot{EntryBase: NewEntry("mine")}}
+	suite.Panics(func() { configSchemaOf(root) })
+}
+
+type mockRootWithStringConfigSchema struct {
+	*mockRoot
+}
+
+func (m *mockRootWithStringConfigSchema) ConfigSchema() interface{} {
+	return ""
+}
+
+func TestConfig(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+)
+
+// readFile downloads a single file from a container.
+func readFile(ctx context.Context, c client.APIClient, cid string, path string) ([]byte, error) {
+	rdr, _, err := c.CopyFromContainer(ctx, cid, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		activity.Record(ctx, "Closed file %v on %v: %v", path, cid, rdr.Close())
+	}()
+
+	// Read one file from the archive.
+	tarReader := tar.NewReader(rdr)
+	if _, err = tarReader.Next(); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(tarReader)
+}
+
+// fileArchive returns a tar archive containing a single file with the given
+// content. It's suitable for CopyToContainer.
+func fileArchive(name string, b []byte, mode os.FileMode) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	mtime := time.Now()
+	hdr := tar.Header{
+		Name: name,
+		Size: int64(len(b)),
+		Mode: int64(mode),
+		// Use PAX format to ensure compatibility with non-ASCII filenames.
+		Format: tar.FormatPAX,
+		// Use of PAX requires we set atime/ctime/mtime. Use now, we just read the file to update it.
+		AccessTime: mtime,
+		ChangeTime: mtime,
+		ModTime:    mtime,
+	}
+
+	if err := tw.WriteHeader(&hdr); err != nil {
+		return nil, err
+	} else if _, err := tw.Write(b); err != nil {
+		return nil, err
+	} else if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,226 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// These are the labels that Docker Compose sets on the resources it creates.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// composeFilters returns filters that match the resources labeled with the given
+// compose label values. The keys are label names.
+func composeFilters(labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+	for label, value := range labels {
+		args.Add("label", label+"="+value)
+	}
+	return args
+}
+
+type composeDir struct {
+	plugin.EntryBase
+	client client.APIClient
+}
+
+func newComposeDir(client client.APIClient) *composeDir {
+	composeDir := &composeDir{
+		EntryBase: plugin.NewEntry("compose"),
+	}
+	composeDir.client = client
+	return composeDir
+}
+
+func (cd *composeDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(cd, "compose").
+		SetDescription(composeDirDescription).
+		IsSingleton()
+}
+
+func (cd *composeDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&composeProject{}).Schema(),
+	}
+}
+
+// List lists the compose projects that have containers, volumes or networks.
+func (cd *composeDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	projectFilter := filters.NewArgs(filters.Arg("label", composeProjectLabel))
+	projects := make(map[string]struct{})
+
+	containers, err := cd.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: projectFilter})
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range containers {
+		projects[inst.Labels[composeProjectLabel]] = struct{}{}
+	}
+
+	volumes, err := cd.client.VolumeList(ctx, projectFilter)
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range volumes.Volumes {
+		projects[inst.Labels[composeProjectLabel]] = struct{}{}
+	}
+
+	networks, err := cd.client.NetworkList(ctx, types.NetworkListOptions{Filters: projectFilter})
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range networks {
+		projects[inst.Labels[composeProjectLabel]] = struct{}{}
+	}
+
+	activity.Record(ctx, "Listing %v compose projects in %v", len(projects), cd)
+	entries := make([]plugin.Entry, 0, len(projects))
+	for name := range projects {
+		entries = append(entries, newComposeProject(name, cd.client))
+	}
+	return entries, nil
+}
+
+type composeProject struct {
+	plugin.EntryBase
+	client client.APIClient
+}
+
+func newComposeProject(name string, client client.APIClient) *composeProject {
+	project := &composeProject{
+		EntryBase: plugin.NewEntry(name),
+	}
+	project.client = client
+	return project
+}
+
+func (p *composeProject) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(p, "project").
+		SetDescription(composeProjectDescription).
+		AddSignal("start", "Starts all of the project's containers. Equivalent to 'docker-compose start'").
+		AddSignal("stop", "Stops all of the project's containers. Equivalent to 'docker-compose stop'").
+		AddSignal("restart", "Restarts all of the project's containers. Equivalent to 'docker-compose restart'")
+}
+
+func (p *composeProject) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&composeServicesDir{}).Schema(),
+		(&volumesDir{}).Schema(),
+		(&networksDir{}).Schema(),
+	}
+}
+
+func (p *composeProject) filters() filters.Args {
+	return composeFilters(map[string]string{composeProjectLabel: p.Name()})
+}
+
+func (p *composeProject) List(ctx context.Context) ([]plugin.Entry, error) {
+	volumes := newVolumesDir(p.client)
+	volumes.filters = p.filters()
+	networks := newNetworksDir(p.client)
+	networks.filters = p.filters()
+	return []plugin.Entry{newComposeServicesDir(p), volumes, networks}, nil
+}
+
+func (p *composeProject) Signal(ctx context.Context, signal string) error {
+	containers, err := p.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: p.filters()})
+	if err != nil {
+		return err
+	}
+	// Signal the containers in the order that they were created, except for stop which
+	// goes in reverse order. That's close to what docker-compose does.
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Created < containers[j].Created
+	})
+	if signal == "stop" {
+		for i, j := 0, len(containers)-1; i < j; i, j = i+1, j-1 {
+			containers[i], containers[j] = containers[j], containers[i]
+		}
+	}
+
+	var errs []string
+	for _, inst := range containers {
+		c := newContainer(inst, p.client)
+		activity.Record(ctx, "Sending %v to container %v of project %v", signal, c.Name(), p.Name())
+		if err := c.Signal(ctx, signal); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", c.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("could not %v all of the project's containers: %v", signal, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+type composeServicesDir struct {
+	plugin.EntryBase
+	project string
+	client  client.APIClient
+}
+
+func newComposeServicesDir(p *composeProject) *composeServicesDir {
+	servicesDir := &composeServicesDir{
+		EntryBase: plugin.NewEntry("services"),
+	}
+	servicesDir.project = p.Name()
+	servicesDir.client = p.client
+	return servicesDir
+}
+
+func (ss *composeServicesDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(ss, "services").IsSingleton()
+}
+
+func (ss *composeServicesDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&composeService{}).Schema(),
+	}
+}
+
+func (ss *composeServicesDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	containers, err := ss.client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: composeFilters(map[string]string{composeProjectLabel: ss.project}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]struct{})
+	for _, inst := range containers {
+		if service, ok := inst.Labels[composeServiceLabel]; ok {
+			services[service] = struct{}{}
+		}
+	}
+
+	activity.Record(ctx, "Listing %v services in %v", len(services), ss)
+	entries := make([]plugin.Entry, 0, len(services))
+	for name := range services {
+		entries = append(entries, newComposeService(ss.project, name, ss.client))
+	}
+	return entries, nil
+}
+
+const composeDirDescription = `
+This directory groups containers, volumes and networks by the Docker Compose
+project and service that created them. It uses the com.docker.compose.project
+and com.docker.compose.service labels.
+`
+
+const composeProjectDescription = `
+This is a Docker Compose project. Its services directory contains the
+project's services, and its volumes and networks directories contain the
+volumes and networks that the project created.
+`
@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/internal/logs"
+)
+
+type composeService struct {
+	plugin.EntryBase
+	project string
+	client  client.APIClient
+}
+
+func newComposeService(project string, name string, client client.APIClient) *composeService {
+	service := &composeService{
+		EntryBase: plugin.NewEntry(name),
+	}
+	service.project = project
+	service.client = client
+	return service
+}
+
+func (s *composeService) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "service").
+		SetDescription(composeServiceDescription)
+}
+
+func (s *composeService) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&composeServiceLogFile{}).Schema(),
+		(&container{}).Schema(),
+	}
+}
+
+// containers returns the service's containers.
+func (s *composeService) containers(ctx context.Context) ([]*container, error) {
+	insts, err := s.client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+		Filters: composeFilters(map[string]string{
+			composeProjectLabel: s.project,
+			composeServiceLabel: s.Name(),
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]*container, len(insts))
+	for i, inst := range insts {
+		containers[i] = newContainer(inst, s.client)
+	}
+	return containers, nil
+}
+
+func (s *composeService) List(ctx context.Context) ([]plugin.Entry, error) {
+	containers, err := s.containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	activity.Record(ctx, "Listing %v containers in %v", len(containers), s)
+	entries := []plugin.Entry{newComposeServiceLogFile(s)}
+	for _, c := range containers {
+		entries = append(entries, c)
+	}
+	return entries, nil
+}
+
+// composeServiceLogFile aggregates the logs of a service's containers. Each line is
+// prefixed with the name of the container that logged it, like 'docker-compose logs'.
+type composeServiceLogFile struct {
+	plugin.EntryBase
+	service *composeService
+}
+
+func newComposeServiceLogFile(s *composeService) *composeServiceLogFile {
+	sl := &composeServiceLogFile{
+		EntryBase: plugin.NewEntry("log"),
+	}
+	sl.service = s
+	return sl
+}
+
+func (sl *composeServiceLogFile) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(sl, "log").
+		SetDescription("This is the aggregated log of all of the service's containers.").
+		IsSingleton()
+}
+
+// logPrefixes returns the prefix of each container's log lines. The container
+// names are padded so that the log lines are aligned.
+func logPrefixes(containers []*container) []string {
+	width := 0
+	for _, c := range containers {
+		if len(c.Name()) > width {
+			width = len(c.Name())
+		}
+	}
+	prefixes := make([]string, len(containers))
+	for i, c := range containers {
+		prefixes[i] = fmt.Sprintf("%-*v | ", width, c.Name())
+	}
+	return prefixes
+}
+
+func (sl *composeServiceLogFile) Read(ctx context.Context) ([]byte, error) {
+	containers, err := sl.service.containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for i, prefix := range logPrefixes(containers) {
+		content, err := newContainerLogFile(containers[i]).Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := logs.PrefixLines(&buf, bytes.NewReader(content), prefix); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (sl *composeServiceLogFile) Stream(ctx context.Context) (io.ReadCloser, error) {
+	containers, err := sl.service.containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes := logPrefixes(containers)
+	streams := make([]logs.Stream, len(containers))
+	for i, c := range containers {
+		rdr, err := newContainerLogFile(c).Stream(ctx)
+		if err != nil {
+			logs.CloseAll(streams)
+			return nil, err
+		}
+		streams[i] = logs.Stream{ReadCloser: rdr, Name: c.Name(), Prefix: prefixes[i]}
+	}
+	return logs.Merge(ctx, "service "+sl.service.Name(), streams), nil
+}
+
+const composeServiceDescription = `
+This is a Docker Compose service. It contains the service's containers, and
+a log file that aggregates their logs like 'docker-compose logs'.
+`
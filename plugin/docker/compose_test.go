@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func entryNames(entries []plugin.Entry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = plugin.Name(entry)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestComposeDirList(t *testing.T) {
+	client := newFakeClient()
+	// A project without containers is still listed
+	client.volumes[0].Labels[composeProjectLabel] = "cache"
+
+	entries, err := newComposeDir(client).List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache", "shop"}, entryNames(entries))
+}
+
+func TestComposeProjectList(t *testing.T) {
+	client := newFakeClient()
+	project := newComposeProject("shop", client)
+
+	entries, err := project.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"networks", "services", "volumes"}, entryNames(entries))
+
+	services, err := newComposeServicesDir(project).List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db", "web"}, entryNames(services))
+
+	// Only the project's networks are included
+	networks, err := entries[2].(*networksDir).List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop_default"}, entryNames(networks))
+}
+
+func TestComposeProjectSignal(t *testing.T) {
+	client := newFakeClient()
+	project := newComposeProject("shop", client)
+
+	// db was created first, so it's started first and stopped last
+	require.NoError(t, project.Signal(context.Background(), "start"))
+	require.NoError(t, project.Signal(context.Background(), "stop"))
+	assert.Equal(t, []string{"5678 start", "1234 start", "1234 stop", "5678 stop"}, client.signals)
+}
+
+func TestComposeServiceLogFile(t *testing.T) {
+	client := newFakeClient()
+	// Give the web service a second container to test the aggregation
+	second := client.containers[0]
+	second.ID = "9012"
+	second.Names = []string{"/web_2"}
+	client.containers = append(client.containers, second)
+	expected := "web   | GET / 200\nweb_2 | GET / 200\n"
+
+	services, err := newComposeServicesDir(newComposeProject("shop", client)).List(context.Background())
+	require.NoError(t, err)
+	var service *composeService
+	for _, entry := range services {
+		if plugin.Name(entry) == "web" {
+			service = entry.(*composeService)
+		}
+	}
+	require.NotNil(t, service)
+
+	entries, err := service.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log", "web", "web_2"}, entryNames(entries))
+
+	log := newComposeServiceLogFile(service)
+	content, err := log.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+
+	rdr, err := log.Stream(context.Background())
+	require.NoError(t, err)
+	content, err = ioutil.ReadAll(rdr)
+	assert.NoError(t, err)
+	assert.NoError(t, rdr.Close())
+	// The streamed lines are interleaved in the order that they arrive
+	assert.ElementsMatch(t, []string{"web   | GET / 200", "web_2 | GET / 200"}, strings.Split(strings.TrimSpace(string(content)), "\n"))
+}
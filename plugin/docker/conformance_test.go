@@ -0,0 +1,536 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	docontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	imagetypes "github.com/docker/docker/api/types/image"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/plugin"
+	plugintest "github.com/puppetlabs/wash/plugin/test"
+	vol "github.com/puppetlabs/wash/volume"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeClient is an in-memory Docker API. It only implements the methods
+// that are needed by the conformance tests; calling any other method panics.
+type fakeClient struct {
+	client.APIClient
+	mux        sync.Mutex
+	containers []types.Container
+	// removed are the IDs of the deleted resources. The suite reaches some
+	// resources through several directories, e.g. a connected container
+	// through both the containers and the networks directories, so it
+	// deletes them more than once.
+	removed  map[string]bool
+	volumes  []*types.Volume
+	images   []types.ImageSummary
+	networks []types.NetworkResource
+	// signals are the signals that were sent, formatted as "<id> <signal>"
+	signals []string
+	// files is the content of each container's filesystem, keyed by path.
+	// Directories are implied by the paths.
+	files map[string]string
+	// archived are the paths that were downloaded with CopyFromContainer
+	archived []string
+}
+
+func newFakeClient() *fakeClient {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Everything but the bridge network belongs to the "shop" compose project.
+	composeLabels := func(service string) map[string]string {
+		labels := map[string]string{composeProjectLabel: "shop"}
+		if service != "" {
+			labels[composeServiceLabel] = service
+		}
+		return labels
+	}
+	return &fakeClient{
+		containers: []types.Container{
+			{
+				ID:      "1234",
+				Names:   []string{"/web"},
+				Image:   "nginx",
+				Created: created.Unix() + 1,
+				State:   "running",
+				Labels:  composeLabels("web"),
+			},
+			{
+				ID:      "5678",
+				Names:   []string{"/db"},
+				Image:   "postgres",
+				Created: created.Unix(),
+				State:   "exited",
+				Labels:  composeLabels("db"),
+			},
+		},
+		removed: make(map[string]bool),
+		volumes: []*types.Volume{
+			{Name: "data", Driver: "local", CreatedAt: created.Format(time.RFC3339), Labels: composeLabels("")},
+		},
+		images: []types.ImageSummary{
+			{ID: "sha256:0123456789abcdef", RepoTags: []string{"nginx:latest"}, Created: created.Unix(), Size: 1024},
+			{ID: "sha256:fedcba9876543210", RepoTags: []string{"<none>:<none>"}, Created: created.Unix(), Size: 2048},
+		},
+		networks: []types.NetworkResource{
+			{
+				ID:         "abcd",
+				Name:       "bridge",
+				Driver:     "bridge",
+				Created:    created,
+				Containers: map[string]types.EndpointResource{"1234": {Name: "web"}},
+			},
+			{
+				ID:      "efgh",
+				Name:    "shop_default",
+				Driver:  "bridge",
+				Created: created,
+				Labels:  composeLabels(""),
+			},
+		},
+		files: map[string]string{
+			"/etc/hostname":   "web\n",
+			"/etc/nginx/mime": "text/html html\n",
+			"/usr/bin/nginx":  "binary",
+			"/index.html":     "<html></html>\n",
+		},
+	}
+}
+
+// archive returns a tar archive of src that's named like the archives that
+// Docker returns.
+func (c *fakeClient) archive(src string) (io.ReadCloser, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	src = path.Clean(src)
+	base := path.Base(src)
+	if src == "/" {
+		base = "."
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	write := func(hdr *tar.Header, content string) error {
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write([]byte(content))
+		return err
+	}
+	if content, ok := c.files[src]; ok {
+		if err := write(&tar.Header{Name: base, Mode: 0644, Size: int64(len(content))}, content); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(&buf), tw.Close()
+	}
+
+	prefix := strings.TrimSuffix(src, "/") + "/"
+	dirs := map[string]bool{}
+	var paths []string
+	for file := range c.files {
+		if strings.HasPrefix(file, prefix) {
+			paths = append(paths, file)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no such file or directory: %v", src)
+	}
+	sort.Strings(paths)
+	if err := write(&tar.Header{Name: base + "/", Typeflag: tar.TypeDir, Mode: 0755}, ""); err != nil {
+		return nil, err
+	}
+	for _, file := range paths {
+		rel := strings.TrimPrefix(file, prefix)
+		segments := strings.Split(rel, "/")
+		for i := 1; i < len(segments); i++ {
+			dir := path.Join(segments[:i]...)
+			if !dirs[dir] {
+				dirs[dir] = true
+				hdr := &tar.Header{Name: base + "/" + dir + "/", Typeflag: tar.TypeDir, Mode: 0755}
+				if err := write(hdr, ""); err != nil {
+					return nil, err
+				}
+			}
+		}
+		content := c.files[file]
+		hdr := &tar.Header{Name: base + "/" + rel, Mode: 0644, Size: int64(len(content))}
+		if err := write(hdr, content); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.NopCloser(&buf), tw.Close()
+}
+
+func (c *fakeClient) findContainer(id string) (types.Container, error) {
+	for _, container := range c.containers {
+		if container.ID == id {
+			return container, nil
+		}
+	}
+	return types.Container{}, fmt.Errorf("no such container: %v", id)
+}
+
+// matchesLabels returns true if labels match all of the filter's "label" values.
+// Like Docker, a value is either a label name or a name=value pair.
+func matchesLabels(filter filters.Args, labels map[string]string) bool {
+	for _, f := range filter.Get("label") {
+		segments := strings.SplitN(f, "=", 2)
+		value, ok := labels[segments[0]]
+		if !ok || (len(segments) == 2 && value != segments[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *fakeClient) connected(container types.Container, filter filters.Args) bool {
+	if !filter.Contains("network") {
+		return true
+	}
+	for _, network := range c.networks {
+		if filter.ExactMatch("network", network.ID) {
+			if _, ok := network.Containers[container.ID]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *fakeClient) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	containers := []types.Container{}
+	for _, container := range c.containers {
+		if c.connected(container, options.Filters) && matchesLabels(options.Filters, container.Labels) {
+			containers = append(containers, container)
+		}
+	}
+	return containers, nil
+}
+
+func (c *fakeClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	container, err := c.findContainer(id)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    container.ID,
+			Name:  container.Names[0],
+			Image: container.Image,
+			State: &types.ContainerState{
+				Status:  container.State,
+				Running: container.State == "running",
+			},
+		},
+		Config: &docontainer.Config{Image: container.Image, Tty: true},
+	}, nil
+}
+
+func (c *fakeClient) ContainerInspectWithRaw(ctx context.Context, id string, getSize bool) (types.ContainerJSON, []byte, error) {
+	inspect, err := c.ContainerInspect(ctx, id)
+	if err != nil {
+		return inspect, nil, err
+	}
+	raw, err := json.Marshal(inspect)
+	return inspect, raw, err
+}
+
+func (c *fakeClient) ContainerLogs(ctx context.Context, id string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("GET / 200\n")), nil
+}
+
+func (c *fakeClient) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i, container := range c.containers {
+		if container.ID == id {
+			c.containers = append(c.containers[:i], c.containers[i+1:]...)
+			c.removed[id] = true
+			return nil
+		}
+	}
+	if c.removed[id] {
+		return nil
+	}
+	return fmt.Errorf("no such container: %v", id)
+}
+
+// fakeStats is the sample that's returned by ContainerStats. It's a running
+// container that uses 512 MiB of memory and 50% of one of its 2 CPUs.
+var fakeStats = types.StatsJSON{
+	Stats: types.Stats{
+		Read: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		CPUStats: types.CPUStats{
+			CPUUsage:    types.CPUUsage{TotalUsage: 2000},
+			SystemUsage: 20000,
+			OnlineCPUs:  2,
+		},
+		PreCPUStats: types.CPUStats{
+			CPUUsage:    types.CPUUsage{TotalUsage: 1000},
+			SystemUsage: 16000,
+		},
+		MemoryStats: types.MemoryStats{
+			Usage: 640 << 20,
+			Limit: 1 << 30,
+			Stats: map[string]uint64{"cache": 128 << 20},
+		},
+		BlkioStats: types.BlkioStats{
+			IoServiceBytesRecursive: []types.BlkioStatEntry{
+				{Op: "Read", Value: 100},
+				{Op: "Write", Value: 200},
+				{Op: "Read", Value: 1},
+			},
+		},
+		PidsStats: types.PidsStats{Current: 3},
+	},
+	Networks: map[string]types.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	},
+}
+
+func (c *fakeClient) ContainerStats(ctx context.Context, id string, stream bool) (types.ContainerStats, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if _, err := c.findContainer(id); err != nil {
+		return types.ContainerStats{}, err
+	}
+	// Streams return two samples
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for i := 0; i < 1 || (stream && i < 2); i++ {
+		if err := encoder.Encode(fakeStats); err != nil {
+			return types.ContainerStats{}, err
+		}
+	}
+	return types.ContainerStats{Body: ioutil.NopCloser(&buf), OSType: "linux"}, nil
+}
+
+func (c *fakeClient) ContainerExecCreate(ctx context.Context, id string, config types.ExecConfig) (types.IDResponse, error) {
+	return types.IDResponse{}, fmt.Errorf("the fake client does not support exec")
+}
+
+func (c *fakeClient) recordSignal(id string, signal string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if _, err := c.findContainer(id); err != nil {
+		return err
+	}
+	c.signals = append(c.signals, id+" "+signal)
+	return nil
+}
+
+func (c *fakeClient) ContainerStart(ctx context.Context, id string, options types.ContainerStartOptions) error {
+	return c.recordSignal(id, "start")
+}
+
+func (c *fakeClient) ContainerStop(ctx context.Context, id string, timeout *time.Duration) error {
+	return c.recordSignal(id, "stop")
+}
+
+func (c *fakeClient) ContainerPause(ctx context.Context, id string) error {
+	return c.recordSignal(id, "pause")
+}
+
+func (c *fakeClient) ContainerUnpause(ctx context.Context, id string) error {
+	return c.recordSignal(id, "resume")
+}
+
+func (c *fakeClient) ContainerRestart(ctx context.Context, id string, timeout *time.Duration) error {
+	return c.recordSignal(id, "restart")
+}
+
+func (c *fakeClient) ContainerKill(ctx context.Context, id string, signal string) error {
+	return c.recordSignal(id, signal)
+}
+
+func (c *fakeClient) ContainerStatPath(ctx context.Context, id string, path string) (types.ContainerPathStat, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if content, ok := c.files[path]; ok {
+		return types.ContainerPathStat{Name: path, Size: int64(len(content)), Mode: 0644}, nil
+	}
+	for file := range c.files {
+		if strings.HasPrefix(file, path+"/") {
+			return types.ContainerPathStat{Name: path, Mode: os.ModeDir | 0755}, nil
+		}
+	}
+	return types.ContainerPathStat{}, fmt.Errorf("no such file: %v", path)
+}
+
+func (c *fakeClient) CopyFromContainer(ctx context.Context, id string, src string) (io.ReadCloser, types.ContainerPathStat, error) {
+	c.mux.Lock()
+	c.archived = append(c.archived, src)
+	c.mux.Unlock()
+	rdr, err := c.archive(src)
+	return rdr, types.ContainerPathStat{}, err
+}
+
+func (c *fakeClient) CopyToContainer(ctx context.Context, id string, dst string, content io.Reader, options types.CopyToContainerOptions) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	tarReader := tar.NewReader(content)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return err
+		}
+		c.files[path.Join(dst, hdr.Name)] = string(content)
+	}
+}
+
+func (c *fakeClient) VolumeList(ctx context.Context, filter filters.Args) (volumetypes.VolumeListOKBody, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	volumes := []*types.Volume{}
+	for _, v := range c.volumes {
+		if matchesLabels(filter, v.Labels) {
+			volumes = append(volumes, v)
+		}
+	}
+	return volumetypes.VolumeListOKBody{Volumes: volumes}, nil
+}
+
+func (c *fakeClient) VolumeRemove(ctx context.Context, id string, force bool) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i, v := range c.volumes {
+		if v.Name == id {
+			c.volumes = append(c.volumes[:i], c.volumes[i+1:]...)
+			c.removed[id] = true
+			return nil
+		}
+	}
+	if c.removed[id] {
+		return nil
+	}
+	return fmt.Errorf("no such volume: %v", id)
+}
+
+func (c *fakeClient) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]types.ImageSummary{}, c.images...), nil
+}
+
+func (c *fakeClient) ImageInspectWithRaw(ctx context.Context, id string) (types.ImageInspect, []byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, img := range c.images {
+		if img.ID == id {
+			inspect := types.ImageInspect{ID: img.ID, RepoTags: img.RepoTags, Size: img.Size}
+			raw, err := json.Marshal(inspect)
+			return inspect, raw, err
+		}
+	}
+	return types.ImageInspect{}, nil, fmt.Errorf("no such image: %v", id)
+}
+
+func (c *fakeClient) ImageHistory(ctx context.Context, id string) ([]imagetypes.HistoryResponseItem, error) {
+	return []imagetypes.HistoryResponseItem{
+		{ID: id, CreatedBy: "/bin/sh -c #(nop) CMD [\"nginx\"]", Size: 0},
+		{ID: "<missing>", CreatedBy: "/bin/sh -c #(nop) ADD file:1234 in /", Size: 1024},
+	}, nil
+}
+
+func (c *fakeClient) ImageRemove(ctx context.Context, id string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i, img := range c.images {
+		if img.ID == id {
+			c.images = append(c.images[:i], c.images[i+1:]...)
+			return []types.ImageDeleteResponseItem{{Deleted: id}}, nil
+		}
+	}
+	return nil, fmt.Errorf("no such image: %v", id)
+}
+
+func (c *fakeClient) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	networks := []types.NetworkResource{}
+	for _, network := range c.networks {
+		if !matchesLabels(options.Filters, network.Labels) {
+			continue
+		}
+		// NetworkList doesn't return the connected containers.
+		network.Containers = nil
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (c *fakeClient) NetworkInspectWithRaw(ctx context.Context, id string, options types.NetworkInspectOptions) (types.NetworkResource, []byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, network := range c.networks {
+		if network.ID == id {
+			raw, err := json.Marshal(network)
+			return network, raw, err
+		}
+	}
+	return types.NetworkResource{}, nil, fmt.Errorf("no such network: %v", id)
+}
+
+func (c *fakeClient) NetworkRemove(ctx context.Context, id string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i, network := range c.networks {
+		if network.ID == id {
+			c.networks = append(c.networks[:i], c.networks[i+1:]...)
+			c.removed[id] = true
+			return nil
+		}
+	}
+	if c.removed[id] {
+		return nil
+	}
+	return fmt.Errorf("no such network: %v", id)
+}
+
+func TestConformance(t *testing.T) {
+	suite.Run(t, &plugintest.Suite{
+		NewRoot: func() plugin.Root {
+			return &Root{client: newFakeClient()}
+		},
+		// Listing volumes, images' filesystems and container filesystems
+		// requires running containers, which the fake client doesn't support.
+		// containerArchiveFS is tested separately because it can't delete
+		// files.
+		Prune: func(e plugin.Entry) bool {
+			switch e.(type) {
+			case *volume, *imageFS, *vol.FS, *containerArchiveFS:
+				return true
+			default:
+				return false
+			}
+		},
+		Destructive: true,
+	})
+}
@@ -15,7 +15,7 @@ import (
 type containerLogFile struct {
 	plugin.EntryBase
 	containerName string
-	client        *client.Client
+	client        client.APIClient
 }
 
 func newContainerLogFile(container *container) *containerLogFile {
@@ -0,0 +1,159 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// containerStats summarizes a container's resource usage the same way that
+// 'docker stats' does.
+type containerStats struct {
+	Read          time.Time `json:"Read"`
+	CPUPercent    float64   `json:"CPUPercent" jsonschema_description:"The CPU usage as a percentage of a single CPU"`
+	MemoryUsage   uint64    `json:"MemoryUsage" jsonschema_description:"The memory usage in bytes, excluding the page cache"`
+	MemoryLimit   uint64    `json:"MemoryLimit"`
+	MemoryPercent float64   `json:"MemoryPercent"`
+	NetworkRx     uint64    `json:"NetworkRx" jsonschema_description:"The bytes received on all networks"`
+	NetworkTx     uint64    `json:"NetworkTx" jsonschema_description:"The bytes sent on all networks"`
+	BlockRead     uint64    `json:"BlockRead"`
+	BlockWrite    uint64    `json:"BlockWrite"`
+	PIDs          uint64    `json:"PIDs"`
+}
+
+// Adapted from https://github.com/docker/cli/blob/v19.03.4/cli/command/container/stats_helpers.go
+func newContainerStats(s *types.StatsJSON) containerStats {
+	stats := containerStats{
+		Read:        s.Read,
+		MemoryUsage: s.MemoryStats.Usage,
+		MemoryLimit: s.MemoryStats.Limit,
+		PIDs:        s.PidsStats.Current,
+	}
+	if cache := s.MemoryStats.Stats["cache"]; cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(s.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = (cpuDelta / systemDelta) * onlineCPUs * 100
+	}
+	if stats.MemoryLimit != 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, network := range s.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+	for _, entry := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+	return stats
+}
+
+// getContainerStats returns a single sample of the container's resource usage.
+func getContainerStats(ctx context.Context, c client.APIClient, id string) (containerStats, error) {
+	resp, err := c.ContainerStats(ctx, id, false)
+	if err != nil {
+		return containerStats{}, err
+	}
+	defer resp.Body.Close()
+
+	var s types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return containerStats{}, err
+	}
+	return newContainerStats(&s), nil
+}
+
+type containerStatsFile struct {
+	plugin.EntryBase
+	containerID string
+	client      client.APIClient
+}
+
+func newContainerStatsFile(container *container) *containerStatsFile {
+	csf := &containerStatsFile{
+		EntryBase: plugin.NewEntry("stats"),
+	}
+	csf.containerID = container.id
+	csf.client = container.client
+	// The stats change constantly.
+	csf.DisableCachingFor(plugin.ReadOp)
+	return csf
+}
+
+func (csf *containerStatsFile) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(csf, "stats").
+		SetDescription(containerStatsFileDescription).
+		IsSingleton()
+}
+
+func (csf *containerStatsFile) Read(ctx context.Context) ([]byte, error) {
+	stats, err := getContainerStats(ctx, csf.client, csf.containerID)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func (csf *containerStatsFile) Stream(ctx context.Context) (io.ReadCloser, error) {
+	resp, err := csf.client.ContainerStats(ctx, csf.containerID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		decoder := json.NewDecoder(resp.Body)
+		encoder := json.NewEncoder(w)
+		for {
+			var s types.StatsJSON
+			if err := decoder.Decode(&s); err != nil {
+				if err != io.EOF {
+					activity.Record(ctx, "Errored reading stats of container %v: %v", csf.containerID, err)
+				}
+				break
+			}
+			if err := encoder.Encode(newContainerStats(&s)); err != nil {
+				// The reader was closed
+				break
+			}
+		}
+		activity.Record(ctx, "Closing stats of container %v: %v, %v", csf.containerID, resp.Body.Close(), w.Close())
+	}()
+	return plugin.CleanupReader{ReadCloser: r, Cleanup: func() {
+		resp.Body.Close()
+	}}, nil
+}
+
+const containerStatsFileDescription = `
+This is the container's resource usage, like the output of 'docker stats'.
+Reading it returns the latest sample as a line of JSON. Streaming it returns
+a new line of JSON for each sample. The latest sample is also included in
+the container's metadata under the Stats key, so you can select containers
+by their usage, e.g. with
+'find docker/containers -fullmeta -meta .Stats.MemoryUsage +1G'.
+`
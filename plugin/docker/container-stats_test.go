@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var expectedFakeStats = containerStats{
+	Read:          fakeStats.Read,
+	CPUPercent:    50,
+	MemoryUsage:   512 << 20,
+	MemoryLimit:   1 << 30,
+	MemoryPercent: 50,
+	NetworkRx:     11,
+	NetworkTx:     22,
+	BlockRead:     101,
+	BlockWrite:    200,
+	PIDs:          3,
+}
+
+func TestNewContainerStats(t *testing.T) {
+	assert.Equal(t, expectedFakeStats, newContainerStats(&fakeStats))
+
+	// A stopped container doesn't have any usage
+	assert.Equal(t, containerStats{}, newContainerStats(&types.StatsJSON{}))
+}
+
+func TestContainerStatsFile(t *testing.T) {
+	client := newFakeClient()
+	csf := newContainerStatsFile(newContainer(client.containers[0], client))
+
+	content, err := csf.Read(context.Background())
+	if assert.NoError(t, err) {
+		var stats containerStats
+		assert.NoError(t, json.Unmarshal(content, &stats))
+		assert.Equal(t, expectedFakeStats, stats)
+	}
+
+	rdr, err := csf.Stream(context.Background())
+	if assert.NoError(t, err) {
+		content, err := ioutil.ReadAll(rdr)
+		assert.NoError(t, err)
+		assert.NoError(t, rdr.Close())
+
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if assert.Len(t, lines, 2) {
+			for _, line := range lines {
+				var stats containerStats
+				assert.NoError(t, json.Unmarshal([]byte(line), &stats))
+				assert.Equal(t, expectedFakeStats, stats)
+			}
+		}
+	}
+}
+
+func TestContainerMetadata_IncludesStatsOfRunningContainers(t *testing.T) {
+	client := newFakeClient()
+
+	meta, err := newContainer(client.containers[0], client).Metadata(context.Background())
+	if assert.NoError(t, err) {
+		if assert.Contains(t, meta, "Stats") {
+			assert.Equal(t, float64(512<<20), meta["Stats"].(map[string]interface{})["MemoryUsage"])
+		}
+	}
+
+	// The second container is stopped
+	meta, err = newContainer(client.containers[1], client).Metadata(context.Background())
+	if assert.NoError(t, err) {
+		assert.NotContains(t, meta, "Stats")
+	}
+}
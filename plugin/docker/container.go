@@ -18,10 +18,11 @@ import (
 type container struct {
 	plugin.EntryBase
 	id     string
-	client *client.Client
+	state  string
+	client client.APIClient
 }
 
-func newContainer(inst types.Container, client *client.Client) *container {
+func newContainer(inst types.Container, client client.APIClient) *container {
 	name := inst.ID
 	if len(inst.Names) > 0 {
 		// The docker API prefixes all names with '/', so remove that.
@@ -35,6 +36,7 @@ func newContainer(inst types.Container, client *client.Client) *container {
 		EntryBase: plugin.NewEntry(name),
 	}
 	cont.id = inst.ID
+	cont.state = inst.State
 	cont.client = client
 
 	startTime := time.Unix(inst.Created, 0)
@@ -49,21 +51,37 @@ func newContainer(inst types.Container, client *client.Client) *container {
 	return cont
 }
 
+// containerMetadata describes a container's metadata. It's only used for the
+// metadata schema.
+type containerMetadata struct {
+	types.ContainerJSON
+	// Stats is only set for running containers.
+	Stats *containerStats `json:"Stats,omitempty"`
+}
+
 func (c *container) Metadata(ctx context.Context) (plugin.JSONObject, error) {
 	// Use raw to also get the container size.
-	_, raw, err := c.client.ContainerInspectWithRaw(ctx, c.id, true)
+	inspect, raw, err := c.client.ContainerInspectWithRaw(ctx, c.id, true)
 	if err != nil {
 		return nil, err
 	}
 
-	return plugin.ToJSONObject(raw), nil
+	meta := plugin.ToJSONObject(raw)
+	if inspect.State != nil && inspect.State.Running {
+		if stats, err := getContainerStats(ctx, c.client, c.id); err == nil {
+			meta["Stats"] = plugin.ToJSONObject(stats)
+		} else {
+			activity.Record(ctx, "Could not get the stats of %v: %v", c.Name(), err)
+		}
+	}
+	return meta, nil
 }
 
 func (c *container) Schema() *plugin.EntrySchema {
 	return plugin.
 		NewEntrySchema(c, "container").
 		SetPartialMetadataSchema(types.Container{}).
-		SetMetadataSchema(types.ContainerJSON{}).
+		SetMetadataSchema(containerMetadata{}).
 		AddSignal("start", "Starts the container. Equivalent to 'docker start <container>'").
 		AddSignal("stop", "Stops the container. Equivalent to 'docker stop <container>'").
 		AddSignal("pause", "Suspends all processes in the container. Equivalent to 'docker pause <container>'").
@@ -75,8 +93,10 @@ func (c *container) Schema() *plugin.EntrySchema {
 func (c *container) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&containerLogFile{}).Schema(),
+		(&containerStatsFile{}).Schema(),
 		(&plugin.MetadataJSONFile{}).Schema(),
 		(&vol.FS{}).Schema(),
+		(&containerArchiveFS{}).Schema(),
 	}
 }
 
@@ -88,10 +108,24 @@ func (c *container) List(ctx context.Context) ([]plugin.Entry, error) {
 		return nil, err
 	}
 	clf := newContainerLogFile(c)
+	csf := newContainerStatsFile(c)
 
-	// Include a view of the remote filesystem using volume.FS. Use a small maxdepth because
-	// VMs can have lots of files and Exec is fast.
-	return []plugin.Entry{clf, cm, vol.NewFS(ctx, "fs", c, 3)}, nil
+	return []plugin.Entry{clf, csf, cm, c.newFS(ctx)}, nil
+}
+
+// newFS returns a view of the container's filesystem. It prefers volume.FS because Exec
+// is fast, but falls back to the archive API if the container isn't running or if
+// volume.FS can't list the filesystem (e.g. because the image doesn't include find).
+func (c *container) newFS(ctx context.Context) plugin.Entry {
+	if c.state == "running" {
+		// Use a small maxdepth because containers can have lots of files and Exec is fast.
+		fs := vol.NewFS(ctx, "fs", c, 3)
+		if !fs.IsInaccessible() {
+			return fs
+		}
+		activity.Record(ctx, "Using the archive API to view the filesystem of %v", c.Name())
+	}
+	return newContainerArchiveFS(c)
 }
 
 func (c *container) Delete(ctx context.Context) (bool, error) {
@@ -0,0 +1,280 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	volpkg "github.com/puppetlabs/wash/volume"
+)
+
+// containerArchiveFS presents a container's filesystem through Docker's archive
+// API. Unlike volume.FS, it doesn't need to exec anything in the container so
+// it works for stopped containers and for minimal images that don't include
+// find and stat.
+type containerArchiveFS struct {
+	plugin.EntryBase
+	containerID string
+	client      client.APIClient
+	files       *archivedFiles
+}
+
+// Limits on the file content that's kept from a listing's archive. Bigger files are
+// downloaded again when they're read.
+const (
+	maxArchivedFileSize  = 64 * 1024
+	maxArchivedFilesSize = 8 * 1024 * 1024
+)
+
+// maxListingArchiveSize is the most of a directory's archive that's downloaded to list
+// it. The archive includes the directory's entire subtree, which can be as big as the
+// image for the root directory.
+var maxListingArchiveSize int64 = 16 * 1024 * 1024
+
+var errListingArchiveTooLarge = fmt.Errorf("the archive is too large")
+
+// limitedArchive returns errListingArchiveTooLarge once more than its limit is read.
+type limitedArchive struct {
+	rdr       io.Reader
+	remaining int64
+}
+
+func (a *limitedArchive) Read(p []byte) (int, error) {
+	if a.remaining <= 0 {
+		return 0, errListingArchiveTooLarge
+	}
+	if int64(len(p)) > a.remaining {
+		p = p[:a.remaining]
+	}
+	n, err := a.rdr.Read(p)
+	a.remaining -= int64(n)
+	return n, err
+}
+
+// wellKnownRootDirs are the top-level directories of a Linux filesystem. They're looked
+// up individually when the root directory's archive is too large to list all of them.
+var wellKnownRootDirs = []string{
+	"bin", "boot", "dev", "etc", "home", "lib", "lib32", "lib64", "libx32", "media",
+	"mnt", "opt", "proc", "root", "run", "sbin", "srv", "sys", "tmp", "usr", "var",
+}
+
+// archivedFiles keeps the content of the small files in the archives that are
+// downloaded to list directories, so that reading those files doesn't download
+// them again. The content expires with the listing.
+type archivedFiles struct {
+	mux   sync.Mutex
+	files map[string]archivedFile
+}
+
+type archivedFile struct {
+	content []byte
+	expires time.Time
+}
+
+func (a *archivedFiles) load(path string) ([]byte, bool) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	f, ok := a.files[path]
+	if !ok || time.Now().After(f.expires) {
+		return nil, false
+	}
+	return f.content, true
+}
+
+func (a *archivedFiles) store(files map[string][]byte) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	now := time.Now()
+	for path, f := range a.files {
+		if now.After(f.expires) {
+			delete(a.files, path)
+		}
+	}
+	expires := now.Add(volpkg.ListTTL)
+	for path, content := range files {
+		a.files[path] = archivedFile{content: content, expires: expires}
+	}
+}
+
+func (a *archivedFiles) delete(path string) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	delete(a.files, path)
+}
+
+func newContainerArchiveFS(c *container) *containerArchiveFS {
+	fs := &containerArchiveFS{
+		EntryBase: plugin.NewEntry("fs"),
+	}
+	fs.containerID = c.id
+	fs.client = c.client
+	fs.files = &archivedFiles{files: make(map[string]archivedFile)}
+	fs.SetTTLOf(plugin.ListOp, volpkg.ListTTL)
+	return fs
+}
+
+func (fs *containerArchiveFS) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(fs, "fs").
+		SetDescription(containerArchiveFSDescription).
+		IsSingleton()
+}
+
+func (fs *containerArchiveFS) ChildSchemas() []*plugin.EntrySchema {
+	return volpkg.ChildSchemas()
+}
+
+func (fs *containerArchiveFS) List(ctx context.Context) ([]plugin.Entry, error) {
+	return volpkg.List(ctx, fs)
+}
+
+// VolumeList lists the directory from its archive. The archive includes the directory's
+// entire subtree, so only the first maxListingArchiveSize bytes are read. If that's not
+// all of it, the child that was being read is listed again on its own, and the later
+// children are missing from the listing; the root directory's well-known children are
+// looked up instead.
+func (fs *containerArchiveFS) VolumeList(ctx context.Context, dirPath string) (volpkg.DirMap, error) {
+	// Keep maxdepth bounded so that we don't create entries for the entire filesystem
+	// up-front.
+	maxdepth := 5
+	src := dirPath
+	if src == volpkg.RootPath {
+		src = "/"
+	}
+
+	activity.Record(ctx, "Downloading an archive of %v from %v", src, fs.containerID)
+	rdr, _, err := fs.client.CopyFromContainer(ctx, fs.containerID, src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		activity.Record(ctx, "Closed archive of %v on %v: %v", src, fs.containerID, rdr.Close())
+	}()
+
+	// We have to download the files' content to read the archive's headers, so keep the
+	// small files' content for VolumeRead.
+	files := make(map[string][]byte)
+	var total int
+	keepFile := func(path string, size int64, content io.Reader) error {
+		if size > maxArchivedFileSize || total+int(size) > maxArchivedFilesSize {
+			return nil
+		}
+		b, err := ioutil.ReadAll(content)
+		if err != nil {
+			return err
+		}
+		files[path] = b
+		total += len(b)
+		return nil
+	}
+	// Entries in the archive are named after src's basename.
+	archive := &limitedArchive{rdr: rdr, remaining: maxListingArchiveSize}
+	dirmap, err := volpkg.ParseTarFiles(archive, path.Dir(src), dirPath, maxdepth, keepFile)
+	if err == errListingArchiveTooLarge {
+		activity.Warnf(ctx, "Stopped reading the archive of %v from %v after %v bytes; its later entries are not listed", src, fs.containerID, maxListingArchiveSize)
+		if dirPath == volpkg.RootPath {
+			fs.addWellKnownRootDirs(ctx, dirmap)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	fs.files.store(files)
+	return dirmap, nil
+}
+
+// addWellKnownRootDirs adds the well-known top-level directories that are missing from
+// the root directory's partial listing. They're marked as unexplored so that they're
+// listed on their own.
+func (fs *containerArchiveFS) addWellKnownRootDirs(ctx context.Context, dirmap volpkg.DirMap) {
+	root := dirmap[volpkg.RootPath]
+	var last string
+	for name := range root {
+		if name > last {
+			last = name
+		}
+	}
+	// The archive lists the children in order, so the ones before the last are complete.
+	for _, name := range wellKnownRootDirs {
+		if _, ok := root[name]; ok || name < last {
+			continue
+		}
+		stat, err := fs.client.ContainerStatPath(ctx, fs.containerID, "/"+name)
+		if err != nil {
+			if !client.IsErrNotFound(err) {
+				activity.Record(ctx, "Unable to stat /%v on %v: %v", name, fs.containerID, err)
+			}
+			continue
+		}
+		var attr plugin.EntryAttributes
+		attr.SetSize(uint64(stat.Size)).SetMtime(stat.Mtime).SetMode(stat.Mode)
+		root[name] = attr
+		if stat.Mode.IsDir() {
+			dirmap["/"+name] = nil
+		}
+	}
+}
+
+func (fs *containerArchiveFS) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	if content, ok := fs.files.load(path); ok {
+		return content, nil
+	}
+	// The archive would contain the link rather than its target, so resolve it first.
+	stat, err := fs.client.ContainerStatPath(ctx, fs.containerID, path)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Mode&os.ModeSymlink != 0 && stat.LinkTarget != "" {
+		activity.Record(ctx, "Resolved %v on %v to %v", path, fs.containerID, stat.LinkTarget)
+		path = stat.LinkTarget
+	}
+	return readFile(ctx, fs.client, fs.containerID, path)
+}
+
+// VolumeStream returns the file's current content. The archive API can't follow
+// a file, but that's OK because containerArchiveFS is mostly used for stopped
+// containers whose files don't change.
+func (fs *containerArchiveFS) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	content, err := fs.VolumeRead(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (fs *containerArchiveFS) VolumeWrite(ctx context.Context, filePath string, b []byte, mode os.FileMode) error {
+	fs.files.delete(filePath)
+	dir, file := path.Split(filePath)
+	archive, err := fileArchive(file, b, mode)
+	if err != nil {
+		return err
+	}
+	return fs.client.CopyToContainer(ctx, fs.containerID, dir, archive, types.CopyToContainerOptions{})
+}
+
+func (fs *containerArchiveFS) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	return false, fmt.Errorf("cannot delete %v: the archive API does not support deleting files", path)
+}
+
+const containerArchiveFSDescription = `
+This is the container's filesystem. It's used instead of an exec-based view
+when the container is stopped or lacks the 'find' and 'stat' commands. For
+List, we download an archive of the directory with Docker's archive API and
+parse its headers. The archive includes the directory's subtree, so we stop
+after 16 MB; a directory that's too big to list this way may be missing its
+later entries, though the root directory's standard directories are always
+listed. Small files' content is kept from that archive until the
+listing expires, so reading them doesn't download them again. Other files are
+downloaded when they're read; Stream returns the same content because the
+archive API can't follow files. Writes upload the
+file. Deleting files isn't supported.
+`
@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	vol "github.com/puppetlabs/wash/volume"
+	"github.com/stretchr/testify/suite"
+)
+
+type ContainerArchiveFSTestSuite struct {
+	suite.Suite
+	ctx    context.Context
+	client *fakeClient
+	fs     *containerArchiveFS
+}
+
+func (s *ContainerArchiveFSTestSuite) SetupTest() {
+	s.ctx = plugin.SetTestCache(datastore.NewMemCache())
+	s.client = newFakeClient()
+	s.fs = newContainerArchiveFS(newContainer(s.client.containers[1], s.client))
+}
+
+func (s *ContainerArchiveFSTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeList_Root() {
+	dirmap, err := s.fs.VolumeList(s.ctx, vol.RootPath)
+	if s.NoError(err) {
+		s.Len(dirmap[vol.RootPath], 3)
+		s.Contains(dirmap[vol.RootPath], "etc")
+		s.Contains(dirmap[vol.RootPath], "usr")
+		s.Contains(dirmap[vol.RootPath], "index.html")
+		s.Contains(dirmap["/etc"], "hostname")
+		s.Contains(dirmap["/etc/nginx"], "mime")
+
+		attr := dirmap["/etc"]["hostname"]
+		s.EqualValues(4, attr.Size())
+		attr = dirmap[vol.RootPath]["etc"]
+		s.True(attr.Mode().IsDir())
+	}
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeList_Subdirectory() {
+	dirmap, err := s.fs.VolumeList(s.ctx, "/etc/nginx")
+	if s.NoError(err) {
+		s.Contains(dirmap["/etc/nginx"], "mime")
+		s.NotContains(dirmap["/etc"], "hostname")
+	}
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeList_LargeArchive() {
+	defer func(size int64) { maxListingArchiveSize = size }(maxListingArchiveSize)
+	maxListingArchiveSize = 8 * 1024
+	s.client.files["/lib/big"] = strings.Repeat("x", 16*1024)
+	s.client.files["/var/log/messages"] = "hello\n"
+
+	dirmap, err := s.fs.VolumeList(s.ctx, vol.RootPath)
+	if s.NoError(err) {
+		s.Contains(dirmap["/etc"], "hostname")
+		// lib's archive was being read when the limit was reached, so it's listed on its own.
+		s.Contains(dirmap[vol.RootPath], "lib")
+		s.Contains(dirmap, "/lib")
+		s.Nil(dirmap["/lib"])
+		// The later well-known directories are looked up.
+		for _, dir := range []string{"usr", "var"} {
+			if s.Contains(dirmap[vol.RootPath], dir) {
+				attr := dirmap[vol.RootPath][dir]
+				s.True(attr.Mode().IsDir())
+			}
+			s.Contains(dirmap, "/"+dir)
+			s.Nil(dirmap["/"+dir])
+		}
+	}
+	s.Equal([]string{"/"}, s.client.archived)
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeRead() {
+	content, err := s.fs.VolumeRead(s.ctx, "/etc/hostname")
+	if s.NoError(err) {
+		s.Equal("web\n", string(content))
+	}
+
+	_, err = s.fs.VolumeRead(s.ctx, "/nonexistent")
+	s.Error(err)
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeRead_FromListing() {
+	_, err := s.fs.VolumeList(s.ctx, vol.RootPath)
+	s.Require().NoError(err)
+	s.Equal([]string{"/"}, s.client.archived)
+
+	// Reading a listed file uses the listing's archive rather than downloading it again.
+	content, err := s.fs.VolumeRead(s.ctx, "/etc/hostname")
+	if s.NoError(err) {
+		s.Equal("web\n", string(content))
+	}
+	s.Equal([]string{"/"}, s.client.archived)
+
+	// Writing the file discards its archived content.
+	s.NoError(s.fs.VolumeWrite(s.ctx, "/etc/hostname", []byte("db\n"), 0644))
+	content, err = s.fs.VolumeRead(s.ctx, "/etc/hostname")
+	if s.NoError(err) {
+		s.Equal("db\n", string(content))
+	}
+	s.Equal([]string{"/", "/etc/hostname"}, s.client.archived)
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeWrite() {
+	s.NoError(s.fs.VolumeWrite(s.ctx, "/etc/motd", []byte("hello"), 0644))
+	s.Equal("hello", s.client.files["/etc/motd"])
+}
+
+func (s *ContainerArchiveFSTestSuite) TestVolumeDelete() {
+	deleted, err := s.fs.VolumeDelete(s.ctx, "/etc/hostname")
+	s.False(deleted)
+	s.Error(err)
+}
+
+func (s *ContainerArchiveFSTestSuite) TestList() {
+	entries, err := plugin.List(s.ctx, s.fs)
+	if s.NoError(err) {
+		_, ok := entries.Load("index.html")
+		s.True(ok)
+	}
+}
+
+func (s *ContainerArchiveFSTestSuite) TestContainerNewFS() {
+	// The fake client doesn't support exec, so the running container also falls back
+	// to the archive API.
+	for _, inst := range s.client.containers {
+		c := newContainer(inst, s.client)
+		c.SetTestID("/docker/containers/" + c.Name())
+		s.IsType(&containerArchiveFS{}, c.newFS(s.ctx), inst.State)
+	}
+
+	c := newContainer(types.Container{ID: "1234", State: "exited"}, s.client)
+	s.IsType(&containerArchiveFS{}, c.newFS(s.ctx))
+}
+
+func TestContainerArchiveFS(t *testing.T) {
+	suite.Run(t, new(ContainerArchiveFSTestSuite))
+}
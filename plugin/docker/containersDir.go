@@ -11,10 +11,10 @@ import (
 
 type containersDir struct {
 	plugin.EntryBase
-	client *client.Client
+	client client.APIClient
 }
 
-func newContainersDir(client *client.Client) *containersDir {
+func newContainersDir(client client.APIClient) *containersDir {
 	containersDir := &containersDir{
 		EntryBase: plugin.NewEntry("containers"),
 	}
@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/plugin"
+	volpkg "github.com/puppetlabs/wash/volume"
+)
+
+type image struct {
+	plugin.EntryBase
+	id     string
+	client client.APIClient
+}
+
+func newImage(inst types.ImageSummary, client client.APIClient) *image {
+	img := &image{
+		EntryBase: plugin.NewEntry(imageName(inst)),
+	}
+	img.id = inst.ID
+	img.client = client
+
+	createdTime := time.Unix(inst.Created, 0)
+	img.
+		SetPartialMetadata(inst).
+		Attributes().
+		SetCrtime(createdTime).
+		SetMtime(createdTime).
+		SetCtime(createdTime).
+		SetAtime(createdTime)
+
+	return img
+}
+
+// imageName returns the image's first tag. Untagged images are named by their
+// short ID, which is what 'docker image ls' shows for them.
+func imageName(inst types.ImageSummary) string {
+	for _, tag := range inst.RepoTags {
+		if tag != "<none>:<none>" {
+			return tag
+		}
+	}
+	return shortID(inst.ID)
+}
+
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+func (i *image) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	_, raw, err := i.client.ImageInspectWithRaw(ctx, i.id)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.ToJSONObject(raw), nil
+}
+
+func (i *image) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(i, "image").
+		SetDescription(imageDescription).
+		SetPartialMetadataSchema(types.ImageSummary{}).
+		SetMetadataSchema(types.ImageInspect{})
+}
+
+func (i *image) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+		(&imageHistoryFile{}).Schema(),
+		(&imageFS{}).Schema(),
+	}
+}
+
+func (i *image) List(ctx context.Context) ([]plugin.Entry, error) {
+	im, err := plugin.NewMetadataJSONFile(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	return []plugin.Entry{im, newImageHistoryFile(i), newImageFS(i)}, nil
+}
+
+func (i *image) Delete(ctx context.Context) (bool, error) {
+	_, err := i.client.ImageRemove(ctx, i.id, types.ImageRemoveOptions{
+		Force:         true,
+		PruneChildren: true,
+	})
+	return true, err
+}
+
+// imageHistoryFile presents the image's layers in the same format as
+// 'docker image history'.
+type imageHistoryFile struct {
+	plugin.EntryBase
+	imageID string
+	client  client.APIClient
+}
+
+func newImageHistoryFile(img *image) *imageHistoryFile {
+	ihf := &imageHistoryFile{
+		EntryBase: plugin.NewEntry("history"),
+	}
+	ihf.imageID = img.id
+	ihf.client = img.client
+	return ihf
+}
+
+func (ihf *imageHistoryFile) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(ihf, "history").
+		SetDescription("Lists the image's layers, newest first. Equivalent to 'docker image history --no-trunc <image>'.").
+		IsSingleton()
+}
+
+func (ihf *imageHistoryFile) Read(ctx context.Context) ([]byte, error) {
+	history, err := ihf.client.ImageHistory(ctx, ihf.imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tCREATED\tSIZE\tCREATED BY\tCOMMENT")
+	for _, layer := range history {
+		id := "<missing>"
+		if layer.ID != "<missing>" {
+			id = shortID(layer.ID)
+		}
+		created := time.Unix(layer.Created, 0).Format(time.RFC3339)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", id, created, layer.Size, layer.CreatedBy, layer.Comment)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// imageFS presents an image's filesystem. It uses temporary containers that
+// are created from the image.
+type imageFS struct {
+	plugin.EntryBase
+	imageID string
+	client  client.APIClient
+}
+
+func newImageFS(img *image) *imageFS {
+	fs := &imageFS{
+		EntryBase: plugin.NewEntry("fs"),
+	}
+	fs.imageID = img.id
+	fs.client = img.client
+	fs.SetTTLOf(plugin.ListOp, volpkg.ListTTL)
+	return fs
+}
+
+func (fs *imageFS) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(fs, "fs").
+		SetDescription(imageFSDescription).
+		IsSingleton()
+}
+
+func (fs *imageFS) ChildSchemas() []*plugin.EntrySchema {
+	return volpkg.ChildSchemas()
+}
+
+func (fs *imageFS) List(ctx context.Context) ([]plugin.Entry, error) {
+	return volpkg.List(ctx, fs)
+}
+
+func (fs *imageFS) tempContainers() tempContainerSpec {
+	return tempContainerSpec{client: fs.client, image: fs.imageID}
+}
+
+func (fs *imageFS) VolumeList(ctx context.Context, path string) (volpkg.DirMap, error) {
+	// Use a larger maxdepth because each VolumeList creates a container, which is slow.
+	maxdepth := 5
+	output, statusCode, err := fs.tempContainers().run(ctx, volpkg.StatCmdPOSIX(path, maxdepth))
+	if err != nil {
+		return nil, err
+	}
+
+	// find exits non-zero for things like file system loops in /proc. ParseStatPOSIX
+	// skips those errors, so only fail if it couldn't parse the output.
+	dirmap, err := volpkg.ParseStatPOSIX(bytes.NewReader(output), volpkg.RootPath, path, maxdepth)
+	if err != nil && statusCode != 0 {
+		return nil, errors.New(strings.Trim(string(output), "\n"))
+	}
+	return dirmap, err
+}
+
+func (fs *imageFS) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	// The archive API works on containers that were never started, so the
+	// image doesn't need any particular command to download a file.
+	cid, remove, err := fs.tempContainers().create(ctx, []string{"true"})
+	if err != nil {
+		return nil, err
+	}
+	defer remove(context.Background())
+
+	return readFile(ctx, fs.client, cid, path)
+}
+
+func (fs *imageFS) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	return fs.tempContainers().stream(ctx, []string{"tail", "-f", path})
+}
+
+func (fs *imageFS) VolumeWrite(ctx context.Context, path string, b []byte, mode os.FileMode) error {
+	return fmt.Errorf("cannot write %v: an image's filesystem is read-only", path)
+}
+
+func (fs *imageFS) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	return false, fmt.Errorf("cannot delete %v: an image's filesystem is read-only", path)
+}
+
+const imageDescription = `
+This is a Docker image. It's named after its first tag, or its short ID
+if it's untagged. Deleting it is equivalent to 'docker rmi --force <image>',
+so all of its tags are removed.
+`
+
+const imageFSDescription = `
+This is a Docker image's filesystem. We create a temporary container from
+the image whenever Wash invokes a currently uncached List/Read/Stream action
+on it or one of its children. For List, we run 'find -exec stat' in the
+container and parse its output, so the image needs those commands. For Read,
+we download the file from a container that's created but never started. For
+Stream, we run 'tail -f' and pass over its output. The filesystem is
+read-only.
+`
@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageName(t *testing.T) {
+	assert.Equal(t, "nginx:latest", imageName(types.ImageSummary{
+		ID:       "sha256:0123456789abcdef",
+		RepoTags: []string{"nginx:latest", "nginx:1.17"},
+	}))
+	assert.Equal(t, "0123456789ab", imageName(types.ImageSummary{
+		ID:       "sha256:0123456789abcdef",
+		RepoTags: []string{"<none>:<none>"},
+	}))
+	assert.Equal(t, "0123456789ab", imageName(types.ImageSummary{ID: "sha256:0123456789abcdef"}))
+}
+
+func TestImageHistoryFileRead(t *testing.T) {
+	img := newImage(types.ImageSummary{ID: "sha256:0123456789abcdef"}, newFakeClient())
+	content, err := newImageHistoryFile(img).Read(context.Background())
+	if assert.NoError(t, err) {
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if assert.Len(t, lines, 3) {
+			assert.Regexp(t, `^IMAGE\s+CREATED\s+SIZE\s+CREATED BY\s+COMMENT$`, lines[0])
+			assert.Regexp(t, `^0123456789ab\s+\S+\s+0\s+/bin/sh -c #\(nop\) CMD \["nginx"\]`, lines[1])
+			assert.Regexp(t, `^<missing>\s+\S+\s+1024\s+/bin/sh -c #\(nop\) ADD file:1234 in /`, lines[2])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+type imagesDir struct {
+	plugin.EntryBase
+	client client.APIClient
+}
+
+func newImagesDir(client client.APIClient) *imagesDir {
+	imagesDir := &imagesDir{
+		EntryBase: plugin.NewEntry("images"),
+	}
+	imagesDir.client = client
+	return imagesDir
+}
+
+func (is *imagesDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(is, "images").IsSingleton()
+}
+
+func (is *imagesDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&image{}).Schema(),
+	}
+}
+
+// List
+func (is *imagesDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	images, err := is.client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	activity.Record(ctx, "Listing %v images in %v", len(images), is)
+	keys := make([]plugin.Entry, len(images))
+	for i, inst := range images {
+		keys[i] = newImage(inst, is.client)
+	}
+	return keys, nil
+}
@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// dockerNetwork is named as such to avoid clashing with the network package.
+type dockerNetwork struct {
+	plugin.EntryBase
+	id     string
+	client client.APIClient
+}
+
+func newNetwork(inst types.NetworkResource, client client.APIClient) *dockerNetwork {
+	net := &dockerNetwork{
+		EntryBase: plugin.NewEntry(inst.Name),
+	}
+	net.id = inst.ID
+	net.client = client
+
+	net.
+		SetPartialMetadata(inst).
+		Attributes().
+		SetCrtime(inst.Created).
+		SetMtime(inst.Created).
+		SetCtime(inst.Created).
+		SetAtime(inst.Created)
+
+	return net
+}
+
+func (n *dockerNetwork) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	_, raw, err := n.client.NetworkInspectWithRaw(ctx, n.id, types.NetworkInspectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.ToJSONObject(raw), nil
+}
+
+func (n *dockerNetwork) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(n, "network").
+		SetDescription(networkDescription).
+		SetPartialMetadataSchema(types.NetworkResource{}).
+		SetMetadataSchema(types.NetworkResource{})
+}
+
+func (n *dockerNetwork) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+		(&container{}).Schema(),
+	}
+}
+
+// List lists the network's metadata.json file and the containers that are
+// connected to it.
+func (n *dockerNetwork) List(ctx context.Context) ([]plugin.Entry, error) {
+	nm, err := plugin.NewMetadataJSONFile(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := n.client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("network", n.id)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activity.Record(ctx, "Listing %v containers in %v", len(containers), n)
+	entries := []plugin.Entry{nm}
+	for _, inst := range containers {
+		entries = append(entries, newContainer(inst, n.client))
+	}
+	return entries, nil
+}
+
+func (n *dockerNetwork) Delete(ctx context.Context) (bool, error) {
+	err := n.client.NetworkRemove(ctx, n.id)
+	return true, err
+}
+
+const networkDescription = `
+This is a Docker network. Its children are its metadata.json file and the
+containers that are connected to it, including stopped ones. Those are the
+same containers that are found in the containers directory.
+`
@@ -16,21 +16,39 @@ import (
 // Root of the Docker plugin
 type Root struct {
 	plugin.EntryBase
+	// client is only set beforehand by the tests, which use it to inject a
+	// fake Docker API client.
+	client    client.APIClient
 	resources []plugin.Entry
 }
 
+// config is empty because the Docker plugin doesn't have any config options.
+// Declaring it means that unknown options are reported.
+type config struct{}
+
+// ConfigSchema returns the root's config schema
+func (r *Root) ConfigSchema() interface{} {
+	return config{}
+}
+
 // Init for root
 func (r *Root) Init(map[string]interface{}) error {
-	dockerCli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		return err
+	if r.client == nil {
+		dockerCli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return err
+		}
+		r.client = dockerCli
 	}
 
 	r.EntryBase = plugin.NewEntry("docker")
 	r.DisableDefaultCaching()
 	r.resources = []plugin.Entry{
-		newContainersDir(dockerCli),
-		newVolumesDir(dockerCli),
+		newContainersDir(r.client),
+		newVolumesDir(r.client),
+		newImagesDir(r.client),
+		newNetworksDir(r.client),
+		newComposeDir(r.client),
 	}
 
 	return nil
@@ -49,6 +67,9 @@ func (r *Root) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&containersDir{}).Schema(),
 		(&volumesDir{}).Schema(),
+		(&imagesDir{}).Schema(),
+		(&networksDir{}).Schema(),
+		(&composeDir{}).Schema(),
 	}
 }
 
@@ -59,6 +80,7 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 
 const rootDescription = `
 This is the Docker plugin root. It lets you interact with Docker resources
-like containers and volumes. These resources are found from the Docker socket
+like containers, volumes, images and networks, and groups them by their
+Docker Compose project. These resources are found from the Docker socket
 or via the DOCKER environment variables.
 `
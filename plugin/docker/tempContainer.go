@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/docker/docker/api/types"
+	docontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// tempContainerSpec describes the temporary containers we create to access
+// filesystems that we can't exec into, like a volume's or an image's.
+type tempContainerSpec struct {
+	client client.APIClient
+	// image is the image that the containers are created from.
+	image string
+	// pullRef is the reference that's pulled if the image isn't found. Leave
+	// it empty to not pull anything.
+	pullRef string
+	mounts  []mount.Mount
+}
+
+// create creates (but doesn't start) a container that runs cmd. cmd replaces
+// the image's entrypoint so that it runs as-is. Returns the container's ID and
+// a function that removes it.
+func (s tempContainerSpec) create(ctx context.Context, cmd []string) (string, func(context.Context), error) {
+	// Use tty to avoid messing with the extra log formatting.
+	cfg := docontainer.Config{Image: s.image, Entrypoint: cmd[:1], Cmd: cmd[1:], Tty: true}
+	hostcfg := docontainer.HostConfig{Mounts: s.mounts}
+	netcfg := network.NetworkingConfig{}
+	created, err := s.client.ContainerCreate(ctx, &cfg, &hostcfg, &netcfg, "")
+	if err != nil {
+		// Pull the image if create failed because it wasn't found.
+		// Taken from https://github.com/docker/cli/blob/v19.03.4/cli/command/container/create.go#L218-L241.
+		if !client.IsErrNotFound(err) || s.pullRef == "" {
+			return "", nil, err
+		}
+
+		var pullRdr io.ReadCloser
+		if pullRdr, err = s.client.ImagePull(ctx, s.pullRef, types.ImagePullOptions{}); err != nil {
+			return "", nil, err
+		}
+		defer pullRdr.Close()
+
+		writer := activity.Writer{Context: ctx, Prefix: "Pulling " + s.image}
+		if _, err := io.Copy(writer, pullRdr); err != nil {
+			return "", nil, err
+		}
+
+		if created, err = s.client.ContainerCreate(ctx, &cfg, &hostcfg, &netcfg, ""); err != nil {
+			return "", nil, err
+		}
+	}
+	for _, warn := range created.Warnings {
+		activity.Record(ctx, "Warning creating %v: %v", created.ID, warn)
+	}
+
+	cid := created.ID
+	remove := func(ctx context.Context) {
+		err := s.client.ContainerRemove(ctx, cid, types.ContainerRemoveOptions{})
+		activity.Record(ctx, "Deleted container %v: %v", cid, err)
+	}
+	return cid, remove, nil
+}
+
+// start creates and starts a container that runs cmd. Returns the ID for a
+// running container and a deletion function for cleanup.
+func (s tempContainerSpec) start(ctx context.Context, cmd []string) (string, func(), error) {
+	cid, remove, err := s.create(ctx, cmd)
+	if err != nil {
+		return "", nil, err
+	}
+
+	activity.Record(ctx, "Starting container %v", cid)
+	if err := s.client.ContainerStart(ctx, cid, types.ContainerStartOptions{}); err != nil {
+		activity.Record(ctx, "Error starting container %v: %v", cid, err)
+		// Run in the background so we still cleanup containers if the context was cancelled.
+		remove(context.Background())
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		// Use a background context to ensure we stop even if the context was cancelled.
+		ctx := context.Background()
+		err := s.client.ContainerKill(ctx, cid, "SIGKILL")
+		activity.Record(ctx, "Stopped temporary container %v: %v", cid, err)
+		remove(ctx)
+	}
+	return cid, cleanup, nil
+}
+
+// run runs cmd in a temporary container and waits for it to finish. It returns
+// the cmd's output and exit code. The output includes stderr if the exit code
+// is non-zero.
+func (s tempContainerSpec) run(ctx context.Context, cmd []string) ([]byte, int64, error) {
+	cid, cleanup, err := s.start(ctx, cmd)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cleanup()
+
+	activity.Record(ctx, "Waiting for container %v", cid)
+	waitC, errC := s.client.ContainerWait(ctx, cid, docontainer.WaitConditionNotRunning)
+	var statusCode int64
+	select {
+	case err := <-errC:
+		return nil, 0, err
+	case result := <-waitC:
+		statusCode = result.StatusCode
+		activity.Record(ctx, "Container %v finished[%v]: %v", cid, result.StatusCode, result.Error)
+	}
+
+	opts := types.ContainerLogsOptions{ShowStdout: true}
+	if statusCode != 0 {
+		opts.ShowStderr = true
+	}
+
+	activity.Record(ctx, "Gathering log for %v", cid)
+	output, err := s.client.ContainerLogs(ctx, cid, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		activity.Record(ctx, "Closed log for %v: %v", cid, output.Close())
+	}()
+
+	bytes, err := ioutil.ReadAll(output)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes, statusCode, nil
+}
+
+// stream runs cmd in a temporary container and returns its output as it's
+// produced. Closing the returned reader stops and removes the container.
+func (s tempContainerSpec) stream(ctx context.Context, cmd []string) (io.ReadCloser, error) {
+	cid, cleanup, err := s.start(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := types.ContainerLogsOptions{ShowStdout: true, Follow: true, Tail: "10"}
+	activity.Record(ctx, "Streaming log for %v", cid)
+	output, err := s.client.ContainerLogs(ctx, cid, opts)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	// Wrap the log output in a ReadCloser that stops and kills the container on Close.
+	return plugin.CleanupReader{ReadCloser: output, Cleanup: cleanup}, nil
+}
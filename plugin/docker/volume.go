@@ -1,35 +1,30 @@
 package docker
 
 import (
-	"archive/tar"
 	"bytes"
 	"context"
 	"errors"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
-	docontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
-	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	volpkg "github.com/puppetlabs/wash/volume"
 )
 
 type volume struct {
 	plugin.EntryBase
-	client *client.Client
+	client client.APIClient
 }
 
 const mountpoint = "/mnt"
 
-func newVolume(c *client.Client, v *types.Volume) (*volume, error) {
+func newVolume(c client.APIClient, v *types.Volume) (*volume, error) {
 	startTime, err := time.Parse(time.RFC3339, v.CreatedAt)
 	if err != nil {
 		return nil, err
@@ -71,112 +66,32 @@ func (v *volume) Delete(ctx context.Context) (bool, error) {
 	return true, err
 }
 
-// Create a container that mounts a volume to a default mountpoint and runs a command.
-// Returns the ID for a running container and a deletion function for cleanup.
-func (v *volume) createContainer(ctx context.Context, cmd []string) (string, func(), error) {
-	// Use tty to avoid messing with the extra log formatting.
-	cfg := docontainer.Config{Image: "busybox", Cmd: cmd, Tty: true}
-	mounts := []mount.Mount{{
-		Type:   mount.TypeVolume,
-		Source: v.Name(),
-		Target: mountpoint,
-	}}
-	hostcfg := docontainer.HostConfig{Mounts: mounts}
-	netcfg := network.NetworkingConfig{}
-	created, err := v.client.ContainerCreate(ctx, &cfg, &hostcfg, &netcfg, "")
-	if err != nil {
-		// Pull busybox if create failed because it wasn't found.
-		// Taken from https://github.com/docker/cli/blob/v19.03.4/cli/command/container/create.go#L218-L241.
-		if !client.IsErrNotFound(err) {
-			return "", nil, err
-		}
-
-		var pullRdr io.ReadCloser
-		if pullRdr, err = v.client.ImagePull(ctx, "busybox:latest", types.ImagePullOptions{}); err != nil {
-			return "", nil, err
-		}
-		defer pullRdr.Close()
-
-		writer := activity.Writer{Context: ctx, Prefix: "Pulling busybox"}
-		if _, err := io.Copy(writer, pullRdr); err != nil {
-			return "", nil, err
-		}
-
-		if created, err = v.client.ContainerCreate(ctx, &cfg, &hostcfg, &netcfg, ""); err != nil {
-			return "", nil, err
-		}
-	}
-	for _, warn := range created.Warnings {
-		activity.Record(ctx, "Warning creating %v: %v", created.ID, warn)
-	}
-
-	cid := created.ID
-	remove := func(ctx context.Context) {
-		err := v.client.ContainerRemove(ctx, cid, types.ContainerRemoveOptions{})
-		activity.Record(ctx, "Deleted container %v: %v", cid, err)
-	}
-
-	activity.Record(ctx, "Starting container %v", cid)
-	if err := v.client.ContainerStart(ctx, cid, types.ContainerStartOptions{}); err != nil {
-		activity.Record(ctx, "Error starting container %v: %v", cid, err)
-		// Run in the background so we still cleanup containers if the context was cancelled.
-		remove(context.Background())
-		return "", nil, err
+// tempContainers describes the temporary containers that mount the volume to a
+// default mountpoint.
+func (v *volume) tempContainers() tempContainerSpec {
+	return tempContainerSpec{
+		client:  v.client,
+		image:   "busybox",
+		pullRef: "busybox:latest",
+		mounts: []mount.Mount{{
+			Type:   mount.TypeVolume,
+			Source: v.Name(),
+			Target: mountpoint,
+		}},
 	}
-
-	cleanup := func() {
-		// Use a background context to ensure we stop even if the context was cancelled.
-		ctx := context.Background()
-		err := v.client.ContainerKill(ctx, cid, "SIGKILL")
-		activity.Record(ctx, "Stopped temporary container %v: %v", cid, err)
-		remove(ctx)
-	}
-	return cid, cleanup, nil
 }
 
 // Runs cmd in a temporary container. If the exit code is 0, then it returns the cmd's output.
 // Otherwise, it wraps the cmd's output in an error object.
 func (v *volume) runInTemporaryContainer(ctx context.Context, cmd []string) ([]byte, error) {
-	// Create a container that mounts a volume and deletes its file. Run rm -rf on it.
-	cid, cleanup, err := v.createContainer(ctx, cmd)
+	output, statusCode, err := v.tempContainers().run(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
-	defer cleanup()
-
-	activity.Record(ctx, "Waiting for container %v", cid)
-	waitC, errC := v.client.ContainerWait(ctx, cid, docontainer.WaitConditionNotRunning)
-	var statusCode int64
-	select {
-	case err := <-errC:
-		return nil, err
-	case result := <-waitC:
-		statusCode = result.StatusCode
-		activity.Record(ctx, "Container %v finished[%v]: %v", cid, result.StatusCode, result.Error)
-	}
-
-	opts := types.ContainerLogsOptions{ShowStdout: true}
 	if statusCode != 0 {
-		opts.ShowStderr = true
+		return nil, errors.New(strings.Trim(string(output), "\n"))
 	}
-
-	activity.Record(ctx, "Gathering log for %v", cid)
-	output, err := v.client.ContainerLogs(ctx, cid, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		activity.Record(ctx, "Closed log for %v: %v", cid, output.Close())
-	}()
-
-	bytes, err := ioutil.ReadAll(output)
-	if err != nil {
-		return nil, err
-	}
-	if statusCode != 0 {
-		return nil, errors.New(strings.Trim(string(bytes), "\n"))
-	}
-	return bytes, nil
+	return output, nil
 }
 
 func (v *volume) VolumeList(ctx context.Context, path string) (volpkg.DirMap, error) {
@@ -191,51 +106,23 @@ func (v *volume) VolumeList(ctx context.Context, path string) (volpkg.DirMap, er
 
 func (v *volume) VolumeRead(ctx context.Context, path string) ([]byte, error) {
 	// Create a container that mounts a volume and waits. Use it to download a file.
-	cid, cleanup, err := v.createContainer(ctx, []string{"sleep", "60"})
+	cid, cleanup, err := v.tempContainers().start(ctx, []string{"sleep", "60"})
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup()
 
-	// Download file, then kill container.
-	rdr, _, err := v.client.CopyFromContainer(ctx, cid, mountpoint+path)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		activity.Record(ctx, "Closed file %v on %v: %v", mountpoint+path, cid, rdr.Close())
-	}()
-
-	// Read one file from the archive.
-	tarReader := tar.NewReader(rdr)
-	if _, err = tarReader.Next(); err != nil {
-		return nil, err
-	}
-	return ioutil.ReadAll(tarReader)
+	return readFile(ctx, v.client, cid, mountpoint+path)
 }
 
 func (v *volume) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
 	// Create a container that mounts a volume and tails a file. Run it and capture the output.
-	cid, cleanup, err := v.createContainer(ctx, []string{"tail", "-f", mountpoint + path})
-	if err != nil {
-		return nil, err
-	}
-
-	opts := types.ContainerLogsOptions{ShowStdout: true, Follow: true, Tail: "10"}
-	activity.Record(ctx, "Streaming log for %v", cid)
-	output, err := v.client.ContainerLogs(ctx, cid, opts)
-	if err != nil {
-		cleanup()
-		return nil, err
-	}
-
-	// Wrap the log output in a ReadCloser that stops and kills the container on Close.
-	return plugin.CleanupReader{ReadCloser: output, Cleanup: cleanup}, nil
+	return v.tempContainers().stream(ctx, []string{"tail", "-f", mountpoint + path})
 }
 
 func (v *volume) VolumeWrite(ctx context.Context, path string, b []byte, mode os.FileMode) error {
 	// Create a container that mounts a volume and waits. Use it to upload a file.
-	cid, cleanup, err := v.createContainer(ctx, []string{"sleep", "60"})
+	cid, cleanup, err := v.tempContainers().start(ctx, []string{"sleep", "60"})
 	if err != nil {
 		return err
 	}
@@ -244,30 +131,12 @@ func (v *volume) VolumeWrite(ctx context.Context, path string, b []byte, mode os
 	// Create a tar of the file contents and upload it. CopyToContainer requires content as a Reader
 	// for a TAR archive.
 	dir, file := filepath.Split(path)
-	var buf bytes.Buffer
-	tw := tar.NewWriter(&buf)
-	mtime := time.Now()
-	hdr := tar.Header{
-		Name: file,
-		Size: int64(len(b)),
-		Mode: int64(mode),
-		// Use PAX format to ensure compatibility with non-ASCII filenames.
-		Format: tar.FormatPAX,
-		// Use of PAX requires we set atime/ctime/mtime. Use now, we just read the file to update it.
-		AccessTime: mtime,
-		ChangeTime: mtime,
-		ModTime:    mtime,
-	}
-
-	if err := tw.WriteHeader(&hdr); err != nil {
-		return err
-	} else if _, err := tw.Write(b); err != nil {
-		return err
-	} else if err := tw.Close(); err != nil {
+	archive, err := fileArchive(file, b, mode)
+	if err != nil {
 		return err
 	}
 
-	return v.client.CopyToContainer(ctx, cid, mountpoint+dir, &buf, types.CopyToContainerOptions{})
+	return v.client.CopyToContainer(ctx, cid, mountpoint+dir, archive, types.CopyToContainerOptions{})
 }
 
 func (v *volume) VolumeDelete(ctx context.Context, path string) (bool, error) {
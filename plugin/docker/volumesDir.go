@@ -11,10 +11,12 @@ import (
 
 type volumesDir struct {
 	plugin.EntryBase
-	client *client.Client
+	client client.APIClient
+	// filters is used to only list some volumes, e.g. a compose project's.
+	filters filters.Args
 }
 
-func newVolumesDir(client *client.Client) *volumesDir {
+func newVolumesDir(client client.APIClient) *volumesDir {
 	volumesDir := &volumesDir{
 		EntryBase: plugin.NewEntry("volumes"),
 	}
@@ -34,7 +36,7 @@ func (vs *volumesDir) ChildSchemas() []*plugin.EntrySchema {
 
 // List
 func (vs *volumesDir) List(ctx context.Context) ([]plugin.Entry, error) {
-	volumes, err := vs.client.VolumeList(ctx, filters.Args{})
+	volumes, err := vs.client.VolumeList(ctx, vs.filters)
 	if err != nil {
 		return nil, err
 	}
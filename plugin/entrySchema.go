@@ -100,7 +100,11 @@ func schema(e Entry) (*EntrySchema, error) {
 		return schema, nil
 	default:
 		// e is a core-plugin
-		return e.Schema(), nil
+		s := e.Schema()
+		if root, ok := e.(HasConfigSchema); ok && s != nil {
+			s.entrySchema.ConfigSchema = configSchemaOf(root)
+		}
+		return s, nil
 	}
 }
 
@@ -113,6 +117,9 @@ type entrySchema struct {
 	PartialMetadataSchema *JSONSchema    `json:"partial_metadata_schema"`
 	MetadataSchema        *JSONSchema    `json:"metadata_schema"`
 	Children              []string       `json:"children"`
+	// ConfigSchema is only set for core plugin roots that implement
+	// HasConfigSchema.
+	ConfigSchema *JSONSchema `json:"config_schema,omitempty"`
 }
 
 // EntrySchema represents an entry's schema. Use plugin.NewEntrySchema
@@ -0,0 +1,21 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	plugintest "github.com/puppetlabs/wash/plugin/test"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestConformance(t *testing.T) {
+	suite.Run(t, &plugintest.Suite{
+		NewRoot: func() plugin.Root {
+			root, err := PluginSpec{Script: "testdata/conformance.sh"}.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return root
+		},
+	})
+}
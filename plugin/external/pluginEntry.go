@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/getlantern/deepcopy"
@@ -110,6 +111,12 @@ func (e decodedExternalPluginEntry) getMungedMethods() (map[string]methodInfo, e
 				return nil, fmt.Errorf("implementation of list must conform to %v, not %v", listFormat, string(tuple.Value))
 			}
 			info.tupleValue = decodedEntries
+		case "metadata":
+			var metadata plugin.JSONObject
+			if err := json.Unmarshal(tuple.Value, &metadata); err != nil || metadata == nil {
+				return nil, fmt.Errorf("Metadata method must provide a JSON object, not %v", string(tuple.Value))
+			}
+			info.tupleValue = metadata
 		case "schema":
 			graph, err := unmarshalSchemaGraph(e.Name, e.TypeID, tuple.Value)
 			if err != nil {
@@ -195,6 +202,9 @@ func (e decodedExternalPluginEntry) toExternalPluginEntry(ctx context.Context, s
 	entry.SetAttributes(e.Attributes)
 	entry.SetPartialMetadata(e.PartialMetadata)
 	entry.setCacheTTLs(e.CacheTTLs)
+	if val := methods["metadata"].tupleValue; val != nil {
+		entry.setPrefetchedMetadata(val.(plugin.JSONObject))
+	}
 	if e.InaccessibleReason != "" {
 		entry.MarkInaccessible(ctx, fmt.Errorf(e.InaccessibleReason))
 	}
@@ -208,7 +218,9 @@ func (e decodedExternalPluginEntry) toExternalPluginEntry(ctx context.Context, s
 	}
 
 	// If some data originated from the parent via list, mark as prefetched.
-	if entry.methods["list"].tupleValue != nil || entry.methods["read"].tupleValue != nil {
+	if entry.methods["list"].tupleValue != nil ||
+		entry.methods["read"].tupleValue != nil ||
+		entry.methods["metadata"].tupleValue != nil {
 		entry.Prefetched()
 	}
 
@@ -236,6 +248,17 @@ type pluginEntry struct {
 	// schemaGraphs is a map of <type_id> => <schema_graph>. It is created
 	// by the root and passed along to child entries in list.
 	schemaGraphs map[string]*linkedhashmap.Map
+	// prefetchedMetadata stores a *prefetchedMetadata. It is set if the entry's
+	// metadata was included in the parent's list response or fetched via the
+	// parent's metadata_batch method.
+	prefetchedMetadata atomic.Value
+}
+
+// prefetchedMetadata is an entry's prefetched metadata. It is used until it
+// expires, which is determined by the entry's metadata TTL.
+type prefetchedMetadata struct {
+	value  plugin.JSONObject
+	expiry time.Time
 }
 
 func (e *pluginEntry) setCacheTTLs(ttls decodedCacheTTLs) {
@@ -455,7 +478,27 @@ func (e *pluginEntry) Write(ctx context.Context, p []byte) error {
 	return inv.RunAndWait(ctx)
 }
 
+func (e *pluginEntry) setPrefetchedMetadata(metadata plugin.JSONObject) {
+	e.prefetchedMetadata.Store(&prefetchedMetadata{
+		value:  metadata,
+		expiry: time.Now().Add(e.TTLOf(plugin.MetadataOp)),
+	})
+}
+
+// getPrefetchedMetadata returns the entry's prefetched metadata if it hasn't
+// expired, nil otherwise.
+func (e *pluginEntry) getPrefetchedMetadata() plugin.JSONObject {
+	prefetched, ok := e.prefetchedMetadata.Load().(*prefetchedMetadata)
+	if !ok || time.Now().After(prefetched.expiry) {
+		return nil
+	}
+	return prefetched.value
+}
+
 func (e *pluginEntry) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	if metadata := e.getPrefetchedMetadata(); metadata != nil {
+		return metadata, nil
+	}
 	if !e.implements("metadata") {
 		// The entry does not override the "Metadata" method so invoke
 		// the default
@@ -478,6 +521,57 @@ func (e *pluginEntry) Metadata(ctx context.Context) (plugin.JSONObject, error) {
 	return metadata, nil
 }
 
+type metadataBatchRequest struct {
+	Path  string `json:"path"`
+	State string `json:"state"`
+}
+
+const metadataBatchFormat = "{\"/path/to/entry1\":{\"key1\":\"value1\"},\"/path/to/entry2\":{\"key1\":\"value2\"}}"
+
+// PrefetchChildMetadata fetches the full metadata of the given children with a
+// single metadata_batch invocation. Children whose metadata was already
+// prefetched, or that do not implement metadata, are skipped. Children that
+// are missing from the script's output will fetch their metadata individually.
+func (e *pluginEntry) PrefetchChildMetadata(ctx context.Context, children []plugin.Entry) error {
+	if !e.implements("metadata_batch") {
+		return nil
+	}
+
+	var batch []*pluginEntry
+	var requests []metadataBatchRequest
+	for _, child := range children {
+		entry, ok := child.(*pluginEntry)
+		if !ok || !entry.implements("metadata") || entry.getPrefetchedMetadata() != nil {
+			continue
+		}
+		batch = append(batch, entry)
+		requests = append(requests, metadataBatchRequest{Path: plugin.ID(entry), State: entry.state})
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	stdin, err := json.Marshal(requests)
+	if err != nil {
+		return err
+	}
+	inv := e.script.NewInvocation(ctx, "metadata_batch", e)
+	inv.SetStdin(bytes.NewReader(stdin))
+	if err := inv.RunAndWait(ctx); err != nil {
+		return err
+	}
+	var metadata map[string]plugin.JSONObject
+	if err := json.Unmarshal(inv.Stdout().Bytes(), &metadata); err != nil {
+		return newStdoutDecodeErr(ctx, "the batched metadata", err, inv, metadataBatchFormat)
+	}
+	for _, entry := range batch {
+		if m, ok := metadata[plugin.ID(entry)]; ok && m != nil {
+			entry.setPrefetchedMetadata(m)
+		}
+	}
+	return nil
+}
+
 func (e *pluginEntry) Signal(ctx context.Context, signal string) error {
 	_, err := e.script.InvokeAndWait(ctx, "signal", e, signal)
 	return err
@@ -170,6 +170,25 @@ func (suite *ExternalPluginEntryTestSuite) TestDecodeExternalPluginEntryMethodTu
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestDecodeExternalPluginEntryMethodTuple_Metadata() {
+	decodedEntry := decodedExternalPluginEntry{
+		Name:    "decodedEntry",
+		Methods: rawMethods(`["metadata", {"foo": "bar"}]`),
+	}
+	entry, err := decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+	if suite.NoError(err) {
+		suite.Equal(plugin.JSONObject{"foo": "bar"}, entry.methods["metadata"].tupleValue)
+		suite.Equal(plugin.JSONObject{"foo": "bar"}, entry.getPrefetchedMetadata())
+		suite.True(plugin.IsPrefetched(entry))
+	}
+
+	for _, invalidValue := range []string{`"foo"`, `null`, `["foo"]`} {
+		decodedEntry.Methods = rawMethods(`["metadata", ` + invalidValue + `]`)
+		_, err = decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+		suite.Regexp("Metadata method must provide a JSON object", err)
+	}
+}
+
 func newMockDecodedEntry(name string) decodedExternalPluginEntry {
 	return decodedExternalPluginEntry{
 		Name:    name,
@@ -942,6 +961,126 @@ func (suite *ExternalPluginEntryTestSuite) TestMetadata_Implemented() {
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestMetadata_Prefetched() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		methods:   map[string]methodInfo{"metadata": methodInfo{}},
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+	ctx := context.Background()
+
+	// Test that Metadata returns the prefetched metadata without shelling out
+	// to the plugin script
+	prefetchedMetadata := plugin.JSONObject{"key": "prefetched"}
+	entry.setPrefetchedMetadata(prefetchedMetadata)
+	metadata, err := entry.Metadata(ctx)
+	if suite.NoError(err) {
+		suite.Equal(prefetchedMetadata, metadata)
+	}
+	mockScript.AssertNotCalled(suite.T(), "InvokeAndWait")
+
+	// Test that Metadata invokes the plugin script once the prefetched
+	// metadata has expired
+	entry.SetTTLOf(plugin.MetadataOp, -1)
+	entry.setPrefetchedMetadata(prefetchedMetadata)
+	mockScript.OnInvokeAndWait(ctx, "metadata", entry).Return(mockInvocation([]byte(`{"key":"value"}`)), nil).Once()
+	metadata, err = entry.Metadata(ctx)
+	if suite.NoError(err) {
+		suite.Equal(plugin.JSONObject{"key": "value"}, metadata)
+	}
+}
+
+func (suite *ExternalPluginEntryTestSuite) TestListWithPrefetchedMetadata() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	stdout := `[{"name": "bar", "methods": [["metadata", {"key": "value"}]], "cache_ttls": {"metadata": 30}}]`
+	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation([]byte(stdout)), nil).Once()
+	entries, err := entry.List(ctx)
+	if suite.NoError(err) && suite.Equal(1, len(entries)) {
+		metadata, err := entries[0].Metadata(ctx)
+		if suite.NoError(err) {
+			suite.Equal(plugin.JSONObject{"key": "value"}, metadata)
+		}
+		mockScript.AssertNotCalled(suite.T(), "InvokeAndWait", ctx, "metadata", entries[0], []string(nil))
+	}
+}
+
+func (suite *ExternalPluginEntryTestSuite) TestPrefetchChildMetadata() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	newEntry := func(name string, methods ...string) *pluginEntry {
+		entry := &pluginEntry{
+			EntryBase: plugin.NewEntry(name),
+			methods:   make(map[string]methodInfo),
+			script:    mockScript,
+			state:     name + "State",
+		}
+		for _, method := range methods {
+			entry.methods[method] = methodInfo{}
+		}
+		entry.SetTestID("/foo/" + name)
+		return entry
+	}
+	parent := newEntry("parent", "list", "metadata_batch")
+	parent.SetTestID("/foo")
+	bar := newEntry("bar", "metadata")
+	baz := newEntry("baz", "metadata")
+	qux := newEntry("qux", "metadata")
+	qux.setPrefetchedMetadata(plugin.JSONObject{"key": "qux"})
+	noMetadata := newEntry("noMetadata")
+	children := []plugin.Entry{bar, baz, qux, noMetadata}
+
+	ctx := context.Background()
+	mockRunAndWait := func(stdout string, err error) *mockedInvocation {
+		mockInv := &mockedInvocation{Command: NewCommand(ctx, "")}
+		mockScript.On("NewInvocation", ctx, "metadata_batch", parent, []string(nil)).Return(mockInv).Once()
+		mockInv.On("RunAndWait", ctx).Return(err).Once()
+		mockInv.On("Stdout").Return(bytes.NewBufferString(stdout))
+		mockInv.On("Stderr").Return(&bytes.Buffer{})
+		return mockInv
+	}
+
+	// Test that if RunAndWait errors, then PrefetchChildMetadata returns its error
+	mockErr := fmt.Errorf("execution error")
+	mockRunAndWait("", mockErr)
+	suite.EqualError(parent.PrefetchChildMetadata(ctx, children), mockErr.Error())
+
+	// Test that PrefetchChildMetadata returns an error if stdout does not have
+	// the right output format
+	mockRunAndWait("bad format", nil)
+	suite.Regexp("stdout", parent.PrefetchChildMetadata(ctx, children))
+
+	// Test that PrefetchChildMetadata only requests metadata for children that
+	// need it, and that it sets their prefetched metadata
+	mockInv := mockRunAndWait(`{"/foo/bar": {"key": "bar"}}`, nil)
+	if suite.NoError(parent.PrefetchChildMetadata(ctx, children)) {
+		stdin, err := ioutil.ReadAll(mockInv.Command.(*command).Stdin)
+		if suite.NoError(err) {
+			suite.JSONEq(
+				`[{"path": "/foo/bar", "state": "barState"}, {"path": "/foo/baz", "state": "bazState"}]`,
+				string(stdin),
+			)
+		}
+		suite.Equal(plugin.JSONObject{"key": "bar"}, bar.getPrefetchedMetadata())
+		// baz was not included in the output, so it'll fetch its own metadata
+		suite.Nil(baz.getPrefetchedMetadata())
+		suite.Equal(plugin.JSONObject{"key": "qux"}, qux.getPrefetchedMetadata())
+	}
+
+	// Test that PrefetchChildMetadata is a no-op if the parent doesn't
+	// implement metadata_batch
+	delete(parent.methods, "metadata_batch")
+	suite.NoError(parent.PrefetchChildMetadata(ctx, children))
+	mockScript.AssertExpectations(suite.T())
+}
+
 func (suite *ExternalPluginEntryTestSuite) TestExec() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
 	entry := &pluginEntry{
@@ -0,0 +1,81 @@
+#!/bin/sh
+# A small external plugin that's used by the plugintest conformance suite.
+# It's invoked as <method> <id> <state> <args...>, or init <config>.
+
+method="$1"
+id="$2"
+
+case "$method" in
+init)
+  cat <<'JSON'
+{
+  "type_id": "root",
+  "methods": [
+    "list",
+    ["schema", {
+      "root": {
+        "label": "conformance",
+        "singleton": true,
+        "methods": ["list", "schema"],
+        "children": ["vmsDir"]
+      },
+      "vmsDir": {
+        "label": "vms",
+        "singleton": true,
+        "methods": ["list", "schema"],
+        "children": ["vm"]
+      },
+      "vm": {
+        "label": "vm",
+        "methods": ["list", "schema", "metadata", "signal", "delete"],
+        "signals": [
+          {"name": "start", "description": "Starts the VM"},
+          {"name": "stop", "description": "Stops the VM"}
+        ],
+        "children": ["log"]
+      },
+      "log": {
+        "label": "log",
+        "singleton": true,
+        "methods": ["read", "schema"]
+      }
+    }]
+  ]
+}
+JSON
+  ;;
+list)
+  case "$id" in
+  /conformance)
+    echo '[{"name": "vms", "type_id": "vmsDir", "methods": ["list", "schema"]}]'
+    ;;
+  /conformance/vms)
+    echo '[
+      {"name": "vm1", "type_id": "vm", "methods": ["list", "schema", "metadata", "signal", "delete"], "partial_metadata": {"state": "running"}},
+      {"name": "vm2", "type_id": "vm", "methods": ["list", "schema", "metadata", "signal", "delete"], "partial_metadata": {"state": "stopped"}}
+    ]'
+    ;;
+  /conformance/vms/*)
+    cat <<'JSON'
+[{"name": "log", "type_id": "log", "methods": [["read", "booted\n"], "schema"]}]
+JSON
+    ;;
+  *)
+    echo "unknown entry $id" >&2
+    exit 1
+    ;;
+  esac
+  ;;
+metadata)
+  echo '{"state": "running", "cpus": 2}'
+  ;;
+signal)
+  ;;
+delete)
+  echo 'true'
+  ;;
+*)
+  echo "unsupported method $method" >&2
+  exit 1
+  ;;
+esac
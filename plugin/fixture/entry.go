@@ -0,0 +1,350 @@
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// entryNode represents a declared entry. Nodes are shared by all of the entry
+// objects that represent them so that writes, deletes and signals are visible
+// to subsequent calls.
+type entryNode struct {
+	// Name is the entry's name. It is required.
+	Name string `json:"name"`
+	// Type is the entry's type. It defaults to the entry's name. Entries of the
+	// same type must support the same actions and signals.
+	Type string `json:"type"`
+	// Attributes are the entry's attributes
+	Attributes plugin.EntryAttributes `json:"attributes"`
+	// PartialMetadata is the entry's partial metadata
+	PartialMetadata plugin.JSONObject `json:"partial_metadata"`
+	// Metadata is the entry's full metadata. It defaults to the partial
+	// metadata.
+	Metadata plugin.JSONObject `json:"metadata"`
+	// Content is the entry's content. Entries with content are readable.
+	Content *string `json:"content"`
+	// Writable indicates that the entry's content can be overwritten
+	Writable bool `json:"writable"`
+	// Deletable indicates that the entry can be deleted
+	Deletable bool `json:"deletable"`
+	// Exec are the entry's exec responses. Entries with exec responses are
+	// execable.
+	Exec []*execResponse `json:"exec"`
+	// Signals are the signals that the entry supports
+	Signals []*signalSpec `json:"signals"`
+	// Children are the entry's children. Entries with children (including an
+	// empty list) are parents.
+	Children []*entryNode `json:"children"`
+
+	parent *entryNode
+}
+
+// execResponse is the output of an exec'ed command. A response with an empty
+// command is used for all commands that don't have their own response.
+type execResponse struct {
+	Command  string `json:"command"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// signalSpec is a signal that the entry supports. The regex is set for signal
+// groups. Metadata is merged into the entry's metadata when the signal's sent,
+// which is useful for modeling state transitions.
+type signalSpec struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Regex       string            `json:"regex"`
+	Metadata    plugin.JSONObject `json:"metadata"`
+
+	regex *regexp.Regexp
+}
+
+func (n *entryNode) cname() string {
+	return strings.Replace(n.Name, "/", "#", -1)
+}
+
+// validate validates n and its descendants. It also sets each node's default
+// type and parent pointer.
+func (n *entryNode) validate() error {
+	if n.Type == rootTypeID && n.parent != nil {
+		return fmt.Errorf("%v: the %v type is reserved for the plugin root", n.path(), rootTypeID)
+	}
+	for _, signal := range n.Signals {
+		if signal.Name == "" || signal.Description == "" {
+			return fmt.Errorf("%v: signals must have a name and a description", n.path())
+		}
+		if signal.Regex != "" {
+			var err error
+			if signal.regex, err = regexp.Compile("(?i)" + signal.Regex); err != nil {
+				return fmt.Errorf("%v: invalid regex for the %v signal group: %v", n.path(), signal.Name, err)
+			}
+		}
+	}
+	if n.Writable && n.Content == nil {
+		// Writable entries are also readable so that writes can be verified
+		empty := ""
+		n.Content = &empty
+	}
+	if n.Content != nil && !n.Attributes.HasSize() {
+		n.Attributes.SetSize(uint64(len(*n.Content)))
+	}
+
+	cnames := make(map[string]bool)
+	for _, child := range n.Children {
+		if child == nil || child.Name == "" {
+			return fmt.Errorf("%v: all children must have a name", n.path())
+		}
+		if child.Type == "" {
+			child.Type = child.Name
+		}
+		child.parent = n
+		if cnames[child.cname()] {
+			return fmt.Errorf("%v: duplicate child %v", n.path(), child.cname())
+		}
+		cnames[child.cname()] = true
+		if err := child.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// path returns the node's path relative to the plugin root
+func (n *entryNode) path() string {
+	if n.parent == nil {
+		return "/"
+	}
+	if n.parent.parent == nil {
+		return "/" + n.cname()
+	}
+	return n.parent.path() + "/" + n.cname()
+}
+
+// actions returns the node's supported actions in sorted order
+func (n *entryNode) actions() []string {
+	var actions []string
+	if n.Children != nil {
+		actions = append(actions, plugin.ListAction().Name)
+	}
+	if n.Content != nil {
+		actions = append(actions, plugin.ReadAction().Name)
+	}
+	if n.Writable {
+		actions = append(actions, plugin.WriteAction().Name)
+	}
+	if n.Exec != nil {
+		actions = append(actions, plugin.ExecAction().Name)
+	}
+	if n.Deletable {
+		actions = append(actions, plugin.DeleteAction().Name)
+	}
+	if n.Signals != nil {
+		actions = append(actions, plugin.SignalAction().Name)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// signalSchemas returns the schemas of the node's signals
+func (n *entryNode) signalSchemas() []plugin.SignalSchema {
+	var s plugin.EntrySchema
+	for _, signal := range n.Signals {
+		if signal.Regex != "" {
+			s.AddSignalGroup(signal.Name, signal.Regex, signal.Description)
+		} else {
+			s.AddSignal(signal.Name, signal.Description)
+		}
+	}
+	return s.Signals
+}
+
+// entry represents a fixture entry
+type entry struct {
+	plugin.EntryBase
+	root *Root
+	node *entryNode
+}
+
+func newEntry(r *Root, n *entryNode) *entry {
+	e := &entry{
+		EntryBase: plugin.NewEntry(n.Name),
+		root:      r,
+		node:      n,
+	}
+	// Fixture entries are stored in memory so there's no point in caching them.
+	// This also ensures that writes are visible to subsequent reads.
+	e.DisableDefaultCaching()
+	e.SetAttributes(n.Attributes)
+	if n.PartialMetadata != nil {
+		e.SetPartialMetadata(copyMetadata(n.PartialMetadata))
+	}
+	return e
+}
+
+// copyMetadata returns a copy of the node's metadata so that callers and the cache
+// don't see the changes that Signal makes to it. Signal only sets top-level keys, so
+// a shallow copy is enough.
+func copyMetadata(meta plugin.JSONObject) plugin.JSONObject {
+	copied := make(plugin.JSONObject, len(meta))
+	for k, v := range meta {
+		copied[k] = v
+	}
+	return copied
+}
+
+// listChildren returns the entries representing n's children
+func listChildren(r *Root, n *entryNode) []plugin.Entry {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	entries := make([]plugin.Entry, len(n.Children))
+	for i, child := range n.Children {
+		entries[i] = newEntry(r, child)
+	}
+	return entries
+}
+
+func (e *entry) MethodSignature(method string) plugin.MethodSignature {
+	e.root.mux.RLock()
+	defer e.root.mux.RUnlock()
+	for _, action := range e.node.actions() {
+		if action == method {
+			return plugin.DefaultSignature
+		}
+	}
+	return plugin.UnsupportedSignature
+}
+
+func (e *entry) RawTypeID() string {
+	return e.node.Type
+}
+
+func (e *entry) SchemaGraph() (*linkedhashmap.Map, error) {
+	return e.root.schemaGraphs[plugin.TypeID(e)], nil
+}
+
+func (e *entry) Schema() *plugin.EntrySchema {
+	// Fixture entries use the schema declared by the fixture file. See
+	// SchemaGraph.
+	return nil
+}
+
+func (e *entry) ChildSchemas() []*plugin.EntrySchema {
+	// Fixture entries use the schema declared by the fixture file. See
+	// SchemaGraph.
+	return []*plugin.EntrySchema{}
+}
+
+func (e *entry) List(ctx context.Context) ([]plugin.Entry, error) {
+	return listChildren(e.root, e.node), nil
+}
+
+func (e *entry) Read(ctx context.Context) ([]byte, error) {
+	e.root.mux.RLock()
+	defer e.root.mux.RUnlock()
+	return []byte(*e.node.Content), nil
+}
+
+func (e *entry) BlockRead(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	// Fixture entries are not block-readable. See MethodSignature.
+	return nil, fmt.Errorf("%v is not block-readable", e.node.path())
+}
+
+func (e *entry) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	e.root.mux.RLock()
+	defer e.root.mux.RUnlock()
+	if e.node.Metadata == nil {
+		if e.node.PartialMetadata != nil {
+			return copyMetadata(e.node.PartialMetadata), nil
+		}
+		return e.EntryBase.Metadata(ctx)
+	}
+	return copyMetadata(e.node.Metadata), nil
+}
+
+func (e *entry) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	cmdline := strings.Join(append([]string{cmd}, args...), " ")
+	var response *execResponse
+	for _, r := range e.node.Exec {
+		if r.Command == cmdline {
+			response = r
+			break
+		} else if r.Command == "" && response == nil {
+			response = r
+		}
+	}
+	if response == nil {
+		return nil, fmt.Errorf("%v does not have a response for '%v'", e.node.path(), cmdline)
+	}
+	activity.Record(ctx, "Responding to %v on %v", cmdline, e.node.path())
+
+	execCmd := plugin.NewExecCommand(ctx)
+	go func() {
+		if _, err := execCmd.Stdout().Write([]byte(response.Stdout)); err != nil {
+			return
+		}
+		if _, err := execCmd.Stderr().Write([]byte(response.Stderr)); err != nil {
+			return
+		}
+		execCmd.CloseStreamsWithError(nil)
+		execCmd.SetExitCode(response.ExitCode)
+	}()
+	return execCmd, nil
+}
+
+func (e *entry) Write(ctx context.Context, p []byte) error {
+	e.root.mux.Lock()
+	defer e.root.mux.Unlock()
+	content := string(p)
+	e.node.Content = &content
+	e.node.Attributes.SetSize(uint64(len(p)))
+	e.node.Attributes.SetMtime(time.Now())
+	return nil
+}
+
+func (e *entry) Delete(ctx context.Context) (bool, error) {
+	e.root.mux.Lock()
+	defer e.root.mux.Unlock()
+	parent := e.node.parent
+	for i, child := range parent.Children {
+		if child == e.node {
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			return true, nil
+		}
+	}
+	// The entry was already deleted
+	return true, nil
+}
+
+func (e *entry) Signal(ctx context.Context, signal string) error {
+	e.root.mux.Lock()
+	defer e.root.mux.Unlock()
+	for _, s := range e.node.Signals {
+		matches := strings.EqualFold(s.Name, signal)
+		if s.regex != nil {
+			matches = s.regex.MatchString(signal)
+		}
+		if !matches {
+			continue
+		}
+		for k, v := range s.Metadata {
+			if e.node.PartialMetadata == nil {
+				e.node.PartialMetadata = plugin.JSONObject{}
+			}
+			e.node.PartialMetadata[k] = v
+			if e.node.Metadata != nil {
+				e.node.Metadata[k] = v
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%v does not support the %v signal", e.node.path(), signal)
+}
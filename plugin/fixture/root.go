@@ -0,0 +1,299 @@
+// Package fixture presents a hierarchy that's declared in a YAML or JSON file.
+// It is meant for testing Wash scripts and demoing Wash without having to
+// stand up real infrastructure like Docker or Kubernetes.
+//
+// A fixture file looks something like
+//
+//	description: A test environment
+//	types:
+//	  container:
+//	    description: A container
+//	    partial_metadata_schema: {type: object, properties: {state: {type: string}}}
+//	entries:
+//	  - name: containers
+//	    children:
+//	      - name: web
+//	        type: container
+//	        partial_metadata: {state: running}
+//	        exec:
+//	          - command: uname -s
+//	            stdout: "Linux\n"
+//	        signals:
+//	          - name: stop
+//	            description: Stops the container
+//	            metadata: {state: exited}
+//	        children:
+//	          - name: log
+//	            content: "GET / 200\n"
+//	            writable: true
+//	            deletable: true
+//
+// See the entryNode type for all of the supported fields.
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/ghodss/yaml"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Root of the fixture plugin
+type Root struct {
+	plugin.EntryBase
+	// mux protects each node's mutable state (content, metadata, children)
+	mux          sync.RWMutex
+	node         *entryNode
+	schemaGraphs map[string]*linkedhashmap.Map
+}
+
+// fixtureFile represents a decoded fixture file
+type fixtureFile struct {
+	Description string              `json:"description"`
+	Types       map[string]typeSpec `json:"types"`
+	Entries     []*entryNode        `json:"entries"`
+}
+
+// typeSpec contains additional schema information for a given type
+type typeSpec struct {
+	Description           string             `json:"description"`
+	PartialMetadataSchema *plugin.JSONSchema `json:"partial_metadata_schema"`
+	MetadataSchema        *plugin.JSONSchema `json:"metadata_schema"`
+}
+
+const rootTypeID = "root"
+
+// Init for root
+func (r *Root) Init(cfg map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("fixture")
+	r.DisableDefaultCaching()
+
+	file := fixtureFile{Description: rootDescription}
+	if fileI, ok := cfg["file"]; ok {
+		path, ok := fileI.(string)
+		if !ok {
+			return fmt.Errorf("fixture.file config must be a string, not %s", fileI)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// ghodss/yaml converts the YAML to JSON before unmarshalling it, so
+		// it handles both YAML and JSON fixture files.
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return fmt.Errorf("could not decode the fixture file %v: %v", path, err)
+		}
+	}
+	if file.Entries == nil {
+		file.Entries = []*entryNode{}
+	}
+
+	r.node = &entryNode{
+		Name:     plugin.Name(r),
+		Type:     rootTypeID,
+		Children: file.Entries,
+	}
+	if err := r.node.validate(); err != nil {
+		return err
+	}
+
+	graphs, err := newSchemaGraphs(r.node, file)
+	if err != nil {
+		return err
+	}
+	r.schemaGraphs = graphs
+	return nil
+}
+
+// List lists the fixture's top-level entries
+func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
+	return listChildren(r, r.node), nil
+}
+
+// Schema returns nil because the root's schema is declared by the fixture
+// file. See SchemaGraph.
+func (r *Root) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+// ChildSchemas is only meant for core plugins. See SchemaGraph.
+func (r *Root) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{}
+}
+
+// MethodSignature returns the signature of the given method. The root can only
+// be listed.
+func (r *Root) MethodSignature(method string) plugin.MethodSignature {
+	if method == plugin.ListAction().Name {
+		return plugin.DefaultSignature
+	}
+	return plugin.UnsupportedSignature
+}
+
+// RawTypeID returns the root's raw type ID
+func (r *Root) RawTypeID() string {
+	return rootTypeID
+}
+
+// SchemaGraph returns the root's schema graph
+func (r *Root) SchemaGraph() (*linkedhashmap.Map, error) {
+	return r.schemaGraphs[plugin.TypeID(r)], nil
+}
+
+// BlockRead is not supported by the root.
+func (r *Root) BlockRead(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return nil, fmt.Errorf("the fixture plugin's root cannot be read")
+}
+
+// newSchemaGraphs creates the fixture's schema graph from the declared nodes,
+// then partitions it into a map of <type_id> => <schema_graph>. Nodes of the
+// same type must support the same actions and signals.
+func newSchemaGraphs(root *entryNode, file fixtureFile) (map[string]*linkedhashmap.Map, error) {
+	type typeInfo struct {
+		node       plugin.EntrySchema
+		firstPath  string
+		children   map[string]bool
+		names      map[string]bool
+		multiplied bool
+	}
+	types := make(map[string]*typeInfo)
+	var order []string
+
+	var visit func(n *entryNode, path string) error
+	visit = func(n *entryNode, path string) error {
+		actions := n.actions()
+		signals := n.signalSchemas()
+		info, ok := types[n.Type]
+		if !ok {
+			info = &typeInfo{
+				firstPath: path,
+				children:  make(map[string]bool),
+				names:     make(map[string]bool),
+			}
+			info.node.Label = n.Type
+			info.node.Actions = actions
+			info.node.Signals = signals
+			if n.Type == rootTypeID {
+				info.node.Label = n.Name
+				info.node.Description = file.Description
+				info.node.Singleton = true
+			} else if spec, ok := file.Types[n.Type]; ok {
+				info.node.Description = strings.Trim(spec.Description, "\n")
+				info.node.PartialMetadataSchema = spec.PartialMetadataSchema
+				info.node.MetadataSchema = spec.MetadataSchema
+			}
+			types[n.Type] = info
+			order = append(order, n.Type)
+		} else {
+			if strings.Join(info.node.Actions, ",") != strings.Join(actions, ",") {
+				return fmt.Errorf(
+					"%v and %v have the same type (%v) but support different actions (%v vs. %v)",
+					info.firstPath,
+					path,
+					n.Type,
+					strings.Join(info.node.Actions, ", "),
+					strings.Join(actions, ", "),
+				)
+			}
+			if !sameSignals(info.node.Signals, signals) {
+				return fmt.Errorf("%v and %v have the same type (%v) but support different signals", info.firstPath, path, n.Type)
+			}
+		}
+		info.names[n.Name] = true
+
+		childTypeCounts := make(map[string]int)
+		for _, child := range n.Children {
+			info.children[child.Type] = true
+			childTypeCounts[child.Type]++
+			if err := visit(child, path+"/"+child.cname()); err != nil {
+				return err
+			}
+		}
+		for childType, count := range childTypeCounts {
+			if count > 1 {
+				types[childType].multiplied = true
+			}
+		}
+		return nil
+	}
+	if err := visit(root, ""); err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]plugin.EntrySchema)
+	for typeID, info := range types {
+		node := info.node
+		if typeID != rootTypeID {
+			// A type is a singleton if it represents a single, statically named
+			// entry like a "metadata.json" file.
+			node.Singleton = !info.multiplied && len(info.names) == 1
+		}
+		if hasAction(info.node.Actions, plugin.ListAction().Name) {
+			node.Children = []string{}
+			for childType := range info.children {
+				node.Children = append(node.Children, namespace(childType))
+			}
+			sort.Strings(node.Children)
+		}
+		nodes[namespace(typeID)] = node
+	}
+
+	var populate func(*linkedhashmap.Map, string)
+	populate = func(g *linkedhashmap.Map, typeID string) {
+		if _, ok := g.Get(typeID); ok {
+			return
+		}
+		node := nodes[typeID]
+		g.Put(typeID, node)
+		for _, child := range node.Children {
+			populate(g, child)
+		}
+	}
+	graphs := make(map[string]*linkedhashmap.Map)
+	for _, typeID := range order {
+		g := linkedhashmap.New()
+		populate(g, namespace(typeID))
+		graphs[namespace(typeID)] = g
+	}
+	return graphs, nil
+}
+
+// namespace returns the type ID of the given fixture type. It matches
+// plugin.TypeID for the fixture plugin's entries.
+func namespace(typeID string) string {
+	return "fixture::" + typeID
+}
+
+func hasAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func sameSignals(a []plugin.SignalSchema, b []plugin.SignalSchema) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name() != b[i].Name() {
+			return false
+		}
+	}
+	return true
+}
+
+const rootDescription = `
+This is the fixture plugin root. It serves the hierarchy declared in the file
+specified by the fixture.file config. Entries can be listed, read, exec'ed (with
+the declared responses), signaled, written to and deleted. Writes and deletes
+are kept in memory, so they are discarded when Wash restarts.
+`
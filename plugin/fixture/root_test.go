@@ -0,0 +1,191 @@
+package fixture
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type RootTestSuite struct {
+	suite.Suite
+	ctx  context.Context
+	root *Root
+}
+
+func (suite *RootTestSuite) SetupTest() {
+	suite.ctx = plugin.SetTestCache(datastore.NewMemCache())
+	suite.root = &Root{}
+	suite.Require().NoError(suite.root.Init(map[string]interface{}{
+		"file": "testdata/fixture.yaml",
+	}))
+	suite.root.SetTestID("/fixture")
+}
+
+func (suite *RootTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+func (suite *RootTestSuite) find(segments ...string) plugin.Entry {
+	var e plugin.Entry = suite.root
+	for _, segment := range segments {
+		entries, err := plugin.List(suite.ctx, e.(plugin.Parent))
+		suite.Require().NoError(err)
+		child, ok := entries.Load(segment)
+		suite.Require().True(ok, "%v was not found", segment)
+		e = child
+	}
+	return e
+}
+
+func (suite *RootTestSuite) initWith(content string) error {
+	f, err := ioutil.TempFile("", "fixture*.yaml")
+	suite.Require().NoError(err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+	return (&Root{}).Init(map[string]interface{}{"file": f.Name()})
+}
+
+func (suite *RootTestSuite) TestInit_NoFile() {
+	r := &Root{}
+	suite.NoError(r.Init(nil))
+	entries, err := r.List(suite.ctx)
+	suite.NoError(err)
+	suite.Empty(entries)
+}
+
+func (suite *RootTestSuite) TestInit_InvalidConfig() {
+	suite.Error((&Root{}).Init(map[string]interface{}{"file": 1}))
+	suite.Error((&Root{}).Init(map[string]interface{}{"file": "testdata/missing.yaml"}))
+}
+
+func (suite *RootTestSuite) TestInit_InvalidFixture() {
+	suite.Regexp("all children must have a name", suite.initWith("entries: [{type: foo}]"))
+	suite.Regexp("/: duplicate child foo", suite.initWith("entries: [{name: foo}, {name: foo}]"))
+	suite.Regexp("reserved for the plugin root", suite.initWith("entries: [{name: foo, type: root}]"))
+	suite.Regexp(
+		"/a and /b have the same type \\(foo\\) but support different actions",
+		suite.initWith("entries: [{name: a, type: foo, content: bar}, {name: b, type: foo}]"),
+	)
+	suite.Regexp("invalid regex", suite.initWith("entries: [{name: a, signals: [{name: a, description: a, regex: '('}]}]"))
+}
+
+func (suite *RootTestSuite) TestListAndRead() {
+	container := suite.find("containers", "web")
+	suite.Equal("/fixture/containers/web", plugin.ID(container))
+	suite.Equal(plugin.JSONObject{"state": "running"}, plugin.PartialMetadata(container))
+	suite.ElementsMatch([]string{"exec", "list", "signal"}, plugin.SupportedActionsOf(container))
+
+	log := suite.find("containers", "web", "log")
+	attr := plugin.Attributes(log)
+	suite.Equal(uint64(10), attr.Size())
+	content, err := plugin.Read(suite.ctx, log, 100, 0)
+	suite.Equal("GET / 200\n", string(content))
+	suite.Equal(io.EOF, err)
+}
+
+func (suite *RootTestSuite) TestExec() {
+	container := suite.find("containers", "web").(plugin.Execable)
+
+	collect := func(cmd string, args ...string) (string, string, int) {
+		execCmd, err := plugin.Exec(suite.ctx, container, cmd, args, plugin.ExecOptions{})
+		suite.Require().NoError(err)
+		var stdout, stderr string
+		for chunk := range execCmd.OutputCh() {
+			suite.NoError(chunk.Err)
+			if chunk.StreamID == plugin.Stdout {
+				stdout += chunk.Data
+			} else {
+				stderr += chunk.Data
+			}
+		}
+		exitCode, err := execCmd.ExitCode()
+		suite.NoError(err)
+		return stdout, stderr, exitCode
+	}
+
+	stdout, stderr, exitCode := collect("uname", "-s")
+	suite.Equal("Linux\n", stdout)
+	suite.Equal("", stderr)
+	suite.Equal(0, exitCode)
+
+	stdout, stderr, exitCode = collect("foo")
+	suite.Equal("", stdout)
+	suite.Equal("command not found\n", stderr)
+	suite.Equal(127, exitCode)
+
+	db := suite.find("containers", "db").(plugin.Execable)
+	_, err := plugin.Exec(suite.ctx, db, "uname", []string{}, plugin.ExecOptions{})
+	suite.Regexp("/containers/db does not have a response for 'uname'", err)
+}
+
+func (suite *RootTestSuite) TestWriteAndDelete() {
+	log := suite.find("containers", "web", "log")
+	suite.NoError(plugin.Write(suite.ctx, log.(plugin.Writable), []byte("hello")))
+
+	log = suite.find("containers", "web", "log")
+	attr := plugin.Attributes(log)
+	suite.Equal(uint64(5), attr.Size())
+	content, err := plugin.Read(suite.ctx, log, 100, 0)
+	suite.Equal("hello", string(content))
+	suite.Equal(io.EOF, err)
+
+	deleted, err := plugin.Delete(suite.ctx, log.(plugin.Deletable))
+	suite.NoError(err)
+	suite.True(deleted)
+	entries, err := plugin.List(suite.ctx, suite.find("containers", "web").(plugin.Parent))
+	suite.NoError(err)
+	suite.Equal(0, entries.Len())
+}
+
+func (suite *RootTestSuite) TestSignal() {
+	container := suite.find("containers", "web")
+	err := plugin.Signal(suite.ctx, container.(plugin.Signalable), "start")
+	suite.Regexp("invalid signal start", err)
+	before, err := plugin.Metadata(suite.ctx, container)
+	suite.Require().NoError(err)
+
+	suite.NoError(plugin.Signal(suite.ctx, container.(plugin.Signalable), "STOP"))
+	// Metadata that was already returned isn't changed by the signal.
+	suite.Equal(plugin.JSONObject{"state": "running"}, before)
+	suite.Equal(plugin.JSONObject{"state": "running"}, plugin.PartialMetadata(container))
+	container = suite.find("containers", "web")
+	meta, err := plugin.Metadata(suite.ctx, container)
+	suite.NoError(err)
+	suite.Equal(plugin.JSONObject{"state": "exited"}, meta)
+}
+
+func (suite *RootTestSuite) TestSchema() {
+	rootSchema, err := plugin.Schema(suite.root)
+	suite.Require().NoError(err)
+	suite.Equal("A test environment", rootSchema.Description)
+	suite.Equal([]string{"fixture::containers", "fixture::metadata.json"}, rootSchema.Children)
+
+	container := suite.find("containers", "web")
+	schema, err := plugin.Schema(container)
+	suite.Require().NoError(err)
+	suite.Equal("container", schema.Label)
+	suite.Equal("A container", schema.Description)
+	suite.False(schema.Singleton)
+	suite.Equal([]string{"fixture::log"}, schema.Children)
+	suite.NotNil(schema.PartialMetadataSchema)
+	if suite.Len(schema.Signals, 1) {
+		suite.Equal("stop", schema.Signals[0].Name())
+	}
+
+	schema, err = plugin.Schema(suite.find("metadata.json"))
+	suite.Require().NoError(err)
+	suite.True(schema.Singleton)
+	suite.Nil(schema.Children)
+}
+
+func TestRoot(t *testing.T) {
+	suite.Run(t, new(RootTestSuite))
+}
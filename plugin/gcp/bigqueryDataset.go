@@ -0,0 +1,79 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+type bigqueryDataset struct {
+	plugin.EntryBase
+	service bigqueryProjectService
+	ref     *bigquery.DatasetReference
+}
+
+func newBigqueryDataset(dataset *bigquery.DatasetListDatasets, service bigqueryProjectService) *bigqueryDataset {
+	d := &bigqueryDataset{
+		EntryBase: plugin.NewEntry(dataset.DatasetReference.DatasetId),
+		service:   service,
+		ref:       dataset.DatasetReference,
+	}
+	d.SetPartialMetadata(dataset)
+	return d
+}
+
+// List lists the dataset's query entry and its tables, views and external tables. The
+// query entry is omitted if a table has the same name.
+func (d *bigqueryDataset) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	hasQueryName := false
+	req := d.service.Tables.List(d.ref.ProjectId, d.ref.DatasetId)
+	err := req.Pages(ctx, func(resp *bigquery.TableList) error {
+		for _, table := range resp.Tables {
+			if table.TableReference.TableId == bigqueryQueryName {
+				hasQueryName = true
+			}
+			entries = append(entries, newBigqueryTable(table, d.service))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hasQueryName {
+		activity.Record(ctx, "Omitting the %v dataset's query entry because a table has the same name", d.ref.DatasetId)
+		return entries, nil
+	}
+	return append([]plugin.Entry{newBigqueryQuery(d.service, d.ref)}, entries...), nil
+}
+
+func (d *bigqueryDataset) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	dataset, err := d.service.Datasets.Get(d.ref.ProjectId, d.ref.DatasetId).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return plugin.ToJSONObject(dataset), nil
+}
+
+func (d *bigqueryDataset) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(d, "dataset").
+		SetPartialMetadataSchema(bigquery.DatasetListDatasets{}).
+		SetMetadataSchema(bigquery.Dataset{}).
+		SetDescription(bigqueryDatasetDescription)
+}
+
+func (d *bigqueryDataset) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&bigqueryQuery{}).Schema(),
+		(&bigqueryTable{}).Schema(),
+	}
+}
+
+const bigqueryDatasetDescription = `
+This is a BigQuery dataset. It contains its tables and views, and a query entry
+that runs standard SQL queries on it, like
+
+  wash exec <dataset>/query 'SELECT name, COUNT(*) AS count FROM names GROUP BY name'
+`
@@ -0,0 +1,61 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/plugin"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+type bigqueryProjectService struct {
+	*bigquery.Service
+	projectID string
+}
+
+type bigqueryDir struct {
+	plugin.EntryBase
+	service bigqueryProjectService
+}
+
+func newBigqueryDir(ctx context.Context, opts clientOptions, projID string) (*bigqueryDir, error) {
+	svc, err := bigquery.NewService(context.Background(), opts.forService("bigquery")...)
+	if err != nil {
+		return nil, err
+	}
+	b := &bigqueryDir{
+		EntryBase: plugin.NewEntry("bigquery"),
+		service:   bigqueryProjectService{Service: svc, projectID: projID},
+	}
+	if _, err := plugin.List(ctx, b); err != nil {
+		b.MarkInaccessible(ctx, err)
+	}
+	return b, nil
+}
+
+// List lists the project's datasets.
+func (b *bigqueryDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	err := b.service.Datasets.List(b.service.projectID).Pages(ctx, func(resp *bigquery.DatasetList) error {
+		for _, dataset := range resp.Datasets {
+			entries = append(entries, newBigqueryDataset(dataset, b.service))
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (b *bigqueryDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(b, "bigquery").
+		IsSingleton().
+		SetDescription(bigqueryDirDescription)
+}
+
+func (b *bigqueryDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&bigqueryDataset{}).Schema(),
+	}
+}
+
+const bigqueryDirDescription = `
+This directory contains the project's BigQuery datasets.
+`
@@ -0,0 +1,70 @@
+package gcp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// bigqueryQueryName is the name of a dataset's query entry.
+const bigqueryQueryName = "query"
+
+// bigqueryQuery runs standard SQL queries on a dataset.
+type bigqueryQuery struct {
+	plugin.EntryBase
+	service bigqueryProjectService
+	ref     *bigquery.DatasetReference
+}
+
+func newBigqueryQuery(service bigqueryProjectService, ref *bigquery.DatasetReference) *bigqueryQuery {
+	return &bigqueryQuery{
+		EntryBase: plugin.NewEntry(bigqueryQueryName),
+		service:   service,
+		ref:       ref,
+	}
+}
+
+// Exec runs the command and its arguments as a standard SQL query, and streams its rows
+// as lines of JSON. Unqualified tables in the query are in the dataset. The query's
+// errors are returned as the command's error.
+func (q *bigqueryQuery) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	query := strings.Join(append([]string{cmd}, args...), " ")
+	activity.Record(ctx, "Querying dataset %v: %v", q.ref.DatasetId, query)
+
+	execCmd := plugin.NewExecCommand(ctx)
+	go func() {
+		err := runBigqueryQuery(ctx, q.service.Service, q.service.projectID, q.ref, query, 0, execCmd.Stdout())
+		if err != nil {
+			activity.Record(ctx, "Query on dataset %v failed: %v", q.ref.DatasetId, err)
+			if _, writeErr := execCmd.Stderr().Write([]byte(err.Error() + "\n")); writeErr != nil {
+				activity.Record(ctx, "Unable to write the query's error: %v", writeErr)
+			}
+		}
+		execCmd.CloseStreamsWithError(nil)
+		if err != nil {
+			execCmd.SetExitCode(1)
+		} else {
+			execCmd.SetExitCode(0)
+		}
+	}()
+	return execCmd, nil
+}
+
+func (q *bigqueryQuery) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(q, bigqueryQueryName).
+		IsSingleton().
+		SetDescription(bigqueryQueryDescription)
+}
+
+const bigqueryQueryDescription = `
+This runs standard SQL queries on its dataset. Exec it with the query, like
+
+  wash exec <dataset>/query 'SELECT name, COUNT(*) AS count FROM names GROUP BY name'
+
+Tables that aren't qualified with a dataset are in its dataset. The query's rows are
+streamed as lines of JSON. Queries are billed like any other BigQuery query, and are
+cancelled if the command is stopped.
+`
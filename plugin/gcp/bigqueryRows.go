@@ -0,0 +1,202 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/puppetlabs/wash/activity"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// The BigQuery API returns rows as lists of cells whose values are strings, nulls,
+// records ({"f": [cells]}) or repeated values ([{"v": value}]). Wash prints them as
+// newline-delimited JSON objects keyed by the schema's field names.
+
+// writeBigqueryRows writes each row as a line of JSON.
+func writeBigqueryRows(w io.Writer, schema *bigquery.TableSchema, rows []*bigquery.TableRow) error {
+	if schema == nil {
+		return fmt.Errorf("the rows do not have a schema")
+	}
+	for _, row := range rows {
+		b, err := json.Marshal(bigqueryRecordToJSON(schema.Fields, row.F))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bigqueryRecordToJSON(fields []*bigquery.TableFieldSchema, cells []*bigquery.TableCell) map[string]interface{} {
+	obj := make(map[string]interface{}, len(fields))
+	for i, field := range fields {
+		var value interface{}
+		if i < len(cells) && cells[i] != nil {
+			value = cells[i].V
+		}
+		obj[field.Name] = bigqueryValueToJSON(field, value)
+	}
+	return obj
+}
+
+func bigqueryValueToJSON(field *bigquery.TableFieldSchema, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if field.Mode == "REPEATED" {
+		elems, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		arr := make([]interface{}, len(elems))
+		elemField := *field
+		elemField.Mode = "NULLABLE"
+		for i, elem := range elems {
+			if cell, ok := elem.(map[string]interface{}); ok {
+				elem = cell["v"]
+			}
+			arr[i] = bigqueryValueToJSON(&elemField, elem)
+		}
+		return arr
+	}
+
+	switch field.Type {
+	case "RECORD", "STRUCT":
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		rawCells, _ := record["f"].([]interface{})
+		cells := make([]*bigquery.TableCell, len(rawCells))
+		for i, rawCell := range rawCells {
+			cell, _ := rawCell.(map[string]interface{})
+			cells[i] = &bigquery.TableCell{V: cell["v"]}
+		}
+		return bigqueryRecordToJSON(field.Fields, cells)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch field.Type {
+	case "INTEGER", "INT64", "FLOAT", "FLOAT64":
+		// NaN and infinities aren't JSON numbers, so they stay strings.
+		if _, err := strconv.ParseFloat(s, 64); err == nil && s != "NaN" && s != "Infinity" && s != "-Infinity" {
+			return json.Number(s)
+		}
+	case "BOOLEAN", "BOOL":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "TIMESTAMP":
+		// Timestamps are seconds since the epoch, like 1.5789636E9.
+		if secs, err := strconv.ParseFloat(s, 64); err == nil {
+			micros := int64(secs*1e6 + 0.5)
+			return time.Unix(0, micros*int64(time.Microsecond)).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
+
+// bigqueryQueryPageSize is the most rows that are fetched at a time.
+const bigqueryQueryPageSize = 1000
+
+// bigqueryQueryWait is how long each request waits for a query to complete.
+var bigqueryQueryWait = 10 * time.Second
+
+// bigqueryCancelTimeout is how long cancelling a query's job can take.
+var bigqueryCancelTimeout = 10 * time.Second
+
+// runBigqueryQuery runs the query and writes its rows as lines of JSON until they've
+// all been written. The query runs in the project, and its unqualified tables are in
+// the default dataset if it's not nil. The query's job is cancelled if ctx is done
+// before its rows are written, so that stopped queries aren't left running.
+func runBigqueryQuery(ctx context.Context, service *bigquery.Service, projectID string, defaultDataset *bigquery.DatasetReference, query string, maxRows int64, w io.Writer) error {
+	useLegacySQL := false
+	pageSize := int64(bigqueryQueryPageSize)
+	if maxRows > 0 && maxRows < pageSize {
+		pageSize = maxRows
+	}
+	// The job's ID is generated here so that the job can be cancelled even if ctx is done
+	// before BigQuery responds.
+	job := &bigquery.JobReference{ProjectId: projectID, JobId: "wash_" + uuid.New().String()}
+	defer func() {
+		if ctx.Err() != nil {
+			cancelBigqueryJob(ctx, service, job)
+		}
+	}()
+	inserted, err := service.Jobs.Insert(projectID, &bigquery.Job{
+		JobReference: job,
+		Configuration: &bigquery.JobConfiguration{
+			Query: &bigquery.JobConfigurationQuery{
+				Query:          query,
+				DefaultDataset: defaultDataset,
+				UseLegacySql:   &useLegacySQL,
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if inserted.JobReference != nil {
+		job = inserted.JobReference
+	}
+	activity.Record(ctx, "Started BigQuery job %v", job.JobId)
+
+	var schema *bigquery.TableSchema
+	var rows []*bigquery.TableRow
+	var complete bool
+	var pageToken string
+	var written int64
+	for {
+		if complete {
+			if maxRows > 0 && written+int64(len(rows)) > maxRows {
+				rows = rows[:maxRows-written]
+			}
+			if err := writeBigqueryRows(w, schema, rows); err != nil {
+				return err
+			}
+			written += int64(len(rows))
+			if pageToken == "" || (maxRows > 0 && written >= maxRows) {
+				return nil
+			}
+		}
+
+		req := service.Jobs.GetQueryResults(job.ProjectId, job.JobId).
+			Location(job.Location).
+			MaxResults(pageSize).
+			TimeoutMs(bigqueryQueryWait.Milliseconds())
+		if complete {
+			req = req.PageToken(pageToken)
+		}
+		results, err := req.Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		complete, schema, rows, pageToken = results.JobComplete, results.Schema, results.Rows, results.PageToken
+	}
+}
+
+// cancelBigqueryJob asks BigQuery to cancel the job. ctx is only used to record
+// activity, since it's already done.
+func cancelBigqueryJob(ctx context.Context, service *bigquery.Service, job *bigquery.JobReference) {
+	cancelCtx, cancel := context.WithTimeout(context.Background(), bigqueryCancelTimeout)
+	defer cancel()
+	req := service.Jobs.Cancel(job.ProjectId, job.JobId)
+	if job.Location != "" {
+		req = req.Location(job.Location)
+	}
+	if _, err := req.Context(cancelCtx).Do(); err != nil {
+		activity.Record(ctx, "Unable to cancel BigQuery job %v: %v", job.JobId, err)
+		return
+	}
+	activity.Record(ctx, "Cancelled BigQuery job %v", job.JobId)
+}
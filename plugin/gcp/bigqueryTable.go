@@ -0,0 +1,86 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/puppetlabs/wash/plugin"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+// bigqueryPreviewRows is the number of rows in a table's preview.
+const bigqueryPreviewRows = 100
+
+type bigqueryTable struct {
+	plugin.EntryBase
+	service bigqueryProjectService
+	ref     *bigquery.TableReference
+	// tableType is TABLE, VIEW, MATERIALIZED_VIEW or EXTERNAL
+	tableType string
+}
+
+func newBigqueryTable(table *bigquery.TableListTables, service bigqueryProjectService) *bigqueryTable {
+	t := &bigqueryTable{
+		EntryBase: plugin.NewEntry(table.TableReference.TableId),
+		service:   service,
+		ref:       table.TableReference,
+		tableType: table.Type,
+	}
+	t.SetPartialMetadata(table)
+	if table.CreationTime > 0 {
+		crtime := time.Unix(0, table.CreationTime*int64(time.Millisecond))
+		t.Attributes().SetCrtime(crtime)
+	}
+	return t
+}
+
+// Metadata returns the table's resource, which includes its schema.
+func (t *bigqueryTable) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	table, err := t.service.Tables.Get(t.ref.ProjectId, t.ref.DatasetId, t.ref.TableId).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return plugin.ToJSONObject(table), nil
+}
+
+// Read returns the table's first rows as lines of JSON. Tables' rows are listed, which
+// is free. Other types of tables, like views, are queried.
+func (t *bigqueryTable) Read(ctx context.Context) ([]byte, error) {
+	var buf bytes.Buffer
+	if t.tableType != "TABLE" {
+		query := fmt.Sprintf("SELECT * FROM `%v.%v.%v` LIMIT %v", t.ref.ProjectId, t.ref.DatasetId, t.ref.TableId, bigqueryPreviewRows)
+		err := runBigqueryQuery(ctx, t.service.Service, t.service.projectID, nil, query, bigqueryPreviewRows, &buf)
+		return buf.Bytes(), err
+	}
+
+	table, err := t.service.Tables.Get(t.ref.ProjectId, t.ref.DatasetId, t.ref.TableId).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.service.Tabledata.List(t.ref.ProjectId, t.ref.DatasetId, t.ref.TableId).
+		MaxResults(bigqueryPreviewRows).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBigqueryRows(&buf, table.Schema, data.Rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *bigqueryTable) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(t, "table").
+		SetPartialMetadataSchema(bigquery.TableListTables{}).
+		SetMetadataSchema(bigquery.Table{}).
+		SetDescription(bigqueryTableDescription)
+}
+
+const bigqueryTableDescription = `
+This is a BigQuery table or view. Its metadata includes its schema. Reading it
+returns a preview of its first 100 rows as lines of JSON. Previewing a view runs
+a query, which is billed.
+`
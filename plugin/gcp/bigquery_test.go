@@ -0,0 +1,274 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery "google.golang.org/api/bigquery/v2"
+)
+
+var testBigquerySchema = &bigquery.TableSchema{
+	Fields: []*bigquery.TableFieldSchema{
+		{Name: "name", Type: "STRING"},
+		{Name: "count", Type: "INTEGER"},
+	},
+}
+
+func bigqueryRow(name, count string) *bigquery.TableRow {
+	return &bigquery.TableRow{F: []*bigquery.TableCell{{V: name}, {V: count}}}
+}
+
+func TestWriteBigqueryRows(t *testing.T) {
+	schema := &bigquery.TableSchema{
+		Fields: []*bigquery.TableFieldSchema{
+			{Name: "int", Type: "INTEGER"},
+			{Name: "float", Type: "FLOAT"},
+			{Name: "nan", Type: "FLOAT"},
+			{Name: "bool", Type: "BOOLEAN"},
+			{Name: "string", Type: "STRING"},
+			{Name: "numeric", Type: "NUMERIC"},
+			{Name: "timestamp", Type: "TIMESTAMP"},
+			{Name: "null", Type: "STRING"},
+			{Name: "repeated", Type: "INTEGER", Mode: "REPEATED"},
+			{Name: "record", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+				{Name: "a", Type: "STRING"},
+				{Name: "b", Type: "RECORD", Mode: "REPEATED", Fields: []*bigquery.TableFieldSchema{
+					{Name: "c", Type: "BOOLEAN"},
+				}},
+			}},
+		},
+	}
+	// Decode the row from JSON so that its cells are like the API's.
+	var row bigquery.TableRow
+	require.NoError(t, json.Unmarshal([]byte(`{"f": [
+		{"v": "42"},
+		{"v": "1.5"},
+		{"v": "NaN"},
+		{"v": "true"},
+		{"v": "hello"},
+		{"v": "123456789012345678901234567.123456789"},
+		{"v": "1.5789636451234E9"},
+		{"v": null},
+		{"v": [{"v": "1"}, {"v": "2"}]},
+		{"v": {"f": [{"v": "x"}, {"v": [{"v": {"f": [{"v": "false"}]}}]}]}}
+	]}`), &row))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeBigqueryRows(&buf, schema, []*bigquery.TableRow{&row}))
+	assert.JSONEq(t, `{
+		"int": 42,
+		"float": 1.5,
+		"nan": "NaN",
+		"bool": true,
+		"string": "hello",
+		"numeric": "123456789012345678901234567.123456789",
+		"timestamp": "2020-01-14T01:00:45.1234Z",
+		"null": null,
+		"repeated": [1, 2],
+		"record": {"a": "x", "b": [{"c": false}]}
+	}`, buf.String())
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+}
+
+// fakeBigquery serves the BigQuery API for a project with a dataset that has a table and
+// a view. Queries are completed on their first poll, and return their rows in pages of
+// one row. A query for "bad" fails, and a query for "slow" never completes. If events
+// isn't nil, "poll" is sent to it when the slow query is polled and "cancel <job ID>
+// <location>" is sent to it when a job is cancelled.
+func fakeBigquery(t *testing.T, queries *[]bigquery.JobConfigurationQuery, events chan<- string) *httptest.Server {
+	rows := []*bigquery.TableRow{bigqueryRow("a", "1"), bigqueryRow("b", "2"), bigqueryRow("c", "3")}
+	write := func(w http.ResponseWriter, resp interface{}) {
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bigquery/v2/projects/project-id/datasets":
+			write(w, bigquery.DatasetList{Datasets: []*bigquery.DatasetListDatasets{
+				{DatasetReference: &bigquery.DatasetReference{ProjectId: "project-id", DatasetId: "dataset"}},
+			}})
+		case "/bigquery/v2/projects/project-id/datasets/dataset/tables":
+			write(w, bigquery.TableList{Tables: []*bigquery.TableListTables{
+				{TableReference: &bigquery.TableReference{ProjectId: "project-id", DatasetId: "dataset", TableId: "names"}, Type: "TABLE", CreationTime: 1578963645123},
+				{TableReference: &bigquery.TableReference{ProjectId: "project-id", DatasetId: "dataset", TableId: "view"}, Type: "VIEW"},
+			}})
+		case "/bigquery/v2/projects/project-id/datasets/dataset/tables/names":
+			write(w, bigquery.Table{Schema: testBigquerySchema})
+		case "/bigquery/v2/projects/project-id/datasets/dataset/tables/names/data":
+			assert.Equal(t, "100", r.URL.Query().Get("maxResults"))
+			write(w, bigquery.TableDataList{Rows: rows[:2]})
+		case "/bigquery/v2/projects/project-id/jobs":
+			var job bigquery.Job
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&job))
+			*queries = append(*queries, *job.Configuration.Query)
+			if job.Configuration.Query.Query == "bad" {
+				http.Error(w, `{"error": {"code": 400, "message": "Syntax error"}}`, http.StatusBadRequest)
+				return
+			}
+			job.JobReference.Location = "US"
+			if job.Configuration.Query.Query == "slow" {
+				job.JobReference.JobId = "slow"
+			}
+			write(w, bigquery.Job{JobReference: job.JobReference})
+		default:
+			if strings.HasPrefix(r.URL.Path, "/bigquery/v2/projects/project-id/jobs/") && strings.HasSuffix(r.URL.Path, "/cancel") {
+				jobID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/bigquery/v2/projects/project-id/jobs/"), "/cancel")
+				if events != nil {
+					events <- "cancel " + jobID + " " + r.URL.Query().Get("location")
+				}
+				write(w, bigquery.JobCancelResponse{})
+				return
+			}
+			if !strings.HasPrefix(r.URL.Path, "/bigquery/v2/projects/project-id/queries/") {
+				http.NotFound(w, r)
+				return
+			}
+			assert.Equal(t, "US", r.URL.Query().Get("location"))
+			if strings.HasSuffix(r.URL.Path, "/slow") {
+				// Like BigQuery, wait for the query until the request times out.
+				if events != nil {
+					events <- "poll"
+				}
+				<-r.Context().Done()
+				return
+			}
+			resp := bigquery.GetQueryResultsResponse{JobComplete: true, Schema: testBigquerySchema}
+			switch r.URL.Query().Get("pageToken") {
+			case "":
+				resp.Rows, resp.PageToken = rows[0:1], "1"
+			case "1":
+				resp.Rows, resp.PageToken = rows[1:2], "2"
+			case "2":
+				resp.Rows = rows[2:3]
+			}
+			write(w, resp)
+		}
+	}))
+}
+
+func newTestBigqueryDataset(ctx context.Context, t *testing.T, server *httptest.Server) *bigqueryDataset {
+	opts := clientOptions{endpoints: map[string]string{"bigquery": server.URL + "/bigquery/v2/"}}
+	dir, err := newBigqueryDir(ctx, opts, "project-id")
+	require.NoError(t, err)
+	datasets, err := dir.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 1)
+	return datasets[0].(*bigqueryDataset)
+}
+
+func TestBigqueryTablePreview(t *testing.T) {
+	var queries []bigquery.JobConfigurationQuery
+	server := fakeBigquery(t, &queries, nil)
+	defer server.Close()
+
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+	dataset := newTestBigqueryDataset(ctx, t, server)
+
+	tables, err := dataset.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, tables, 3)
+	table, view := tables[1].(*bigqueryTable), tables[2].(*bigqueryTable)
+	assert.Equal(t, "names", table.Name())
+
+	data, err := table.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"count\":1,\"name\":\"a\"}\n{\"count\":2,\"name\":\"b\"}\n", string(data))
+	assert.Empty(t, queries)
+
+	// Views are queried.
+	data, err = view.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"count\":1,\"name\":\"a\"}\n{\"count\":2,\"name\":\"b\"}\n{\"count\":3,\"name\":\"c\"}\n", string(data))
+	require.Len(t, queries, 1)
+	assert.Equal(t, "SELECT * FROM `project-id.dataset.view` LIMIT 100", queries[0].Query)
+}
+
+func execOutput(t *testing.T, cmd plugin.ExecCommand) (string, string, int) {
+	var stdout, stderr string
+	for chunk := range cmd.OutputCh() {
+		require.NoError(t, chunk.Err)
+		if chunk.StreamID == plugin.Stdout {
+			stdout += chunk.Data
+		} else {
+			stderr += chunk.Data
+		}
+	}
+	exitCode, err := cmd.ExitCode()
+	require.NoError(t, err)
+	return stdout, stderr, exitCode
+}
+
+func TestBigqueryQueryExec(t *testing.T) {
+	var queries []bigquery.JobConfigurationQuery
+	server := fakeBigquery(t, &queries, nil)
+	defer server.Close()
+
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+	entries, err := newTestBigqueryDataset(ctx, t, server).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	query := entries[0].(*bigqueryQuery)
+	assert.Equal(t, "query", query.Name())
+
+	cmd, err := query.Exec(ctx, "SELECT", []string{"*", "FROM", "names"}, plugin.ExecOptions{})
+	require.NoError(t, err)
+	stdout, stderr, exitCode := execOutput(t, cmd)
+	assert.Equal(t, "{\"count\":1,\"name\":\"a\"}\n{\"count\":2,\"name\":\"b\"}\n{\"count\":3,\"name\":\"c\"}\n", stdout)
+	assert.Empty(t, stderr)
+	assert.Equal(t, 0, exitCode)
+
+	require.Len(t, queries, 1)
+	assert.Equal(t, "SELECT * FROM names", queries[0].Query)
+	assert.Equal(t, "dataset", queries[0].DefaultDataset.DatasetId)
+	require.NotNil(t, queries[0].UseLegacySql)
+	assert.False(t, *queries[0].UseLegacySql)
+
+	cmd, err = query.Exec(ctx, "bad", nil, plugin.ExecOptions{})
+	require.NoError(t, err)
+	stdout, stderr, exitCode = execOutput(t, cmd)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "Syntax error")
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestBigqueryQueryExec_CancelsJob(t *testing.T) {
+	var queries []bigquery.JobConfigurationQuery
+	events := make(chan string, 1)
+	server := fakeBigquery(t, &queries, events)
+	defer server.Close()
+
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+	dataset := newTestBigqueryDataset(ctx, t, server)
+	query := newBigqueryQuery(dataset.service, dataset.ref)
+
+	waitFor := func(event string) {
+		select {
+		case actual := <-events:
+			assert.Equal(t, event, actual)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "timed out waiting for "+event)
+		}
+	}
+
+	execCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd, err := query.Exec(execCtx, "slow", nil, plugin.ExecOptions{})
+	require.NoError(t, err)
+	waitFor("poll")
+	cancel()
+	waitFor("cancel slow US")
+	for range cmd.OutputCh() {
+	}
+}
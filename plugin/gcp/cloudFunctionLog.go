@@ -30,7 +30,7 @@ func newCloudFunctionLog(ctx context.Context, service cloudFunctionsProjectServi
 	)
 	clf, err := newCloudLogFile(
 		ctx,
-		service.client,
+		service.opts,
 		fields,
 		service.projectID,
 		filter,
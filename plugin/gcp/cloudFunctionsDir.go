@@ -3,18 +3,16 @@ package gcp
 import (
 	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/puppetlabs/wash/plugin"
 	"google.golang.org/api/cloudfunctions/v1"
-	"google.golang.org/api/option"
 )
 
 type cloudFunctionsProjectService struct {
 	*cloudfunctions.Service
 	projectID string
 	// We need to pass this around to access cloud function logs
-	client *http.Client
+	opts clientOptions
 }
 
 type cloudFunctionsDir struct {
@@ -22,14 +20,14 @@ type cloudFunctionsDir struct {
 	service cloudFunctionsProjectService
 }
 
-func newCloudFunctionsDir(ctx context.Context, client *http.Client, projID string) (*cloudFunctionsDir, error) {
-	svc, err := cloudfunctions.NewService(context.Background(), option.WithHTTPClient(client))
+func newCloudFunctionsDir(ctx context.Context, opts clientOptions, projID string) (*cloudFunctionsDir, error) {
+	svc, err := cloudfunctions.NewService(context.Background(), opts.forService("cloudfunctions")...)
 	if err != nil {
 		return nil, err
 	}
 	cf := &cloudFunctionsDir{
 		EntryBase: plugin.NewEntry("cloud_functions"),
-		service:   cloudFunctionsProjectService{Service: svc, projectID: projID, client: client},
+		service:   cloudFunctionsProjectService{Service: svc, projectID: projID, opts: opts},
 	}
 	if _, err := plugin.List(ctx, cf); err != nil {
 		cf.MarkInaccessible(ctx, err)
@@ -4,14 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/puppetlabs/wash/activity"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
 	"google.golang.org/api/logging/v2"
-	"google.golang.org/api/option"
 )
 
 type cloudLogEntryField struct {
@@ -38,12 +36,12 @@ type cloudLogFile struct {
 
 func newCloudLogFile(
 	ctx context.Context,
-	client *http.Client,
+	opts clientOptions,
 	fields []cloudLogEntryField,
 	projectID string,
 	filter string,
 ) (*cloudLogFile, error) {
-	svc, err := logging.NewService(ctx, option.WithHTTPClient(client))
+	svc, err := logging.NewService(ctx, opts.forService("logging")...)
 	if err != nil {
 		return nil, err
 	}
@@ -3,10 +3,8 @@ package gcp
 import (
 	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/puppetlabs/wash/plugin"
-	"google.golang.org/api/option"
 	"google.golang.org/api/run/v1"
 )
 
@@ -14,7 +12,7 @@ type cloudRunProjectAPIService struct {
 	*run.APIService
 	projectID string
 	// We need to pass this around to access cloud function logs
-	client *http.Client
+	opts clientOptions
 }
 
 type cloudRunDir struct {
@@ -22,14 +20,14 @@ type cloudRunDir struct {
 	apiService cloudRunProjectAPIService
 }
 
-func newCloudRunDir(ctx context.Context, client *http.Client, projID string) (*cloudRunDir, error) {
-	svc, err := run.NewService(context.Background(), option.WithHTTPClient(client))
+func newCloudRunDir(ctx context.Context, opts clientOptions, projID string) (*cloudRunDir, error) {
+	svc, err := run.NewService(context.Background(), opts.forService("run")...)
 	if err != nil {
 		return nil, err
 	}
 	cr := &cloudRunDir{
 		EntryBase:  plugin.NewEntry("cloud_run"),
-		apiService: cloudRunProjectAPIService{APIService: svc, projectID: projID, client: client},
+		apiService: cloudRunProjectAPIService{APIService: svc, projectID: projID, opts: opts},
 	}
 	if _, err := plugin.List(ctx, cr); err != nil {
 		cr.MarkInaccessible(ctx, err)
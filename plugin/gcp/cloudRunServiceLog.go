@@ -28,7 +28,7 @@ func newCloudRunServiceLog(ctx context.Context, apiService cloudRunProjectAPISer
 	)
 	clf, err := newCloudLogFile(
 		ctx,
-		apiService.client,
+		apiService.opts,
 		fields,
 		apiService.projectID,
 		filter,
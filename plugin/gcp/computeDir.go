@@ -2,16 +2,16 @@ package gcp
 
 import (
 	"context"
-	"net/http"
 
 	"github.com/puppetlabs/wash/plugin"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 type computeProjectService struct {
 	*compute.Service
 	projectID string
+	// opts creates the OS Login clients and IAP tunnels used to exec on instances
+	opts clientOptions
 }
 
 type computeDir struct {
@@ -23,14 +23,14 @@ var _ = plugin.Parent(&computeDir{})
 
 const computeScope = compute.CloudPlatformScope
 
-func newComputeDir(ctx context.Context, client *http.Client, projID string) (*computeDir, error) {
-	svc, err := compute.NewService(context.Background(), option.WithHTTPClient(client))
+func newComputeDir(ctx context.Context, opts clientOptions, projID string) (*computeDir, error) {
+	svc, err := compute.NewService(context.Background(), opts.forService("compute")...)
 	if err != nil {
 		return nil, err
 	}
 	c := &computeDir{
 		EntryBase: plugin.NewEntry("compute"),
-		service:   computeProjectService{Service: svc, projectID: projID},
+		service:   computeProjectService{Service: svc, projectID: projID, opts: opts},
 	}
 	if _, err := plugin.List(ctx, c); err != nil {
 		c.MarkInaccessible(ctx, err)
@@ -9,6 +9,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -115,7 +116,7 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 		return nil, err
 	}
 
-	keyAdded, err := c.addPublicKey(ctx, user, key)
+	keyAdded, user, err := c.addKey(ctx, user, key)
 	if err != nil {
 		return nil, err
 	}
@@ -127,18 +128,21 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 	// Exec with associated private key.
 	activity.Record(ctx, "Found user %v for %v", user, c.Name())
 
-	hostname := getExternalIP(c.instance)
-	if hostname == "" {
-		return nil, fmt.Errorf("%v does not have an external IP address", c.Name())
-	}
-
 	identity := transport.Identity{
-		Host:         hostname,
 		User:         user,
 		IdentityFile: conf.privateKey,
 		KnownHosts:   conf.knownHosts,
 		HostKeyAlias: hostKeyAlias(c.instance),
 	}
+	if hostname := getExternalIP(c.instance); hostname != "" {
+		identity.Host = hostname
+	} else {
+		// Connect through an IAP tunnel. Internal IPs aren't unique across networks, so
+		// the host key alias identifies the connection instead.
+		activity.Record(ctx, "%v does not have an external IP address, connecting through IAP", c.Name())
+		identity.Host = hostKeyAlias(c.instance)
+		identity.Dial = c.dialIAP
+	}
 	if keyAdded {
 		// It may take some time for the new key to be added to the instance. Retry for up to 15s.
 		identity.Retries = 30
@@ -146,6 +150,46 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
 }
 
+// addKey ensures that the key can be used to log in to the instance. If OS Login is
+// enabled, the key is added to the current account's OS Login profile and the account's
+// POSIX username is returned as the user. Otherwise it's added to instance or project
+// metadata for the user. Returns true if the key was added, false if it was already present.
+func (c *computeInstance) addKey(ctx context.Context, user, key string) (bool, string, error) {
+	// Project metadata is only needed if the instance doesn't set enable-oslogin.
+	var projectMetadata *compute.Metadata
+	if findKey(c.instance.Metadata, osLoginKey) == nil {
+		proj, err := c.service.Projects.Get(c.service.projectID).Context(ctx).Do()
+		if err != nil {
+			activity.Record(ctx, "Unable to get metadata for project %v: %v", c.service.projectID, err)
+		} else {
+			projectMetadata = proj.CommonInstanceMetadata
+		}
+	}
+
+	if osLoginEnabled(c.instance.Metadata, projectMetadata) {
+		activity.Record(ctx, "OS Login is enabled for %v", c.Name())
+		user, keyAdded, err := addOSLoginKey(ctx, c.service.opts, c.service.projectID, key)
+		return keyAdded, user, err
+	}
+	keyAdded, err := c.addPublicKey(ctx, user, key)
+	return keyAdded, user, err
+}
+
+// dialIAP opens an IAP tunnel to the instance's SSH port on its first network interface.
+func (c *computeInstance) dialIAP(ctx context.Context) (net.Conn, error) {
+	networkInterface := "nic0"
+	if len(c.instance.NetworkInterfaces) > 0 && c.instance.NetworkInterfaces[0].Name != "" {
+		networkInterface = c.instance.NetworkInterfaces[0].Name
+	}
+	return dialIAPTunnel(ctx, c.service.opts.endpoint("iap", iapTunnelURL), c.service.opts.tokens, iapTarget{
+		project:          c.service.projectID,
+		zone:             getZone(c.instance),
+		instance:         c.Name(),
+		networkInterface: networkInterface,
+		port:             22,
+	})
+}
+
 // Based on https://github.com/google-cloud-sdk/google-cloud-sdk/blob/v255.0.0/lib/googlecloudsdk/command_lib/compute/ssh_utils.py#L106
 func getExternalIP(instance *compute.Instance) string {
 	for _, intf := range instance.NetworkInterfaces {
@@ -335,6 +379,9 @@ const (
 
 func findKey(metadata *compute.Metadata, key string) *string {
 	var value *string
+	if metadata == nil {
+		return value
+	}
 	for _, item := range metadata.Items {
 		if item.Key == key {
 			value = item.Value
@@ -371,4 +418,10 @@ This is a GCP Compute instance. Its Exec method mirrors running gcloud compute s
 If not already present, it will generate a Google Compute-specific SSH key pair and
 known hosts file in your ~/.ssh directory and ensure they’re present on the machine
 you’re trying to connect to. Your current $USER name will be used as the login user.
+
+If OS Login is enabled by the instance's or project's enable-oslogin metadata, the
+key is added to your account's OS Login profile instead, and your account's POSIX
+username is used as the login user. Instances without an external IP address are
+connected to through an IAP TCP forwarding tunnel, which needs a firewall rule that
+allows SSH from 35.235.240.0/20.
 `
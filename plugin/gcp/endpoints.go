@@ -0,0 +1,125 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// services are the services whose endpoints can be configured, keyed by the names
+// they're configured with. The values are true for gRPC services.
+var services = map[string]bool{
+	"cloudresourcemanager": false,
+	"compute":              false,
+	"storage":              false,
+	"monitoring":           true,
+	"firestore":            true,
+	"pubsub":               true,
+	"cloudfunctions":       false,
+	"run":                  false,
+	"logging":              false,
+	"oauth2":               false,
+	"oslogin":              false,
+	"secretmanager":        false,
+	"bigquery":             false,
+	"iap":                  false, // the websocket URL of IAP's TCP forwarding relay
+}
+
+// emulatedServices are the services that can be sent to emulators.
+var emulatedServices = map[string]bool{
+	"firestore": true,
+	"pubsub":    true,
+	"storage":   true,
+}
+
+// validateServices returns an error if a key isn't one of the valid services.
+func validateServices(keys map[string]string, valid map[string]bool) error {
+	for key := range keys {
+		if _, ok := valid[key]; !ok {
+			names := make([]string, 0, len(valid))
+			for name := range valid {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("%v: must be one of %v", key, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// clientOptions creates the options that the services' clients are created with.
+type clientOptions struct {
+	// httpClient authenticates requests to the HTTP services. It's nil if requests
+	// aren't authenticated.
+	httpClient *http.Client
+	// tokens authorize requests that aren't made with a client library, like IAP tunnels.
+	// It's nil if requests aren't authenticated.
+	tokens        oauth2.TokenSource
+	endpoints     map[string]string
+	emulatorHosts map[string]string
+}
+
+// forService returns the options for the service's client. Clients of emulated
+// services connect to the emulator without authentication or TLS.
+func (o clientOptions) forService(service string) []option.ClientOption {
+	if host, ok := o.emulatorHosts[service]; ok {
+		if service == "storage" {
+			// The storage client also needs STORAGE_EMULATOR_HOST to download objects
+			// from the emulator, which Init sets.
+			return []option.ClientOption{
+				option.WithEndpoint("http://" + host + "/storage/v1/"),
+				option.WithoutAuthentication(),
+			}
+		}
+		opts := []option.ClientOption{
+			option.WithEndpoint(host),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithInsecure()),
+		}
+		if service == "firestore" {
+			opts = append(opts, option.WithGRPCDialOption(grpc.WithPerRPCCredentials(firestoreEmulatorCreds{})))
+		}
+		return opts
+	}
+
+	var opts []option.ClientOption
+	if o.httpClient == nil {
+		opts = append(opts, option.WithoutAuthentication())
+		if services[service] {
+			opts = append(opts, option.WithGRPCDialOption(grpc.WithInsecure()))
+		}
+	} else if !services[service] {
+		opts = append(opts, option.WithHTTPClient(o.httpClient))
+	}
+	if endpoint, ok := o.endpoints[service]; ok {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	return opts
+}
+
+// endpoint returns the service's configured endpoint, or the default if it's not
+// configured.
+func (o clientOptions) endpoint(service, defaultEndpoint string) string {
+	if endpoint, ok := o.endpoints[service]; ok {
+		return endpoint
+	}
+	return defaultEndpoint
+}
+
+// firestoreEmulatorCreds authorizes requests to the Firestore emulator as an admin,
+// like the Firestore client does when FIRESTORE_EMULATOR_HOST is set.
+type firestoreEmulatorCreds struct{}
+
+func (firestoreEmulatorCreds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer owner"}, nil
+}
+
+func (firestoreEmulatorCreds) RequireTransportSecurity() bool {
+	return false
+}
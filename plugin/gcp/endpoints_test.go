@@ -0,0 +1,123 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// fakeGCP is a stand-in for the Resource Manager API and a storage emulator. It
+// serves a project with a bucket that has one object. Other requests get a 404.
+func fakeGCP(content string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/projects":
+			fmt.Fprint(w, `{"projects": [{"name": "project", "projectId": "project-id"}]}`)
+		case "/storage/v1/b":
+			fmt.Fprint(w, `{"items": [{"name": "bucket", "timeCreated": "2020-01-01T00:00:00Z"}]}`)
+		case "/storage/v1/b/bucket/o":
+			fmt.Fprintf(w, `{"items": [{"name": "hello.txt", "bucket": "bucket", "size": "%v", "updated": "2020-01-01T00:00:00Z"}]}`, len(content))
+		case "/bucket/hello.txt":
+			var start, end int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %v-%v/%v", start, end, len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, content[start:end+1])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestEndpointsSendServicesToEmulators(t *testing.T) {
+	server := fakeGCP("hello world")
+	defer server.Close()
+	pubsubServer := pstest.NewServer()
+	defer pubsubServer.Close()
+
+	// Create a topic in the Pub/Sub emulator.
+	ctx := context.Background()
+	conn, err := grpc.Dial(pubsubServer.Addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client, err := pubsub.NewClient(ctx, "project-id", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	_, err = client.CreateTopic(ctx, "topic")
+	require.NoError(t, err)
+
+	if host, ok := os.LookupEnv("STORAGE_EMULATOR_HOST"); ok {
+		defer os.Setenv("STORAGE_EMULATOR_HOST", host)
+	} else {
+		defer os.Unsetenv("STORAGE_EMULATOR_HOST")
+	}
+
+	ctx = plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	// The services without a stand-in are sent to one that doesn't implement them, so
+	// they're inaccessible instead of being sent to GCP.
+	root := &Root{}
+	require.NoError(t, root.Init(map[string]interface{}{
+		"endpoints": map[string]interface{}{
+			"cloudresourcemanager": server.URL + "/",
+			"compute":              server.URL + "/compute/v1/",
+			"cloudfunctions":       server.URL + "/",
+			"run":                  server.URL + "/",
+			"logging":              server.URL + "/",
+			"monitoring":           pubsubServer.Addr,
+		},
+		"emulator_hosts": map[string]interface{}{
+			"firestore": pubsubServer.Addr,
+			"pubsub":    pubsubServer.Addr,
+			"storage":   strings.TrimPrefix(server.URL, "http://"),
+		},
+		"without_authentication": true,
+	}))
+	root.SetTestID("/gcp")
+
+	find := func(parent plugin.Entry, path ...string) plugin.Entry {
+		entry := parent
+		for _, name := range path {
+			entries, err := plugin.List(ctx, entry.(plugin.Parent))
+			require.NoError(t, err)
+			child, ok := entries.Load(name)
+			require.True(t, ok, "%v was not listed", name)
+			entry = child
+		}
+		return entry
+	}
+
+	object := find(root, "project", "storage", "bucket", "hello.txt")
+	data, err := plugin.Read(ctx, object, 5, 6)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+
+	find(root, "project", "pubsub", "topic")
+}
+
+func TestInitRejectsUnknownServices(t *testing.T) {
+	root := &Root{}
+	err := root.Init(map[string]interface{}{
+		"emulator_hosts":         map[string]interface{}{"compute": "localhost:8080"},
+		"without_authentication": true,
+	})
+	assert.EqualError(t, err, "invalid config: gcp.emulator_hosts.compute: must be one of firestore, pubsub, storage")
+}
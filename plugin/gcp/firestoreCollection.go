@@ -2,8 +2,12 @@ package gcp
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 )
 
@@ -26,9 +30,11 @@ func (coll *firestoreCollection) List(ctx context.Context) ([]plugin.Entry, erro
 	if err != nil {
 		return nil, err
 	}
-	entries := make([]plugin.Entry, len(docs))
+	entries := make([]plugin.Entry, len(docs)+2)
+	entries[0] = newFirestoreCreateDocuments(coll)
+	entries[1] = newFirestoreNewDocument(coll)
 	for ix, doc := range docs {
-		entries[ix] = newFirestoreDocument(coll.client, coll.path, doc)
+		entries[ix+2] = newFirestoreDocument(coll.client, coll.path, doc)
 	}
 	return entries, nil
 }
@@ -55,6 +61,8 @@ func (coll *firestoreCollection) Schema() *plugin.EntrySchema {
 
 func (coll *firestoreCollection) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
+		(&firestoreCreateDocuments{}).Schema(),
+		(&firestoreNewDocument{}).Schema(),
 		(&firestoreDocument{}).Schema(),
 	}
 }
@@ -62,4 +70,140 @@ func (coll *firestoreCollection) ChildSchemas() []*plugin.EntrySchema {
 const firestoreCollectionDescription = `
 This is a Firestore collection. See the 'firestore' directory's docs for
 more details on why we have this kind of entry.
+
+You can create documents with the IDs you choose by writing their data, keyed
+by ID, to the collection's __create__ file, like
+
+  echo '{"alice": {"foo": 5}, "bob": {"foo": 6}}' > <collection>/__create__
+
+The write fails without creating any of them if one of the documents already
+exists. You can also create a document with an automatically generated ID by
+writing its data to the collection's __new__ file, like
+
+  echo '{"foo": 5}' > <collection>/__new__
+`
+
+// firestoreCreateDocumentsName is the name of the file that creates documents with
+// chosen IDs. Document IDs can't match __.*__, so no document has the same name.
+const firestoreCreateDocumentsName = "__create__"
+
+// firestoreCreateDocuments represents a collection's __create__ file. Writing to it
+// creates documents with the IDs that their data is keyed by.
+type firestoreCreateDocuments struct {
+	plugin.EntryBase
+	coll *firestoreCollection
+}
+
+func newFirestoreCreateDocuments(coll *firestoreCollection) *firestoreCreateDocuments {
+	create := &firestoreCreateDocuments{
+		EntryBase: plugin.NewEntry(firestoreCreateDocumentsName),
+	}
+	create.coll = coll
+	return create
+}
+
+// Write creates the documents in a single batch, so either all of them are created or
+// none are.
+func (create *firestoreCreateDocuments) Write(ctx context.Context, b []byte) error {
+	var docs map[string]json.RawMessage
+	if err := json.Unmarshal(b, &docs); err != nil {
+		return fmt.Errorf("new documents in %v must be a JSON object of their data keyed by ID: %w", create.coll.path, err)
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("no new documents for %v", create.coll.path)
+	}
+
+	batch := create.coll.client.Batch()
+	for id, raw := range docs {
+		if err := validateFirestoreDocumentID(id); err != nil {
+			return err
+		}
+		data, err := unmarshalFirestoreData(create.coll.client, raw)
+		if err != nil {
+			return fmt.Errorf("invalid data for new document %v: %w", firestorePath(create.coll.path, id), err)
+		}
+		batch.Create(create.coll.client.Doc(firestorePath(create.coll.path, id)), data)
+	}
+	if _, err := batch.Commit(ctx); err != nil {
+		return err
+	}
+	for id := range docs {
+		activity.Record(ctx, "Created document %v", firestorePath(create.coll.path, id))
+	}
+	return nil
+}
+
+func (create *firestoreCreateDocuments) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(create, firestoreCreateDocumentsName).
+		IsSingleton().
+		SetDescription(firestoreCreateDocumentsDescription)
+}
+
+const firestoreCreateDocumentsDescription = `
+This is a Firestore collection's __create__ file. Writing a JSON object to it
+creates a document in the collection for each of the object's keys. The key is
+the document's ID and its value is the document's data, like
+
+  {"alice": {"foo": 5}, "bob": {"foo": 6}}
+
+Either all of the documents are created or none are, and the write fails if one
+of them already exists. See data.json's docs for how Firestore types are written.
+`
+
+// validateFirestoreDocumentID returns an error if Firestore doesn't allow the ID.
+func validateFirestoreDocumentID(id string) error {
+	switch {
+	case id == "", id == ".", id == "..":
+		return fmt.Errorf("%q is not a valid document ID", id)
+	case strings.Contains(id, "/"):
+		return fmt.Errorf("document ID %v can't contain a /", id)
+	case len(id) >= 4 && strings.HasPrefix(id, "__") && strings.HasSuffix(id, "__"):
+		return fmt.Errorf("document ID %v can't match __.*__", id)
+	}
+	return nil
+}
+
+// firestoreNewDocumentName is the name of the file that creates documents. Document IDs
+// can't match __.*__, so no document has the same name.
+const firestoreNewDocumentName = "__new__"
+
+// firestoreNewDocument represents a collection's __new__ file. Writing to it creates a
+// document in the collection.
+type firestoreNewDocument struct {
+	plugin.EntryBase
+	coll *firestoreCollection
+}
+
+func newFirestoreNewDocument(coll *firestoreCollection) *firestoreNewDocument {
+	newDoc := &firestoreNewDocument{
+		EntryBase: plugin.NewEntry(firestoreNewDocumentName),
+	}
+	newDoc.coll = coll
+	return newDoc
+}
+
+// Write creates a document with the data and an automatically generated ID.
+func (newDoc *firestoreNewDocument) Write(ctx context.Context, b []byte) error {
+	data, err := unmarshalFirestoreData(newDoc.coll.client, b)
+	if err != nil {
+		return fmt.Errorf("invalid data for a new document in %v: %w", newDoc.coll.path, err)
+	}
+	ref, _, err := newDoc.coll.client.Collection(newDoc.coll.path).Add(ctx, data)
+	if err != nil {
+		return err
+	}
+	activity.Record(ctx, "Created document %v", firestorePath(newDoc.coll.path, ref.ID))
+	return nil
+}
+
+func (newDoc *firestoreNewDocument) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(newDoc, firestoreNewDocumentName).
+		IsSingleton().
+		SetDescription(firestoreNewDocumentDescription)
+}
+
+const firestoreNewDocumentDescription = `
+This is a Firestore collection's __new__ file. Writing JSON to it creates a
+document with that data in the collection. The document gets an automatically
+generated ID. See data.json's docs for how Firestore types are written.
 `
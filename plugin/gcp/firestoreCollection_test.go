@@ -0,0 +1,273 @@
+package gcp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeFirestore is a stand-in for Firestore that keeps documents in memory, keyed by
+// their resource name. It implements the RPCs that the plugin uses. Each commit
+// advances its clock by a second, so each write gets a new update time.
+type fakeFirestore struct {
+	pb.UnimplementedFirestoreServer
+	mux  sync.Mutex
+	docs map[string]*pb.Document
+	now  time.Time
+}
+
+// newFakeFirestore starts a fake Firestore. It returns the fake, a function that returns
+// a client of a project that's connected to it, and a function that stops it.
+func newFakeFirestore(t *testing.T) (*fakeFirestore, func(project string) *firestore.Client, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fake := &fakeFirestore{
+		docs: make(map[string]*pb.Document),
+		now:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	server := grpc.NewServer()
+	pb.RegisterFirestoreServer(server, fake)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+
+	opts := clientOptions{emulatorHosts: map[string]string{"firestore": lis.Addr().String()}}
+	var clients []*firestore.Client
+	newClient := func(project string) *firestore.Client {
+		client, err := firestore.NewClient(context.Background(), project, opts.forService("firestore")...)
+		require.NoError(t, err)
+		clients = append(clients, client)
+		return client
+	}
+	return fake, newClient, func() {
+		for _, client := range clients {
+			client.Close()
+		}
+		server.Stop()
+	}
+}
+
+func (f *fakeFirestore) timestamp() *timestamp.Timestamp {
+	ts, _ := ptypes.TimestampProto(f.now)
+	return ts
+}
+
+// children returns the documents that are directly in the collection.
+func (f *fakeFirestore) children(collection string) []*pb.Document {
+	var docs []*pb.Document
+	for name, doc := range f.docs {
+		if strings.HasPrefix(name, collection+"/") && !strings.Contains(strings.TrimPrefix(name, collection+"/"), "/") {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+func (f *fakeFirestore) ListCollectionIds(ctx context.Context, req *pb.ListCollectionIdsRequest) (*pb.ListCollectionIdsResponse, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	ids := make(map[string]bool)
+	for name := range f.docs {
+		if rel := strings.TrimPrefix(name, req.Parent+"/"); rel != name {
+			if segments := strings.Split(rel, "/"); len(segments) == 2 {
+				ids[segments[0]] = true
+			}
+		}
+	}
+	resp := &pb.ListCollectionIdsResponse{}
+	for id := range ids {
+		resp.CollectionIds = append(resp.CollectionIds, id)
+	}
+	return resp, nil
+}
+
+func (f *fakeFirestore) RunQuery(req *pb.RunQueryRequest, stream pb.Firestore_RunQueryServer) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	readTime := f.timestamp()
+	for _, doc := range f.children(req.Parent + "/" + req.GetStructuredQuery().From[0].CollectionId) {
+		if err := stream.Send(&pb.RunQueryResponse{Document: doc, ReadTime: readTime}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeFirestore) BatchGetDocuments(req *pb.BatchGetDocumentsRequest, stream pb.Firestore_BatchGetDocumentsServer) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	readTime := f.timestamp()
+	for _, name := range req.Documents {
+		resp := &pb.BatchGetDocumentsResponse{ReadTime: readTime}
+		if doc, ok := f.docs[name]; ok {
+			resp.Result = &pb.BatchGetDocumentsResponse_Found{Found: doc}
+		} else {
+			resp.Result = &pb.BatchGetDocumentsResponse_Missing{Missing: name}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeFirestore) Commit(ctx context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.now = f.now.Add(time.Second)
+	commitTime := f.timestamp()
+	resp := &pb.CommitResponse{CommitTime: commitTime}
+	writeName := func(write *pb.Write) string {
+		if update := write.GetUpdate(); update != nil {
+			return update.Name
+		}
+		return write.GetDelete()
+	}
+	// Commits are atomic, so check all of the preconditions before writing anything.
+	for _, write := range req.Writes {
+		name := writeName(write)
+		existing := f.docs[name]
+		switch pre := write.GetCurrentDocument().GetConditionType().(type) {
+		case *pb.Precondition_UpdateTime:
+			if existing == nil || !proto.Equal(pre.UpdateTime, existing.UpdateTime) {
+				return nil, status.Errorf(codes.FailedPrecondition, "%v was updated", name)
+			}
+		case *pb.Precondition_Exists:
+			if pre.Exists && existing == nil {
+				return nil, status.Errorf(codes.NotFound, "%v not found", name)
+			}
+			if !pre.Exists && existing != nil {
+				return nil, status.Errorf(codes.AlreadyExists, "%v already exists", name)
+			}
+		}
+	}
+	for _, write := range req.Writes {
+		name := writeName(write)
+		existing := f.docs[name]
+		if write.GetDelete() != "" {
+			delete(f.docs, name)
+		} else {
+			update := write.GetUpdate()
+			fields := update.Fields
+			if write.UpdateMask != nil {
+				fields = make(map[string]*pb.Value)
+				if existing != nil {
+					for field, value := range existing.Fields {
+						fields[field] = value
+					}
+				}
+				for _, field := range write.UpdateMask.FieldPaths {
+					if value, ok := update.Fields[field]; ok {
+						fields[field] = value
+					} else {
+						delete(fields, field)
+					}
+				}
+			}
+			doc := &pb.Document{Name: name, Fields: fields, CreateTime: commitTime, UpdateTime: commitTime}
+			if existing != nil {
+				doc.CreateTime = existing.CreateTime
+			}
+			f.docs[name] = doc
+		}
+		resp.WriteResults = append(resp.WriteResults, &pb.WriteResult{UpdateTime: commitTime})
+	}
+	return resp, nil
+}
+
+func TestFirestoreCollection_NewDocument(t *testing.T) {
+	_, newClient, stop := newFakeFirestore(t)
+	defer stop()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	client := newClient("project")
+	coll := newFirestoreCollection(client, "", client.Collection("users"))
+	entries, err := coll.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"__create__", "__new__"}, entryNames(entries))
+
+	newDoc := entries[1].(*firestoreNewDocument)
+	err = newDoc.Write(ctx, []byte(`{"name": {"$bytes": "invalid"}}`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid data for a new document in users")
+	}
+	require.NoError(t, newDoc.Write(ctx, []byte(`{"name": "alice"}`)))
+
+	entries, err = coll.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	doc := entries[2].(*firestoreDocument)
+	assert.Len(t, plugin.Name(doc), 20)
+	content, err := listDataJSON(ctx, t, doc).Read(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "alice"}`, string(content))
+}
+
+func TestFirestoreCollection_CreateDocuments(t *testing.T) {
+	_, newClient, stop := newFakeFirestore(t)
+	defer stop()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	client := newClient("project")
+	coll := newFirestoreCollection(client, "", client.Collection("users"))
+	entries, err := coll.List(ctx)
+	require.NoError(t, err)
+	create := entries[0].(*firestoreCreateDocuments)
+
+	for data, expectedErr := range map[string]string{
+		`[{"name": "alice"}]`:                  "new documents in users must be a JSON object of their data keyed by ID",
+		`{}`:                                   "no new documents for users",
+		`{"a/b": {"name": "alice"}}`:           "document ID a/b can't contain a /",
+		`{"__alice__": {"name": "alice"}}`:     "document ID __alice__ can't match __.*__",
+		`{"alice": {"name": {"$bytes": "x"}}}`: "invalid data for new document users/alice",
+	} {
+		err := create.Write(ctx, []byte(data))
+		if assert.Error(t, err, data) {
+			assert.Contains(t, err.Error(), expectedErr)
+		}
+	}
+
+	require.NoError(t, create.Write(ctx, []byte(`{"alice": {"name": "alice"}, "bob": {"name": "bob"}}`)))
+	entries, err = coll.List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"__create__", "__new__", "alice", "bob"}, entryNames(entries))
+	for _, entry := range entries[2:] {
+		content, err := listDataJSON(ctx, t, entry.(*firestoreDocument)).Read(ctx)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "`+plugin.Name(entry)+`"}`, string(content))
+	}
+
+	// Creating an existing document fails without creating the others
+	err = create.Write(ctx, []byte(`{"carol": {"name": "carol"}, "alice": {"name": "alice2"}}`))
+	if assert.Error(t, err) {
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	}
+	_, err = client.Doc("users/carol").Get(ctx)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func entryNames(entries []plugin.Entry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = plugin.Name(entry)
+	}
+	return names
+}
@@ -12,8 +12,8 @@ type firestoreDir struct {
 	client *firestore.Client
 }
 
-func newFirestoreDir(ctx context.Context, projID string) (*firestoreDir, error) {
-	cli, err := firestore.NewClient(context.Background(), projID)
+func newFirestoreDir(ctx context.Context, opts clientOptions, projID string) (*firestoreDir, error) {
+	cli, err := firestore.NewClient(context.Background(), projID, opts.forService("firestore")...)
 	if err != nil {
 		return nil, err
 	}
@@ -2,18 +2,20 @@ package gcp
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type firestoreDocument struct {
 	plugin.EntryBase
 	client *firestore.Client
 	path   string
-	data   map[string]interface{}
 }
 
 type firestoreDocumentMetadata struct {
@@ -28,7 +30,6 @@ func newFirestoreDocument(client *firestore.Client, parent string, snapshot *fir
 		EntryBase: plugin.NewEntry(snapshot.Ref.ID),
 		client:    client,
 		path:      firestorePath(parent, snapshot.Ref.ID),
-		data:      snapshot.Data(),
 	}
 
 	metadata := firestoreDocumentMetadata{
@@ -46,12 +47,18 @@ func newFirestoreDocument(client *firestore.Client, parent string, snapshot *fir
 	return doc
 }
 
+// List lists the document's data.json and subcollections. The data's read again because
+// the entry's data is stale once data.json is written.
 func (doc *firestoreDocument) List(ctx context.Context) ([]plugin.Entry, error) {
-	colls, err := doc.client.Doc(doc.path).Collections(ctx).GetAll()
+	snapshot, err := doc.client.Doc(doc.path).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("document %v was deleted", doc.path)
+	}
 	if err != nil {
 		return nil, err
 	}
-	dataJSON, err := newFirestoreDocumentDataJSON(doc.data)
+	dataJSON := newFirestoreDocumentDataJSON(doc, snapshot)
+	colls, err := doc.client.Doc(doc.path).Collections(ctx).GetAll()
 	if err != nil {
 		return nil, err
 	}
@@ -84,25 +91,56 @@ more details on why we have this kind of entry.
 
 type firestoreDocumentDataJSON struct {
 	plugin.EntryBase
-	bytes []byte
+	doc *firestoreDocument
+	// snapshot is the document's data when data.json was listed
+	snapshot *firestore.DocumentSnapshot
 }
 
-func newFirestoreDocumentDataJSON(data map[string]interface{}) (*firestoreDocumentDataJSON, error) {
-	dataBytes, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		// This should never happen
-		return nil, err
-	}
+func newFirestoreDocumentDataJSON(doc *firestoreDocument, snapshot *firestore.DocumentSnapshot) *firestoreDocumentDataJSON {
 	dataEntry := &firestoreDocumentDataJSON{
 		EntryBase: plugin.NewEntry("data.json"),
-		bytes:     dataBytes,
+		doc:       doc,
+		snapshot:  snapshot,
 	}
 	dataEntry.DisableDefaultCaching()
-	return dataEntry, nil
+	return dataEntry
 }
 
 func (data *firestoreDocumentDataJSON) Read(ctx context.Context) ([]byte, error) {
-	return data.bytes, nil
+	return marshalFirestoreData(data.snapshot.Data())
+}
+
+// Write replaces the document's data. It fails if the document was updated since it
+// was listed so that concurrent edits aren't lost.
+func (data *firestoreDocumentDataJSON) Write(ctx context.Context, b []byte) error {
+	newData, err := unmarshalFirestoreData(data.doc.client, b)
+	if err != nil {
+		return fmt.Errorf("invalid data for document %v: %w", data.doc.path, err)
+	}
+	ref := data.doc.client.Doc(data.doc.path)
+
+	// Update replaces each top-level field, and deletes the fields that were removed.
+	var updates []firestore.Update
+	for field, value := range newData {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{field}, Value: value})
+	}
+	for field := range data.snapshot.Data() {
+		if _, ok := newData[field]; !ok {
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{field}, Value: firestore.Delete})
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	_, err = ref.Update(ctx, updates, firestore.LastUpdateTime(data.snapshot.UpdateTime))
+	if status.Code(err) == codes.FailedPrecondition {
+		return fmt.Errorf("document %v was updated after it was read; read it again and retry your changes", data.doc.path)
+	}
+	if err != nil {
+		return err
+	}
+	activity.Record(ctx, "Updated %v fields of document %v", len(updates), data.doc.path)
+	return nil
 }
 
 func (data *firestoreDocumentDataJSON) Schema() *plugin.EntrySchema {
@@ -115,4 +153,19 @@ const firestoreDocumentDataJSONDescription = `
 This is a Firestore document's data as pretty-printed JSON. See the
 'firestore' directory's docs for more details on why we have this
 kind of entry.
+
+Writing to it replaces the document's data. The write fails if the document
+was updated since data.json was read, so re-read it and retry your changes.
+
+Firestore types that JSON doesn't have are objects with a single key that
+names the type, like
+
+  {"$timestamp": "2020-01-02T15:04:05.123456Z"}
+  {"$reference": "collection/document"}
+  {"$geopoint": {"latitude": 45.5, "longitude": -122.6}}
+  {"$bytes": "aGVsbG8="}
+  {"$double": "NaN"}
+
+Numbers with a decimal point or an exponent are doubles, and other numbers are
+integers. A map whose only key is one of these is wrapped in {"$map": {...}}.
 `
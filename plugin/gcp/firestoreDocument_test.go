@@ -0,0 +1,53 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listDataJSON returns the document's data.json. FUSE lists the document again after
+// data.json is written, so each call returns a new entry.
+func listDataJSON(ctx context.Context, t *testing.T, doc *firestoreDocument) *firestoreDocumentDataJSON {
+	entries, err := doc.List(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	return entries[0].(*firestoreDocumentDataJSON)
+}
+
+func TestFirestoreDocumentDataJSON_WriteReadWrite(t *testing.T) {
+	_, newClient, stop := newFakeFirestore(t)
+	defer stop()
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	client := newClient("project")
+	_, err := client.Doc("users/alice").Create(ctx, map[string]interface{}{"name": "alice", "age": 1})
+	require.NoError(t, err)
+	docs, err := newFirestoreCollection(client, "", client.Collection("users")).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+	doc := docs[2].(*firestoreDocument)
+
+	stale := listDataJSON(ctx, t, doc)
+	require.NoError(t, stale.Write(ctx, []byte(`{"name": "alice", "age": 2}`)))
+
+	data := listDataJSON(ctx, t, doc)
+	content, err := data.Read(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "alice", "age": 2}`, string(content))
+	// The second write's precondition and removed fields come from the data that was
+	// read after the first write.
+	require.NoError(t, data.Write(ctx, []byte(`{"name": "alice"}`)))
+	snapshot, err := client.Doc("users/alice").Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "alice"}, snapshot.Data())
+
+	// Writing data that was read before the document was updated fails.
+	err = stale.Write(ctx, []byte(`{"name": "bob"}`))
+	assert.EqualError(t, err, "document users/alice was updated after it was read; read it again and retry your changes")
+}
@@ -0,0 +1,238 @@
+package gcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Firestore has types that JSON doesn't, so data.json represents them as objects
+// with a single key that names the type:
+//
+//   {"$timestamp": "2020-01-02T15:04:05.123456Z"}
+//   {"$reference": "collection/document"}
+//   {"$geopoint": {"latitude": 45.5, "longitude": -122.6}}
+//   {"$bytes": "<base64>"}
+//   {"$double": "NaN"}, {"$double": "Infinity"} or {"$double": "-Infinity"}
+//
+// Doubles are always written with a decimal point or an exponent so that they're
+// distinguished from integers. A map whose only key is one of the above, or $map, is
+// wrapped in {"$map": {...}}.
+const (
+	firestoreTimestampKey = "$timestamp"
+	firestoreReferenceKey = "$reference"
+	firestoreGeopointKey  = "$geopoint"
+	firestoreBytesKey     = "$bytes"
+	firestoreDoubleKey    = "$double"
+	firestoreMapKey       = "$map"
+)
+
+var firestoreTypeKeys = map[string]bool{
+	firestoreTimestampKey: true,
+	firestoreReferenceKey: true,
+	firestoreGeopointKey:  true,
+	firestoreBytesKey:     true,
+	firestoreDoubleKey:    true,
+	firestoreMapKey:       true,
+}
+
+// marshalFirestoreData returns the document data as pretty-printed JSON.
+func marshalFirestoreData(data map[string]interface{}) ([]byte, error) {
+	return json.MarshalIndent(firestoreMapToJSON(data), "", "  ")
+}
+
+// unmarshalFirestoreData parses JSON that marshalFirestoreData returns into document
+// data. References are resolved with the client.
+func unmarshalFirestoreData(client *firestore.Client, b []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the document's JSON object")
+	}
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the document's data must be a JSON object")
+	}
+	return firestoreMapFromJSON(client, obj)
+}
+
+func firestoreMapToJSON(m map[string]interface{}) map[string]interface{} {
+	obj := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		obj[k] = firestoreValueToJSON(v)
+	}
+	return obj
+}
+
+func firestoreValueToJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return map[string]interface{}{firestoreDoubleKey: "NaN"}
+		case math.IsInf(v, 1):
+			return map[string]interface{}{firestoreDoubleKey: "Infinity"}
+		case math.IsInf(v, -1):
+			return map[string]interface{}{firestoreDoubleKey: "-Infinity"}
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return json.Number(s)
+	case []byte:
+		return map[string]interface{}{firestoreBytesKey: base64.StdEncoding.EncodeToString(v)}
+	case time.Time:
+		return map[string]interface{}{firestoreTimestampKey: v.UTC().Format(time.RFC3339Nano)}
+	case *latlng.LatLng:
+		return map[string]interface{}{firestoreGeopointKey: map[string]interface{}{
+			"latitude":  v.Latitude,
+			"longitude": v.Longitude,
+		}}
+	case *firestore.DocumentRef:
+		return map[string]interface{}{firestoreReferenceKey: firestoreRelativePath(v)}
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, elem := range v {
+			arr[i] = firestoreValueToJSON(elem)
+		}
+		return arr
+	case map[string]interface{}:
+		obj := firestoreMapToJSON(v)
+		if len(v) == 1 {
+			for k := range v {
+				if firestoreTypeKeys[k] {
+					return map[string]interface{}{firestoreMapKey: obj}
+				}
+			}
+		}
+		return obj
+	default:
+		// nil, bool, int64 and string are the same in JSON.
+		return v
+	}
+}
+
+// firestoreRelativePath returns the document's path relative to its database, like
+// collection/document.
+func firestoreRelativePath(ref *firestore.DocumentRef) string {
+	segments := strings.SplitN(ref.Path, "/documents/", 2)
+	return segments[len(segments)-1]
+}
+
+func firestoreMapFromJSON(client *firestore.Client, obj map[string]interface{}) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		value, err := firestoreValueFromJSON(client, v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", k, err)
+		}
+		m[k] = value
+	}
+	return m, nil
+}
+
+func firestoreValueFromJSON(client *firestore.Client, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		s := v.String()
+		if strings.ContainsAny(s, ".eE") {
+			return v.Float64()
+		}
+		return v.Int64()
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, elem := range v {
+			value, err := firestoreValueFromJSON(client, elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%v]: %v", i, err)
+			}
+			arr[i] = value
+		}
+		return arr, nil
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return firestoreMapFromJSON(client, v)
+		}
+		for key, typed := range v {
+			if !firestoreTypeKeys[key] {
+				return firestoreMapFromJSON(client, v)
+			}
+			value, err := firestoreTypedValueFromJSON(client, key, typed)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", key, err)
+			}
+			return value, nil
+		}
+	}
+	// nil, bool and string are the same in Firestore.
+	return value, nil
+}
+
+func firestoreTypedValueFromJSON(client *firestore.Client, key string, value interface{}) (interface{}, error) {
+	if key == firestoreMapKey {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("must be an object")
+		}
+		return firestoreMapFromJSON(client, obj)
+	}
+	if key == firestoreGeopointKey {
+		obj, ok := value.(map[string]interface{})
+		if !ok || len(obj) != 2 {
+			return nil, fmt.Errorf("must be an object with a latitude and a longitude")
+		}
+		var point latlng.LatLng
+		for field, dest := range map[string]*float64{"latitude": &point.Latitude, "longitude": &point.Longitude} {
+			n, ok := obj[field].(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("%v must be a number", field)
+			}
+			f, err := n.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", field, err)
+			}
+			*dest = f
+		}
+		return &point, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+	switch key {
+	case firestoreTimestampKey:
+		return time.Parse(time.RFC3339Nano, s)
+	case firestoreReferenceKey:
+		if strings.Count(s, "/")%2 != 1 {
+			return nil, fmt.Errorf("%v is not a document's path", s)
+		}
+		return client.Doc(s), nil
+	case firestoreBytesKey:
+		return base64.StdEncoding.DecodeString(s)
+	case firestoreDoubleKey:
+		switch s {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return nil, fmt.Errorf("must be NaN, Infinity or -Infinity")
+	}
+	return nil, fmt.Errorf("unknown type")
+}
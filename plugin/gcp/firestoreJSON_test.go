@@ -0,0 +1,92 @@
+package gcp
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc"
+)
+
+func newTestFirestoreClient(t *testing.T) *firestore.Client {
+	// Connections are lazy, and the tests don't make any requests.
+	conn, err := grpc.Dial("localhost:1", grpc.WithInsecure())
+	require.NoError(t, err)
+	client, err := firestore.NewClient(context.Background(), "project-id", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	return client
+}
+
+func TestFirestoreDataRoundTrips(t *testing.T) {
+	client := newTestFirestoreClient(t)
+	defer client.Close()
+
+	data := map[string]interface{}{
+		"null":      nil,
+		"bool":      true,
+		"int":       int64(3),
+		"double":    float64(3),
+		"fraction":  1.5,
+		"string":    "hello",
+		"timestamp": time.Date(2020, 1, 2, 15, 4, 5, 123456000, time.UTC),
+		"reference": client.Doc("collection/document"),
+		"geopoint":  &latlng.LatLng{Latitude: 45.5, Longitude: -122.6},
+		"bytes":     []byte{0, 1, 2},
+		"infinity":  math.Inf(1),
+		"array":     []interface{}{int64(1), "two", map[string]interface{}{"three": 3.5}},
+		"map":       map[string]interface{}{"nested": map[string]interface{}{"a": "b"}},
+		"escaped":   map[string]interface{}{"$bytes": "not bytes"},
+	}
+
+	b, err := marshalFirestoreData(data)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"double": 3.0`)
+	assert.Contains(t, string(b), `"int": 3,`)
+	assert.Contains(t, string(b), `"$timestamp": "2020-01-02T15:04:05.123456Z"`)
+	assert.Contains(t, string(b), `"$reference": "collection/document"`)
+	assert.Contains(t, string(b), `"$map"`)
+
+	parsed, err := unmarshalFirestoreData(client, b)
+	require.NoError(t, err)
+	assert.Equal(t, client.Doc("collection/document").Path, parsed["reference"].(*firestore.DocumentRef).Path)
+	delete(data, "reference")
+	delete(parsed, "reference")
+	assert.Equal(t, data, parsed)
+}
+
+func TestFirestoreDataNaN(t *testing.T) {
+	client := newTestFirestoreClient(t)
+	defer client.Close()
+
+	b, err := marshalFirestoreData(map[string]interface{}{"nan": math.NaN()})
+	require.NoError(t, err)
+	parsed, err := unmarshalFirestoreData(client, b)
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(parsed["nan"].(float64)))
+}
+
+func TestUnmarshalFirestoreDataErrors(t *testing.T) {
+	client := newTestFirestoreClient(t)
+	defer client.Close()
+
+	cases := map[string]string{
+		`[1, 2]`:                                "the document's data must be a JSON object",
+		`{} {}`:                                 "unexpected data after the document's JSON object",
+		`{"a": {"$timestamp": 1}}`:              "a: $timestamp: must be a string",
+		`{"a": {"$reference": "collection"}}`:   "a: $reference: collection is not a document's path",
+		`{"a": {"$double": "1.5"}}`:             "a: $double: must be NaN, Infinity or -Infinity",
+		`{"a": {"$geopoint": {"latitude": 1}}}`: "a: $geopoint: must be an object with a latitude and a longitude",
+		`{"a": [{"$map": "b"}]}`:                "a: [0]: $map: must be an object",
+		`{"a": {"$geopoint": {"latitude": "1", "longitude": 2}}}`: "a: $geopoint: latitude must be a number",
+	}
+	for input, expected := range cases {
+		_, err := unmarshalFirestoreData(client, []byte(input))
+		assert.EqualError(t, err, expected, input)
+	}
+}
@@ -0,0 +1,221 @@
+package gcp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/oauth2"
+)
+
+// This file implements the client side of IAP's TCP forwarding relay, which is what
+// gcloud compute start-iap-tunnel uses. It's modeled on gcloud's
+// lib/googlecloudsdk/api_lib/compute/iap_tunnel_websocket_utils.py. Every websocket
+// message starts with a big-endian 2-byte tag.
+
+const iapTunnelURL = "wss://tunnel.cloudproxy.app/v4/connect"
+
+const (
+	iapSubprotocol = "relay.tunnel.cloudproxy.app"
+	iapOrigin      = "bot:iap-tunneler"
+)
+
+// Message tags
+const (
+	iapConnectSuccessSID   = 0x0001
+	iapReconnectSuccessAck = 0x0002
+	iapData                = 0x0004
+	iapAck                 = 0x0007
+)
+
+// iapMaxDataLength is the most data that a data message can hold.
+const iapMaxDataLength = 16384
+
+// iapTarget is the instance port that a tunnel connects to.
+type iapTarget struct {
+	project, zone, instance, networkInterface string
+	port                                      int
+}
+
+// iapTunnel is a TCP connection to an instance through IAP. It implements net.Conn.
+type iapTunnel struct {
+	conn *websocket.Conn
+	// writeMux guards writes to conn
+	writeMux sync.Mutex
+	// unread is data that we received but haven't returned from Read yet
+	unread   []byte
+	received uint64
+}
+
+var _ = net.Conn(&iapTunnel{})
+
+// dialIAPTunnel opens a tunnel to the target. Requests are authorized with tokens from
+// the token source if it's not nil.
+func dialIAPTunnel(ctx context.Context, endpoint string, tokens oauth2.TokenSource, target iapTarget) (*iapTunnel, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IAP tunnel endpoint %v: %v", endpoint, err)
+	}
+	query := u.Query()
+	query.Set("project", target.project)
+	query.Set("zone", target.zone)
+	query.Set("instance", target.instance)
+	query.Set("interface", target.networkInterface)
+	query.Set("port", strconv.Itoa(target.port))
+	query.Set("newWebsocket", "true")
+	u.RawQuery = query.Encode()
+
+	header := http.Header{}
+	header.Set("Origin", iapOrigin)
+	if tokens != nil {
+		token, err := tokens.Token()
+		if err != nil {
+			return nil, fmt.Errorf("could not get a token for the IAP tunnel: %v", err)
+		}
+		header.Set("Authorization", token.Type()+" "+token.AccessToken)
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{iapSubprotocol}
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("could not open an IAP tunnel to %v: %v", target.instance, resp.Status)
+		}
+		return nil, fmt.Errorf("could not open an IAP tunnel to %v: %v", target.instance, err)
+	}
+
+	// The relay sends the session's ID once it's connected to the instance. We don't
+	// reconnect, so we don't need the ID.
+	tunnel := &iapTunnel{conn: conn}
+	tag, _, err := tunnel.readMessage()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not open an IAP tunnel to %v: %v", target.instance, err)
+	}
+	if tag != iapConnectSuccessSID {
+		conn.Close()
+		return nil, fmt.Errorf("could not open an IAP tunnel to %v: expected a connect message, got tag %v", target.instance, tag)
+	}
+	return tunnel, nil
+}
+
+// readMessage returns the next message's tag and the rest of the message.
+func (t *iapTunnel) readMessage() (uint16, []byte, error) {
+	for {
+		messageType, data, err := t.conn.ReadMessage()
+		if err != nil {
+			if closeErr, ok := err.(*websocket.CloseError); ok {
+				if closeErr.Code == websocket.CloseNormalClosure {
+					return 0, nil, io.EOF
+				}
+				return 0, nil, fmt.Errorf("the IAP tunnel was closed: %v (%v)", closeErr.Text, closeErr.Code)
+			}
+			return 0, nil, err
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		if len(data) < 2 {
+			return 0, nil, fmt.Errorf("the IAP tunnel sent a message without a tag")
+		}
+		return binary.BigEndian.Uint16(data[0:2]), data[2:], nil
+	}
+}
+
+func (t *iapTunnel) write(b []byte) error {
+	t.writeMux.Lock()
+	defer t.writeMux.Unlock()
+	return t.conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+// Read returns the data that the instance sent. Acks are sent as data is received.
+func (t *iapTunnel) Read(p []byte) (int, error) {
+	for len(t.unread) == 0 {
+		tag, msg, err := t.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		switch tag {
+		case iapData:
+			if len(msg) < 4 || len(msg)-4 < int(binary.BigEndian.Uint32(msg[0:4])) {
+				return 0, fmt.Errorf("the IAP tunnel sent a truncated data message")
+			}
+			t.unread = msg[4 : 4+binary.BigEndian.Uint32(msg[0:4])]
+			t.received += uint64(len(t.unread))
+			if err := t.ack(); err != nil {
+				return 0, err
+			}
+		case iapAck, iapReconnectSuccessAck:
+			// We don't resend data, so we don't need to track what the relay received.
+		default:
+			return 0, fmt.Errorf("the IAP tunnel sent a message with unknown tag %v", tag)
+		}
+	}
+	n := copy(p, t.unread)
+	t.unread = t.unread[n:]
+	return n, nil
+}
+
+// ack tells the relay how much data we've received.
+func (t *iapTunnel) ack() error {
+	b := make([]byte, 10)
+	binary.BigEndian.PutUint16(b[0:2], iapAck)
+	binary.BigEndian.PutUint64(b[2:10], t.received)
+	return t.write(b)
+}
+
+// Write sends data to the instance.
+func (t *iapTunnel) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n := len(p) - written
+		if n > iapMaxDataLength {
+			n = iapMaxDataLength
+		}
+		b := make([]byte, 6+n)
+		binary.BigEndian.PutUint16(b[0:2], iapData)
+		binary.BigEndian.PutUint32(b[2:6], uint32(n))
+		copy(b[6:], p[written:written+n])
+		if err := t.write(b); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func (t *iapTunnel) Close() error {
+	return t.conn.Close()
+}
+
+func (t *iapTunnel) LocalAddr() net.Addr {
+	return t.conn.LocalAddr()
+}
+
+func (t *iapTunnel) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+func (t *iapTunnel) SetDeadline(deadline time.Time) error {
+	if err := t.conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	return t.conn.SetWriteDeadline(deadline)
+}
+
+func (t *iapTunnel) SetReadDeadline(deadline time.Time) error {
+	return t.conn.SetReadDeadline(deadline)
+}
+
+func (t *iapTunnel) SetWriteDeadline(deadline time.Time) error {
+	return t.conn.SetWriteDeadline(deadline)
+}
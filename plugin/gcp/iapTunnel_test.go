@@ -0,0 +1,133 @@
+package gcp
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func iapMessage(tag uint16, data []byte) []byte {
+	b := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(b[0:2], tag)
+	copy(b[2:], data)
+	return b
+}
+
+func iapDataMessage(data string) []byte {
+	b := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(data)))
+	copy(b[4:], data)
+	return iapMessage(iapData, b)
+}
+
+// newIAPUpgrader returns an upgrader that accepts the relay's subprotocol and the
+// tunnel's bot origin.
+func newIAPUpgrader() websocket.Upgrader {
+	return websocket.Upgrader{
+		Subprotocols: []string{iapSubprotocol},
+		CheckOrigin:  func(r *http.Request) bool { return r.Header.Get("Origin") == iapOrigin },
+	}
+}
+
+// fakeIAPRelay plays the relay's side of a tunnel. It connects and then echoes the
+// data that it's sent. The acks that it's sent are sent to acks, which is closed when
+// the client disconnects.
+func fakeIAPRelay(t *testing.T, acks chan<- uint64) *httptest.Server {
+	upgrader := newIAPUpgrader()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(acks)
+		query := r.URL.Query()
+		assert.Equal(t, "project-id", query.Get("project"))
+		assert.Equal(t, "us-west1-a", query.Get("zone"))
+		assert.Equal(t, "instance", query.Get("instance"))
+		assert.Equal(t, "nic0", query.Get("interface"))
+		assert.Equal(t, "22", query.Get("port"))
+		assert.Equal(t, iapOrigin, r.Header.Get("Origin"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, iapMessage(iapConnectSuccessSID, []byte{0, 0, 0, 3, 's', 'i', 'd'})))
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch binary.BigEndian.Uint16(msg[0:2]) {
+			case iapData:
+				length := binary.BigEndian.Uint32(msg[2:6])
+				assert.True(t, length <= iapMaxDataLength)
+				require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, iapDataMessage(string(msg[6:6+length]))))
+			case iapAck:
+				acks <- binary.BigEndian.Uint64(msg[2:10])
+			}
+		}
+	}))
+}
+
+var testIAPTarget = iapTarget{project: "project-id", zone: "us-west1-a", instance: "instance", networkInterface: "nic0", port: 22}
+
+func TestIAPTunnel(t *testing.T) {
+	acks := make(chan uint64, 100)
+	server := fakeIAPRelay(t, acks)
+	defer server.Close()
+
+	tokens := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http")
+	tunnel, err := dialIAPTunnel(context.Background(), endpoint, tokens, testIAPTarget)
+	require.NoError(t, err)
+
+	_, err = tunnel.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(tunnel, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hel", string(buf))
+	_, err = io.ReadFull(tunnel, buf[:2])
+	require.NoError(t, err)
+	assert.Equal(t, "lo", string(buf[:2]))
+
+	// Data is split into messages that the relay accepts.
+	large := strings.Repeat("x", 2*iapMaxDataLength+1)
+	n, err := tunnel.Write([]byte(large))
+	require.NoError(t, err)
+	assert.Equal(t, len(large), n)
+	echoed := make([]byte, len(large))
+	_, err = io.ReadFull(tunnel, echoed)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(echoed))
+
+	require.NoError(t, tunnel.Close())
+	var received []uint64
+	for ack := range acks {
+		received = append(received, ack)
+	}
+	assert.Equal(t, []uint64{5, 5 + iapMaxDataLength, 5 + 2*iapMaxDataLength, uint64(5 + len(large))}, received)
+}
+
+func TestIAPTunnelReportsClosedConnections(t *testing.T) {
+	upgrader := newIAPUpgrader()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		msg := websocket.FormatCloseMessage(4003, "failed to connect to backend")
+		require.NoError(t, conn.WriteMessage(websocket.CloseMessage, msg))
+	}))
+	defer server.Close()
+
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http")
+	_, err := dialIAPTunnel(context.Background(), endpoint, nil, testIAPTarget)
+	assert.EqualError(t, err, "could not open an IAP tunnel to instance: the IAP tunnel was closed: failed to connect to backend (4003)")
+}
@@ -0,0 +1,101 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	compute "google.golang.org/api/compute/v1"
+	oauth2api "google.golang.org/api/oauth2/v2"
+	"google.golang.org/api/oslogin/v1"
+)
+
+// OS Login is enabled by the enable-oslogin metadata key, as described in
+// https://cloud.google.com/compute/docs/instances/managing-instance-access.
+const osLoginKey = "enable-oslogin"
+
+// userinfoScope lets us look up the current account's email, which is its OS Login ID.
+const userinfoScope = oauth2api.UserinfoEmailScope
+
+// osLoginEnabled returns true if OS Login is enabled by the instance's or the project's
+// metadata. Instance metadata overrides project metadata.
+func osLoginEnabled(instance, project *compute.Metadata) bool {
+	for _, metadata := range []*compute.Metadata{instance, project} {
+		if enabled := findKey(metadata, osLoginKey); enabled != nil {
+			return strings.EqualFold(*enabled, "true")
+		}
+	}
+	return false
+}
+
+// addOSLoginKey adds the SSH public key to the current account's OS Login profile and
+// returns the account's POSIX username in the project. Returns true if the key was
+// added, false if it was already present.
+//
+// This is modeled on how gcloud compute ssh imports keys when OS Login is enabled.
+func addOSLoginKey(ctx context.Context, opts clientOptions, projectID, key string) (string, bool, error) {
+	userinfo, err := oauth2api.NewService(context.Background(), opts.forService("oauth2")...)
+	if err != nil {
+		return "", false, err
+	}
+	info, err := userinfo.Userinfo.Get().Context(ctx).Do()
+	if err != nil {
+		return "", false, fmt.Errorf("unable to find the current account for OS Login: %v", err)
+	}
+	if info.Email == "" {
+		return "", false, fmt.Errorf("unable to find the current account for OS Login: its credentials do not include its email")
+	}
+
+	service, err := oslogin.NewService(context.Background(), opts.forService("oslogin")...)
+	if err != nil {
+		return "", false, err
+	}
+	user := "users/" + info.Email
+	profile, err := service.Users.GetLoginProfile(user).ProjectId(projectID).Context(ctx).Do()
+	if err != nil {
+		return "", false, fmt.Errorf("unable to get the OS Login profile of %v: %v", info.Email, err)
+	}
+
+	keyAdded := false
+	if !hasOSLoginKey(profile, key) {
+		resp, err := service.Users.ImportSshPublicKey(user, &oslogin.SshPublicKey{Key: key}).ProjectId(projectID).Context(ctx).Do()
+		if err != nil {
+			return "", false, fmt.Errorf("unable to add SSH key to the OS Login profile of %v: %v", info.Email, err)
+		}
+		profile = resp.LoginProfile
+		keyAdded = true
+	}
+
+	username := posixUsername(profile)
+	if username == "" {
+		return "", false, fmt.Errorf("%v does not have a POSIX account in project %v", info.Email, projectID)
+	}
+	return username, keyAdded, nil
+}
+
+// hasOSLoginKey returns true if the profile has the key. Keys are compared by their
+// type and data, ignoring comments.
+func hasOSLoginKey(profile *oslogin.LoginProfile, key string) bool {
+	fields := strings.Fields(key)
+	for _, existing := range profile.SshPublicKeys {
+		existingFields := strings.Fields(existing.Key)
+		if len(fields) >= 2 && len(existingFields) >= 2 && fields[0] == existingFields[0] && fields[1] == existingFields[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// posixUsername returns the username of the profile's primary POSIX account. If none
+// is primary, the first account's username is returned.
+func posixUsername(profile *oslogin.LoginProfile) string {
+	if profile == nil || len(profile.PosixAccounts) == 0 {
+		return ""
+	}
+	for _, account := range profile.PosixAccounts {
+		if account.Primary {
+			return account.Username
+		}
+	}
+	return profile.PosixAccounts[0].Username
+}
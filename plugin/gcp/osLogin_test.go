@@ -0,0 +1,99 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/oslogin/v1"
+)
+
+func metadataWith(key, value string) *compute.Metadata {
+	return &compute.Metadata{Items: []*compute.MetadataItems{{Key: key, Value: &value}}}
+}
+
+func TestOSLoginEnabled(t *testing.T) {
+	enabled := metadataWith(osLoginKey, "TRUE")
+	disabled := metadataWith(osLoginKey, "false")
+	unset := metadataWith("other", "true")
+
+	assert.False(t, osLoginEnabled(nil, nil))
+	assert.False(t, osLoginEnabled(unset, unset))
+	assert.True(t, osLoginEnabled(enabled, nil))
+	assert.True(t, osLoginEnabled(unset, enabled))
+	assert.False(t, osLoginEnabled(disabled, enabled))
+	assert.True(t, osLoginEnabled(enabled, disabled))
+}
+
+// fakeOSLogin serves the userinfo and OS Login APIs for user@example.com. The profile
+// starts with the keys, and imported keys are added to it.
+func fakeOSLogin(t *testing.T, keys ...string) (*httptest.Server, *int) {
+	profile := oslogin.LoginProfile{
+		Name: "user@example.com",
+		PosixAccounts: []*oslogin.PosixAccount{
+			{Username: "other"},
+			{Username: "user_example_com", Primary: true},
+		},
+		SshPublicKeys: map[string]oslogin.SshPublicKey{},
+	}
+	for i, key := range keys {
+		profile.SshPublicKeys[fmt.Sprint(i)] = oslogin.SshPublicKey{Key: key}
+	}
+
+	imports := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/v2/userinfo":
+			fmt.Fprint(w, `{"email": "user@example.com"}`)
+		case "/v1/users/user@example.com/loginProfile":
+			assert.Equal(t, "project-id", r.URL.Query().Get("projectId"))
+			assert.NoError(t, json.NewEncoder(w).Encode(profile))
+		case "/v1/users/user@example.com:importSshPublicKey":
+			assert.Equal(t, "project-id", r.URL.Query().Get("projectId"))
+			var key oslogin.SshPublicKey
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&key))
+			imports++
+			profile.SshPublicKeys["imported"] = key
+			assert.NoError(t, json.NewEncoder(w).Encode(oslogin.ImportSshPublicKeyResponse{LoginProfile: &profile}))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, &imports
+}
+
+func TestAddOSLoginKey(t *testing.T) {
+	server, imports := fakeOSLogin(t, "ssh-rsa AAAAexisting old@comment")
+	defer server.Close()
+	opts := clientOptions{endpoints: map[string]string{"oauth2": server.URL + "/", "oslogin": server.URL + "/"}}
+
+	user, keyAdded, err := addOSLoginKey(context.Background(), opts, "project-id", "ssh-rsa AAAAexisting me@host")
+	require.NoError(t, err)
+	assert.Equal(t, "user_example_com", user)
+	assert.False(t, keyAdded)
+	assert.Equal(t, 0, *imports)
+
+	user, keyAdded, err = addOSLoginKey(context.Background(), opts, "project-id", "ssh-rsa AAAAnew me@host")
+	require.NoError(t, err)
+	assert.Equal(t, "user_example_com", user)
+	assert.True(t, keyAdded)
+	assert.Equal(t, 1, *imports)
+
+	_, keyAdded, err = addOSLoginKey(context.Background(), opts, "project-id", "ssh-rsa AAAAnew me@host")
+	require.NoError(t, err)
+	assert.False(t, keyAdded)
+	assert.Equal(t, 1, *imports)
+}
+
+func TestPosixUsername(t *testing.T) {
+	assert.Equal(t, "", posixUsername(&oslogin.LoginProfile{}))
+	assert.Equal(t, "first", posixUsername(&oslogin.LoginProfile{
+		PosixAccounts: []*oslogin.PosixAccount{{Username: "first"}, {Username: "second"}},
+	}))
+}
@@ -3,28 +3,27 @@ package gcp
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strings"
 	"sync"
 
 	"github.com/puppetlabs/wash/plugin"
 	crm "google.golang.org/api/cloudresourcemanager/v1"
-	"google.golang.org/api/option"
 )
 
 type project struct {
 	plugin.EntryBase
-	client *http.Client
-	id     string
+	opts       clientOptions
+	pubsubOpts pubsubOptions
+	id         string
 }
 
 // NewProject creates a new project with a collection of service clients.
-func newProject(p *crm.Project, client *http.Client) *project {
+func newProject(p *crm.Project, opts clientOptions, pubsubOpts pubsubOptions) *project {
 	name := p.Name
 	if name == "" {
 		name = p.ProjectId
 	}
-	proj := &project{EntryBase: plugin.NewEntry(name), client: client, id: p.ProjectId}
+	proj := &project{EntryBase: plugin.NewEntry(name), opts: opts, pubsubOpts: pubsubOpts, id: p.ProjectId}
 	proj.SetPartialMetadata(p)
 	return proj
 }
@@ -47,13 +46,16 @@ func (p *project) List(ctx context.Context) ([]plugin.Entry, error) {
 		}
 	}
 
-	go func() { save(newComputeDir(ctx, p.client, p.id)) }()
-	go func() { save(newStorageDir(ctx, p.client, p.id)) }()
-	go func() { save(newFirestoreDir(ctx, p.id)) }()
-	go func() { save(newPubsubDir(ctx, p.id)) }()
-	go func() { save(newCloudFunctionsDir(ctx, p.client, p.id)) }()
-	go func() { save(newCloudRunDir(ctx, p.client, p.id)) }()
-	wg.Add(6)
+	go func() { save(newComputeDir(ctx, p.opts, p.id)) }()
+	go func() { save(newStorageDir(ctx, p.opts, p.id)) }()
+	go func() { save(newFirestoreDir(ctx, p.opts, p.id)) }()
+	go func() { save(newPubsubDir(ctx, p.opts, p.id)) }()
+	go func() { save(newPubsubSubscriptionsDir(ctx, p.opts, p.pubsubOpts, p.id)) }()
+	go func() { save(newCloudFunctionsDir(ctx, p.opts, p.id)) }()
+	go func() { save(newCloudRunDir(ctx, p.opts, p.id)) }()
+	go func() { save(newSecretsDir(ctx, p.opts, p.id)) }()
+	go func() { save(newBigqueryDir(ctx, p.opts, p.id)) }()
+	wg.Add(9)
 	wg.Wait()
 
 	if len(errs) > 0 {
@@ -63,7 +65,7 @@ func (p *project) List(ctx context.Context) ([]plugin.Entry, error) {
 }
 
 func (p *project) Delete(ctx context.Context) (bool, error) {
-	crmService, err := crm.NewService(context.Background(), option.WithHTTPClient(p.client))
+	crmService, err := crm.NewService(context.Background(), p.opts.forService("cloudresourcemanager")...)
 	if err != nil {
 		return false, err
 	}
@@ -84,8 +86,11 @@ func (p *project) ChildSchemas() []*plugin.EntrySchema {
 		(&storageDir{}).Schema(),
 		(&firestoreDir{}).Schema(),
 		(&pubsubDir{}).Schema(),
+		(&pubsubSubscriptionsDir{}).Schema(),
 		(&cloudFunctionsDir{}).Schema(),
 		(&cloudRunDir{}).Schema(),
+		(&secretsDir{}).Schema(),
+		(&bigqueryDir{}).Schema(),
 	}
 }
 
@@ -13,8 +13,8 @@ type pubsubDir struct {
 	client *pubsub.Client
 }
 
-func newPubsubDir(ctx context.Context, projID string) (*pubsubDir, error) {
-	cli, err := pubsub.NewClient(context.Background(), projID)
+func newPubsubDir(ctx context.Context, opts clientOptions, projID string) (*pubsubDir, error) {
+	cli, err := pubsub.NewClient(context.Background(), projID, opts.forService("pubsub")...)
 	if err != nil {
 		return nil, err
 	}
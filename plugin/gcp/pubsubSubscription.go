@@ -0,0 +1,229 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"google.golang.org/genproto/googleapis/monitoring/v3"
+	pubsubpb "google.golang.org/genproto/googleapis/pubsub/v1"
+)
+
+type pubsubSubscription struct {
+	plugin.EntryBase
+	pubsubSubscriptionsClient
+	sub *pubsub.Subscription
+}
+
+// pubsubBacklog is a subscription's backlog, as last reported to Cloud Monitoring.
+// Its fields are null if the backlog couldn't be retrieved.
+type pubsubBacklog struct {
+	UndeliveredMessages            *int64
+	OldestUnackedMessageAgeSeconds *int64
+}
+
+type pubsubSubscriptionMetadata struct {
+	pubsub.SubscriptionConfig
+	Topic   string
+	Backlog pubsubBacklog
+}
+
+func newPubsubSubscription(client pubsubSubscriptionsClient, sub *pubsub.Subscription) *pubsubSubscription {
+	return &pubsubSubscription{
+		EntryBase:                 plugin.NewEntry(sub.ID()),
+		pubsubSubscriptionsClient: client,
+		sub:                       sub,
+	}
+}
+
+func (s *pubsubSubscription) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	cfg, err := s.sub.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := pubsubSubscriptionMetadata{SubscriptionConfig: cfg}
+	if cfg.Topic != nil {
+		meta.Topic = cfg.Topic.ID()
+	}
+	meta.Backlog.UndeliveredMessages = s.latestMetric(ctx, "num_undelivered_messages")
+	meta.Backlog.OldestUnackedMessageAgeSeconds = s.latestMetric(ctx, "oldest_unacked_message_age")
+	return plugin.ToJSONObject(meta), nil
+}
+
+// latestMetric returns the latest value of the subscription's metric from Cloud
+// Monitoring, or nil if there isn't one. Pub/Sub reports the metrics every minute.
+func (s *pubsubSubscription) latestMetric(ctx context.Context, metric string) *int64 {
+	if s.metrics == nil {
+		return nil
+	}
+	now := time.Now()
+	req := &monitoring.ListTimeSeriesRequest{
+		Name:   "projects/" + s.projectID,
+		Filter: `metric.type = "pubsub.googleapis.com/subscription/` + metric + `" AND resource.label.subscription_id = "` + s.sub.ID() + `"`,
+		Interval: &monitoring.TimeInterval{
+			StartTime: &timestamp.Timestamp{Seconds: now.Add(-10 * time.Minute).Unix()},
+			EndTime:   &timestamp.Timestamp{Seconds: now.Unix()},
+		},
+		PageSize: 1,
+	}
+	series, err := s.metrics.ListTimeSeries(ctx, req).Next()
+	if err != nil {
+		activity.Record(ctx, "Unable to get %v for subscription %v from Cloud Monitoring: %v", metric, s.sub.ID(), err)
+		return nil
+	}
+	if len(series.Points) <= 0 {
+		activity.Record(ctx, "Cloud Monitoring returned no data points for %v of subscription %v", metric, s.sub.ID())
+		return nil
+	}
+	// Points are returned in reverse time order.
+	value := series.Points[0].Value.GetInt64Value()
+	return &value
+}
+
+func (s *pubsubSubscription) List(ctx context.Context) ([]plugin.Entry, error) {
+	return []plugin.Entry{newPubsubSubscriptionPeek(s)}, nil
+}
+
+// Stream streams the subscription's messages. They're acked if auto_ack is set.
+func (s *pubsubSubscription) Stream(ctx context.Context) (io.ReadCloser, error) {
+	// Use a new handle because the watcher changes its receive settings.
+	return newPubsubWatcher(ctx, s.Subscription(s.sub.ID()), s.opts.AutoAck, nil), nil
+}
+
+func (s *pubsubSubscription) Signal(ctx context.Context, signal string) error {
+	if signal == "purge" {
+		// Seeking to now acks all of the messages that have been published.
+		return s.sub.SeekToTime(ctx, time.Now())
+	}
+
+	target := strings.TrimPrefix(signal, "seek-")
+	// Signals are lower case, but times are parsed in upper case.
+	t, err := time.Parse(time.RFC3339, strings.ToUpper(target))
+	if err != nil {
+		d, durationErr := time.ParseDuration(target)
+		if durationErr != nil {
+			return fmt.Errorf("invalid signal %v: %v is not an RFC3339 time or a duration", signal, target)
+		}
+		t = time.Now().Add(-d)
+	}
+	activity.Record(ctx, "Seeking subscription %v to %v", s.sub.ID(), t)
+	return s.sub.SeekToTime(ctx, t)
+}
+
+func (s *pubsubSubscription) Delete(ctx context.Context) (bool, error) {
+	return true, s.sub.Delete(ctx)
+}
+
+func (s *pubsubSubscription) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(s, "subscription").
+		SetMetadataSchema(&pubsubSubscriptionMetadata{}).
+		SetDescription(pubsubSubscriptionDescription).
+		AddSignal("purge", "Acks all of the subscription's messages").
+		AddSignalGroup("seek", `\Aseek-.+`, "Consists of seek-<time> and seek-<duration>, like seek-2020-01-02T15:04:05Z\nor seek-1h. Marks the messages published after the time, or within the duration,\nas unacked and the messages published before it as acked")
+}
+
+func (s *pubsubSubscription) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&pubsubSubscriptionPeek{}).Schema(),
+	}
+}
+
+const pubsubSubscriptionDescription = `
+A Cloud Pub/Sub subscription. Its metadata includes its backlog as last reported to
+Cloud Monitoring.
+
+Tailing it streams its messages, which are acked if auto_ack is set in the pubsub
+config. Otherwise they're redelivered after the stream is closed.
+
+Acked messages can be replayed by seeking the subscription to a time before they
+were published if the subscription retains acked messages. For example
+
+  signal seek-1h <subscription>
+
+replays the last hour's messages, and
+
+  signal purge <subscription>
+
+acks all of its messages.
+`
+
+// peekCount is the number of messages that peek returns.
+const peekCount = 10
+
+// peekTimeout is how long peek waits for messages if there aren't any.
+var peekTimeout = 3 * time.Second
+
+// pubsubSubscriptionPeek returns a subscription's next messages without acking them.
+type pubsubSubscriptionPeek struct {
+	plugin.EntryBase
+	subscription *pubsubSubscription
+}
+
+func newPubsubSubscriptionPeek(subscription *pubsubSubscription) *pubsubSubscriptionPeek {
+	peek := &pubsubSubscriptionPeek{
+		EntryBase:    plugin.NewEntry("peek"),
+		subscription: subscription,
+	}
+	peek.DisableCachingFor(plugin.ReadOp)
+	return peek
+}
+
+func (p *pubsubSubscriptionPeek) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(p, "peek").
+		IsSingleton().
+		SetDescription(pubsubSubscriptionPeekDescription)
+}
+
+// Read pulls up to peekCount of the subscription's messages and nacks them so that
+// they're redelivered.
+func (p *pubsubSubscriptionPeek) Read(ctx context.Context) ([]byte, error) {
+	pullCtx, cancel := context.WithTimeout(ctx, peekTimeout)
+	defer cancel()
+	resp, err := p.subscription.subscriber.Pull(pullCtx, &pubsubpb.PullRequest{
+		Subscription: p.subscription.sub.String(),
+		MaxMessages:  peekCount,
+	})
+	if err != nil {
+		if pullCtx.Err() == nil {
+			return nil, err
+		}
+		// There weren't any messages.
+		resp = &pubsubpb.PullResponse{}
+	}
+
+	var b strings.Builder
+	ackIDs := make([]string, 0, len(resp.ReceivedMessages))
+	for _, received := range resp.ReceivedMessages {
+		ackIDs = append(ackIDs, received.AckId)
+		publishTime, err := ptypes.Timestamp(received.Message.PublishTime)
+		if err != nil {
+			activity.Record(ctx, "Invalid publish time for message %v: %v", received.Message.MessageId, err)
+		}
+		b.WriteString(formatMessage(&pubsub.Message{Data: received.Message.Data, PublishTime: publishTime}) + "\n")
+	}
+	if len(ackIDs) > 0 {
+		err = p.subscription.subscriber.ModifyAckDeadline(ctx, &pubsubpb.ModifyAckDeadlineRequest{
+			Subscription:       p.subscription.sub.String(),
+			AckIds:             ackIDs,
+			AckDeadlineSeconds: 0,
+		})
+		if err != nil {
+			activity.Record(ctx, "Unable to nack the peeked messages of subscription %v: %v", p.subscription.sub.ID(), err)
+		}
+	}
+	activity.Record(ctx, "Peeked at %v messages of subscription %v", len(ackIDs), p.subscription.sub.ID())
+	return []byte(b.String()), nil
+}
+
+const pubsubSubscriptionPeekDescription = `
+Up to 10 of the subscription's next messages. Reading it waits a few seconds for
+messages if there aren't any. The messages aren't acked, so they're redelivered.
+`
@@ -0,0 +1,130 @@
+package gcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	vkit "cloud.google.com/go/pubsub/apiv1"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+type pubsubSubscriptionTestSuite struct {
+	suite.Suite
+	server *pstest.Server
+	conn   *grpc.ClientConn
+	client *pubsub.Client
+	topic  *pubsub.Topic
+	sub    *pubsubSubscription
+}
+
+func (s *pubsubSubscriptionTestSuite) SetupSuite() {
+	peekTimeout = 500 * time.Millisecond
+}
+
+func (s *pubsubSubscriptionTestSuite) TearDownSuite() {
+	peekTimeout = 3 * time.Second
+}
+
+func (s *pubsubSubscriptionTestSuite) SetupTest() {
+	ctx := context.Background()
+	s.server = pstest.NewServer()
+	var err error
+	s.conn, err = grpc.Dial(s.server.Addr, grpc.WithInsecure())
+	s.Require().NoError(err)
+	s.client, err = pubsub.NewClient(ctx, "project-id", option.WithGRPCConn(s.conn))
+	s.Require().NoError(err)
+	s.topic, err = s.client.CreateTopic(ctx, "topic")
+	s.Require().NoError(err)
+	sub, err := s.client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: s.topic})
+	s.Require().NoError(err)
+	subscriber, err := vkit.NewSubscriberClient(ctx, option.WithGRPCConn(s.conn))
+	s.Require().NoError(err)
+	s.sub = newPubsubSubscription(pubsubSubscriptionsClient{Client: s.client, subscriber: subscriber, projectID: "project-id"}, sub)
+}
+
+func (s *pubsubSubscriptionTestSuite) TearDownTest() {
+	s.topic.Stop()
+	s.client.Close()
+	s.conn.Close()
+	s.server.Close()
+}
+
+func (s *pubsubSubscriptionTestSuite) publish(msgs ...string) {
+	for _, msg := range msgs {
+		_, err := s.topic.Publish(context.Background(), &pubsub.Message{Data: []byte(msg)}).Get(context.Background())
+		s.Require().NoError(err)
+	}
+}
+
+func (s *pubsubSubscriptionTestSuite) peek() []string {
+	data, err := newPubsubSubscriptionPeek(s.sub).Read(context.Background())
+	s.Require().NoError(err)
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line != "" {
+			msgs = append(msgs, line[strings.Index(line, "| ")+2:])
+		}
+	}
+	return msgs
+}
+
+func (s *pubsubSubscriptionTestSuite) TestPeekDoesNotAck() {
+	s.publish("one", "two")
+	s.ElementsMatch([]string{"one", "two"}, s.peek())
+	s.ElementsMatch([]string{"one", "two"}, s.peek())
+}
+
+func (s *pubsubSubscriptionTestSuite) TestStreamAcksWithAutoAck() {
+	s.sub.opts.AutoAck = true
+	s.publish("one")
+	stream, err := s.sub.Stream(context.Background())
+	s.Require().NoError(err)
+	buf := make([]byte, 100)
+	n, err := stream.Read(buf)
+	s.Require().NoError(err)
+	s.Contains(string(buf[:n]), "| one")
+	s.NoError(stream.Close())
+
+	s.Eventually(func() bool { return len(s.peek()) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func (s *pubsubSubscriptionTestSuite) TestStreamDoesNotAckByDefault() {
+	s.publish("one")
+	stream, err := s.sub.Stream(context.Background())
+	s.Require().NoError(err)
+	buf := make([]byte, 100)
+	n, err := stream.Read(buf)
+	s.Require().NoError(err)
+	s.Contains(string(buf[:n]), "| one")
+	s.NoError(stream.Close())
+
+	msgs := s.server.Messages()
+	s.Require().Len(msgs, 1)
+	s.Equal(0, msgs[0].Acks)
+}
+
+func (s *pubsubSubscriptionTestSuite) TestPurge() {
+	s.publish("one", "two")
+	s.NoError(s.sub.Signal(context.Background(), "purge"))
+	s.Empty(s.peek())
+}
+
+func (s *pubsubSubscriptionTestSuite) TestSeekRejectsInvalidTargets() {
+	s.EqualError(s.sub.Signal(context.Background(), "seek-yesterday"), "invalid signal seek-yesterday: yesterday is not an RFC3339 time or a duration")
+}
+
+func TestPubsubSubscription(t *testing.T) {
+	suite.Run(t, new(pubsubSubscriptionTestSuite))
+}
+
+func TestFormatMessage(t *testing.T) {
+	msg := &pubsub.Message{Data: []byte("hello"), PublishTime: time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)}
+	assert.Equal(t, "Jan  2 03:04:05.006 | hello", formatMessage(msg))
+}
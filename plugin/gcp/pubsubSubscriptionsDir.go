@@ -0,0 +1,107 @@
+package gcp
+
+import (
+	"context"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3"
+	"cloud.google.com/go/pubsub"
+	vkit "cloud.google.com/go/pubsub/apiv1"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"google.golang.org/api/iterator"
+)
+
+// pubsubOptions are the Pub/Sub options in the plugin's config.
+type pubsubOptions struct {
+	AutoAck bool `json:"auto_ack" jsonschema_description:"Ack the messages that are streamed from subscriptions. If false, streamed messages are redelivered after the stream is closed."`
+}
+
+type pubsubSubscriptionsClient struct {
+	*pubsub.Client
+	// subscriber peeks at messages with a single pull, which Receive can't do
+	subscriber *vkit.SubscriberClient
+	metrics    *monitoring.MetricClient
+	projectID  string
+	opts       pubsubOptions
+}
+
+type pubsubSubscriptionsDir struct {
+	plugin.EntryBase
+	pubsubSubscriptionsClient
+}
+
+func newPubsubSubscriptionsDir(ctx context.Context, opts clientOptions, pubsubOpts pubsubOptions, projID string) (*pubsubSubscriptionsDir, error) {
+	clientContext := context.Background()
+	cli, err := pubsub.NewClient(clientContext, projID, opts.forService("pubsub")...)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriber, err := vkit.NewSubscriberClient(clientContext, opts.forService("pubsub")...)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := monitoring.NewMetricClient(clientContext, opts.forService("monitoring")...)
+	if err != nil {
+		activity.Record(ctx, "Unable to create metrics client for %v/subscriptions: %v", projID, err)
+	}
+
+	s := &pubsubSubscriptionsDir{
+		EntryBase: plugin.NewEntry("subscriptions"),
+		pubsubSubscriptionsClient: pubsubSubscriptionsClient{
+			Client:     cli,
+			subscriber: subscriber,
+			metrics:    metrics,
+			projectID:  projID,
+			opts:       pubsubOpts,
+		},
+	}
+	if _, err := plugin.List(ctx, s); err != nil {
+		s.MarkInaccessible(ctx, err)
+	}
+	return s, nil
+}
+
+// List all subscriptions
+func (s *pubsubSubscriptionsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	subs := make([]plugin.Entry, 0)
+	it := s.Subscriptions(ctx)
+	for {
+		sub, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, newPubsubSubscription(s.pubsubSubscriptionsClient, sub))
+	}
+	return subs, nil
+}
+
+func (s *pubsubSubscriptionsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(s, "subscriptions").
+		IsSingleton().
+		SetDescription(pubsubSubscriptionsDirDescription)
+}
+
+func (s *pubsubSubscriptionsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&pubsubSubscription{}).Schema(),
+	}
+}
+
+const pubsubSubscriptionsDirDescription = `
+This directory contains the project's Cloud Pub/Sub subscriptions.
+
+You can stream a subscription's messages with tail -f, and peek at its next
+messages without acking them by cat'ing its peek file. Streamed messages are
+only acked if you add
+
+gcp:
+  pubsub:
+    auto_ack: true
+
+to Wash's config file.
+`
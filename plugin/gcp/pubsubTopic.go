@@ -75,42 +75,50 @@ func (t *pubsubTopic) Delete(ctx context.Context) (bool, error) {
 	return true, t.topic.Delete(ctx)
 }
 
-// A ReadCloser that subscribes to a topic and buffers all messages that appear there.
-type pubsubTopicWatcher struct {
-	ctx   context.Context
-	sub   *pubsub.Subscription
-	queue <-chan *pubsub.Message
-	err   <-chan error
+// A ReadCloser that receives a subscription's messages and buffers them.
+type pubsubWatcher struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	queue   <-chan *pubsub.Message
+	err     <-chan error
+	cleanup func() error
 }
 
-func (t *pubsubTopic) newPubsubTopicWatcher(ctx context.Context) (*pubsubTopicWatcher, error) {
-	sub, err := t.client.CreateSubscription(ctx, "wash-"+uuid.New().String(), pubsub.SubscriptionConfig{
-		Topic:            t.topic,
-		AckDeadline:      10 * time.Second,
-		ExpirationPolicy: 24 * time.Hour,
-	})
-	if err != nil {
-		return nil, err
+// newPubsubWatcher starts receiving the subscription's messages. Messages are acked
+// if ack is true. Otherwise they're held until the watcher is closed, so they're
+// redelivered once their ack deadline passes. cleanup is called when the watcher is
+// closed.
+func newPubsubWatcher(ctx context.Context, sub *pubsub.Subscription, ack bool, cleanup func() error) *pubsubWatcher {
+	ctx, cancel := context.WithCancel(ctx)
+	if !ack {
+		// Held messages would otherwise stop Receive once there are too many.
+		sub.ReceiveSettings.MaxOutstandingMessages = -1
+		sub.ReceiveSettings.MaxOutstandingBytes = -1
 	}
 
 	// Use a buffer so we can Ack messages quickly.
 	queue := make(chan *pubsub.Message, 5)
 	errCh := make(chan error)
-	watcher := &pubsubTopicWatcher{ctx: ctx, sub: sub, queue: queue, err: errCh}
+	watcher := &pubsubWatcher{ctx: ctx, cancel: cancel, queue: queue, err: errCh, cleanup: cleanup}
 
 	bufferMessages := func(_ context.Context, msg *pubsub.Message) {
-		msg.Ack()
-		queue <- msg
+		if ack {
+			msg.Ack()
+		}
+		select {
+		case queue <- msg:
+		case <-ctx.Done():
+		}
 	}
 	go func() {
 		errCh <- sub.Receive(ctx, bufferMessages)
 		close(errCh)
 		close(queue)
 	}()
-	return watcher, nil
+	return watcher
 }
 
-func (w *pubsubTopicWatcher) Read(p []byte) (int, error) {
+func (w *pubsubWatcher) Read(p []byte) (int, error) {
 	// Wait for an outstanding message, context completion, or error.
 	select {
 	case <-w.ctx.Done():
@@ -119,20 +127,38 @@ func (w *pubsubTopicWatcher) Read(p []byte) (int, error) {
 		activity.Record(w.ctx, "Reading next message: %v", msg)
 
 		// TODO: don't truncate messages longer than the read buffer.
-		s := fmt.Sprintf("%v | %v", msg.PublishTime.Format(time.StampMilli), string(msg.Data))
-		return copy(p, []byte(s)), nil
+		return copy(p, []byte(formatMessage(msg))), nil
 	case err := <-w.err:
 		return 0, err
 	}
 }
 
-func (w *pubsubTopicWatcher) Close() error {
-	return w.sub.Delete(context.Background())
+func (w *pubsubWatcher) Close() error {
+	w.cancel()
+	if w.cleanup == nil {
+		return nil
+	}
+	return w.cleanup()
+}
+
+// formatMessage formats a message as its publish time and data.
+func formatMessage(msg *pubsub.Message) string {
+	return fmt.Sprintf("%v | %v", msg.PublishTime.Format(time.StampMilli), string(msg.Data))
 }
 
 func (t *pubsubTopic) Stream(ctx context.Context) (io.ReadCloser, error) {
-	// Create subscription and wrap it in a ReadCloser to handle cleanup.
-	return t.newPubsubTopicWatcher(ctx)
+	// Create a temporary subscription and delete it when the stream's closed.
+	sub, err := t.client.CreateSubscription(ctx, "wash-"+uuid.New().String(), pubsub.SubscriptionConfig{
+		Topic:            t.topic,
+		AckDeadline:      10 * time.Second,
+		ExpirationPolicy: 24 * time.Hour,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newPubsubWatcher(ctx, sub, true, func() error {
+		return sub.Delete(context.Background())
+	}), nil
 }
 
 func (t *pubsubTopic) Write(ctx context.Context, b []byte) error {
@@ -4,52 +4,84 @@ package gcp
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"os"
 	"time"
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	crm "google.golang.org/api/cloudresourcemanager/v1"
-	"google.golang.org/api/option"
 )
 
 // Root of the GCP plugin
 type Root struct {
 	plugin.EntryBase
-	oauthClient *http.Client
-	projects    map[string]struct{}
+	clientOpts clientOptions
+	pubsubOpts pubsubOptions
+	projects   map[string]struct{}
 }
 
 // serviceScopes lists all scopes used by this module.
-var serviceScopes = []string{crm.CloudPlatformScope, computeScope, storageScope}
+var serviceScopes = []string{crm.CloudPlatformScope, computeScope, storageScope, userinfoScope}
+
+type config struct {
+	Projects              []string          `json:"projects" jsonschema_description:"The GCP projects to list (by name or project ID). If omitted, all projects are listed."`
+	Endpoints             map[string]string `json:"endpoints" jsonschema_description:"Endpoints that services are sent to instead of GCP, keyed by the service, like compute, storage or pubsub. gRPC services take a host:port and HTTP services take a URL."`
+	EmulatorHosts         map[string]string `json:"emulator_hosts" jsonschema_description:"The host:port of the emulators for the firestore, pubsub and storage services. Emulators are connected to without authentication or TLS."`
+	WithoutAuthentication bool              `json:"without_authentication" jsonschema_description:"Send requests without credentials. This is useful for stand-ins that don't check them."`
+	Pubsub                pubsubOptions     `json:"pubsub" jsonschema_description:"Options for Cloud Pub/Sub."`
+}
+
+// ConfigSchema returns the root's config schema
+func (r *Root) ConfigSchema() interface{} {
+	return config{}
+}
 
 // Init for root
 func (r *Root) Init(cfg map[string]interface{}) error {
 	r.EntryBase = plugin.NewEntry("gcp")
 	r.SetTTLOf(plugin.ListOp, 1*time.Minute)
 
-	// We use the auto-generated SDK because it's the only one that allows us to list
-	// projects for the current credentials.
-	oauthClient, err := google.DefaultClient(context.Background(), serviceScopes...)
-	r.oauthClient = oauthClient
-
-	if projsI, ok := cfg["projects"]; ok {
-		projs, ok := projsI.([]interface{})
-		if !ok {
-			return fmt.Errorf("gcp.projects config must be an array of strings, not %s", projsI)
-		}
+	var c config
+	if err := plugin.DecodeConfig(cfg, &c); err != nil {
+		return err
+	}
+	if c.Projects != nil {
 		r.projects = make(map[string]struct{})
-		for _, elem := range projs {
-			proj, ok := elem.(string)
-			if !ok {
-				return fmt.Errorf("gcp.projects config must be an array of strings, not %s", projs)
-			}
+		for _, proj := range c.Projects {
 			r.projects[proj] = struct{}{}
 		}
 	}
+	if err := validateServices(c.Endpoints, services); err != nil {
+		return fmt.Errorf("invalid config: gcp.endpoints.%v", err)
+	}
+	if err := validateServices(c.EmulatorHosts, emulatedServices); err != nil {
+		return fmt.Errorf("invalid config: gcp.emulator_hosts.%v", err)
+	}
+	r.pubsubOpts = c.Pubsub
+	r.clientOpts = clientOptions{endpoints: c.Endpoints, emulatorHosts: c.EmulatorHosts}
+	if host, ok := c.EmulatorHosts["storage"]; ok {
+		// The storage client only downloads objects over HTTP from an emulator that's
+		// set with STORAGE_EMULATOR_HOST.
+		if err := os.Setenv("STORAGE_EMULATOR_HOST", host); err != nil {
+			return err
+		}
+	}
+	if c.WithoutAuthentication {
+		return nil
+	}
 
-	return err
+	// We use the auto-generated SDK because it's the only one that allows us to list
+	// projects for the current credentials.
+	ctx := context.Background()
+	creds, err := google.FindDefaultCredentials(ctx, serviceScopes...)
+	if err != nil {
+		return err
+	}
+	r.clientOpts.tokens = creds.TokenSource
+	r.clientOpts.httpClient = oauth2.NewClient(ctx, creds.TokenSource)
+	return nil
 }
 
 // ChildSchemas returns the root's child schema
@@ -68,7 +100,7 @@ func (r *Root) Schema() *plugin.EntrySchema {
 
 // List the available GCP projects
 func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
-	crmService, err := crm.NewService(context.Background(), option.WithHTTPClient(r.oauthClient))
+	crmService, err := crm.NewService(context.Background(), r.clientOpts.forService("cloudresourcemanager")...)
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +121,7 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 				continue
 			}
 		}
-		projects = append(projects, newProject(proj, r.oauthClient))
+		projects = append(projects, newProject(proj, r.clientOpts, r.pubsubOpts))
 	}
 	return projects, nil
 }
@@ -113,4 +145,20 @@ gcp:
   projects: [project-1, project-2]
 
 to Wash’s config file. Project can be referenced either by name or project ID.
+
+To use emulators and other local stand-ins, send services to them with
+
+gcp:
+  emulator_hosts:
+    firestore: localhost:8080
+    pubsub: localhost:8085
+    storage: localhost:4443
+  endpoints:
+    compute: http://localhost:9000/compute/v1/
+  without_authentication: true
+
+Emulators are connected to without authentication or TLS. The endpoints of the
+cloudresourcemanager, compute, storage, cloudfunctions, run, logging, oauth2,
+oslogin, iap, secretmanager and bigquery services are URLs, and those of the firestore, pubsub and
+monitoring services are host:port. Set without_authentication if the stand-ins don't check credentials.
 `
@@ -0,0 +1,192 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+// secret is a Secret Manager secret. It contains its versions and latest, which is
+// the most recently created version.
+type secret struct {
+	plugin.EntryBase
+	service secretsProjectService
+	// name is the secret's resource name, like projects/<project>/secrets/<secret>
+	name string
+}
+
+func newSecret(s *secretmanager.Secret, service secretsProjectService) *secret {
+	sec := &secret{
+		EntryBase: plugin.NewEntry(path.Base(s.Name)),
+		service:   service,
+		name:      s.Name,
+	}
+	sec.SetPartialMetadata(s)
+	if crtime, err := time.Parse(time.RFC3339Nano, s.CreateTime); err == nil {
+		sec.Attributes().SetCrtime(crtime)
+	}
+	return sec
+}
+
+func (s *secret) List(ctx context.Context) ([]plugin.Entry, error) {
+	entries := []plugin.Entry{newSecretLatest(s)}
+	err := s.service.Projects.Secrets.Versions.List(s.name).Pages(ctx, func(resp *secretmanager.ListSecretVersionsResponse) error {
+		for _, version := range resp.Versions {
+			entries = append(entries, newSecretVersion(version, s.service))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *secret) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(s, "secret").
+		SetPartialMetadataSchema(secretmanager.Secret{}).
+		SetDescription(secretDescription)
+}
+
+func (s *secret) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&secretLatest{}).Schema(),
+		(&secretVersion{}).Schema(),
+	}
+}
+
+const secretDescription = `
+This is a Secret Manager secret. It contains its versions, which are named by their
+number, and latest, which is the most recently created version. Writing to latest
+adds a new version, like
+
+  echo -n hunter2 > latest
+
+Versions can be disabled with
+
+  signal disable <version>
+
+and enabled again with the enable signal. Secret values are never cached or written
+to the activity journal.
+`
+
+// accessSecretVersion returns the version's data. The name can be a version number or
+// latest.
+func accessSecretVersion(ctx context.Context, service secretsProjectService, name string) ([]byte, error) {
+	resp, err := service.Projects.Secrets.Versions.Access(name).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Payload.Data)
+}
+
+// secretLatest is the most recently created version of a secret. Writing to it adds a
+// version.
+type secretLatest struct {
+	plugin.EntryBase
+	secret *secret
+}
+
+func newSecretLatest(s *secret) *secretLatest {
+	latest := &secretLatest{
+		EntryBase: plugin.NewEntry("latest"),
+		secret:    s,
+	}
+	latest.DisableCachingFor(plugin.ReadOp)
+	latest.Attributes().SetMode(0600)
+	return latest
+}
+
+func (l *secretLatest) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(l, "latest").
+		IsSingleton().
+		SetDescription(secretLatestDescription)
+}
+
+// Read returns the most recently created version's data. Secret Manager fails to read
+// it if that version is disabled or destroyed, even if older versions are enabled.
+func (l *secretLatest) Read(ctx context.Context) ([]byte, error) {
+	return accessSecretVersion(ctx, l.secret.service, l.secret.name+"/versions/latest")
+}
+
+// Write adds the data as a new version of the secret.
+func (l *secretLatest) Write(ctx context.Context, b []byte) error {
+	req := &secretmanager.AddSecretVersionRequest{
+		Payload: &secretmanager.SecretPayload{Data: base64.StdEncoding.EncodeToString(b)},
+	}
+	version, err := l.secret.service.Projects.Secrets.AddVersion(l.secret.name, req).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	activity.Record(ctx, "Added version %v of the %v secret", path.Base(version.Name), l.secret.Name())
+	return nil
+}
+
+const secretLatestDescription = `
+The secret's most recently created version. Reading it fails if that version
+is disabled or destroyed, even if older versions are enabled; read an enabled
+version by its number instead. Writing to it adds a new version, which becomes
+the latest version.
+`
+
+// secretVersion is a version of a secret.
+type secretVersion struct {
+	plugin.EntryBase
+	service secretsProjectService
+	// name is the version's resource name, like projects/<project>/secrets/<secret>/versions/<number>
+	name string
+}
+
+func newSecretVersion(version *secretmanager.SecretVersion, service secretsProjectService) *secretVersion {
+	v := &secretVersion{
+		EntryBase: plugin.NewEntry(path.Base(version.Name)),
+		service:   service,
+		name:      version.Name,
+	}
+	v.DisableCachingFor(plugin.ReadOp)
+	v.
+		SetPartialMetadata(version).
+		Attributes().
+		SetMode(0600)
+	if crtime, err := time.Parse(time.RFC3339Nano, version.CreateTime); err == nil {
+		v.Attributes().SetCrtime(crtime).SetMtime(crtime)
+	}
+	return v
+}
+
+func (v *secretVersion) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "version").
+		SetPartialMetadataSchema(secretmanager.SecretVersion{}).
+		SetDescription(secretVersionDescription).
+		AddSignal("disable", "Disables the version, so that it can't be read").
+		AddSignal("enable", "Enables the version")
+}
+
+// Read returns the version's data. Disabled and destroyed versions can't be read.
+func (v *secretVersion) Read(ctx context.Context) ([]byte, error) {
+	return accessSecretVersion(ctx, v.service, v.name)
+}
+
+func (v *secretVersion) Signal(ctx context.Context, signal string) error {
+	var err error
+	switch signal {
+	case "disable":
+		_, err = v.service.Projects.Secrets.Versions.Disable(v.name, &secretmanager.DisableSecretVersionRequest{}).Context(ctx).Do()
+	case "enable":
+		_, err = v.service.Projects.Secrets.Versions.Enable(v.name, &secretmanager.EnableSecretVersionRequest{}).Context(ctx).Do()
+	default:
+		err = fmt.Errorf("unsupported signal %v", signal)
+	}
+	return err
+}
+
+const secretVersionDescription = `
+A version of the secret. Its metadata includes its state, which is ENABLED,
+DISABLED or DESTROYED. Only enabled versions can be read.
+`
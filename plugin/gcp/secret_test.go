@@ -0,0 +1,158 @@
+package gcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+// fakeSecretManager serves the Secret Manager API for a project with one secret.
+type fakeSecretManager struct {
+	mux      sync.Mutex
+	versions []*secretmanager.SecretVersion
+	data     []string
+}
+
+const fakeSecretName = "projects/project-id/secrets/password"
+
+func (f *fakeSecretManager) addVersion(data string) *secretmanager.SecretVersion {
+	version := &secretmanager.SecretVersion{
+		Name:       fmt.Sprintf("%v/versions/%v", fakeSecretName, len(f.versions)+1),
+		CreateTime: "2020-01-02T15:04:05.123456Z",
+		State:      "ENABLED",
+	}
+	f.versions = append(f.versions, version)
+	f.data = append(f.data, data)
+	return version
+}
+
+func (f *fakeSecretManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	var resp interface{}
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case path == "projects/project-id/secrets":
+		resp = secretmanager.ListSecretsResponse{Secrets: []*secretmanager.Secret{{Name: fakeSecretName, CreateTime: "2020-01-02T15:04:05Z"}}}
+	case path == fakeSecretName+"/versions":
+		resp = secretmanager.ListSecretVersionsResponse{Versions: f.versions}
+	case path == fakeSecretName+":addVersion":
+		var req secretmanager.AddSecretVersionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := base64.StdEncoding.DecodeString(req.Payload.Data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp = f.addVersion(string(data))
+	case path == fakeSecretName+"/versions/latest:access":
+		for i := len(f.versions) - 1; i >= 0; i-- {
+			if f.versions[i].State == "ENABLED" {
+				resp = f.access(i)
+				break
+			}
+		}
+	default:
+		for i, version := range f.versions {
+			switch path {
+			case version.Name + ":access":
+				if version.State != "ENABLED" {
+					http.Error(w, `{"error": {"code": 400, "message": "version is disabled"}}`, http.StatusBadRequest)
+					return
+				}
+				resp = f.access(i)
+			case version.Name + ":disable":
+				version.State = "DISABLED"
+				resp = version
+			case version.Name + ":enable":
+				version.State = "ENABLED"
+				resp = version
+			}
+		}
+	}
+	if resp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (f *fakeSecretManager) access(i int) *secretmanager.AccessSecretVersionResponse {
+	return &secretmanager.AccessSecretVersionResponse{
+		Name:    f.versions[i].Name,
+		Payload: &secretmanager.SecretPayload{Data: base64.StdEncoding.EncodeToString([]byte(f.data[i]))},
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	fake := &fakeSecretManager{}
+	fake.addVersion("one")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	opts := clientOptions{endpoints: map[string]string{"secretmanager": server.URL + "/"}}
+	dir, err := newSecretsDir(ctx, opts, "project-id")
+	require.NoError(t, err)
+	secrets, err := dir.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	secret := secrets[0].(*secret)
+	assert.Equal(t, "password", secret.Name())
+
+	versions := func() map[string]plugin.Entry {
+		entries, err := secret.List(ctx)
+		require.NoError(t, err)
+		byName := make(map[string]plugin.Entry)
+		for _, entry := range entries {
+			byName[plugin.Name(entry)] = entry
+		}
+		return byName
+	}
+	read := func(entry plugin.Entry) string {
+		data, err := entry.(plugin.Readable).Read(ctx)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	children := versions()
+	require.Contains(t, children, "latest")
+	require.Contains(t, children, "1")
+	assert.Equal(t, "one", read(children["latest"]))
+	assert.Equal(t, "one", read(children["1"]))
+
+	// Writing to latest adds a version.
+	require.NoError(t, children["latest"].(plugin.Writable).Write(ctx, []byte("two")))
+	children = versions()
+	require.Contains(t, children, "2")
+	assert.Equal(t, "two", read(children["latest"]))
+	assert.Equal(t, "two", read(children["2"]))
+
+	// Disabled versions can't be read, and latest skips them.
+	require.NoError(t, plugin.Signal(ctx, children["2"].(plugin.Signalable), "DISABLE"))
+	assert.Equal(t, "one", read(children["latest"]))
+	_, err = children["2"].(plugin.Readable).Read(ctx)
+	assert.Error(t, err)
+
+	require.NoError(t, plugin.Signal(ctx, children["2"].(plugin.Signalable), "enable"))
+	assert.Equal(t, "two", read(children["latest"]))
+}
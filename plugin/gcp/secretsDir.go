@@ -0,0 +1,67 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+type secretsProjectService struct {
+	*secretmanager.Service
+	projectID string
+}
+
+type secretsDir struct {
+	plugin.EntryBase
+	service secretsProjectService
+}
+
+func newSecretsDir(ctx context.Context, opts clientOptions, projID string) (*secretsDir, error) {
+	svc, err := secretmanager.NewService(context.Background(), opts.forService("secretmanager")...)
+	if err != nil {
+		return nil, err
+	}
+	s := &secretsDir{
+		EntryBase: plugin.NewEntry("secrets"),
+		service:   secretsProjectService{Service: svc, projectID: projID},
+	}
+	if _, err := plugin.List(ctx, s); err != nil {
+		s.MarkInaccessible(ctx, err)
+	}
+	return s, nil
+}
+
+// List lists the Secret Manager secrets.
+func (s *secretsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	req := s.service.Projects.Secrets.List("projects/" + s.service.projectID)
+	err := req.Pages(ctx, func(resp *secretmanager.ListSecretsResponse) error {
+		for _, secret := range resp.Secrets {
+			entries = append(entries, newSecret(secret, s.service))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v secrets", len(entries))
+	return entries, nil
+}
+
+func (s *secretsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(s, "secrets").
+		IsSingleton().
+		SetDescription(secretsDirDescription)
+}
+
+func (s *secretsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&secret{}).Schema(),
+	}
+}
+
+const secretsDirDescription = `
+This directory contains the project's Secret Manager secrets.
+`
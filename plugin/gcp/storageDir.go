@@ -2,14 +2,12 @@ package gcp
 
 import (
 	"context"
-	"net/http"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3"
 	"cloud.google.com/go/storage"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 )
 
 type storageProjectClient struct {
@@ -25,14 +23,14 @@ type storageDir struct {
 
 const storageScope = storage.ScopeReadOnly
 
-func newStorageDir(ctx context.Context, client *http.Client, projID string) (*storageDir, error) {
+func newStorageDir(ctx context.Context, opts clientOptions, projID string) (*storageDir, error) {
 	clientContext := context.Background()
-	cli, err := storage.NewClient(clientContext, option.WithHTTPClient(client))
+	cli, err := storage.NewClient(clientContext, opts.forService("storage")...)
 	if err != nil {
 		return nil, err
 	}
 
-	metrics, err := monitoring.NewMetricClient(clientContext)
+	metrics, err := monitoring.NewMetricClient(clientContext, opts.forService("monitoring")...)
 	if err != nil {
 		activity.Record(ctx, "Unable to create metrics client for %v/storage: %v", projID, err)
 	}
@@ -0,0 +1,80 @@
+// Package logs aggregates the logs of several containers into one log, like
+// 'docker-compose logs' and 'kubectl logs --prefix' do.
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Stream is one of the logs that's merged by Merge.
+type Stream struct {
+	io.ReadCloser
+	// Name describes the log in activity messages.
+	Name string
+	// Prefix is prepended to each of the log's lines.
+	Prefix string
+}
+
+// PrefixLines copies rdr's lines to w, prefixing each with prefix.
+func PrefixLines(w io.Writer, rdr io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(rdr)
+	for scanner.Scan() {
+		if _, err := fmt.Fprintln(w, prefix+scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// CloseAll closes the streams that were opened. It's used to clean up when opening
+// one of the streams fails.
+func CloseAll(streams []Stream) {
+	for _, stream := range streams {
+		if stream.ReadCloser != nil {
+			stream.Close()
+		}
+	}
+}
+
+// Merge returns a reader of the streams' prefixed lines, in the order that they
+// arrive. The reader ends once all of the streams have ended, and closing it
+// closes the streams. name describes the merged log in activity messages.
+func Merge(ctx context.Context, name string, streams []Stream) io.ReadCloser {
+	// Lines from different streams are interleaved as they arrive, so serialize the
+	// writes to keep each line intact.
+	r, w := io.Pipe()
+	lw := &lockedWriter{w: w}
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func(stream Stream) {
+			defer wg.Done()
+			if err := PrefixLines(lw, stream, stream.Prefix); err != nil {
+				activity.Record(ctx, "Errored streaming the log of %v: %v", stream.Name, err)
+			}
+		}(stream)
+	}
+	go func() {
+		wg.Wait()
+		activity.Record(ctx, "Closing the log of %v: %v", name, w.Close())
+	}()
+	return plugin.CleanupReader{ReadCloser: r, Cleanup: func() { CloseAll(streams) }}
+}
+
+type lockedWriter struct {
+	mux sync.Mutex
+	w   io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mux.Lock()
+	defer lw.mux.Unlock()
+	return lw.w.Write(p)
+}
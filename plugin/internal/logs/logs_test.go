@@ -0,0 +1,56 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixLines(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, PrefixLines(&buf, strings.NewReader("one\ntwo\n"), "web | "))
+	assert.Equal(t, "web | one\nweb | two\n", buf.String())
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMerge(t *testing.T) {
+	web := &closeRecorder{Reader: strings.NewReader("one\ntwo\n")}
+	db := &closeRecorder{Reader: strings.NewReader("three\n")}
+	rdr := Merge(context.Background(), "shop", []Stream{
+		{ReadCloser: web, Name: "web", Prefix: "web | "},
+		{ReadCloser: db, Name: "db", Prefix: "db  | "},
+	})
+
+	content, err := ioutil.ReadAll(rdr)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{"db  | three", "web | one", "web | two"}, lines)
+
+	assert.False(t, web.closed)
+	assert.NoError(t, rdr.Close())
+	assert.True(t, web.closed)
+	assert.True(t, db.closed)
+}
+
+func TestCloseAll_SkipsUnopenedStreams(t *testing.T) {
+	web := &closeRecorder{Reader: strings.NewReader("")}
+	CloseAll([]Stream{{ReadCloser: web}, {}})
+	assert.True(t, web.closed)
+}
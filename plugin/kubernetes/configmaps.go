@@ -0,0 +1,285 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/volume"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// dataKey returns the data key of a volume path. The keys are files in the volume's root.
+func dataKey(path string) (string, error) {
+	key := strings.TrimPrefix(path, "/")
+	if key == "" || strings.Contains(key, "/") {
+		return "", fmt.Errorf("%v is not a data key", path)
+	}
+	return key, nil
+}
+
+// dataDirMap returns a DirMap that contains a file for each key. sizes maps each key to
+// the size of its value.
+func dataDirMap(sizes map[string]int, mode os.FileMode, mtime time.Time) volume.DirMap {
+	children := make(volume.Children, len(sizes))
+	for key, size := range sizes {
+		attr := plugin.EntryAttributes{}
+		attr.
+			SetMode(mode).
+			SetSize(uint64(size)).
+			SetCrtime(mtime).
+			SetMtime(mtime).
+			SetCtime(mtime).
+			SetAtime(mtime)
+		children[key] = attr
+	}
+	return volume.DirMap{volume.RootPath: children}
+}
+
+// dataObject tracks the resource version of a ConfigMap or a Secret. Updates are made
+// against the version that was last seen so that they don't overwrite changes that were
+// made since, which is the optimistic concurrency that Kubernetes supports.
+type dataObject struct {
+	mux             sync.Mutex
+	resourceVersion string
+}
+
+func (d *dataObject) seen() string {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.resourceVersion
+}
+
+func (d *dataObject) see(resourceVersion string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.resourceVersion = resourceVersion
+}
+
+// updateError explains a conflict, which means that the object was changed by
+// someone else since it was last seen.
+func updateError(kind string, ns string, name string, err error) error {
+	if k8serrors.IsConflict(err) {
+		return fmt.Errorf(
+			"the %v %v/%v was changed since it was listed, so the update was rejected to avoid overwriting the change. List it again to get the latest content, then retry",
+			kind, ns, name,
+		)
+	}
+	return err
+}
+
+type configMapsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	ns     string
+}
+
+func newConfigMapsDir(ns *namespace) *configMapsDir {
+	cs := &configMapsDir{
+		EntryBase: plugin.NewEntry("configmaps"),
+	}
+	cs.client = ns.client
+	cs.ns = ns.Name()
+	return cs
+}
+
+func (cs *configMapsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(cs, "configmaps").IsSingleton()
+}
+
+func (cs *configMapsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&configMap{}).Schema(),
+	}
+}
+
+func (cs *configMapsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	configMapList, err := cs.client.CoreV1().ConfigMaps(cs.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(configMapList.Items))
+	for i := range configMapList.Items {
+		entries[i] = newConfigMap(cs.client, &configMapList.Items[i])
+	}
+	return entries, nil
+}
+
+// configMap presents a ConfigMap's keys as files. It implements volume.Interface so
+// that writing a key that doesn't exist adds it.
+type configMap struct {
+	plugin.EntryBase
+	dataObject
+	client  k8s.Interface
+	ns      string
+	created time.Time
+}
+
+func newConfigMap(client k8s.Interface, cm *corev1.ConfigMap) *configMap {
+	c := &configMap{
+		EntryBase: plugin.NewEntry(cm.Name),
+	}
+	c.client = client
+	c.ns = cm.Namespace
+	c.created = cm.CreationTimestamp.Time
+	c.see(cm.ResourceVersion)
+	c.SetTTLOf(plugin.ListOp, volume.ListTTL)
+	c.
+		SetPartialMetadata(cm).
+		Attributes().
+		SetCrtime(c.created).
+		SetAtime(c.created)
+	return c
+}
+
+func (c *configMap) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(c, "configmap").
+		SetDescription(configMapDescription).
+		SetPartialMetadataSchema(corev1.ConfigMap{})
+}
+
+func (c *configMap) ChildSchemas() []*plugin.EntrySchema {
+	return volume.ChildSchemas()
+}
+
+func (c *configMap) List(ctx context.Context) ([]plugin.Entry, error) {
+	return volume.List(ctx, c)
+}
+
+func (c *configMap) Delete(ctx context.Context) (bool, error) {
+	err := c.client.CoreV1().ConfigMaps(c.ns).Delete(ctx, c.Name(), metav1.DeleteOptions{})
+	return true, err
+}
+
+func (c *configMap) get(ctx context.Context) (*corev1.ConfigMap, error) {
+	return c.client.CoreV1().ConfigMaps(c.ns).Get(ctx, c.Name(), metav1.GetOptions{})
+}
+
+// update updates the ConfigMap from the version that was last seen.
+func (c *configMap) update(ctx context.Context, cm *corev1.ConfigMap) error {
+	cm.ResourceVersion = c.seen()
+	updated, err := c.client.CoreV1().ConfigMaps(c.ns).Update(ctx, cm, metav1.UpdateOptions{FieldManager: fieldManager})
+	if err != nil {
+		return updateError("ConfigMap", c.ns, c.Name(), err)
+	}
+	c.see(updated.ResourceVersion)
+	return nil
+}
+
+func (c *configMap) VolumeList(ctx context.Context, path string) (volume.DirMap, error) {
+	cm, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.see(cm.ResourceVersion)
+
+	sizes := make(map[string]int)
+	for key, value := range cm.Data {
+		sizes[key] = len(value)
+	}
+	for key, value := range cm.BinaryData {
+		sizes[key] = len(value)
+	}
+	return dataDirMap(sizes, 0644, c.created), nil
+}
+
+func (c *configMap) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	key, err := dataKey(path)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := cm.Data[key]; ok {
+		return []byte(value), nil
+	}
+	if value, ok := cm.BinaryData[key]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("the ConfigMap %v/%v does not have the %v key", c.ns, c.Name(), key)
+}
+
+// VolumeStream returns the key's current value. The ConfigMap's keys aren't logs,
+// so there's nothing to follow.
+func (c *configMap) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	content, err := c.VolumeRead(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// VolumeWrite sets the key's value. New keys are added to the ConfigMap's data, or to
+// its binary data if the value isn't valid UTF-8 like Kubernetes requires.
+func (c *configMap) VolumeWrite(ctx context.Context, path string, b []byte, _ os.FileMode) error {
+	key, err := dataKey(path)
+	if err != nil {
+		return err
+	}
+	cm, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, isBinary := cm.BinaryData[key]
+	if _, isData := cm.Data[key]; !isData && !isBinary {
+		isBinary = !utf8.Valid(b)
+	}
+	if isBinary {
+		if cm.BinaryData == nil {
+			cm.BinaryData = make(map[string][]byte)
+		}
+		cm.BinaryData[key] = b
+	} else {
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[key] = string(b)
+	}
+	activity.Record(ctx, "Writing the %v key of ConfigMap %v/%v", key, c.ns, c.Name())
+	return c.update(ctx, cm)
+}
+
+// VolumeDelete removes the key from the ConfigMap.
+func (c *configMap) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	key, err := dataKey(path)
+	if err != nil {
+		return false, err
+	}
+	cm, err := c.get(ctx)
+	if err != nil {
+		return false, err
+	}
+	delete(cm.Data, key)
+	delete(cm.BinaryData, key)
+	activity.Record(ctx, "Removing the %v key of ConfigMap %v/%v", key, c.ns, c.Name())
+	if err := c.update(ctx, cm); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+const configMapDescription = `
+This is a Kubernetes ConfigMap. It contains a file for each of its keys.
+Writing a file updates the key's value, and deleting a file removes the key.
+Writing a key that doesn't exist adds it.
+
+Updates are rejected if the ConfigMap was changed since it was listed, so
+that they don't overwrite someone else's changes. List the ConfigMap again
+to get the latest content, then retry the update.
+`
@@ -0,0 +1,246 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/plugin"
+	plugintest "github.com/puppetlabs/wash/plugin/test"
+	"github.com/puppetlabs/wash/volume"
+	"github.com/stretchr/testify/suite"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	restfake "k8s.io/client-go/rest/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeClientset wraps the fake clientset so that CoreV1's REST client is
+// non-nil. Exec uses the REST client, so exec requests go to the (unreachable)
+// server in testdata/kubeconfig instead of panicking.
+type fakeClientset struct {
+	*fake.Clientset
+	restClient rest.Interface
+}
+
+func (c *fakeClientset) CoreV1() typedv1.CoreV1Interface {
+	return fakeCoreV1{c.Clientset.CoreV1(), c.restClient}
+}
+
+type fakeCoreV1 struct {
+	typedv1.CoreV1Interface
+	restClient rest.Interface
+}
+
+func (c fakeCoreV1) RESTClient() rest.Interface {
+	return c.restClient
+}
+
+func (c fakeCoreV1) Pods(namespace string) typedv1.PodInterface {
+	return fakePods{c.CoreV1Interface.Pods(namespace)}
+}
+
+// fakePods serves pod logs, which aren't supported by the fake clientset
+type fakePods struct {
+	typedv1.PodInterface
+}
+
+func (p fakePods) GetLogs(name string, opts *corev1.PodLogOptions) *rest.Request {
+	client := &restfake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Resp: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("GET / 200\n")),
+		},
+	}
+	return client.Get()
+}
+
+// newFakePod returns a running pod that's owned by the given controller, if any.
+func newFakePod(name string, owner types.UID) *corev1.Pod {
+	created := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	pd := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: created},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "nginx",
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: created}},
+			}},
+		},
+	}
+	if owner != "" {
+		pd.OwnerReferences = []metav1.OwnerReference{{UID: owner}}
+	}
+	return pd
+}
+
+func newFakeClientset(cfg *rest.Config) (k8s.Interface, error) {
+	clientset, err := k8s.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	created := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	meta := func(name string, uid types.UID, owner types.UID) metav1.ObjectMeta {
+		m := metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid, CreationTimestamp: created}
+		if owner != "" {
+			m.OwnerReferences = []metav1.OwnerReference{{UID: owner}}
+		}
+		return m
+	}
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", CreationTimestamp: created}},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default", CreationTimestamp: created},
+		},
+		// The web deployment owns the web pod through a replica set
+		&appsv1.Deployment{ObjectMeta: meta("web", "web-deployment", "")},
+		&appsv1.ReplicaSet{ObjectMeta: meta("web-5d4f", "web-replicaset", "web-deployment")},
+		newFakePod("web", "web-replicaset"),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", CreationTimestamp: created}},
+		&corev1.ConfigMap{
+			ObjectMeta: meta("settings", "", ""),
+			Data:       map[string]string{"color": "blue"},
+			BinaryData: map[string][]byte{"logo.png": {0x89, 'P', 'N', 'G'}},
+		},
+		&corev1.Secret{
+			ObjectMeta: meta("credentials", "", ""),
+			Data:       map[string][]byte{"password": []byte("hunter2")},
+		},
+		&corev1.Event{
+			ObjectMeta:     meta("web.1", "", ""),
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web"},
+			Type:           "Normal",
+			Reason:         "Started",
+			Message:        "Started container nginx",
+			LastTimestamp:  created,
+		},
+		&appsv1.StatefulSet{ObjectMeta: meta("db", "db-statefulset", "")},
+		newFakePod("db-0", "db-statefulset"),
+		&appsv1.DaemonSet{ObjectMeta: meta("agent", "agent-daemonset", "")},
+		newFakePod("agent-x1", "agent-daemonset"),
+		// The backup cron job owns the backup-1-abcde pod through a job
+		&batchv1beta1.CronJob{
+			ObjectMeta: meta("backup", "backup-cronjob", ""),
+			Spec: batchv1beta1.CronJobSpec{
+				Schedule: "@daily",
+				JobTemplate: batchv1beta1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: newFakePod("", "").Spec}},
+				},
+			},
+		},
+		&batchv1.Job{ObjectMeta: meta("backup-1", "backup-job", "backup-cronjob")},
+		newFakePod("backup-1-abcde", "backup-job"),
+	}
+	fakeset := fake.NewSimpleClientset(objects...)
+	fakeset.Resources = fakeResources
+	// The suite reaches owned pods through both the pods directory and their
+	// controllers, so it deletes them twice.
+	fakeset.PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteAction)
+		_, err := fakeset.Tracker().Get(del.GetResource(), del.GetNamespace(), del.GetName())
+		return k8serrors.IsNotFound(err), nil, nil
+	})
+	// Evictions delete the pod.
+	fakeset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		return true, nil, fakeset.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+	})
+	return &fakeClientset{
+		Clientset:  fakeset,
+		restClient: clientset.CoreV1().RESTClient(),
+	}, nil
+}
+
+// fakeResources are the resources that are returned by the discovery API.
+// Pods, PVCs and ConfigMaps have kind-specific entries, so they're skipped by the
+// namespace.
+var fakeResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"get", "list", "delete"}},
+			{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: []string{"get"}},
+			{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", Namespaced: true, Verbs: []string{"get", "list"}},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"get", "list", "patch", "delete"}},
+			{Name: "namespaces", Kind: "Namespace", Verbs: []string{"get", "list", "delete"}},
+			{Name: "bindings", Kind: "Binding", Namespaced: true, Verbs: []string{"create"}},
+		},
+	},
+}
+
+// The fake dynamic client only supports unstructured objects.
+func newFakeDynamicClient(cfg *rest.Config) (dynamic.Interface, error) {
+	created := "2020-01-01T00:00:00Z"
+	return dynamicfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": "default", "creationTimestamp": created},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "settings", "namespace": "default", "creationTimestamp": created},
+			"data":       map[string]interface{}{"color": "blue"},
+		}},
+	), nil
+}
+
+func TestConformance(t *testing.T) {
+	kubeconfig, ok := os.LookupEnv("KUBECONFIG")
+	if err := os.Setenv("KUBECONFIG", "testdata/kubeconfig"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if ok {
+			os.Setenv("KUBECONFIG", kubeconfig)
+		} else {
+			os.Unsetenv("KUBECONFIG")
+		}
+	}()
+
+	suite.Run(t, &plugintest.Suite{
+		NewRoot: func() plugin.Root {
+			return &Root{newClientset: newFakeClientset, newDynamicClient: newFakeDynamicClient}
+		},
+		// Listing PVCs, container filesystems and nodes requires exec'ing
+		// commands, which the fake clientset doesn't support. Nodes are tested
+		// separately.
+		Prune: func(e plugin.Entry) bool {
+			switch e.(type) {
+			case *pvc, *volume.FS, *node:
+				return true
+			default:
+				return false
+			}
+		},
+		Destructive: true,
+	})
+}
@@ -15,7 +15,7 @@ import (
 type containerLogFile struct {
 	plugin.EntryBase
 	namespace, podName, containerName string
-	client                            *k8s.Clientset
+	client                            k8s.Interface
 }
 
 func newContainerLogFile(container *container) *containerLogFile {
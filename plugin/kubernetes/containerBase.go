@@ -4,17 +4,20 @@ import (
 	"context"
 	"io"
 
+	"github.com/pkg/errors"
 	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
 	corev1 "k8s.io/api/core/v1"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	k8exec "k8s.io/client-go/util/exec"
 )
 
 // A general purpose container object that implements executing commands.
 // If the pod contains only a single container, the container object may be omitted.
 type containerBase struct {
-	client    *k8s.Clientset
+	client    k8s.Interface
 	config    *rest.Config
 	pod       *corev1.Pod
 	container *corev1.Container
@@ -58,6 +61,42 @@ func (c *containerBase) newExecutor(ctx context.Context, cmd string, args []stri
 	return executor{ctx: ctx, exec: e, opts: opts, name: c.String()}, err
 }
 
+// exec runs the command asynchronously. done is called when the command finishes, if
+// it's non-nil.
+func (c *containerBase) exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions, done func()) (plugin.ExecCommand, error) {
+	execCmd := plugin.NewExecCommand(ctx)
+	executor, err := c.newExecutor(ctx, cmd, args, remotecommand.StreamOptions{
+		Stdout: execCmd.Stdout(),
+		Stderr: execCmd.Stderr(),
+		Stdin:  opts.Stdin,
+		Tty:    opts.Tty,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes.container.Exec request")
+	}
+
+	errHandler := func(err error) {
+		if err == nil {
+			execCmd.SetExitCode(0)
+		} else if exerr, ok := err.(k8exec.ExitError); ok {
+			execCmd.SetExitCode(exerr.ExitStatus())
+			err = nil
+		} else {
+			// Set the exit code error so that callers don't block
+			// when trying to retrieve the command's exit code
+			execCmd.SetExitCodeErr(err)
+		}
+		execCmd.CloseStreamsWithError(err)
+		if done != nil {
+			done()
+		}
+	}
+
+	cleanup := executor.AsyncStream(errHandler)
+	execCmd.SetStopFunc(cleanup)
+	return execCmd, nil
+}
+
 func (c *containerBase) String() string {
 	s := c.pod.Namespace + "/" + c.pod.Name
 	if c.container != nil {
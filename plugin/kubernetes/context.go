@@ -6,22 +6,30 @@ import (
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type k8context struct {
 	plugin.EntryBase
-	client    *k8s.Clientset
+	client    k8s.Interface
+	dynamic   dynamic.Interface
+	discovery discovery.CachedDiscoveryInterface
 	config    *rest.Config
 	defaultns string
 }
 
-func newK8Context(name string, client *k8s.Clientset, config *rest.Config, defaultns string) *k8context {
+func newK8Context(name string, client k8s.Interface, dynamic dynamic.Interface, config *rest.Config, defaultns string) *k8context {
 	context := &k8context{
 		EntryBase: plugin.NewEntry(name),
 	}
 	context.client = client
+	context.dynamic = dynamic
+	// Every namespace needs the available resources, so cache them.
+	context.discovery = memory.NewMemCacheClient(client.Discovery())
 	context.config = config
 	context.defaultns = defaultns
 	return context
@@ -36,10 +44,14 @@ func (c *k8context) Schema() *plugin.EntrySchema {
 func (c *k8context) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&namespace{}).Schema(),
+		(&clusterDir{}).Schema(),
 	}
 }
 
 func (c *k8context) List(ctx context.Context) ([]plugin.Entry, error) {
+	// Refresh the available resources so that new CRDs show up.
+	c.discovery.Invalidate()
+
 	nsi := c.client.CoreV1().Namespaces()
 	nsList, err := nsi.List(ctx, metav1.ListOptions{})
 	if err != nil {
@@ -48,17 +60,18 @@ func (c *k8context) List(ctx context.Context) ([]plugin.Entry, error) {
 		if err != nil {
 			activity.Record(ctx, "Error loading default namespace, metadata will not be available: %v", err)
 		}
-		return []plugin.Entry{newNamespace(c.defaultns, ns, c.client, c.config)}, nil
+		return []plugin.Entry{newNamespace(c.defaultns, ns, c), newClusterDir(c)}, nil
 	}
 
 	namespaces := make([]plugin.Entry, len(nsList.Items))
 	for i, ns := range nsList.Items {
-		namespaces[i] = newNamespace(ns.Name, &ns, c.client, c.config)
+		namespaces[i] = newNamespace(ns.Name, &ns, c)
 	}
 	activity.Record(ctx, "Listing namespaces: %+v", namespaces)
-	return namespaces, nil
+	return append(namespaces, newClusterDir(c)), nil
 }
 
 const contextDescription = `
-This is a Kubernetes context.
+This is a Kubernetes context. It contains the cluster's namespaces, and a
+_cluster directory with the cluster-scoped resources.
 `
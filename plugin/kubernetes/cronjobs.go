@@ -0,0 +1,146 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type cronJobsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	ns     string
+}
+
+func newCronJobsDir(ns *namespace) *cronJobsDir {
+	cs := &cronJobsDir{
+		EntryBase: plugin.NewEntry("cronjobs"),
+	}
+	cs.client = ns.client
+	cs.config = ns.config
+	cs.ns = ns.Name()
+	return cs
+}
+
+func (cs *cronJobsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(cs, "cronjobs").IsSingleton()
+}
+
+func (cs *cronJobsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&cronJob{}).Schema(),
+	}
+}
+
+func (cs *cronJobsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	cronJobList, err := cs.client.BatchV1beta1().CronJobs(cs.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(cronJobList.Items))
+	for i := range cronJobList.Items {
+		entries[i] = newCronJob(&cronJobList.Items[i], cs.client, cs.config)
+	}
+	return entries, nil
+}
+
+type cronJob struct {
+	workload
+}
+
+func newCronJob(c *batchv1beta1.CronJob, client k8s.Interface, config *rest.Config) *cronJob {
+	cj := &cronJob{
+		workload: newWorkload(c, client, config),
+	}
+	cj.deleteFn = client.BatchV1beta1().CronJobs(c.Namespace).Delete
+	cj.SetPartialMetadata(c)
+	return cj
+}
+
+func (c *cronJob) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(c, "cronjob").
+		SetDescription(cronJobDescription).
+		SetPartialMetadataSchema(batchv1beta1.CronJob{}).
+		AddSignal("pause", "Suspends the cron job's schedule. Running jobs aren't affected").
+		AddSignal("resume", "Resumes the cron job's schedule").
+		AddSignal("trigger", "Runs the cron job now. Equivalent to 'kubectl create job --from=cronjob/<name>'")
+}
+
+func (c *cronJob) Signal(ctx context.Context, signal string) error {
+	patch := func(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) error {
+		_, err := c.client.BatchV1beta1().CronJobs(c.ns).Patch(ctx, name, pt, data, opts)
+		return err
+	}
+	switch signal {
+	case "pause":
+		return c.patch(ctx, patch, signal, specPatch("suspend", true))
+	case "resume":
+		return c.patch(ctx, patch, signal, specPatch("suspend", false))
+	case "trigger":
+		return c.trigger(ctx)
+	}
+	return fmt.Errorf("unsupported signal %v", signal)
+}
+
+// maxJobNameLength is the longest job name that's valid in a label, which the
+// job controller requires.
+const maxJobNameLength = 63
+
+// trigger creates a job from the cron job's template like kubectl does. The job is
+// owned by the cron job so that it's listed with the cron job's other jobs.
+func (c *cronJob) trigger(ctx context.Context) error {
+	cj, err := c.client.BatchV1beta1().CronJobs(c.ns).Get(ctx, c.Name(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	suffix := "-manual-" + rand.String(5)
+	prefix := cj.Name
+	if len(prefix)+len(suffix) > maxJobNameLength {
+		prefix = prefix[:maxJobNameLength-len(suffix)]
+	}
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for k, v := range cj.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	controller := true
+	jb := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        prefix + suffix,
+			Namespace:   c.ns,
+			Labels:      cj.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: batchv1beta1.SchemeGroupVersion.String(),
+				Kind:       "CronJob",
+				Name:       cj.Name,
+				UID:        cj.UID,
+				Controller: &controller,
+			}},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}
+	jb, err = c.client.BatchV1().Jobs(c.ns).Create(ctx, jb, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	activity.Record(ctx, "Triggered %v, which created job %v", c, jb.Name)
+	return nil
+}
+
+const cronJobDescription = `
+This is a Kubernetes cron job. It contains the pods of the jobs that it
+created, and a log file that aggregates their logs. Use the trigger signal
+to run it now, and the pause and resume signals to suspend its schedule.
+`
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puppetlabs/wash/plugin"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type daemonSetsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	ns     string
+}
+
+func newDaemonSetsDir(ns *namespace) *daemonSetsDir {
+	ds := &daemonSetsDir{
+		EntryBase: plugin.NewEntry("daemonsets"),
+	}
+	ds.client = ns.client
+	ds.config = ns.config
+	ds.ns = ns.Name()
+	return ds
+}
+
+func (ds *daemonSetsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(ds, "daemonsets").IsSingleton()
+}
+
+func (ds *daemonSetsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&daemonSet{}).Schema(),
+	}
+}
+
+func (ds *daemonSetsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	daemonSetList, err := ds.client.AppsV1().DaemonSets(ds.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(daemonSetList.Items))
+	for i := range daemonSetList.Items {
+		entries[i] = newDaemonSet(&daemonSetList.Items[i], ds.client, ds.config)
+	}
+	return entries, nil
+}
+
+type daemonSet struct {
+	workload
+}
+
+func newDaemonSet(d *appsv1.DaemonSet, client k8s.Interface, config *rest.Config) *daemonSet {
+	ds := &daemonSet{
+		workload: newWorkload(d, client, config),
+	}
+	ds.deleteFn = client.AppsV1().DaemonSets(d.Namespace).Delete
+	ds.SetPartialMetadata(d)
+	return ds
+}
+
+func (d *daemonSet) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "daemonset").
+		SetDescription(daemonSetDescription).
+		SetPartialMetadataSchema(appsv1.DaemonSet{}).
+		AddSignal("restart", "Restarts the daemon set's pods. Equivalent to 'kubectl rollout restart'")
+}
+
+func (d *daemonSet) Signal(ctx context.Context, signal string) error {
+	if signal != "restart" {
+		return fmt.Errorf("unsupported signal %v", signal)
+	}
+	return d.patch(ctx, func(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) error {
+		_, err := d.client.AppsV1().DaemonSets(d.ns).Patch(ctx, name, pt, data, opts)
+		return err
+	}, signal, restartPatch())
+}
+
+const daemonSetDescription = `
+This is a Kubernetes daemon set. It contains the pods that it runs on the
+cluster's nodes, and a log file that aggregates their logs. Deleting it also
+deletes its pods.
+`
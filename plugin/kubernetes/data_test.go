@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"testing"
+
+	"github.com/puppetlabs/wash/volume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// checkResourceVersions makes the clientset reject updates to stale objects and bump the
+// resource version of updated objects, like the API server. The fake clientset does
+// neither.
+func checkResourceVersions(t *testing.T, client *fakeClientset) {
+	client.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update := action.(k8stesting.UpdateAction)
+		obj, err := meta.Accessor(update.GetObject())
+		require.NoError(t, err)
+		current, err := client.Tracker().Get(action.GetResource(), action.GetNamespace(), obj.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		currentObj, err := meta.Accessor(current)
+		require.NoError(t, err)
+		if obj.GetResourceVersion() != currentObj.GetResourceVersion() {
+			return true, nil, k8serrors.NewConflict(action.GetResource().GroupResource(), obj.GetName(), nil)
+		}
+		version, _ := strconv.Atoi(currentObj.GetResourceVersion())
+		obj.SetResourceVersion(strconv.Itoa(version + 1))
+		return true, update.GetObject(), client.Tracker().Update(action.GetResource(), update.GetObject(), action.GetNamespace())
+	})
+}
+
+func TestConfigMap(t *testing.T) {
+	client := newTestClientset(t)
+	checkResourceVersions(t, client)
+	ctx := context.Background()
+	configMaps := client.CoreV1().ConfigMaps("default")
+	cm, err := configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	require.NoError(t, err)
+	c := newConfigMap(client, cm)
+
+	dirmap, err := c.VolumeList(ctx, volume.RootPath)
+	require.NoError(t, err)
+	if assert.Contains(t, dirmap, volume.RootPath) {
+		assert.Len(t, dirmap[volume.RootPath], 2)
+		attr := dirmap[volume.RootPath]["color"]
+		assert.Equal(t, uint64(4), attr.Size())
+	}
+	content, err := c.VolumeRead(ctx, "/color")
+	require.NoError(t, err)
+	assert.Equal(t, "blue", string(content))
+	content, err = c.VolumeRead(ctx, "/logo.png")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, content)
+
+	// Update a key, then add text and binary keys
+	require.NoError(t, c.VolumeWrite(ctx, "/color", []byte("red"), 0644))
+	require.NoError(t, c.VolumeWrite(ctx, "/size", []byte("large"), 0644))
+	require.NoError(t, c.VolumeWrite(ctx, "/icon.ico", []byte{0xff, 0xfe}, 0644))
+	deleted, err := c.VolumeDelete(ctx, "/logo.png")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	cm, err = configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"color": "red", "size": "large"}, cm.Data)
+	assert.Equal(t, map[string][]byte{"icon.ico": {0xff, 0xfe}}, cm.BinaryData)
+
+	_, err = c.VolumeRead(ctx, "/logo.png")
+	assert.EqualError(t, err, "the ConfigMap default/settings does not have the logo.png key")
+}
+
+func TestConfigMapConflict(t *testing.T) {
+	client := newTestClientset(t)
+	checkResourceVersions(t, client)
+	ctx := context.Background()
+	configMaps := client.CoreV1().ConfigMaps("default")
+	cm, err := configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	require.NoError(t, err)
+	c := newConfigMap(client, cm)
+
+	// Someone else changes the ConfigMap after it was listed
+	cm.Data["color"] = "green"
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	err = c.VolumeWrite(ctx, "/color", []byte("red"), 0644)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the ConfigMap default/settings was changed since it was listed")
+	}
+	_, err = c.VolumeDelete(ctx, "/color")
+	assert.Error(t, err)
+	cm, err = configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "green", cm.Data["color"])
+
+	// Listing it again picks up the change
+	_, err = c.VolumeList(ctx, volume.RootPath)
+	require.NoError(t, err)
+	require.NoError(t, c.VolumeWrite(ctx, "/color", []byte("red"), 0644))
+}
+
+func TestSecret(t *testing.T) {
+	client := newTestClientset(t)
+	checkResourceVersions(t, client)
+	ctx := context.Background()
+	secrets := client.CoreV1().Secrets("default")
+	sc, err := secrets.Get(ctx, "credentials", metav1.GetOptions{})
+	require.NoError(t, err)
+	sc.Annotations = map[string]string{
+		corev1.LastAppliedConfigAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`,
+		"owner":                            "ops",
+	}
+	s := newSecret(client, sc)
+	metadata, err := s.Metadata(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, metadata, "data")
+	annotations := metadata["metadata"].(map[string]interface{})["annotations"]
+	assert.Equal(t, map[string]interface{}{"owner": "ops"}, annotations)
+	assert.Contains(t, sc.Annotations, corev1.LastAppliedConfigAnnotation)
+
+	content, err := s.VolumeRead(ctx, "/password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(content))
+	rdr, err := s.VolumeStream(ctx, "/password")
+	require.NoError(t, err)
+	content, err = ioutil.ReadAll(rdr)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(content))
+
+	require.NoError(t, s.VolumeWrite(ctx, "/username", []byte("admin"), 0600))
+	_, err = s.VolumeDelete(ctx, "/password")
+	require.NoError(t, err)
+	sc, err = secrets.Get(ctx, "credentials", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"username": []byte("admin")}, sc.Data)
+
+	sc.Data["username"] = []byte("root")
+	_, err = secrets.Update(ctx, sc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	err = s.VolumeWrite(ctx, "/username", []byte("guest"), 0600)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "the Secret default/credentials was changed since it was listed")
+	}
+}
@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puppetlabs/wash/plugin"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type deploymentsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	ns     string
+}
+
+func newDeploymentsDir(ns *namespace) *deploymentsDir {
+	ds := &deploymentsDir{
+		EntryBase: plugin.NewEntry("deployments"),
+	}
+	ds.client = ns.client
+	ds.config = ns.config
+	ds.ns = ns.Name()
+	return ds
+}
+
+func (ds *deploymentsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(ds, "deployments").IsSingleton()
+}
+
+func (ds *deploymentsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&deployment{}).Schema(),
+	}
+}
+
+func (ds *deploymentsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	deploymentList, err := ds.client.AppsV1().Deployments(ds.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(deploymentList.Items))
+	for i := range deploymentList.Items {
+		entries[i] = newDeployment(&deploymentList.Items[i], ds.client, ds.config)
+	}
+	return entries, nil
+}
+
+type deployment struct {
+	workload
+}
+
+func newDeployment(d *appsv1.Deployment, client k8s.Interface, config *rest.Config) *deployment {
+	dp := &deployment{
+		workload: newWorkload(d, client, config),
+	}
+	dp.deleteFn = client.AppsV1().Deployments(d.Namespace).Delete
+	dp.SetPartialMetadata(d)
+	return dp
+}
+
+func (d *deployment) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "deployment").
+		SetDescription(deploymentDescription).
+		SetPartialMetadataSchema(appsv1.Deployment{}).
+		AddSignal("restart", "Restarts the deployment's pods. Equivalent to 'kubectl rollout restart'").
+		AddSignalGroup("scale", scaleSignalRegex.String(), scaleSignalDescription).
+		AddSignal("pause", "Pauses the deployment's rollouts. Equivalent to 'kubectl rollout pause'").
+		AddSignal("resume", "Resumes the deployment's rollouts. Equivalent to 'kubectl rollout resume'")
+}
+
+func (d *deployment) Signal(ctx context.Context, signal string) error {
+	patch := func(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) error {
+		_, err := d.client.AppsV1().Deployments(d.ns).Patch(ctx, name, pt, data, opts)
+		return err
+	}
+	switch signal {
+	case "restart":
+		return d.patch(ctx, patch, signal, restartPatch())
+	case "pause":
+		return d.patch(ctx, patch, signal, specPatch("paused", true))
+	case "resume":
+		return d.patch(ctx, patch, signal, specPatch("paused", false))
+	}
+	if scale, ok := scalePatch(signal); ok {
+		return d.patch(ctx, patch, signal, scale)
+	}
+	return fmt.Errorf("unsupported signal %v", signal)
+}
+
+const deploymentDescription = `
+This is a Kubernetes deployment. It contains the pods that it owns through
+its replica sets, and a log file that aggregates their logs. Deleting it
+also deletes its pods.
+`
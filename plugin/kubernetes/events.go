@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// eventsFile contains a namespace's events. If ns is empty, then it contains the
+// events of all namespaces.
+type eventsFile struct {
+	plugin.EntryBase
+	client k8s.Interface
+	ns     string
+}
+
+func newEventsFile(client k8s.Interface, ns string) *eventsFile {
+	ef := &eventsFile{
+		EntryBase: plugin.NewEntry("events"),
+	}
+	ef.client = client
+	ef.ns = ns
+	// Events happen constantly.
+	ef.DisableCachingFor(plugin.ReadOp)
+	return ef
+}
+
+func (ef *eventsFile) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(ef, "events").
+		SetDescription(eventsFileDescription).
+		IsSingleton()
+}
+
+// lastSeen returns the last time that the event happened.
+func lastSeen(e *corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+// formatEvent formats the event as a single line, similar to 'kubectl get events'. The
+// object's namespace is included when listing the events of all namespaces.
+func (ef *eventsFile) formatEvent(e *corev1.Event) string {
+	object := e.InvolvedObject.Name
+	if ef.ns == "" && e.InvolvedObject.Namespace != "" {
+		object = e.InvolvedObject.Namespace + "/" + object
+	}
+	line := fmt.Sprintf(
+		"%v %v %v %v/%v: %v",
+		lastSeen(e).Format(time.RFC3339),
+		e.Type,
+		e.Reason,
+		e.InvolvedObject.Kind,
+		object,
+		e.Message,
+	)
+	if e.Count > 1 {
+		line += fmt.Sprintf(" (x%v)", e.Count)
+	}
+	return line + "\n"
+}
+
+// events returns the current events, oldest first, and the list's resource version.
+func (ef *eventsFile) events(ctx context.Context) ([]corev1.Event, string, error) {
+	eventList, err := ef.client.CoreV1().Events(ef.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	events := eventList.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return lastSeen(&events[i]).Before(lastSeen(&events[j]))
+	})
+	return events, eventList.ResourceVersion, nil
+}
+
+// Read returns the recent events. The API server only keeps events for a short time,
+// an hour by default.
+func (ef *eventsFile) Read(ctx context.Context) ([]byte, error) {
+	events, _, err := ef.events(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for i := range events {
+		buf.WriteString(ef.formatEvent(&events[i]))
+	}
+	return buf.Bytes(), nil
+}
+
+// Stream returns the last 10 events, followed by new and updated events as they happen.
+func (ef *eventsFile) Stream(ctx context.Context) (io.ReadCloser, error) {
+	events, resourceVersion, err := ef.events(ctx)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := ef.client.CoreV1().Events(ef.ns).Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) > 10 {
+		events = events[len(events)-10:]
+	}
+	r, w := io.Pipe()
+	go func() {
+		for i := range events {
+			if _, err := io.WriteString(w, ef.formatEvent(&events[i])); err != nil {
+				// The reader was closed
+				watcher.Stop()
+				return
+			}
+		}
+		for e := range watcher.ResultChan() {
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				event, ok := e.Object.(*corev1.Event)
+				if !ok {
+					continue
+				}
+				if _, err := io.WriteString(w, ef.formatEvent(event)); err != nil {
+					watcher.Stop()
+					return
+				}
+			case watch.Error:
+				err := fmt.Errorf("watching the events errored: %v", e.Object)
+				activity.Record(ctx, "Closing %v: %v", ef, err)
+				watcher.Stop()
+				w.CloseWithError(err)
+				return
+			}
+		}
+		activity.Record(ctx, "Closing %v: %v", ef, w.Close())
+	}()
+	return plugin.CleanupReader{ReadCloser: r, Cleanup: watcher.Stop}, nil
+}
+
+const eventsFileDescription = `
+These are the Kubernetes events, like the output of 'kubectl get events'.
+Reading it returns the recent events, oldest first; the API server only keeps
+events for an hour by default. Streaming it returns the last 10 events and
+follows new and updated events. The events file in the _cluster directory
+contains the events of all namespaces.
+`
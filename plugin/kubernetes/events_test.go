@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestEvent(name string, ns string, lastSeen time.Time, count int32) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: ns},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: ns, Name: "web"},
+		Type:           "Warning",
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+		LastTimestamp:  metav1.NewTime(lastSeen),
+		Count:          count,
+	}
+}
+
+func TestEventsFileRead(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+	_, err := client.CoreV1().Events("kube-system").Create(ctx, newTestEvent("web.2", "kube-system", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), 3), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	content, err := newEventsFile(client, "default").Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2020-01-01T00:00:00Z Normal Started Pod/web: Started container nginx\n", string(content))
+
+	// The cluster's events are sorted by when they were last seen and include the namespace
+	content, err = newEventsFile(client, "").Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2019-01-01T00:00:00Z Warning BackOff Pod/kube-system/web: Back-off restarting failed container (x3)\n"+
+		"2020-01-01T00:00:00Z Normal Started Pod/default/web: Started container nginx\n", string(content))
+}
+
+func TestEventsFileStream(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+
+	rdr, err := newEventsFile(client, "default").Stream(ctx)
+	require.NoError(t, err)
+	lines := bufio.NewScanner(rdr)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "2020-01-01T00:00:00Z Normal Started Pod/web: Started container nginx", lines.Text())
+
+	_, err = client.CoreV1().Events("default").Create(ctx, newTestEvent("web.2", "default", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), 2), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "2020-01-02T00:00:00Z Warning BackOff Pod/web: Back-off restarting failed container (x2)", lines.Text())
+	assert.NoError(t, rdr.Close())
+}
@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/plugin"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type jobsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	ns     string
+}
+
+func newJobsDir(ns *namespace) *jobsDir {
+	js := &jobsDir{
+		EntryBase: plugin.NewEntry("jobs"),
+	}
+	js.client = ns.client
+	js.config = ns.config
+	js.ns = ns.Name()
+	return js
+}
+
+func (js *jobsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(js, "jobs").IsSingleton()
+}
+
+func (js *jobsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&job{}).Schema(),
+	}
+}
+
+func (js *jobsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	jobList, err := js.client.BatchV1().Jobs(js.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(jobList.Items))
+	for i := range jobList.Items {
+		entries[i] = newJob(&jobList.Items[i], js.client, js.config)
+	}
+	return entries, nil
+}
+
+type job struct {
+	workload
+}
+
+func newJob(j *batchv1.Job, client k8s.Interface, config *rest.Config) *job {
+	jb := &job{
+		workload: newWorkload(j, client, config),
+	}
+	jb.deleteFn = client.BatchV1().Jobs(j.Namespace).Delete
+	jb.SetPartialMetadata(j)
+	return jb
+}
+
+func (j *job) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(j, "job").
+		SetDescription(jobDescription).
+		SetPartialMetadataSchema(batchv1.Job{})
+}
+
+const jobDescription = `
+This is a Kubernetes job. It contains the pods that ran it, and a log file
+that aggregates their logs. Deleting it also deletes its pods.
+`
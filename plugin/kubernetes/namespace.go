@@ -3,35 +3,66 @@ package kubernetes
 import (
 	"context"
 
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type namespace struct {
 	plugin.EntryBase
-	client    *k8s.Clientset
+	client    k8s.Interface
+	dynamic   dynamic.Interface
+	discovery discovery.DiscoveryInterface
 	config    *rest.Config
 	resources []plugin.Entry
 }
 
-func newNamespace(name string, meta *corev1.Namespace, c *k8s.Clientset, cfg *rest.Config) *namespace {
+func newNamespace(name string, meta *corev1.Namespace, c *k8context) *namespace {
 	ns := &namespace{
 		EntryBase: plugin.NewEntry(name),
 	}
-	ns.client = c
-	ns.config = cfg
+	ns.client = c.client
+	ns.dynamic = c.dynamic
+	ns.discovery = c.discovery
+	ns.config = c.config
 	ns.resources = []plugin.Entry{
 		newPodsDir(ns),
 		newPVCSDir(ns),
+		newConfigMapsDir(ns),
+		newSecretsDir(ns),
+		newDeploymentsDir(ns),
+		newStatefulSetsDir(ns),
+		newDaemonSetsDir(ns),
+		newJobsDir(ns),
+		newCronJobsDir(ns),
+		newEventsFile(ns.client, name),
 	}
 	// TODO: Figure out other attributes that we could set here, if any.
 	ns.SetPartialMetadata(meta)
 	return ns
 }
 
+// kindSpecificResources are the resource types that have kind-specific entries in a
+// namespace, so they don't need a generic directory.
+var kindSpecificResources = []schema.GroupResource{
+	{Resource: "pods"},
+	{Resource: "persistentvolumeclaims"},
+	{Resource: "configmaps"},
+	{Resource: "secrets"},
+	{Group: "apps", Resource: "deployments"},
+	{Group: "apps", Resource: "statefulsets"},
+	{Group: "apps", Resource: "daemonsets"},
+	{Group: "batch", Resource: "jobs"},
+	{Group: "batch", Resource: "cronjobs"},
+	{Resource: "events"},
+}
+
 func (n *namespace) Schema() *plugin.EntrySchema {
 	return plugin.
 		NewEntrySchema(n, "namespace").
@@ -43,11 +74,27 @@ func (n *namespace) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&podsDir{}).Schema(),
 		(&pvcsDir{}).Schema(),
+		(&configMapsDir{}).Schema(),
+		(&secretsDir{}).Schema(),
+		(&deploymentsDir{}).Schema(),
+		(&statefulSetsDir{}).Schema(),
+		(&daemonSetsDir{}).Schema(),
+		(&jobsDir{}).Schema(),
+		(&cronJobsDir{}).Schema(),
+		(&eventsFile{}).Schema(),
+		(&resourceDir{}).Schema(),
 	}
 }
 
+// List returns the kind-specific resources followed by a generic directory for each of
+// the remaining resource types.
 func (n *namespace) List(ctx context.Context) ([]plugin.Entry, error) {
-	return n.resources, nil
+	resources, err := newResourceDirs(ctx, n.discovery, n.dynamic, n.Name(), kindSpecificResources...)
+	if err != nil {
+		activity.Record(ctx, "Errored discovering the resources in namespace %v: %v", n.Name(), err)
+		return n.resources, nil
+	}
+	return append(append([]plugin.Entry{}, n.resources...), resources...), nil
 }
 
 func (n *namespace) Delete(ctx context.Context) (bool, error) {
@@ -56,5 +103,8 @@ func (n *namespace) Delete(ctx context.Context) (bool, error) {
 }
 
 const namespaceDescription = `
-This is a Kubernetes namespace.
+This is a Kubernetes namespace. It contains a directory for each of its
+resource types. Pods, persistent volume claims, ConfigMaps, Secrets and
+workload controllers like deployments have their own entries; the other types
+contain the YAML of their objects. The events file contains the namespace's events.
 `
@@ -0,0 +1,218 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/volume"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type nodesDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	// debugns is the namespace of the pods that are used to exec on the nodes.
+	debugns string
+}
+
+func newNodesDir(c *k8context) *nodesDir {
+	ns := &nodesDir{
+		EntryBase: plugin.NewEntry("nodes"),
+	}
+	ns.client = c.client
+	ns.config = c.config
+	ns.debugns = c.defaultns
+	return ns
+}
+
+func (ns *nodesDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(ns, "nodes").IsSingleton()
+}
+
+func (ns *nodesDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&node{}).Schema(),
+	}
+}
+
+func (ns *nodesDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	nodeList, err := ns.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(nodeList.Items))
+	for i := range nodeList.Items {
+		entries[i] = newNode(ns, &nodeList.Items[i])
+	}
+	return entries, nil
+}
+
+type node struct {
+	plugin.EntryBase
+	client  k8s.Interface
+	config  *rest.Config
+	debugns string
+}
+
+func newNode(ns *nodesDir, n *corev1.Node) *node {
+	nd := &node{
+		EntryBase: plugin.NewEntry(n.Name),
+	}
+	nd.client = ns.client
+	nd.config = ns.config
+	nd.debugns = ns.debugns
+	nd.
+		SetPartialMetadata(n).
+		Attributes().
+		SetCrtime(n.CreationTimestamp.Time).
+		SetAtime(n.CreationTimestamp.Time)
+	return nd
+}
+
+func (n *node) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(n, "node").
+		SetDescription(nodeDescription).
+		SetPartialMetadataSchema(corev1.Node{}).
+		AddSignal("cordon", "Marks the node as unschedulable. Equivalent to 'kubectl cordon'").
+		AddSignal("uncordon", "Marks the node as schedulable. Equivalent to 'kubectl uncordon'").
+		AddSignal("drain", "Cordons the node and evicts its pods. Equivalent to\n'kubectl drain --ignore-daemonsets --delete-local-data', except that it doesn't wait for the pods to terminate")
+}
+
+func (n *node) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+		(&volume.FS{}).Schema(),
+	}
+}
+
+func (n *node) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	nd, err := n.client.CoreV1().Nodes().Get(ctx, n.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return plugin.ToJSONObject(nd), nil
+}
+
+func (n *node) List(ctx context.Context) ([]plugin.Entry, error) {
+	meta, err := plugin.NewMetadataJSONFile(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	// Every exec creates a privileged debug pod, so only list the filesystem when fs is
+	// listed, and use a small maxdepth.
+	return []plugin.Entry{meta, volume.NewLazyFS("fs", n, 3)}, nil
+}
+
+// Exec runs the command on the host in a privileged debug pod, which is deleted when
+// the command finishes. The pod runs in the context's default namespace.
+func (n *node) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	podi := n.client.CoreV1().Pods(n.debugns)
+	tempPod, err := createNodeDebugContainer(ctx, podi, n.Name())
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		// Use a background context to ensure deletion happens even if context was cancelled.
+		activity.Record(ctx, "Deleted debug pod %v: %v", tempPod.pod.Name, tempPod.delete(context.Background()))
+	}
+	if err := tempPod.waitOnCreation(ctx); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	c := containerBase{client: n.client, config: n.config, pod: tempPod.pod}
+	// Run the command in the host's filesystem.
+	execCmd, err := c.exec(ctx, "chroot", append([]string{nodeDebugHostPath, cmd}, args...), opts, cleanup)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return execCmd, nil
+}
+
+func (n *node) Signal(ctx context.Context, signal string) error {
+	switch signal {
+	case "cordon":
+		return n.setUnschedulable(ctx, true)
+	case "uncordon":
+		return n.setUnschedulable(ctx, false)
+	case "drain":
+		if err := n.setUnschedulable(ctx, true); err != nil {
+			return err
+		}
+		return n.evictPods(ctx)
+	}
+	return fmt.Errorf("unsupported signal %v", signal)
+}
+
+func (n *node) setUnschedulable(ctx context.Context, unschedulable bool) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%v}}`, unschedulable))
+	_, err := n.client.CoreV1().Nodes().Patch(ctx, n.Name(), types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+	return err
+}
+
+// evictPods evicts the node's pods, except for the pods that are managed by daemon sets
+// and mirror pods, which would be recreated on the node anyway.
+func (n *node) evictPods(ctx context.Context) error {
+	podList, err := n.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + n.Name(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, pd := range podList.Items {
+		if pd.Spec.NodeName != n.Name() || isDaemonSetPod(&pd) || isMirrorPod(&pd) {
+			continue
+		}
+		if pd.Status.Phase == corev1.PodSucceeded || pd.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		activity.Record(ctx, "Evicting pod %v/%v from %v", pd.Namespace, pd.Name, n)
+		err := n.client.CoreV1().Pods(pd.Namespace).Evict(ctx, &policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pd.Name, Namespace: pd.Namespace},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v/%v: %v", pd.Namespace, pd.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("could not evict all of the node's pods: %v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func isDaemonSetPod(pd *corev1.Pod) bool {
+	for _, ref := range pd.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+func isMirrorPod(pd *corev1.Pod) bool {
+	_, ok := pd.Annotations[corev1.MirrorPodAnnotationKey]
+	return ok
+}
+
+const nodeDescription = `
+This is a Kubernetes node. Exec runs commands on the host in a privileged
+debug pod, like 'kubectl debug node/<node>'. The pod is created in the
+context's default namespace for each command and deleted when the command
+finishes, so commands take a few seconds to start. The fs directory uses the
+same mechanism to show the host's filesystem.
+
+Use the cordon, uncordon and drain signals to manage the node's scheduling.
+`
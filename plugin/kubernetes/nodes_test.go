@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeSignal(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+	// A daemon set's pod and a completed pod on the node aren't evicted
+	controller := true
+	agent := newFakePod("agent-x2", "")
+	agent.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Controller: &controller}}
+	completed := newFakePod("completed", "")
+	completed.Status.Phase = corev1.PodSucceeded
+	elsewhere := newFakePod("elsewhere", "")
+	elsewhere.Spec.NodeName = "node-2"
+	for _, pd := range []*corev1.Pod{agent, completed, elsewhere} {
+		_, err := client.CoreV1().Pods("default").Create(ctx, pd, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	nodes, err := newNodesDir(&k8context{client: client, defaultns: "default"}).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	nd := nodes[0].(*node)
+	isUnschedulable := func() bool {
+		n, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		return n.Spec.Unschedulable
+	}
+
+	require.NoError(t, nd.Signal(ctx, "cordon"))
+	assert.True(t, isUnschedulable())
+	require.NoError(t, nd.Signal(ctx, "uncordon"))
+	assert.False(t, isUnschedulable())
+
+	require.NoError(t, nd.Signal(ctx, "drain"))
+	assert.True(t, isUnschedulable())
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"agent-x2", "completed", "elsewhere"}, podNames(pods.Items))
+}
+
+func TestNodeList_CreatesNoPods(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	before, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+
+	nodes, err := newNodesDir(&k8context{client: client, defaultns: "default"}).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	entries, err := nodes[0].(*node).List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// Listing the fs directory would create a debug pod, but listing the node doesn't.
+	after, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, podNames(before.Items), podNames(after.Items))
+}
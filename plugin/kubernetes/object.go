@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// fieldManager identifies wash's changes in an object's managed fields.
+const fieldManager = "wash"
+
+// object is a YAML file that contains a Kubernetes object. Writing it applies
+// the new content with server-side apply.
+type object struct {
+	plugin.EntryBase
+	client    dynamic.ResourceInterface
+	name      string
+	namespace string
+	kind      string
+	version   string
+}
+
+func newObject(client dynamic.ResourceInterface, obj *unstructured.Unstructured) *object {
+	o := &object{
+		EntryBase: plugin.NewEntry(obj.GetName() + ".yaml"),
+	}
+	o.client = client
+	o.name = obj.GetName()
+	o.namespace = obj.GetNamespace()
+	o.kind = obj.GetKind()
+	o.version = obj.GetAPIVersion()
+
+	created := obj.GetCreationTimestamp().Time
+	o.
+		SetPartialMetadata(obj.Object).
+		Attributes().
+		SetCrtime(created).
+		SetMtime(created).
+		SetCtime(created).
+		SetAtime(created)
+	return o
+}
+
+func (o *object) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(o, "object").
+		SetDescription(objectDescription)
+}
+
+// Read returns the object as YAML. The managed fields are omitted because they're
+// noisy, and because server-side apply rejects them.
+func (o *object) Read(ctx context.Context) ([]byte, error) {
+	obj, err := o.client.Get(ctx, o.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	obj.SetManagedFields(nil)
+	return yaml.Marshal(obj.Object)
+}
+
+// Write applies the new content. Since YAML is a superset of JSON, the content can be
+// either. It must describe the same object.
+func (o *object) Write(ctx context.Context, b []byte) error {
+	content, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return fmt.Errorf("could not parse the new content of %v: %v", o.name, err)
+	}
+	var obj unstructured.Unstructured
+	if err := obj.UnmarshalJSON(content); err != nil {
+		return fmt.Errorf("could not parse the new content of %v: %v", o.name, err)
+	}
+
+	var mismatches []string
+	check := func(field, expected, actual string) {
+		if expected != actual {
+			mismatches = append(mismatches, fmt.Sprintf("%v is %q instead of %q", field, actual, expected))
+		}
+	}
+	check("metadata.name", o.name, obj.GetName())
+	check("metadata.namespace", o.namespace, obj.GetNamespace())
+	check("kind", o.kind, obj.GetKind())
+	if len(mismatches) > 0 {
+		return fmt.Errorf("the new content describes a different object: %v", strings.Join(mismatches, ", "))
+	}
+
+	obj.SetManagedFields(nil)
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	// Force the apply so that wash takes ownership of any fields that other managers
+	// set, like 'kubectl apply --server-side --force-conflicts'. Writing the file
+	// means that the user wants this content.
+	force := true
+	activity.Record(ctx, "Applying %v %v", o.kind, o.name)
+	_, err = o.client.Patch(ctx, o.name, types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	})
+	return err
+}
+
+func (o *object) Delete(ctx context.Context) (bool, error) {
+	err := o.client.Delete(ctx, o.name, metav1.DeleteOptions{})
+	return true, err
+}
+
+const objectDescription = `
+This is a Kubernetes object. Reading it returns the object as YAML, like
+'kubectl get -o yaml' but without the managed fields. Its metadata is the
+entire object, so you can filter objects with find's meta primary, e.g.
+'find -meta .spec.replicas +1'.
+
+Writing the file applies the new content with server-side apply, so you can
+edit it in place. The content can be YAML or JSON and must describe the same
+object. Deleting the file deletes the object.
+`
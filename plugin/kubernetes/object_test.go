@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func newTestObject() (*object, *dynamicfake.FakeDynamicClient) {
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":          "settings",
+			"namespace":     "default",
+			"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+		},
+		"data": map[string]interface{}{"color": "blue"},
+	}}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), cm)
+	return newObject(client.Resource(configMapsGVR).Namespace("default"), cm), client
+}
+
+func TestObjectRead(t *testing.T) {
+	obj, _ := newTestObject()
+	assert.Equal(t, "settings.yaml", obj.Name())
+
+	content, err := obj.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: v1
+data:
+  color: blue
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+`, string(content))
+}
+
+func TestObjectWrite(t *testing.T) {
+	obj, client := newTestObject()
+
+	// The fake client doesn't support server-side apply, so record the patch instead.
+	var patch k8stesting.PatchAction
+	client.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = action.(k8stesting.PatchAction)
+		return true, nil, nil
+	})
+
+	// JSON is valid YAML
+	content := `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings", "namespace": "default", "managedFields": [{"manager": "kubectl"}]}, "data": {"color": "red"}}`
+	require.NoError(t, obj.Write(context.Background(), []byte(content)))
+	require.NotNil(t, patch)
+	assert.Equal(t, "settings", patch.GetName())
+	assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+
+	var applied map[string]interface{}
+	require.NoError(t, json.Unmarshal(patch.GetPatch(), &applied))
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "settings", "namespace": "default"},
+		"data":       map[string]interface{}{"color": "red"},
+	}, applied)
+}
+
+func TestObjectWriteRejectsOtherObjects(t *testing.T) {
+	obj, _ := newTestObject()
+
+	err := obj.Write(context.Background(), []byte(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: other
+  namespace: default
+`))
+	assert.EqualError(t, err, `the new content describes a different object: metadata.name is "other" instead of "settings", kind is "Secret" instead of "ConfigMap"`)
+
+	assert.Error(t, obj.Write(context.Background(), []byte("{")))
+}
+
+func TestObjectDelete(t *testing.T) {
+	obj, client := newTestObject()
+
+	deleted, err := obj.Delete(context.Background())
+	assert.True(t, deleted)
+	require.NoError(t, err)
+	_, err = client.Resource(configMapsGVR).Namespace("default").Get(context.Background(), "settings", metav1.GetOptions{})
+	assert.Error(t, err)
+}
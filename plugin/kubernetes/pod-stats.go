@@ -0,0 +1,191 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// podMetrics is the subset of the metrics.k8s.io/v1beta1 PodMetrics resource that we use.
+// We don't use k8s.io/metrics to avoid the extra dependency.
+type podMetrics struct {
+	Timestamp  metav1.Time `json:"timestamp"`
+	Containers []struct {
+		Name  string              `json:"name"`
+		Usage corev1.ResourceList `json:"usage"`
+	} `json:"containers"`
+}
+
+// podStats summarizes a pod's resource usage the same way that 'kubectl top pod' does.
+// The metrics API doesn't report network usage.
+type podStats struct {
+	Timestamp     time.Time             `json:"timestamp"`
+	CPUMillicores int64                 `json:"cpuMillicores" jsonschema_description:"The CPU usage of all the containers in millicores"`
+	MemoryBytes   int64                 `json:"memoryBytes" jsonschema_description:"The memory usage of all the containers in bytes"`
+	Containers    []containerUsageStats `json:"containers"`
+}
+
+type containerUsageStats struct {
+	Name          string `json:"name"`
+	CPUMillicores int64  `json:"cpuMillicores"`
+	MemoryBytes   int64  `json:"memoryBytes"`
+}
+
+func newPodStats(m *podMetrics) podStats {
+	stats := podStats{
+		Timestamp:  m.Timestamp.Time,
+		Containers: make([]containerUsageStats, len(m.Containers)),
+	}
+	for i, c := range m.Containers {
+		usage := containerUsageStats{
+			Name:          c.Name,
+			CPUMillicores: c.Usage.Cpu().MilliValue(),
+			MemoryBytes:   c.Usage.Memory().Value(),
+		}
+		stats.CPUMillicores += usage.CPUMillicores
+		stats.MemoryBytes += usage.MemoryBytes
+		stats.Containers[i] = usage
+	}
+	return stats
+}
+
+// getPodStats returns the latest sample of the pod's resource usage from the metrics API.
+// The metrics API is only available if metrics-server is installed in the cluster.
+func getPodStats(ctx context.Context, client k8s.Interface, ns string, name string) (podStats, error) {
+	raw, err := client.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1", "namespaces", ns, "pods", name).
+		Do(ctx).
+		Raw()
+	if err != nil {
+		if k8serrors.IsNotFound(err) || k8serrors.IsServiceUnavailable(err) {
+			return podStats{}, fmt.Errorf("the metrics API is unavailable, is metrics-server installed?: %v", err)
+		}
+		return podStats{}, err
+	}
+
+	var m podMetrics
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return podStats{}, fmt.Errorf("could not decode the metrics of pod %v/%v: %v", ns, name, err)
+	}
+	return newPodStats(&m), nil
+}
+
+// metricsAvailable returns whether the metrics API has the stats of the namespace's pods.
+// It's checked once when listing the namespace's pods, rather than once per pod.
+func metricsAvailable(ctx context.Context, client k8s.Interface, ns string) bool {
+	err := client.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1", "namespaces", ns, "pods").
+		Param("limit", "1").
+		Do(ctx).
+		Error()
+	if err != nil {
+		activity.Record(ctx, "Not listing the stats of the pods in %v: %v", ns, err)
+		return false
+	}
+	return true
+}
+
+type podStatsFile struct {
+	plugin.EntryBase
+	ns      string
+	podName string
+	client  k8s.Interface
+}
+
+func newPodStatsFile(p *pod) *podStatsFile {
+	psf := &podStatsFile{
+		EntryBase: plugin.NewEntry("stats"),
+	}
+	psf.ns = p.ns
+	psf.podName = p.Name()
+	psf.client = p.client
+	// The stats change constantly.
+	psf.DisableCachingFor(plugin.ReadOp)
+	return psf
+}
+
+func (psf *podStatsFile) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(psf, "stats").
+		SetDescription(podStatsFileDescription).
+		IsSingleton()
+}
+
+func (psf *podStatsFile) Read(ctx context.Context) ([]byte, error) {
+	stats, err := getPodStats(ctx, psf.client, psf.ns, psf.podName)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// podStatsInterval is how often Stream polls the metrics API. The metrics are
+// usually collected less often, so Stream only emits new samples.
+var podStatsInterval = 15 * time.Second
+
+func (psf *podStatsFile) Stream(ctx context.Context) (io.ReadCloser, error) {
+	stats, err := getPodStats(ctx, psf.client, psf.ns, psf.podName)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		encoder := json.NewEncoder(w)
+		ticker := time.NewTicker(podStatsInterval)
+		defer ticker.Stop()
+
+		var last time.Time
+		for {
+			if !stats.Timestamp.Equal(last) {
+				if err := encoder.Encode(stats); err != nil {
+					// The reader was closed
+					return
+				}
+				last = stats.Timestamp
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				activity.Record(ctx, "Closing stats of pod %v/%v: %v", psf.ns, psf.podName, w.Close())
+				return
+			case <-ticker.C:
+			}
+
+			if stats, err = getPodStats(ctx, psf.client, psf.ns, psf.podName); err != nil {
+				activity.Record(ctx, "Errored reading stats of pod %v/%v: %v", psf.ns, psf.podName, err)
+				w.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return plugin.CleanupReader{ReadCloser: r, Cleanup: func() {
+		close(done)
+	}}, nil
+}
+
+const podStatsFileDescription = `
+This is the pod's resource usage, like the output of 'kubectl top pod'. It
+comes from the metrics API, so it's only listed if metrics-server is installed
+in the cluster. Reading it returns the latest sample as a line of JSON.
+Streaming it returns a new line of JSON for each sample. The latest sample is
+also included in the pod's metadata under the stats key, so you can select
+pods by their usage, e.g. with
+'find kubernetes -k '*pod' -fullmeta -meta .stats.memoryBytes +1G'.
+`
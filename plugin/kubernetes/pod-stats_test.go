@@ -0,0 +1,191 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
+)
+
+const webPodMetrics = `{
+  "kind": "PodMetrics",
+  "apiVersion": "metrics.k8s.io/v1beta1",
+  "metadata": {"name": "web", "namespace": "default"},
+  "timestamp": "2020-01-01T00:00:00Z",
+  "window": "30s",
+  "containers": [
+    {"name": "nginx", "usage": {"cpu": "250m", "memory": "1Gi"}},
+    {"name": "sidecar", "usage": {"cpu": "5m", "memory": "512Ki"}}
+  ]
+}`
+
+type PodStatsTestSuite struct {
+	suite.Suite
+	metricsStatus int
+	requests      []string
+	client        *fakeClientset
+}
+
+func (s *PodStatsTestSuite) SetupTest() {
+	s.metricsStatus = http.StatusOK
+	s.requests = nil
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "nginx"}, {Name: "sidecar"}},
+		},
+	}
+	s.client = &fakeClientset{
+		Clientset: fake.NewSimpleClientset(pod),
+		restClient: &restfake.RESTClient{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+			Client: restfake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+				s.requests = append(s.requests, req.URL.Path)
+				body := webPodMetrics
+				if s.metricsStatus != http.StatusOK {
+					body = `{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`
+				}
+				return &http.Response{
+					StatusCode: s.metricsStatus,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(strings.NewReader(body)),
+				}, nil
+			}),
+		},
+	}
+}
+
+func (s *PodStatsTestSuite) newPod() *pod {
+	p, err := s.client.CoreV1().Pods("default").Get(context.Background(), "web", metav1.GetOptions{})
+	s.Require().NoError(err)
+	pd, err := newPod(context.Background(), s.client, nil, "default", p, s.metricsStatus == http.StatusOK)
+	s.Require().NoError(err)
+	return pd
+}
+
+var expectedWebPodStats = podStats{
+	Timestamp:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	CPUMillicores: 255,
+	MemoryBytes:   1<<30 + 512<<10,
+	Containers: []containerUsageStats{
+		{Name: "nginx", CPUMillicores: 250, MemoryBytes: 1 << 30},
+		{Name: "sidecar", CPUMillicores: 5, MemoryBytes: 512 << 10},
+	},
+}
+
+func (s *PodStatsTestSuite) TestGetPodStats() {
+	stats, err := getPodStats(context.Background(), s.client, "default", "web")
+	if s.NoError(err) {
+		s.Equal(expectedWebPodStats.CPUMillicores, stats.CPUMillicores)
+		s.Equal(expectedWebPodStats.MemoryBytes, stats.MemoryBytes)
+		s.Equal(expectedWebPodStats.Containers, stats.Containers)
+		s.True(expectedWebPodStats.Timestamp.Equal(stats.Timestamp))
+	}
+	s.Equal([]string{"/apis/metrics.k8s.io/v1beta1/namespaces/default/pods/web"}, s.requests)
+}
+
+func (s *PodStatsTestSuite) TestGetPodStats_MetricsAPIUnavailable() {
+	s.metricsStatus = http.StatusNotFound
+	_, err := getPodStats(context.Background(), s.client, "default", "web")
+	s.Regexp("the metrics API is unavailable", err)
+}
+
+func (s *PodStatsTestSuite) TestRead() {
+	content, err := newPodStatsFile(s.newPod()).Read(context.Background())
+	if s.NoError(err) {
+		s.True(strings.HasSuffix(string(content), "\n"))
+		var stats podStats
+		s.NoError(json.Unmarshal(content, &stats))
+		s.Equal(expectedWebPodStats.MemoryBytes, stats.MemoryBytes)
+	}
+}
+
+func (s *PodStatsTestSuite) TestStream() {
+	rdr, err := newPodStatsFile(s.newPod()).Stream(context.Background())
+	if s.NoError(err) {
+		var stats podStats
+		s.NoError(json.NewDecoder(rdr).Decode(&stats))
+		s.Equal(expectedWebPodStats.CPUMillicores, stats.CPUMillicores)
+		s.NoError(rdr.Close())
+	}
+}
+
+func (s *PodStatsTestSuite) TestList() {
+	entries, err := s.newPod().List(context.Background())
+	if s.NoError(err) && s.Len(entries, 3) {
+		s.IsType(&podStatsFile{}, entries[2])
+	}
+
+	s.metricsStatus = http.StatusNotFound
+	entries, err = s.newPod().List(context.Background())
+	if s.NoError(err) {
+		s.Len(entries, 2)
+	}
+}
+
+func (s *PodStatsTestSuite) TestPodsDirList_ChecksMetricsOnce() {
+	_, err := s.client.CoreV1().Pods("default").Create(context.Background(), &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "postgres"}}},
+	}, metav1.CreateOptions{})
+	s.Require().NoError(err)
+
+	pods := &podsDir{EntryBase: plugin.NewEntry("pods"), client: s.client, ns: "default"}
+	entries, err := pods.List(context.Background())
+	if s.NoError(err) && s.Len(entries, 2) {
+		for _, entry := range entries {
+			s.True(entry.(*pod).hasStats)
+		}
+	}
+	s.Equal([]string{"/apis/metrics.k8s.io/v1beta1/namespaces/default/pods"}, s.requests)
+
+	s.requests = nil
+	s.metricsStatus = http.StatusNotFound
+	entries, err = pods.List(context.Background())
+	if s.NoError(err) && s.Len(entries, 2) {
+		for _, entry := range entries {
+			s.False(entry.(*pod).hasStats)
+			podEntries, err := entry.(*pod).List(context.Background())
+			if s.NoError(err) {
+				for _, podEntry := range podEntries {
+					s.IsType(&container{}, podEntry)
+				}
+			}
+			meta, err := entry.(*pod).Metadata(context.Background())
+			if s.NoError(err) {
+				s.NotContains(meta, "stats")
+			}
+		}
+	}
+	// Neither listing the pods nor getting their metadata asks for their stats.
+	s.Len(s.requests, 1)
+}
+
+func (s *PodStatsTestSuite) TestMetadata() {
+	meta, err := s.newPod().Metadata(context.Background())
+	if s.NoError(err) && s.Contains(meta, "stats") {
+		s.Equal(float64(255), meta["stats"].(map[string]interface{})["cpuMillicores"])
+	}
+
+	s.metricsStatus = http.StatusNotFound
+	meta, err = s.newPod().Metadata(context.Background())
+	if s.NoError(err) {
+		s.NotContains(meta, "stats")
+		s.Contains(meta, "spec")
+	}
+}
+
+func TestPodStats(t *testing.T) {
+	suite.Run(t, new(PodStatsTestSuite))
+}
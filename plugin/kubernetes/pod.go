@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"context"
 
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,18 +13,21 @@ import (
 
 type pod struct {
 	plugin.EntryBase
-	client *k8s.Clientset
+	client k8s.Interface
 	config *rest.Config
 	ns     string
+	// hasStats is whether the metrics API was available when the pod was listed.
+	hasStats bool
 }
 
-func newPod(ctx context.Context, client *k8s.Clientset, config *rest.Config, ns string, p *corev1.Pod) (*pod, error) {
+func newPod(ctx context.Context, client k8s.Interface, config *rest.Config, ns string, p *corev1.Pod, hasStats bool) (*pod, error) {
 	pd := &pod{
 		EntryBase: plugin.NewEntry(p.Name),
 	}
 	pd.client = client
 	pd.config = config
 	pd.ns = ns
+	pd.hasStats = hasStats
 
 	pd.
 		SetPartialMetadata(p).
@@ -34,16 +38,43 @@ func newPod(ctx context.Context, client *k8s.Clientset, config *rest.Config, ns
 	return pd, nil
 }
 
+// podMetadata describes a pod's metadata. It's only used for the metadata schema.
+type podMetadata struct {
+	corev1.Pod
+	// Stats is only set if the metrics API is available.
+	Stats *podStats `json:"stats,omitempty"`
+}
+
 func (p *pod) Schema() *plugin.EntrySchema {
 	return plugin.
 		NewEntrySchema(p, "pod").
-		SetPartialMetadataSchema(corev1.Pod{})
+		SetPartialMetadataSchema(corev1.Pod{}).
+		SetMetadataSchema(podMetadata{})
 }
 
 func (p *pod) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&container{}).Schema(),
+		(&podStatsFile{}).Schema(),
+	}
+}
+
+func (p *pod) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	pd, err := p.client.CoreV1().Pods(p.ns).Get(ctx, p.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	meta := plugin.ToJSONObject(pd)
+	if !p.hasStats {
+		return meta, nil
+	}
+	if stats, err := getPodStats(ctx, p.client, p.ns, p.Name()); err == nil {
+		meta["stats"] = plugin.ToJSONObject(stats)
+	} else {
+		activity.Record(ctx, "Could not get the stats of pod %v/%v: %v", p.ns, p.Name(), err)
 	}
+	return meta, nil
 }
 
 func (p *pod) List(ctx context.Context) ([]plugin.Entry, error) {
@@ -62,6 +93,16 @@ func (p *pod) List(ctx context.Context) ([]plugin.Entry, error) {
 		entries[i] = c
 	}
 
+	// Only include the stats if the metrics API is available. Skip them if a container's
+	// named stats to avoid a name clash.
+	hasStatsContainer := false
+	for _, c := range pd.Spec.Containers {
+		hasStatsContainer = hasStatsContainer || c.Name == "stats"
+	}
+	if p.hasStats && !hasStatsContainer {
+		entries = append(entries, newPodStatsFile(p))
+	}
+
 	return entries, nil
 }
 
@@ -11,7 +11,7 @@ import (
 
 type podsDir struct {
 	plugin.EntryBase
-	client *k8s.Clientset
+	client k8s.Interface
 	config *rest.Config
 	ns     string
 }
@@ -43,9 +43,10 @@ func (ps *podsDir) List(ctx context.Context) ([]plugin.Entry, error) {
 	if err != nil {
 		return nil, err
 	}
+	hasStats := len(podList.Items) > 0 && metricsAvailable(ctx, ps.client, ps.ns)
 	entries := make([]plugin.Entry, len(podList.Items))
 	for i, p := range podList.Items {
-		pd, err := newPod(ctx, ps.client, ps.config, ps.ns, &p)
+		pd, err := newPod(ctx, ps.client, ps.config, ps.ns, &p, hasStats)
 		if err != nil {
 			return nil, err
 		}
@@ -21,12 +21,12 @@ type pvc struct {
 	plugin.EntryBase
 	pvci      typedv1.PersistentVolumeClaimInterface
 	podi      typedv1.PodInterface
-	client    *k8s.Clientset
+	client    k8s.Interface
 	config    *rest.Config
 	namespace string
 }
 
-func newPVC(pi typedv1.PersistentVolumeClaimInterface, client *k8s.Clientset, config *rest.Config, ns string, p *corev1.PersistentVolumeClaim) *pvc {
+func newPVC(pi typedv1.PersistentVolumeClaimInterface, client k8s.Interface, config *rest.Config, ns string, p *corev1.PersistentVolumeClaim) *pvc {
 	vol := &pvc{
 		EntryBase: plugin.NewEntry(p.Name),
 	}
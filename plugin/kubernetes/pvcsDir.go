@@ -11,7 +11,7 @@ import (
 
 type pvcsDir struct {
 	plugin.EntryBase
-	client *k8s.Clientset
+	client k8s.Interface
 	config *rest.Config
 	ns     string
 }
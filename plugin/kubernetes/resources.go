@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+// resourceType is a kind of object that's served by the API server, e.g.
+// deployments or a CRD.
+type resourceType struct {
+	// name is the name of the resource's directory. It's the resource's plural
+	// name, qualified by its group if that name is served by several groups
+	// (like kubectl's "deployments.apps").
+	name       string
+	gvr        schema.GroupVersionResource
+	kind       string
+	namespaced bool
+}
+
+// discoverResources returns the resource types that can be listed and read, using the
+// preferred version of each. It returns the namespaced resources if namespaced is true,
+// and the cluster-scoped resources otherwise.
+func discoverResources(ctx context.Context, d discovery.DiscoveryInterface, namespaced bool) ([]resourceType, error) {
+	lists, err := discovery.ServerPreferredResources(d)
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, err
+		}
+		// Some aggregated APIs (like metrics.k8s.io) are often unavailable. Keep the rest.
+		activity.Record(ctx, "Errored discovering some resources: %v", err)
+	}
+
+	var types []resourceType
+	groups := make(map[string]int)
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list.APIResources {
+			if r.Namespaced != namespaced || !hasVerbs(r, "list", "get") {
+				continue
+			}
+			types = append(types, resourceType{
+				name:       r.Name,
+				gvr:        gv.WithResource(r.Name),
+				kind:       r.Kind,
+				namespaced: r.Namespaced,
+			})
+			groups[r.Name]++
+		}
+	}
+
+	for i, t := range types {
+		if groups[t.name] > 1 && t.gvr.Group != "" {
+			types[i].name = t.name + "." + t.gvr.Group
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].name < types[j].name
+	})
+	return types, nil
+}
+
+func hasVerbs(r metav1.APIResource, verbs ...string) bool {
+	for _, verb := range verbs {
+		found := false
+		for _, v := range r.Verbs {
+			if v == verb {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// newResourceDirs returns a directory for each discovered resource type, skipping the types
+// in skip. The latter is used to skip the resources that have kind-specific entries. Types
+// are matched by group so that they're skipped even if their directory's name is qualified
+// (like deployments.apps).
+func newResourceDirs(ctx context.Context, d discovery.DiscoveryInterface, client dynamic.Interface, ns string, skip ...schema.GroupResource) ([]plugin.Entry, error) {
+	types, err := discoverResources(ctx, d, ns != "")
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[schema.GroupResource]bool)
+	for _, gr := range skip {
+		skipped[gr] = true
+	}
+	var entries []plugin.Entry
+	for _, t := range types {
+		if !skipped[t.gvr.GroupResource()] {
+			entries = append(entries, newResourceDir(t, client, ns))
+		}
+	}
+	return entries, nil
+}
+
+// resourceDir lists the objects of a resource type.
+type resourceDir struct {
+	plugin.EntryBase
+	resource resourceType
+	client   dynamic.Interface
+	ns       string
+}
+
+func newResourceDir(t resourceType, client dynamic.Interface, ns string) *resourceDir {
+	rd := &resourceDir{
+		EntryBase: plugin.NewEntry(t.name),
+	}
+	rd.resource = t
+	rd.client = client
+	rd.ns = ns
+	rd.SetPartialMetadata(map[string]interface{}{
+		"group":      t.gvr.Group,
+		"version":    t.gvr.Version,
+		"resource":   t.gvr.Resource,
+		"kind":       t.kind,
+		"namespaced": t.namespaced,
+	})
+	return rd
+}
+
+func (rd *resourceDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(rd, "resource").
+		SetDescription(resourceDirDescription)
+}
+
+func (rd *resourceDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&object{}).Schema(),
+	}
+}
+
+func (rd *resourceDir) resourceInterface() dynamic.ResourceInterface {
+	if rd.ns == "" {
+		return rd.client.Resource(rd.resource.gvr)
+	}
+	return rd.client.Resource(rd.resource.gvr).Namespace(rd.ns)
+}
+
+func (rd *resourceDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	list, err := rd.resourceInterface().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v %v in %v", len(list.Items), rd.resource.gvr, rd)
+
+	entries := make([]plugin.Entry, len(list.Items))
+	for i := range list.Items {
+		entries[i] = newObject(rd.resourceInterface(), &list.Items[i])
+	}
+	return entries, nil
+}
+
+// clusterDir lists the cluster-scoped resources. Its name starts with an underscore
+// because that's not valid in a namespace's name, so it can't clash with the namespaces
+// that are listed beside it.
+type clusterDir struct {
+	plugin.EntryBase
+	k8ctx *k8context
+}
+
+func newClusterDir(c *k8context) *clusterDir {
+	cd := &clusterDir{
+		EntryBase: plugin.NewEntry("_cluster"),
+	}
+	cd.k8ctx = c
+	return cd
+}
+
+func (cd *clusterDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(cd, "cluster").
+		SetDescription(clusterDirDescription).
+		IsSingleton()
+}
+
+func (cd *clusterDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&nodesDir{}).Schema(),
+		(&eventsFile{}).Schema(),
+		(&resourceDir{}).Schema(),
+	}
+}
+
+// List returns the nodes and the events of all namespaces, followed by a generic
+// directory for each of the other cluster-scoped resource types.
+func (cd *clusterDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	entries := []plugin.Entry{newNodesDir(cd.k8ctx), newEventsFile(cd.k8ctx.client, "")}
+	resources, err := newResourceDirs(ctx, cd.k8ctx.discovery, cd.k8ctx.dynamic, "", schema.GroupResource{Resource: "nodes"})
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, resources...), nil
+}
+
+const resourceDirDescription = `
+This is a Kubernetes resource type, e.g. configmaps or a custom resource. It
+contains a YAML file for each of the type's objects. The available types are
+found with the discovery API, so custom resources are included once their
+CRDs are installed. Types that are served by several API groups are named
+after their group too, like kubectl's 'deployments.apps'.
+`
+
+const clusterDirDescription = `
+This directory contains the cluster's cluster-scoped resources, like nodes,
+namespaces and custom resource definitions, and the events of all
+namespaces. Its name starts with an underscore so that it can't clash with a
+namespace's name.
+`
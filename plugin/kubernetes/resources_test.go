@@ -0,0 +1,93 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiscoverResources(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "events", Kind: "Event", Namespaced: true, Verbs: []string{"get", "list"}},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"get", "list"}},
+				{Name: "nodes", Kind: "Node", Verbs: []string{"get", "list"}},
+				{Name: "bindings", Kind: "Binding", Namespaced: true, Verbs: []string{"create"}},
+			},
+		},
+		{
+			GroupVersion: "events.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "events", Kind: "Event", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"get", "list"}},
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true, Verbs: []string{"get"}},
+			},
+		},
+	}
+
+	types, err := discoverResources(context.Background(), client.Discovery(), true)
+	require.NoError(t, err)
+	// Only the names that are served by several groups are qualified, and the resources
+	// that can't be listed are skipped.
+	assert.Equal(t, []resourceType{
+		{name: "configmaps", gvr: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, kind: "ConfigMap", namespaced: true},
+		{name: "deployments", gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, kind: "Deployment", namespaced: true},
+		{name: "events", gvr: schema.GroupVersionResource{Version: "v1", Resource: "events"}, kind: "Event", namespaced: true},
+		{name: "events.events.k8s.io", gvr: schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1beta1", Resource: "events"}, kind: "Event", namespaced: true},
+	}, types)
+
+	types, err = discoverResources(context.Background(), client.Discovery(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []resourceType{
+		{name: "nodes", gvr: schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, kind: "Node"},
+	}, types)
+}
+
+func TestNewResourceDirs_SkipsByGroupResource(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"get", "list"}},
+				{Name: "services", Kind: "Service", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "extensions/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+	}
+
+	// deployments.apps is skipped even though its name is qualified, but the
+	// extensions group's deployments aren't.
+	entries, err := newResourceDirs(context.Background(), client.Discovery(), nil, "default", kindSpecificResources...)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, plugin.Name(entry))
+	}
+	assert.Equal(t, []string{"deployments.extensions", "services"}, names)
+}
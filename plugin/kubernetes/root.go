@@ -10,7 +10,9 @@ import (
 	"github.com/puppetlabs/wash/plugin"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
@@ -21,15 +23,34 @@ import (
 // Root of the Kubernetes plugin
 type Root struct {
 	plugin.EntryBase
+	// newClientset creates a context's clientset. The tests use it to
+	// inject a fake clientset.
+	newClientset func(*rest.Config) (k8s.Interface, error)
+	// newDynamicClient creates a context's dynamic client, which is used for
+	// the resources that don't have kind-specific entries.
+	newDynamicClient func(*rest.Config) (dynamic.Interface, error)
 }
 
-func createContext(raw clientcmdapi.Config, name string, access clientcmd.ConfigAccess) (plugin.Entry, error) {
+// config is empty because the Kubernetes plugin doesn't have any config options.
+// Declaring it means that unknown options are reported.
+type config struct{}
+
+// ConfigSchema returns the root's config schema
+func (r *Root) ConfigSchema() interface{} {
+	return config{}
+}
+
+func (r *Root) createContext(raw clientcmdapi.Config, name string, access clientcmd.ConfigAccess) (plugin.Entry, error) {
 	config := clientcmd.NewNonInteractiveClientConfig(raw, name, &clientcmd.ConfigOverrides{}, access)
 	cfg, err := config.ClientConfig()
 	if err != nil {
 		return nil, err
 	}
-	clientset, err := k8s.NewForConfig(cfg)
+	clientset, err := r.newClientset(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := r.newDynamicClient(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -37,13 +58,23 @@ func createContext(raw clientcmdapi.Config, name string, access clientcmd.Config
 	if err != nil {
 		return nil, err
 	}
-	return newK8Context(name, clientset, cfg, defaultns), nil
+	return newK8Context(name, clientset, dynamicClient, cfg, defaultns), nil
 }
 
 // Init for root
 func (r *Root) Init(map[string]interface{}) error {
 	r.EntryBase = plugin.NewEntry("kubernetes")
 	r.DisableDefaultCaching()
+	if r.newClientset == nil {
+		r.newClientset = func(cfg *rest.Config) (k8s.Interface, error) {
+			return k8s.NewForConfig(cfg)
+		}
+	}
+	if r.newDynamicClient == nil {
+		r.newDynamicClient = func(cfg *rest.Config) (dynamic.Interface, error) {
+			return dynamic.NewForConfig(cfg)
+		}
+	}
 
 	return nil
 }
@@ -83,7 +114,7 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 
 	contexts := make([]plugin.Entry, 0)
 	for name := range raw.Contexts {
-		ctx, err := createContext(raw, name, config.ConfigAccess())
+		ctx, err := r.createContext(raw, name, config.ConfigAccess())
 		if err != nil {
 			activity.Warnf(context.Background(), "loading context %v failed: %+v", name, err)
 			continue
@@ -96,7 +127,8 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 
 const rootDescription = `
 This is the Kubernetes plugin root. It lets you interact with Kubernetes resources
-like pods and persistent volume claims.
+like pods, persistent volume claims and deployments. Other resources, including custom
+resources, are presented as YAML files that you can edit to apply changes.
 
 Kubernetes contexts are extracted from ~/.kube/config.
 `
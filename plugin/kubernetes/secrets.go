@@ -0,0 +1,211 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/volume"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+type secretsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	ns     string
+}
+
+func newSecretsDir(ns *namespace) *secretsDir {
+	ss := &secretsDir{
+		EntryBase: plugin.NewEntry("secrets"),
+	}
+	ss.client = ns.client
+	ss.ns = ns.Name()
+	return ss
+}
+
+func (ss *secretsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(ss, "secrets").IsSingleton()
+}
+
+func (ss *secretsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&secret{}).Schema(),
+	}
+}
+
+func (ss *secretsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	secretList, err := ss.client.CoreV1().Secrets(ss.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(secretList.Items))
+	for i := range secretList.Items {
+		entries[i] = newSecret(ss.client, &secretList.Items[i])
+	}
+	return entries, nil
+}
+
+// secret presents a Secret's keys as files containing their decoded values. The
+// API returns the values base64-encoded; the client decodes them when reading and
+// encodes them when writing.
+type secret struct {
+	plugin.EntryBase
+	dataObject
+	client  k8s.Interface
+	ns      string
+	created time.Time
+}
+
+func newSecret(client k8s.Interface, sc *corev1.Secret) *secret {
+	s := &secret{
+		EntryBase: plugin.NewEntry(sc.Name),
+	}
+	s.client = client
+	s.ns = sc.Namespace
+	s.created = sc.CreationTimestamp.Time
+	s.see(sc.ResourceVersion)
+	s.SetTTLOf(plugin.ListOp, volume.ListTTL)
+	// Don't include the secret's data in its metadata. kubectl apply also stores the
+	// data in an annotation.
+	meta := sc.DeepCopy()
+	meta.Data = nil
+	meta.StringData = nil
+	delete(meta.Annotations, corev1.LastAppliedConfigAnnotation)
+	s.
+		SetPartialMetadata(meta).
+		Attributes().
+		SetCrtime(s.created).
+		SetAtime(s.created)
+	return s
+}
+
+func (s *secret) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "secret").
+		SetDescription(secretDescription).
+		SetPartialMetadataSchema(corev1.Secret{})
+}
+
+func (s *secret) ChildSchemas() []*plugin.EntrySchema {
+	return volume.ChildSchemas()
+}
+
+func (s *secret) List(ctx context.Context) ([]plugin.Entry, error) {
+	return volume.List(ctx, s)
+}
+
+func (s *secret) Delete(ctx context.Context) (bool, error) {
+	err := s.client.CoreV1().Secrets(s.ns).Delete(ctx, s.Name(), metav1.DeleteOptions{})
+	return true, err
+}
+
+func (s *secret) get(ctx context.Context) (*corev1.Secret, error) {
+	return s.client.CoreV1().Secrets(s.ns).Get(ctx, s.Name(), metav1.GetOptions{})
+}
+
+// update updates the Secret from the version that was last seen.
+func (s *secret) update(ctx context.Context, sc *corev1.Secret) error {
+	sc.ResourceVersion = s.seen()
+	updated, err := s.client.CoreV1().Secrets(s.ns).Update(ctx, sc, metav1.UpdateOptions{FieldManager: fieldManager})
+	if err != nil {
+		return updateError("Secret", s.ns, s.Name(), err)
+	}
+	s.see(updated.ResourceVersion)
+	return nil
+}
+
+func (s *secret) VolumeList(ctx context.Context, path string) (volume.DirMap, error) {
+	sc, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.see(sc.ResourceVersion)
+
+	sizes := make(map[string]int)
+	for key, value := range sc.Data {
+		sizes[key] = len(value)
+	}
+	return dataDirMap(sizes, 0600, s.created), nil
+}
+
+func (s *secret) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	key, err := dataKey(path)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := sc.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("the Secret %v/%v does not have the %v key", s.ns, s.Name(), key)
+	}
+	return value, nil
+}
+
+// VolumeStream returns the key's current value. The Secret's keys aren't logs, so
+// there's nothing to follow.
+func (s *secret) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	content, err := s.VolumeRead(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// VolumeWrite sets the key's value, adding the key if it doesn't exist.
+func (s *secret) VolumeWrite(ctx context.Context, path string, b []byte, _ os.FileMode) error {
+	key, err := dataKey(path)
+	if err != nil {
+		return err
+	}
+	sc, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	if sc.Data == nil {
+		sc.Data = make(map[string][]byte)
+	}
+	sc.Data[key] = b
+	activity.Record(ctx, "Writing the %v key of Secret %v/%v", key, s.ns, s.Name())
+	return s.update(ctx, sc)
+}
+
+// VolumeDelete removes the key from the Secret.
+func (s *secret) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	key, err := dataKey(path)
+	if err != nil {
+		return false, err
+	}
+	sc, err := s.get(ctx)
+	if err != nil {
+		return false, err
+	}
+	delete(sc.Data, key)
+	activity.Record(ctx, "Removing the %v key of Secret %v/%v", key, s.ns, s.Name())
+	if err := s.update(ctx, sc); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+const secretDescription = `
+This is a Kubernetes Secret. It contains a file for each of its keys, with
+the key's decoded value; values are base64-encoded again when they're
+written. Writing a file updates the key's value, and deleting a file removes
+the key. Writing a key that doesn't exist adds it.
+
+Updates are rejected if the Secret was changed since it was listed, so that
+they don't overwrite someone else's changes. List the Secret again to get the
+latest content, then retry the update.
+`
@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puppetlabs/wash/plugin"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type statefulSetsDir struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	ns     string
+}
+
+func newStatefulSetsDir(ns *namespace) *statefulSetsDir {
+	ss := &statefulSetsDir{
+		EntryBase: plugin.NewEntry("statefulsets"),
+	}
+	ss.client = ns.client
+	ss.config = ns.config
+	ss.ns = ns.Name()
+	return ss
+}
+
+func (ss *statefulSetsDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(ss, "statefulsets").IsSingleton()
+}
+
+func (ss *statefulSetsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&statefulSet{}).Schema(),
+	}
+}
+
+func (ss *statefulSetsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	statefulSetList, err := ss.client.AppsV1().StatefulSets(ss.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]plugin.Entry, len(statefulSetList.Items))
+	for i := range statefulSetList.Items {
+		entries[i] = newStatefulSet(&statefulSetList.Items[i], ss.client, ss.config)
+	}
+	return entries, nil
+}
+
+type statefulSet struct {
+	workload
+}
+
+func newStatefulSet(s *appsv1.StatefulSet, client k8s.Interface, config *rest.Config) *statefulSet {
+	st := &statefulSet{
+		workload: newWorkload(s, client, config),
+	}
+	st.deleteFn = client.AppsV1().StatefulSets(s.Namespace).Delete
+	st.SetPartialMetadata(s)
+	return st
+}
+
+func (s *statefulSet) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "statefulset").
+		SetDescription(statefulSetDescription).
+		SetPartialMetadataSchema(appsv1.StatefulSet{}).
+		AddSignal("restart", "Restarts the stateful set's pods. Equivalent to 'kubectl rollout restart'").
+		AddSignalGroup("scale", scaleSignalRegex.String(), scaleSignalDescription)
+}
+
+func (s *statefulSet) Signal(ctx context.Context, signal string) error {
+	patch := func(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) error {
+		_, err := s.client.AppsV1().StatefulSets(s.ns).Patch(ctx, name, pt, data, opts)
+		return err
+	}
+	if signal == "restart" {
+		return s.patch(ctx, patch, signal, restartPatch())
+	}
+	if scale, ok := scalePatch(signal); ok {
+		return s.patch(ctx, patch, signal, scale)
+	}
+	return fmt.Errorf("unsupported signal %v", signal)
+}
+
+const statefulSetDescription = `
+This is a Kubernetes stateful set. It contains the pods that it owns, and a
+log file that aggregates their logs. Deleting it also deletes its pods, but
+not their persistent volume claims.
+`
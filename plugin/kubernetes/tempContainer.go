@@ -67,6 +67,59 @@ func createContainer(ctx context.Context, podi typedv1.PodInterface, volumeClaim
 	return
 }
 
+// The host's filesystem is mounted here in node debug containers.
+const nodeDebugHostPath = "/host"
+
+// Create a privileged container on the node that shares the host's namespaces and mounts its
+// filesystem, like 'kubectl debug node/<node>'. It tolerates all taints so that it can run on
+// cordoned and tainted nodes.
+func createNodeDebugContainer(ctx context.Context, podi typedv1.PodInterface, nodeName string) (c tempContainer, err error) {
+	privileged := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "wash-node-debug-",
+		},
+		Spec: corev1.PodSpec{
+			NodeName:    nodeName,
+			HostPID:     true,
+			HostNetwork: true,
+			HostIPC:     true,
+			Containers: []corev1.Container{
+				{
+					Name:  "debugger",
+					Image: "busybox",
+					Args:  []string{"sleep", "604800"},
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: &privileged,
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "host-root",
+							MountPath: nodeDebugHostPath,
+						},
+					},
+				},
+			},
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations: []corev1.Toleration{
+				{Operator: corev1.TolerationOpExists},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "host-root",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+					},
+				},
+			},
+		},
+	}
+
+	c.podi = podi
+	c.pod, err = podi.Create(ctx, pod, metav1.CreateOptions{})
+	return
+}
+
 var errPodTerminated = errors.New("Pod terminated unexpectedly")
 
 func (c *tempContainer) waitOnCreation(ctx context.Context) error {
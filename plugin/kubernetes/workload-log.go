@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/internal/logs"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// workloadLogFile aggregates the logs of all of a workload's containers. Each line
+// is prefixed with the pod that logged it, like 'kubectl logs --prefix'.
+type workloadLogFile struct {
+	plugin.EntryBase
+	workload *workload
+}
+
+func newWorkloadLogFile(w *workload) *workloadLogFile {
+	wl := &workloadLogFile{
+		EntryBase: plugin.NewEntry("log"),
+	}
+	wl.workload = w
+	return wl
+}
+
+func (wl *workloadLogFile) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(wl, "log").
+		SetDescription(workloadLogFileDescription).
+		IsSingleton()
+}
+
+// podContainerLog identifies a container's log.
+type podContainerLog struct {
+	pod, container, prefix string
+}
+
+// containerLogs returns the workload's container logs. The prefixes are padded so that
+// the log lines are aligned. They only include the container's name if the pod has
+// several containers.
+func (wl *workloadLogFile) containerLogs(ctx context.Context) ([]podContainerLog, error) {
+	pods, err := ownedPods(ctx, wl.workload.client, wl.workload.ns, wl.workload.uid)
+	if err != nil {
+		return nil, err
+	}
+
+	var containerLogs []podContainerLog
+	width := 0
+	for _, pd := range pods {
+		for _, c := range pd.Spec.Containers {
+			prefix := pd.Name
+			if len(pd.Spec.Containers) > 1 {
+				prefix += "/" + c.Name
+			}
+			if len(prefix) > width {
+				width = len(prefix)
+			}
+			containerLogs = append(containerLogs, podContainerLog{pod: pd.Name, container: c.Name, prefix: prefix})
+		}
+	}
+	for i := range containerLogs {
+		containerLogs[i].prefix = fmt.Sprintf("%-*v | ", width, containerLogs[i].prefix)
+	}
+	return containerLogs, nil
+}
+
+func (wl *workloadLogFile) Read(ctx context.Context) ([]byte, error) {
+	containerLogs, err := wl.containerLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, log := range containerLogs {
+		req := wl.workload.client.CoreV1().Pods(wl.workload.ns).GetLogs(log.pod, &corev1.PodLogOptions{
+			Container: log.container,
+		})
+		content, err := req.Do(ctx).Raw()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the logs of %v/%v: %v", log.pod, log.container, err)
+		}
+		if err := logs.PrefixLines(&buf, bytes.NewReader(content), log.prefix); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (wl *workloadLogFile) Stream(ctx context.Context) (io.ReadCloser, error) {
+	containerLogs, err := wl.containerLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := make([]logs.Stream, len(containerLogs))
+	var tailLines int64 = 10
+	for i, log := range containerLogs {
+		req := wl.workload.client.CoreV1().Pods(wl.workload.ns).GetLogs(log.pod, &corev1.PodLogOptions{
+			Container: log.container,
+			Follow:    true,
+			TailLines: &tailLines,
+		})
+		rdr, err := req.Stream(ctx)
+		if err != nil {
+			logs.CloseAll(streams)
+			return nil, err
+		}
+		streams[i] = logs.Stream{ReadCloser: rdr, Name: log.pod + "/" + log.container, Prefix: log.prefix}
+	}
+	return logs.Merge(ctx, wl.workload.String(), streams), nil
+}
+
+const workloadLogFileDescription = `
+This is the aggregated log of all of the workload's containers. Each line is
+prefixed with the pod that logged it, and with the container if the pod has
+several containers. Streaming it follows all of the containers' logs.
+`
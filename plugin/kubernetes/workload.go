@@ -0,0 +1,171 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// workload contains the behavior that's shared by the workload controllers, like
+// deployments and jobs. Each controller has its own entry type that embeds workload
+// and adds its signals.
+type workload struct {
+	plugin.EntryBase
+	client k8s.Interface
+	config *rest.Config
+	ns     string
+	uid    types.UID
+	// deleteFn deletes the workload. It's set by each controller's entry type.
+	deleteFn func(ctx context.Context, name string, opts metav1.DeleteOptions) error
+}
+
+func newWorkload(obj metav1.Object, client k8s.Interface, config *rest.Config) workload {
+	wl := workload{
+		EntryBase: plugin.NewEntry(obj.GetName()),
+	}
+	wl.client = client
+	wl.config = config
+	wl.ns = obj.GetNamespace()
+	wl.uid = obj.GetUID()
+	wl.
+		Attributes().
+		SetCrtime(obj.GetCreationTimestamp().Time).
+		SetAtime(obj.GetCreationTimestamp().Time)
+	return wl
+}
+
+func (w *workload) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&workloadLogFile{}).Schema(),
+		(&pod{}).Schema(),
+	}
+}
+
+// List returns the log followed by the workload's pods.
+func (w *workload) List(ctx context.Context) ([]plugin.Entry, error) {
+	pods, err := ownedPods(ctx, w.client, w.ns, w.uid)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Listing %v pods in %v", len(pods), w)
+
+	hasStats := len(pods) > 0 && metricsAvailable(ctx, w.client, w.ns)
+	entries := []plugin.Entry{newWorkloadLogFile(w)}
+	for i := range pods {
+		pd, err := newPod(ctx, w.client, w.config, w.ns, &pods[i], hasStats)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, pd)
+	}
+	return entries, nil
+}
+
+// Delete deletes the workload and, in the background, its pods. That's what kubectl does.
+func (w *workload) Delete(ctx context.Context) (bool, error) {
+	propagation := metav1.DeletePropagationBackground
+	err := w.deleteFn(ctx, w.Name(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+	return true, err
+}
+
+// ownedPods returns the pods that are owned by the object with the given UID, either
+// directly or through intermediate controllers. For example, a deployment owns its pods
+// through replica sets and a cron job owns its pods through jobs.
+func ownedPods(ctx context.Context, client k8s.Interface, ns string, uid types.UID) ([]corev1.Pod, error) {
+	owners := make(map[types.UID][]metav1.OwnerReference)
+	replicaSets, err := client.AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range replicaSets.Items {
+		owners[rs.UID] = rs.OwnerReferences
+	}
+	jobs, err := client.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs.Items {
+		owners[job.UID] = job.OwnerReferences
+	}
+
+	var isOwned func(refs []metav1.OwnerReference, depth int) bool
+	isOwned = func(refs []metav1.OwnerReference, depth int) bool {
+		// Owner references shouldn't have cycles, but don't trust them.
+		if depth > 5 {
+			return false
+		}
+		for _, ref := range refs {
+			if ref.UID == uid || isOwned(owners[ref.UID], depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+
+	podList, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var pods []corev1.Pod
+	for _, pd := range podList.Items {
+		if isOwned(pd.OwnerReferences, 0) {
+			pods = append(pods, pd)
+		}
+	}
+	return pods, nil
+}
+
+// restartPatch returns a patch that restarts a workload's pods like 'kubectl rollout
+// restart', which changes an annotation in the pod template.
+func restartPatch() []byte {
+	return []byte(fmt.Sprintf(
+		`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`,
+		time.Now().Format(time.RFC3339),
+	))
+}
+
+var scaleSignalRegex = regexp.MustCompile(`\Ascale-(\d+)\z`)
+
+const scaleSignalDescription = "Scales the workload to N replicas, e.g. scale-3. Equivalent to\n'kubectl scale --replicas=N'"
+
+// scalePatch returns a patch that scales a workload if signal is a scale signal.
+func scalePatch(signal string) ([]byte, bool) {
+	match := scaleSignalRegex.FindStringSubmatch(signal)
+	if match == nil {
+		return nil, false
+	}
+	replicas, err := strconv.ParseInt(match[1], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+	return []byte(fmt.Sprintf(`{"spec":{"replicas":%v}}`, replicas)), true
+}
+
+// specPatch returns a patch that sets the spec's field to value.
+func specPatch(field string, value interface{}) []byte {
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{field: value}})
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal the patch of %v: %v", field, err))
+	}
+	return patch
+}
+
+// patchFunc patches a workload. The typed clients return different types, so each
+// controller wraps its client's Patch method.
+type patchFunc func(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) error
+
+func (w *workload) patch(ctx context.Context, fn patchFunc, signal string, patch []byte) error {
+	activity.Record(ctx, "Sending %v to %v with the patch %s", signal, w, patch)
+	return fn(ctx, w.Name(), types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func newTestClientset(t *testing.T) *fakeClientset {
+	clientset, err := newFakeClientset(&rest.Config{Host: "127.0.0.1:1"})
+	require.NoError(t, err)
+	return clientset.(*fakeClientset)
+}
+
+func podNames(pods []corev1.Pod) []string {
+	names := make([]string, len(pods))
+	for i, pd := range pods {
+		names[i] = pd.Name
+	}
+	return names
+}
+
+func TestOwnedPods(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+
+	pods, err := ownedPods(ctx, client, "default", "web-deployment")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, podNames(pods))
+
+	pods, err = ownedPods(ctx, client, "default", "backup-cronjob")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup-1-abcde"}, podNames(pods))
+
+	pods, err = ownedPods(ctx, client, "default", "other")
+	require.NoError(t, err)
+	assert.Empty(t, pods)
+}
+
+func TestDeploymentSignal(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+	deployments := client.AppsV1().Deployments("default")
+	d, err := deployments.Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	dp := newDeployment(d, client, nil)
+
+	require.NoError(t, dp.Signal(ctx, "scale-3"))
+	require.NoError(t, dp.Signal(ctx, "pause"))
+	require.NoError(t, dp.Signal(ctx, "restart"))
+	d, err = deployments.Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	if assert.NotNil(t, d.Spec.Replicas) {
+		assert.Equal(t, int32(3), *d.Spec.Replicas)
+	}
+	assert.True(t, d.Spec.Paused)
+	assert.Contains(t, d.Spec.Template.Annotations, "kubectl.kubernetes.io/restartedAt")
+
+	require.NoError(t, dp.Signal(ctx, "resume"))
+	d, err = deployments.Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, d.Spec.Paused)
+}
+
+func TestCronJobTrigger(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+	cj, err := client.BatchV1beta1().CronJobs("default").Get(ctx, "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, newCronJob(cj, client, nil).Signal(ctx, "trigger"))
+	jobs, err := client.BatchV1().Jobs("default").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, jobs.Items, 2)
+	var triggered bool
+	for _, jb := range jobs.Items {
+		if strings.HasPrefix(jb.Name, "backup-manual-") {
+			triggered = true
+			assert.Equal(t, "manual", jb.Annotations["cronjob.kubernetes.io/instantiate"])
+			if assert.Len(t, jb.OwnerReferences, 1) {
+				assert.Equal(t, cj.UID, jb.OwnerReferences[0].UID)
+			}
+			assert.Equal(t, cj.Spec.JobTemplate.Spec, jb.Spec)
+		}
+	}
+	assert.True(t, triggered, "expected a backup-manual-* job")
+}
+
+func TestWorkloadLogFile(t *testing.T) {
+	client := newTestClientset(t)
+	ctx := context.Background()
+	// Give the stateful set a second pod with two containers
+	pd := newFakePod("db-1", "db-statefulset")
+	pd.Spec.Containers = append(pd.Spec.Containers, corev1.Container{Name: "sidecar"})
+	_, err := client.CoreV1().Pods("default").Create(ctx, pd, metav1.CreateOptions{})
+	require.NoError(t, err)
+	s, err := client.AppsV1().StatefulSets("default").Get(ctx, "db", metav1.GetOptions{})
+	require.NoError(t, err)
+	st := newStatefulSet(s, client, nil)
+
+	entries, err := st.List(ctx)
+	require.NoError(t, err)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = plugin.Name(entry)
+	}
+	assert.Equal(t, []string{"log", "db-0", "db-1"}, names)
+
+	log := newWorkloadLogFile(&st.workload)
+	content, err := log.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "db-0         | GET / 200\ndb-1/nginx   | GET / 200\ndb-1/sidecar | GET / 200\n", string(content))
+
+	rdr, err := log.Stream(ctx)
+	require.NoError(t, err)
+	content, err = ioutil.ReadAll(rdr)
+	assert.NoError(t, err)
+	assert.NoError(t, rdr.Close())
+	assert.ElementsMatch(t, []string{"db-0         | GET / 200", "db-1/nginx   | GET / 200", "db-1/sidecar | GET / 200"}, strings.Split(strings.TrimSpace(string(content)), "\n"))
+}
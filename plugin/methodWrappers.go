@@ -29,7 +29,8 @@ func IsInvalidInputErr(err error) bool {
 // This file contains all of the plugin.<Method> wrappers. You should
 // invoke plugin.<Method> instead of e.<Method> because plugin.<Method>
 // could contain additional, plugin-agnostic code required to get e.<Method>
-// working correctly (like e.g. caching and validation).
+// working correctly (like e.g. caching and validation), and because
+// plugin.<Method> is where results are recorded by StartRecording.
 
 // DefaultTimeout is the default timeout for prefetching
 var DefaultTimeout = 10 * time.Second
@@ -114,7 +115,14 @@ func Schema(e Entry) (*EntrySchema, error) {
 //
 // Note that List's results could be cached.
 func List(ctx context.Context, p Parent) (*EntryMap, error) {
-	return cachedList(ctx, p)
+	entries, err := cachedList(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if r := recording(); r != nil {
+		r.recordList(p, entries)
+	}
+	return entries, nil
 }
 
 // Read reads up to size bits of the entry's content starting at the given offset.
@@ -163,6 +171,9 @@ func Read(ctx context.Context, e Entry, size int64, offset int64) (data []byte,
 	if actualSize := int64(len(data)); actualSize > size {
 		return nil, fmt.Errorf("requested %v bytes (input was %v), but plugin's API returned %v bytes", size, inputSize, actualSize)
 	}
+	if r := recording(); r != nil && (err == nil || err == io.EOF) {
+		r.recordRead(e, offset, data)
+	}
 	return
 }
 
@@ -193,17 +204,49 @@ func PartialMetadata(e Entry) JSONObject {
 
 // Metadata returns the entry's metadata. Note that Metadata's results could be cached.
 func Metadata(ctx context.Context, e Entry) (JSONObject, error) {
-	return cachedMetadata(ctx, e)
+	meta, err := cachedMetadata(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	if r := recording(); r != nil {
+		r.recordMetadata(e, meta)
+	}
+	return meta, nil
+}
+
+// PrefetchChildMetadata fetches the full metadata of the given children in
+// bulk if p supports it. Subsequent Metadata calls on those children will not
+// need to fetch their metadata one at a time. It is a no-op if p does not
+// support bulk metadata fetches.
+func PrefetchChildMetadata(ctx context.Context, p Parent, children []Entry) error {
+	if prefetcher, ok := p.(childMetadataPrefetcher); ok && len(children) > 0 {
+		return prefetcher.PrefetchChildMetadata(ctx, children)
+	}
+	return nil
 }
 
 // Exec execs the command on the given entry.
 func Exec(ctx context.Context, e Execable, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
-	return e.Exec(ctx, cmd, args, opts)
+	execCmd, err := e.Exec(ctx, cmd, args, opts)
+	if err != nil {
+		return nil, err
+	}
+	if r := recording(); r != nil {
+		execCmd = r.recordExec(ctx, e, cmd, args, execCmd)
+	}
+	return execCmd, nil
 }
 
 // Stream streams the entry's content for updates.
 func Stream(ctx context.Context, s Streamable) (io.ReadCloser, error) {
-	return s.Stream(ctx)
+	rdr, err := s.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r := recording(); r != nil {
+		rdr = r.recordStream(s, rdr)
+	}
+	return rdr, nil
 }
 
 // Write sends the supplied buffer to the entry.
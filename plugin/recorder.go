@@ -0,0 +1,394 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cassette is a recording of the List, Read, Metadata, Stream and Exec results
+// of every entry in a subtree of Wash's filesystem. Cassettes are created by
+// StartRecording/StopRecording, and they are served by the replay plugin.
+//
+// Entries is a map of <path> => <recorded_entry>, where <path> is the entry's
+// ID relative to Root. The recorded root's path is "".
+type Cassette struct {
+	Root       string                    `json:"root"`
+	RecordedAt time.Time                 `json:"recorded_at"`
+	Schema     map[string]EntrySchema    `json:"schema,omitempty"`
+	Entries    map[string]*CassetteEntry `json:"entries"`
+}
+
+// CassetteEntry represents a recorded entry. Fields that are nil were not
+// recorded, e.g. a nil Children array means that the entry was never listed.
+type CassetteEntry struct {
+	Name            string          `json:"name"`
+	TypeID          string          `json:"type_id"`
+	Actions         []string        `json:"actions"`
+	BlockReadable   bool            `json:"block_readable,omitempty"`
+	SlashReplacer   string          `json:"slash_replacer,omitempty"`
+	Prefetched      bool            `json:"prefetched,omitempty"`
+	Attributes      EntryAttributes `json:"attributes"`
+	PartialMetadata JSONObject      `json:"partial_metadata,omitempty"`
+	Metadata        JSONObject      `json:"metadata"`
+	Children        []string        `json:"children"`
+	Content         []byte          `json:"content"`
+	Stream          []byte          `json:"stream"`
+	Execs           []CassetteExec  `json:"execs,omitempty"`
+}
+
+// CassetteExec represents a recorded Exec invocation.
+type CassetteExec struct {
+	Cmd      string              `json:"cmd"`
+	Args     []string            `json:"args"`
+	Output   []CassetteExecChunk `json:"output"`
+	ExitCode int                 `json:"exit_code"`
+}
+
+// CassetteExecChunk represents a chunk of a recorded Exec invocation's output.
+type CassetteExecChunk struct {
+	StreamID ExecPacketType `json:"stream"`
+	Data     string         `json:"data"`
+}
+
+// FindExec returns the most recent recording of cmd + args, or nil if cmd + args
+// was not recorded.
+func (e *CassetteEntry) FindExec(cmd string, args []string) *CassetteExec {
+	for i := len(e.Execs) - 1; i >= 0; i-- {
+		if e.Execs[i].matches(cmd, args) {
+			return &e.Execs[i]
+		}
+	}
+	return nil
+}
+
+func (e *CassetteExec) matches(cmd string, args []string) bool {
+	if e.Cmd != cmd || len(e.Args) != len(args) {
+		return false
+	}
+	for i := range args {
+		if e.Args[i] != args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadCassette reads the cassette stored at path.
+func ReadCassette(path string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(content, &c); err != nil {
+		return nil, fmt.Errorf("could not decode the cassette at %v: %v", path, err)
+	}
+	if c.Entries == nil {
+		c.Entries = make(map[string]*CassetteEntry)
+	}
+	return &c, nil
+}
+
+// maxRecordedStreamSize is the maximum number of bytes of a stream that will
+// be recorded.
+const maxRecordedStreamSize = 1 << 20
+
+type recorder struct {
+	mux            sync.Mutex
+	cassettePath   string
+	cassette       *Cassette
+	schemaRecorded bool
+}
+
+var activeRecorder *recorder
+var activeRecorderMux sync.RWMutex
+
+// StartRecording starts recording the results of the plugin.<Method> wrappers for
+// root and all of its descendants. The recording is written to a cassette at
+// cassettePath when StopRecording is called. Cassettes can then be served by the
+// replay plugin.
+func StartRecording(root string, cassettePath string) error {
+	if !strings.HasPrefix(root, "/") {
+		return fmt.Errorf("the recorded path must be absolute (rooted at Wash's mountpoint), not %v", root)
+	}
+	if len(cassettePath) == 0 {
+		return fmt.Errorf("a cassette must be specified")
+	}
+
+	activeRecorderMux.Lock()
+	defer activeRecorderMux.Unlock()
+	if activeRecorder != nil {
+		return fmt.Errorf("a recording of %v is already in progress", activeRecorder.cassette.Root)
+	}
+	activeRecorder = &recorder{
+		cassettePath: cassettePath,
+		cassette: &Cassette{
+			Root:       strings.TrimRight(root, "/"),
+			RecordedAt: time.Now(),
+			Entries:    make(map[string]*CassetteEntry),
+		},
+	}
+	return nil
+}
+
+// StopRecording stops the current recording and writes its cassette. It noops if
+// nothing is being recorded.
+func StopRecording() error {
+	activeRecorderMux.Lock()
+	r := activeRecorder
+	activeRecorder = nil
+	activeRecorderMux.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	content, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal the cassette: %v", err)
+	}
+	return ioutil.WriteFile(r.cassettePath, content, 0640)
+}
+
+// recording returns the active recorder, or nil if nothing is being recorded.
+func recording() *recorder {
+	activeRecorderMux.RLock()
+	defer activeRecorderMux.RUnlock()
+	return activeRecorder
+}
+
+// covers returns true if id is the recorded root or one of its descendants.
+func (r *recorder) covers(id string) bool {
+	return id == r.cassette.Root || strings.HasPrefix(id, r.cassette.Root+"/")
+}
+
+func (r *recorder) relativePath(id string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(id, r.cassette.Root), "/")
+}
+
+// entry returns e's recording, creating it if necessary. It returns nil if
+// e is not being recorded. r.mux must be held by the caller.
+func (r *recorder) entry(e Entry) *CassetteEntry {
+	id := e.eb().id
+	if !r.covers(id) {
+		return nil
+	}
+	path := r.relativePath(id)
+	rec, ok := r.cassette.Entries[path]
+	if !ok {
+		rec = &CassetteEntry{}
+		r.cassette.Entries[path] = rec
+	}
+	rec.Name = e.eb().name
+	rec.TypeID = TypeID(e)
+	rec.Actions = SupportedActionsOf(e)
+	rec.BlockReadable = ReadAction().signature(e) == BlockReadableSignature
+	if e.eb().slashReplacer != '#' {
+		rec.SlashReplacer = string(e.eb().slashReplacer)
+	}
+	rec.Prefetched = e.eb().isPrefetched
+	rec.Attributes = e.eb().attributes
+	rec.PartialMetadata = e.eb().specifiedPartialMetadata
+	return rec
+}
+
+// recordSchema records e's schema graph if e is the recorded root.
+func (r *recorder) recordSchema(e Entry) {
+	r.mux.Lock()
+	alreadyRecorded := r.schemaRecorded
+	r.mux.Unlock()
+	if alreadyRecorded || e.eb().id != r.cassette.Root {
+		return
+	}
+
+	var schema map[string]EntrySchema
+	graph, err := SchemaGraph(e)
+	if err == nil && graph != nil {
+		schema = make(map[string]EntrySchema)
+		graph.Each(func(key interface{}, value interface{}) {
+			schema[key.(string)] = value.(EntrySchema)
+		})
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.cassette.Schema = schema
+	r.schemaRecorded = true
+}
+
+func (r *recorder) recordList(p Parent, entries *EntryMap) {
+	r.recordSchema(p)
+	entries.Range(func(_ string, child Entry) bool {
+		r.recordSchema(child)
+		return true
+	})
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	cnames := []string{}
+	entries.Range(func(cname string, child Entry) bool {
+		cnames = append(cnames, cname)
+		r.entry(child)
+		return true
+	})
+	if rec := r.entry(p); rec != nil {
+		sort.Strings(cnames)
+		rec.Children = cnames
+	}
+}
+
+func (r *recorder) recordRead(e Entry, offset int64, data []byte) {
+	r.recordSchema(e)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rec := r.entry(e)
+	if rec == nil {
+		return
+	}
+	end := offset + int64(len(data))
+	if rec.Content == nil {
+		rec.Content = []byte{}
+	}
+	if int64(len(rec.Content)) < end {
+		content := make([]byte, end)
+		copy(content, rec.Content)
+		rec.Content = content
+	}
+	copy(rec.Content[offset:end], data)
+}
+
+func (r *recorder) recordMetadata(e Entry, meta JSONObject) {
+	r.recordSchema(e)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if rec := r.entry(e); rec != nil {
+		rec.Metadata = meta
+	}
+}
+
+func (r *recorder) recordStream(e Entry, rdr io.ReadCloser) io.ReadCloser {
+	r.recordSchema(e)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rec := r.entry(e)
+	if rec == nil {
+		return rdr
+	}
+	rec.Stream = []byte{}
+	return &streamRecorder{ReadCloser: rdr, recorder: r, rec: rec}
+}
+
+// streamRecorder tees a stream's content into its entry's recording.
+type streamRecorder struct {
+	io.ReadCloser
+	recorder *recorder
+	rec      *CassetteEntry
+}
+
+func (s *streamRecorder) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		s.recorder.mux.Lock()
+		if remaining := maxRecordedStreamSize - len(s.rec.Stream); remaining > 0 {
+			if n < remaining {
+				remaining = n
+			}
+			s.rec.Stream = append(s.rec.Stream, p[:remaining]...)
+		}
+		s.recorder.mux.Unlock()
+	}
+	return n, err
+}
+
+func (r *recorder) recordExec(ctx context.Context, e Entry, cmd string, args []string, execCmd ExecCommand) ExecCommand {
+	r.recordSchema(e)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rec := r.entry(e)
+	if rec == nil {
+		return execCmd
+	}
+	recorded := &execRecorder{
+		ExecCommand: execCmd,
+		recorder:    r,
+		rec:         rec,
+		exec:        CassetteExec{Cmd: cmd, Args: args, Output: []CassetteExecChunk{}},
+		outputCh:    make(chan ExecOutputChunk),
+		done:        make(chan struct{}),
+	}
+	go recorded.forwardOutput(ctx)
+	return recorded
+}
+
+// execRecorder records an Exec invocation's output and exit code. The invocation is
+// recorded once it finishes, whether or not the caller asks for its exit code.
+type execRecorder struct {
+	ExecCommand
+	recorder    *recorder
+	rec         *CassetteEntry
+	exec        CassetteExec
+	outputCh    chan ExecOutputChunk
+	done        chan struct{}
+	exitCode    int
+	exitCodeErr error
+}
+
+func (c *execRecorder) forwardOutput(ctx context.Context) {
+	forwarding := true
+	for chunk := range c.ExecCommand.OutputCh() {
+		if chunk.Err == nil {
+			c.recorder.mux.Lock()
+			c.exec.Output = append(c.exec.Output, CassetteExecChunk{StreamID: chunk.StreamID, Data: chunk.Data})
+			c.recorder.mux.Unlock()
+		}
+		if !forwarding {
+			// Keep draining the command's output so that its producer isn't blocked.
+			continue
+		}
+		select {
+		case c.outputCh <- chunk:
+		case <-ctx.Done():
+			forwarding = false
+			close(c.outputCh)
+		}
+	}
+	if forwarding {
+		close(c.outputCh)
+	}
+
+	c.exitCode, c.exitCodeErr = c.ExecCommand.ExitCode()
+	if c.exitCodeErr == nil {
+		c.recorder.mux.Lock()
+		c.exec.ExitCode = c.exitCode
+		for i, exec := range c.rec.Execs {
+			if exec.matches(c.exec.Cmd, c.exec.Args) {
+				c.rec.Execs = append(c.rec.Execs[:i], c.rec.Execs[i+1:]...)
+				break
+			}
+		}
+		c.rec.Execs = append(c.rec.Execs, c.exec)
+		c.recorder.mux.Unlock()
+	}
+	close(c.done)
+}
+
+func (c *execRecorder) OutputCh() <-chan ExecOutputChunk {
+	return c.outputCh
+}
+
+func (c *execRecorder) ExitCode() (int, error) {
+	<-c.done
+	return c.exitCode, c.exitCodeErr
+}
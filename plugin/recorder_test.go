@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecorderTestSuite struct {
+	suite.Suite
+	ctx          context.Context
+	cassettePath string
+}
+
+func (suite *RecorderTestSuite) SetupTest() {
+	suite.ctx = SetTestCache(datastore.NewMemCache())
+	dir, err := ioutil.TempDir("", "wash_recorder_test")
+	suite.Require().NoError(err)
+	suite.cassettePath = filepath.Join(dir, "cassette.json")
+}
+
+func (suite *RecorderTestSuite) TearDownTest() {
+	UnsetTestCache()
+	suite.NoError(StopRecording())
+	os.RemoveAll(filepath.Dir(suite.cassettePath))
+}
+
+func (suite *RecorderTestSuite) TestStartRecording_ValidatesInput() {
+	suite.Error(StartRecording("docker", suite.cassettePath))
+	suite.Error(StartRecording("/docker", ""))
+	suite.NoError(StartRecording("/docker", suite.cassettePath))
+	suite.Error(StartRecording("/docker", suite.cassettePath))
+}
+
+func (suite *RecorderTestSuite) TestStopRecording_NotRecording_Noops() {
+	suite.NoError(StopRecording())
+	_, err := os.Stat(suite.cassettePath)
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *RecorderTestSuite) TestRecordsTheSubtree() {
+	root := newRecorderTestsDir("root")
+	root.SetTestID("/root")
+	dir := newRecorderTestsDir("dir")
+	file := newRecorderTestsFile("file", "hello world")
+	dir.children = []Entry{file}
+	other := newRecorderTestsDir("other")
+	root.children = []Entry{dir, other}
+
+	suite.Require().NoError(StartRecording("/root/dir", suite.cassettePath))
+
+	entries, err := List(suite.ctx, root)
+	suite.Require().NoError(err)
+	_, err = List(suite.ctx, other)
+	suite.Require().NoError(err)
+	_, err = List(suite.ctx, dir)
+	suite.Require().NoError(err)
+	_, err = Read(suite.ctx, file, 5, 6)
+	suite.Require().NoError(err)
+	_, err = Metadata(suite.ctx, file)
+	suite.Require().NoError(err)
+
+	rdr, err := Stream(suite.ctx, file)
+	suite.Require().NoError(err)
+	streamed, err := ioutil.ReadAll(rdr)
+	suite.Require().NoError(err)
+	suite.Equal("hello world", string(streamed))
+
+	cmd, err := Exec(suite.ctx, file, "echo", []string{"foo"}, ExecOptions{})
+	suite.Require().NoError(err)
+	for chunk := range cmd.OutputCh() {
+		suite.Equal("foo", chunk.Data)
+	}
+	exitCode, err := cmd.ExitCode()
+	suite.Require().NoError(err)
+	suite.Equal(0, exitCode)
+	suite.Equal(2, entries.Len())
+
+	suite.Require().NoError(StopRecording())
+	cassette, err := ReadCassette(suite.cassettePath)
+	suite.Require().NoError(err)
+
+	suite.Equal("/root/dir", cassette.Root)
+	suite.Len(cassette.Entries, 2)
+	suite.Contains(cassette.Schema, TypeID(dir))
+	suite.Contains(cassette.Schema, TypeID(file))
+
+	recordedDir := cassette.Entries[""]
+	suite.Require().NotNil(recordedDir)
+	suite.Equal("dir", recordedDir.Name)
+	suite.Equal([]string{"file"}, recordedDir.Children)
+	suite.Equal([]string{"list"}, recordedDir.Actions)
+
+	recordedFile := cassette.Entries["file"]
+	suite.Require().NotNil(recordedFile)
+	suite.Nil(recordedFile.Children)
+	suite.Equal("\x00\x00\x00\x00\x00\x00world", string(recordedFile.Content))
+	suite.Equal(JSONObject{"file": "metadata"}, recordedFile.Metadata)
+	suite.Equal("hello world", string(recordedFile.Stream))
+	if suite.Len(recordedFile.Execs, 1) {
+		exec := recordedFile.FindExec("echo", []string{"foo"})
+		suite.Require().NotNil(exec)
+		suite.Equal([]CassetteExecChunk{{StreamID: Stdout, Data: "foo"}}, exec.Output)
+		suite.Equal(0, exec.ExitCode)
+	}
+	suite.Nil(recordedFile.FindExec("echo", []string{"bar"}))
+}
+
+func (suite *RecorderTestSuite) TestRecordsExecsWhenTheyFinish() {
+	file := newRecorderTestsFile("file", "")
+	file.SetTestID("/root/file")
+	suite.Require().NoError(StartRecording("/root", suite.cassettePath))
+
+	// The exec is recorded even though its exit code is never asked for.
+	cmd, err := Exec(suite.ctx, file, "echo", []string{"foo"}, ExecOptions{})
+	suite.Require().NoError(err)
+	for chunk := range cmd.OutputCh() {
+		suite.Equal("foo", chunk.Data)
+	}
+	<-cmd.(*execRecorder).done
+
+	suite.Require().NoError(StopRecording())
+	cassette, err := ReadCassette(suite.cassettePath)
+	suite.Require().NoError(err)
+	recordedFile := cassette.Entries["file"]
+	suite.Require().NotNil(recordedFile)
+	exec := recordedFile.FindExec("echo", []string{"foo"})
+	suite.Require().NotNil(exec)
+	suite.Equal([]CassetteExecChunk{{StreamID: Stdout, Data: "foo"}}, exec.Output)
+}
+
+func (suite *RecorderTestSuite) TestDrainsCancelledExecs() {
+	file := newRecorderTestsFile("file", "")
+	file.SetTestID("/root/file")
+	file.finished = make(chan struct{})
+	suite.Require().NoError(StartRecording("/root", suite.cassettePath))
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cmd, err := Exec(ctx, file, "echo", []string{"foo", "bar"}, ExecOptions{})
+	suite.Require().NoError(err)
+	cancel()
+
+	for _, ch := range []chan struct{}{file.finished, cmd.(*execRecorder).done} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			suite.FailNow("the cancelled exec did not finish")
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	suite.Run(t, new(RecorderTestSuite))
+}
+
+type recorderTestsDir struct {
+	EntryBase
+	children []Entry
+}
+
+func newRecorderTestsDir(name string) *recorderTestsDir {
+	return &recorderTestsDir{EntryBase: NewEntry(name)}
+}
+
+func (d *recorderTestsDir) Schema() *EntrySchema {
+	return NewEntrySchema(d, "dir")
+}
+
+func (d *recorderTestsDir) ChildSchemas() []*EntrySchema {
+	return []*EntrySchema{
+		(&recorderTestsDir{}).Schema(),
+		(&recorderTestsFile{}).Schema(),
+	}
+}
+
+func (d *recorderTestsDir) List(context.Context) ([]Entry, error) {
+	return d.children, nil
+}
+
+type recorderTestsFile struct {
+	EntryBase
+	content string
+	// finished is closed once Exec's command finishes, if it's set.
+	finished chan struct{}
+}
+
+func newRecorderTestsFile(name string, content string) *recorderTestsFile {
+	f := &recorderTestsFile{EntryBase: NewEntry(name), content: content}
+	f.Attributes().SetSize(uint64(len(content)))
+	return f
+}
+
+func (f *recorderTestsFile) Schema() *EntrySchema {
+	return NewEntrySchema(f, "file")
+}
+
+func (f *recorderTestsFile) Read(context.Context) ([]byte, error) {
+	return []byte(f.content), nil
+}
+
+func (f *recorderTestsFile) Metadata(context.Context) (JSONObject, error) {
+	return JSONObject{"file": "metadata"}, nil
+}
+
+func (f *recorderTestsFile) Stream(context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(f.content)), nil
+}
+
+func (f *recorderTestsFile) Exec(ctx context.Context, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
+	execCmd := NewExecCommand(ctx)
+	go func() {
+		_, _ = execCmd.Stdout().Write([]byte(strings.Join(args, " ")))
+		execCmd.CloseStreamsWithError(nil)
+		execCmd.SetExitCode(0)
+		if f.finished != nil {
+			close(f.finished)
+		}
+	}()
+	return execCmd, nil
+}
@@ -62,7 +62,16 @@ func (r *Registry) RegisterPlugin(root Root, config map[string]interface{}) erro
 		r.mux.Unlock()
 	}
 
-	if err := root.Init(config); err != nil {
+	initPlugin := func() error {
+		if root, ok := root.(HasConfigSchema); ok {
+			if err := validateConfig(root, config); err != nil {
+				return err
+			}
+		}
+		return root.Init(config)
+	}
+
+	if err := initPlugin(); err != nil {
 		// Create a stubPluginRoot so that Wash users can see the plugin's
 		// documentation via 'describe <plugin>'. This is important b/c the
 		// plugin docs also include details on how to set it up. Note that
@@ -98,28 +107,50 @@ func (r *Registry) List(ctx context.Context) ([]Entry, error) {
 type stubRoot struct {
 	EntryBase
 	pluginDocumentation string
+	configSchema        *JSONSchema
 }
 
 func newStubRoot(root Root) *stubRoot {
 	stubRoot := &stubRoot{
-		EntryBase: NewEntry(Name(root)),
+		EntryBase: NewEntry(pluginNameOf(root)),
 	}
 	stubRoot.DisableDefaultCaching()
 	schema := root.Schema()
 	if schema != nil {
 		stubRoot.pluginDocumentation = schema.Description
 	}
+	if root, ok := root.(HasConfigSchema); ok {
+		// Include the config schema so that 'docs <plugin>' shows the
+		// config options, which is useful when the config was invalid.
+		stubRoot.configSchema = configSchemaOf(root)
+	}
 	return stubRoot
 }
 
+// pluginNameOf returns the plugin's name. Core plugin roots set their name in
+// Init, so pluginNameOf falls back to the root schema's label (which is the
+// plugin's name) if the registry rejected the plugin's config before calling
+// Init.
+func pluginNameOf(root Root) string {
+	if name := root.eb().name; name != "" {
+		return name
+	}
+	if schema := root.Schema(); schema != nil {
+		return schema.Label
+	}
+	return ""
+}
+
 func (r *stubRoot) Init(map[string]interface{}) error {
 	return nil
 }
 
 func (r *stubRoot) Schema() *EntrySchema {
-	return NewEntrySchema(r, CName(r)).
+	schema := NewEntrySchema(r, CName(r)).
 		SetDescription(r.pluginDocumentation).
 		IsSingleton()
+	schema.ConfigSchema = r.configSchema
+	return schema
 }
 
 func (r *stubRoot) ChildSchemas() []*EntrySchema {
@@ -117,6 +117,75 @@ func (suite *RegistryTestSuite) TestRegisterPluginRegisteredPlugin() {
 	suite.Panics(panicFunc, "r.RegisterPlugin: the mine plugin's already been registered")
 }
 
+type mockRootWithConfigSchema struct {
+	*mockRoot
+}
+
+type mockConfig struct {
+	Names   []string `json:"names" jsonschema_description:"Some names"`
+	Enabled bool     `json:"enabled" jsonschema:"required"`
+}
+
+func (m *mockRootWithConfigSchema) ConfigSchema() interface{} {
+	return mockConfig{}
+}
+
+func (suite *RegistryTestSuite) TestRegisterPluginWithConfigSchema() {
+	reg := NewRegistry()
+	m := &mockRootWithConfigSchema{&mockRoot{EntryBase: NewEntry("mine")}}
+	cfg := map[string]interface{}{"names": []interface{}{"foo"}, "enabled": true}
+	m.On("Init", cfg).Return(nil)
+
+	suite.NoError(reg.RegisterPlugin(m, cfg))
+	m.AssertExpectations(suite.T())
+	suite.Equal(m, reg.Plugins()["mine"])
+}
+
+func (suite *RegistryTestSuite) TestRegisterPluginInvalidConfig() {
+	reg := NewRegistry()
+	m := &mockRootWithConfigSchema{&mockRoot{EntryBase: NewEntry("mine")}}
+	cfg := map[string]interface{}{"names": []interface{}{"foo", 1}, "nmes": "foo"}
+
+	err := reg.RegisterPlugin(m, cfg)
+	if suite.Error(err) {
+		suite.Regexp("^invalid config: ", err)
+		suite.Regexp("mine: enabled is required", err)
+		suite.Regexp("mine: Additional property nmes is not allowed", err)
+		suite.Regexp("mine.names.1: Invalid type. Expected: string, given: integer", err)
+	}
+	m.AssertNotCalled(suite.T(), "Init", mock.Anything)
+
+	// The stub root should still include the config schema so that users
+	// can see the config options via 'docs <plugin>'.
+	stub, ok := reg.Plugins()["mine"].(*stubRoot)
+	if suite.True(ok, "expected a stub plugin root to be registered") {
+		schema, err := Schema(stub)
+		if suite.NoError(err) && suite.NotNil(schema.ConfigSchema) {
+			suite.Contains(schema.ConfigSchema.Properties, "names")
+			suite.Equal([]string{"enabled"}, schema.ConfigSchema.Required)
+		}
+	}
+}
+
+// mockCoreRoot mimics core plugin roots, which set their name in Init.
+type mockCoreRoot struct {
+	*mockRootWithConfigSchema
+}
+
+func (m *mockCoreRoot) Schema() *EntrySchema {
+	return NewEntrySchema(m, "core")
+}
+
+func (suite *RegistryTestSuite) TestRegisterPluginInvalidConfig_NameSetInInit() {
+	reg := NewRegistry()
+	m := &mockCoreRoot{&mockRootWithConfigSchema{&mockRoot{}}}
+
+	err := reg.RegisterPlugin(m, map[string]interface{}{"enabled": "yes"})
+	suite.Regexp("core.enabled: Invalid type. Expected: boolean, given: string", err)
+	_, ok := reg.Plugins()["core"].(*stubRoot)
+	suite.True(ok, "expected a stub plugin root to be registered")
+}
+
 type mockRootWithDelete struct {
 	*mockRoot
 }
@@ -0,0 +1,195 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// entry represents a replayed entry
+type entry struct {
+	plugin.EntryBase
+	cassette *cassette
+	path     string
+	rec      *plugin.CassetteEntry
+}
+
+func newEntry(c *cassette, path string) *entry {
+	rec := c.Entries[path]
+	e := &entry{
+		EntryBase: plugin.NewEntry(rec.Name),
+		cassette:  c,
+		path:      path,
+		rec:       rec,
+	}
+	if rec.SlashReplacer != "" {
+		e.SetSlashReplacer([]rune(rec.SlashReplacer)[0])
+	}
+	if rec.Prefetched {
+		e.Prefetched()
+	}
+	e.SetAttributes(rec.Attributes)
+	if rec.PartialMetadata != nil {
+		e.SetPartialMetadata(rec.PartialMetadata)
+	}
+	return e
+}
+
+func (e *entry) notRecordedErr(what string) error {
+	return fmt.Errorf("%v of %v were not recorded in the cassette", what, e.originalID())
+}
+
+// originalID returns the ID of the entry that was recorded
+func (e *entry) originalID() string {
+	if e.path == "" {
+		return e.cassette.Root
+	}
+	return e.cassette.Root + "/" + e.path
+}
+
+func (e *entry) MethodSignature(method string) plugin.MethodSignature {
+	for _, action := range e.rec.Actions {
+		if action != method {
+			continue
+		}
+		if action == plugin.ReadAction().Name && e.rec.BlockReadable {
+			return plugin.BlockReadableSignature
+		}
+		return plugin.DefaultSignature
+	}
+	return plugin.UnsupportedSignature
+}
+
+func (e *entry) RawTypeID() string {
+	return e.rec.TypeID
+}
+
+func (e *entry) SchemaGraph() (*linkedhashmap.Map, error) {
+	graphs := e.cassette.root.schemaGraphs
+	if graphs == nil {
+		return nil, nil
+	}
+	graph, ok := graphs[plugin.TypeID(e)]
+	if !ok {
+		return nil, fmt.Errorf("the cassette's schema does not include %v", e.rec.TypeID)
+	}
+	return graph, nil
+}
+
+func (e *entry) Schema() *plugin.EntrySchema {
+	// Replayed entries use the cassette's schema. See SchemaGraph.
+	return nil
+}
+
+func (e *entry) ChildSchemas() []*plugin.EntrySchema {
+	// Replayed entries use the cassette's schema. See SchemaGraph.
+	return []*plugin.EntrySchema{}
+}
+
+func (e *entry) List(ctx context.Context) ([]plugin.Entry, error) {
+	if e.rec.Children == nil {
+		return nil, e.notRecordedErr("the children")
+	}
+	entries := make([]plugin.Entry, 0, len(e.rec.Children))
+	for _, cname := range e.rec.Children {
+		path := cname
+		if e.path != "" {
+			path = e.path + "/" + cname
+		}
+		if _, ok := e.cassette.Entries[path]; !ok {
+			activity.Warnf(ctx, "Omitting %v: it was not recorded in the cassette", path)
+			continue
+		}
+		entries = append(entries, newEntry(e.cassette, path))
+	}
+	return entries, nil
+}
+
+func (e *entry) Read(ctx context.Context) ([]byte, error) {
+	if e.rec.Content == nil {
+		return nil, e.notRecordedErr("the contents")
+	}
+	return e.rec.Content, nil
+}
+
+func (e *entry) BlockRead(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	if e.rec.Content == nil {
+		return nil, e.notRecordedErr("the contents")
+	}
+	contentSize := int64(len(e.rec.Content))
+	if offset >= contentSize {
+		return []byte{}, nil
+	}
+	end := offset + size
+	if end > contentSize {
+		end = contentSize
+	}
+	return e.rec.Content[offset:end], nil
+}
+
+func (e *entry) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	if e.rec.Metadata == nil {
+		// The entry's full metadata wasn't recorded, so fallback to its
+		// partial metadata.
+		return e.EntryBase.Metadata(ctx)
+	}
+	return e.rec.Metadata, nil
+}
+
+func (e *entry) Stream(ctx context.Context) (io.ReadCloser, error) {
+	if e.rec.Stream == nil {
+		return nil, e.notRecordedErr("the stream's contents")
+	}
+	return ioutil.NopCloser(bytes.NewReader(e.rec.Stream)), nil
+}
+
+func (e *entry) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	recorded := e.rec.FindExec(cmd, args)
+	if recorded == nil {
+		cmdline := strings.Join(append([]string{cmd}, args...), " ")
+		return nil, e.notRecordedErr(fmt.Sprintf("the results of '%v'", cmdline))
+	}
+	activity.Record(ctx, "Replaying %v %v on %v", cmd, args, e.originalID())
+
+	execCmd := plugin.NewExecCommand(ctx)
+	go func() {
+		for _, chunk := range recorded.Output {
+			var err error
+			switch chunk.StreamID {
+			case plugin.Stderr:
+				_, err = execCmd.Stderr().Write([]byte(chunk.Data))
+			default:
+				_, err = execCmd.Stdout().Write([]byte(chunk.Data))
+			}
+			if err != nil {
+				return
+			}
+		}
+		execCmd.CloseStreamsWithError(nil)
+		execCmd.SetExitCode(recorded.ExitCode)
+	}()
+	return execCmd, nil
+}
+
+func (e *entry) Write(ctx context.Context, p []byte) error {
+	return e.modificationErr("write to")
+}
+
+func (e *entry) Delete(ctx context.Context) (bool, error) {
+	return false, e.modificationErr("delete")
+}
+
+func (e *entry) Signal(ctx context.Context, signal string) error {
+	return e.modificationErr("signal")
+}
+
+func (e *entry) modificationErr(action string) error {
+	return fmt.Errorf("cannot %v %v: replayed entries cannot be modified", action, e.originalID())
+}
@@ -0,0 +1,180 @@
+// Package replay presents entries that were recorded into cassettes by the
+// Wash server's `record` option. Replayed entries do not talk to any plugin
+// API, so the replay plugin is useful for deterministic tests and demos of
+// FUSE, find, ps, etc.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Root of the replay plugin
+type Root struct {
+	plugin.EntryBase
+	cassettes    []*cassette
+	schemaGraphs map[string]*linkedhashmap.Map
+}
+
+// cassette is a wrapper to a plugin.Cassette. It is shared by all the
+// cassette's replayed entries.
+type cassette struct {
+	*plugin.Cassette
+	root *Root
+}
+
+// Init for root
+func (r *Root) Init(cfg map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("replay")
+	r.DisableDefaultCaching()
+
+	var paths []string
+	if cassettesI, ok := cfg["cassettes"]; ok {
+		cassettes, ok := cassettesI.([]interface{})
+		if !ok {
+			return fmt.Errorf("replay.cassettes config must be an array of strings, not %s", cassettesI)
+		}
+		for _, elem := range cassettes {
+			path, ok := elem.(string)
+			if !ok {
+				return fmt.Errorf("replay.cassettes config must be an array of strings, not %s", cassettes)
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	r.cassettes = nil
+	for _, path := range paths {
+		c, err := plugin.ReadCassette(path)
+		if err != nil {
+			return err
+		}
+		if _, ok := c.Entries[""]; !ok {
+			return fmt.Errorf("the cassette at %v does not include its root (%v)", path, c.Root)
+		}
+		r.cassettes = append(r.cassettes, &cassette{Cassette: c, root: r})
+	}
+	r.schemaGraphs = r.partitionSchemaGraph()
+	return nil
+}
+
+// List lists the roots of the configured cassettes.
+func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
+	entries := make([]plugin.Entry, len(r.cassettes))
+	for i, c := range r.cassettes {
+		entries[i] = newEntry(c, "")
+	}
+	return entries, nil
+}
+
+// Schema returns nil because the root's schema is the merged schema of each
+// configured cassette. See SchemaGraph.
+func (r *Root) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+// ChildSchemas is only meant for core plugins. See SchemaGraph.
+func (r *Root) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{}
+}
+
+// MethodSignature returns the signature of the given method. The root can only
+// be listed.
+func (r *Root) MethodSignature(method string) plugin.MethodSignature {
+	if method == plugin.ListAction().Name {
+		return plugin.DefaultSignature
+	}
+	return plugin.UnsupportedSignature
+}
+
+// RawTypeID returns the root's raw type ID
+func (r *Root) RawTypeID() string {
+	return "root"
+}
+
+// SchemaGraph returns the root's schema graph. It is nil if one of the
+// configured cassettes did not record its schema.
+func (r *Root) SchemaGraph() (*linkedhashmap.Map, error) {
+	if r.schemaGraphs == nil {
+		return nil, nil
+	}
+	return r.schemaGraphs[plugin.TypeID(r)], nil
+}
+
+// BlockRead is not supported by the root.
+func (r *Root) BlockRead(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return nil, fmt.Errorf("the replay plugin's root cannot be read")
+}
+
+// partitionSchemaGraph merges the configured cassettes' schemas into a single
+// graph (namespacing each type ID by the replay plugin's name), then partitions
+// it into a map of <type_id> => <schema_graph>. It returns nil if one of the
+// cassettes did not record its schema.
+func (r *Root) partitionSchemaGraph() map[string]*linkedhashmap.Map {
+	rootTypeID := plugin.TypeID(r)
+	var rootNode plugin.EntrySchema
+	rootNode.Label = plugin.Name(r)
+	rootNode.Description = rootDescription
+	rootNode.Singleton = true
+	rootNode.Actions = []string{plugin.ListAction().Name}
+	rootNode.Children = []string{}
+
+	nodes := map[string]plugin.EntrySchema{rootTypeID: rootNode}
+	for _, c := range r.cassettes {
+		if c.Schema == nil {
+			return nil
+		}
+		rootNode.Children = append(rootNode.Children, c.namespace(c.Entries[""].TypeID))
+		for typeID, node := range c.Schema {
+			children := []string{}
+			for _, child := range node.Children {
+				children = append(children, c.namespace(child))
+			}
+			node.Children = children
+			nodes[c.namespace(typeID)] = node
+		}
+	}
+	nodes[rootTypeID] = rootNode
+
+	var populate func(*linkedhashmap.Map, string)
+	populate = func(g *linkedhashmap.Map, typeID string) {
+		if _, ok := g.Get(typeID); ok {
+			return
+		}
+		node, ok := nodes[typeID]
+		if !ok {
+			return
+		}
+		g.Put(typeID, node)
+		children := append([]string{}, node.Children...)
+		sort.Strings(children)
+		for _, child := range children {
+			populate(g, child)
+		}
+	}
+	schemaGraphs := make(map[string]*linkedhashmap.Map)
+	for typeID := range nodes {
+		g := linkedhashmap.New()
+		populate(g, typeID)
+		schemaGraphs[typeID] = g
+	}
+	return schemaGraphs
+}
+
+// namespace returns the replayed type ID of the recorded typeID
+func (c *cassette) namespace(typeID string) string {
+	return plugin.Name(c.root) + "::" + typeID
+}
+
+const rootDescription = `
+This is the replay plugin root. It serves the entries recorded in the cassettes
+listed in the replay.cassettes config. Recorded entries can be listed, read,
+streamed, exec'ed (with previously recorded commands) and have their metadata
+queried without talking to the original plugin's API.
+
+Use the server's record.path and record.cassette config to create a cassette.
+`
@@ -0,0 +1,130 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type RootTestSuite struct {
+	suite.Suite
+	ctx  context.Context
+	root *Root
+}
+
+func (suite *RootTestSuite) SetupTest() {
+	suite.ctx = plugin.SetTestCache(datastore.NewMemCache())
+	suite.root = &Root{}
+	suite.Require().NoError(suite.root.Init(map[string]interface{}{
+		"cassettes": []interface{}{"testdata/containers.json"},
+	}))
+	suite.root.SetTestID("/replay")
+}
+
+func (suite *RootTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+func (suite *RootTestSuite) find(segments ...string) plugin.Entry {
+	var e plugin.Entry = suite.root
+	for _, segment := range segments {
+		entries, err := plugin.List(suite.ctx, e.(plugin.Parent))
+		suite.Require().NoError(err)
+		child, ok := entries.Load(segment)
+		suite.Require().True(ok, "%v was not found", segment)
+		e = child
+	}
+	return e
+}
+
+func (suite *RootTestSuite) TestInit_InvalidConfig() {
+	suite.Error((&Root{}).Init(map[string]interface{}{"cassettes": "foo"}))
+	suite.Error((&Root{}).Init(map[string]interface{}{"cassettes": []interface{}{1}}))
+	suite.Error((&Root{}).Init(map[string]interface{}{"cassettes": []interface{}{"testdata/missing.json"}}))
+}
+
+func (suite *RootTestSuite) TestInit_NoCassettes() {
+	r := &Root{}
+	suite.NoError(r.Init(nil))
+	entries, err := r.List(suite.ctx)
+	suite.NoError(err)
+	suite.Empty(entries)
+}
+
+func (suite *RootTestSuite) TestListAndReadReplayedEntries() {
+	container := suite.find("containers", "web")
+	suite.Equal("/replay/containers/web", plugin.ID(container))
+	suite.Equal(plugin.JSONObject{"Image": "nginx"}, plugin.PartialMetadata(container))
+	meta, err := plugin.Metadata(suite.ctx, container)
+	suite.NoError(err)
+	suite.Equal(plugin.JSONObject{"Image": "nginx", "State": map[string]interface{}{"Running": true}}, meta)
+	suite.ElementsMatch([]string{"list", "exec", "delete", "signal"}, plugin.SupportedActionsOf(container))
+
+	log := suite.find("containers", "web", "log")
+	content, err := plugin.Read(suite.ctx, log, 100, 0)
+	suite.Equal("GET / 200\n", string(content))
+	suite.Equal(io.EOF, err)
+
+	rdr, err := plugin.Stream(suite.ctx, log.(plugin.Streamable))
+	suite.Require().NoError(err)
+	streamed, err := ioutil.ReadAll(rdr)
+	suite.NoError(err)
+	suite.Equal("GET /index.html 200\n", string(streamed))
+
+	_, err = plugin.List(suite.ctx, log.(plugin.Parent))
+	suite.Regexp("children of /docker/containers/web/log were not recorded", err)
+}
+
+func (suite *RootTestSuite) TestExecReplaysRecordedCommands() {
+	container := suite.find("containers", "web").(plugin.Execable)
+
+	cmd, err := plugin.Exec(suite.ctx, container, "uname", []string{"-s"}, plugin.ExecOptions{})
+	suite.Require().NoError(err)
+	var stdout string
+	for chunk := range cmd.OutputCh() {
+		suite.NoError(chunk.Err)
+		suite.Equal(plugin.Stdout, chunk.StreamID)
+		stdout += chunk.Data
+	}
+	suite.Equal("Linux\n", stdout)
+	exitCode, err := cmd.ExitCode()
+	suite.NoError(err)
+	suite.Equal(0, exitCode)
+
+	_, err = plugin.Exec(suite.ctx, container, "uname", []string{"-a"}, plugin.ExecOptions{})
+	suite.Regexp("results of 'uname -a' of /docker/containers/web were not recorded", err)
+}
+
+func (suite *RootTestSuite) TestModificationsAreRejected() {
+	container := suite.find("containers", "web")
+	_, err := plugin.Delete(suite.ctx, container.(plugin.Deletable))
+	suite.Regexp("replayed entries cannot be modified", err)
+}
+
+func (suite *RootTestSuite) TestSchema() {
+	rootSchema, err := plugin.Schema(suite.root)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"replay::docker::github.com/puppetlabs/wash/plugin/docker/containersDir"}, rootSchema.Children)
+
+	container := suite.find("containers", "web")
+	schema, err := plugin.Schema(container)
+	suite.Require().NoError(err)
+	suite.Equal("container", schema.Label)
+	suite.Equal([]string{"replay::docker::github.com/puppetlabs/wash/plugin/docker/containerLogFile"}, schema.Children)
+	if suite.Len(schema.Signals, 1) {
+		suite.Equal("start", schema.Signals[0].Name())
+	}
+
+	graph, err := plugin.SchemaGraph(container)
+	suite.Require().NoError(err)
+	suite.Equal(2, graph.Size())
+}
+
+func TestRoot(t *testing.T) {
+	suite.Run(t, new(RootTestSuite))
+}
@@ -0,0 +1,154 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+)
+
+// hostFacts are details about a host's system that are gathered when it's first exec'd on.
+type hostFacts struct {
+	Kernel        string `json:"kernel"`
+	Hostname      string `json:"hostname"`
+	KernelRelease string `json:"kernel_release"`
+	Architecture  string `json:"architecture"`
+	// OSRelease contains the variables in /etc/os-release, like ID and VERSION_ID. It's
+	// empty on systems without one, like macOS.
+	OSRelease map[string]string `json:"os_release,omitempty"`
+}
+
+const factsScript = "uname -s; uname -n; uname -r; uname -m; cat /etc/os-release 2>/dev/null || true"
+
+// factsStore keeps each host's facts for as long as the plugin's loaded, so that they
+// outlive the host entries.
+type factsStore struct {
+	mux   sync.Mutex
+	hosts map[string]*gatheredFacts
+}
+
+// gatheredFacts are a host's facts. gathering is held while they're gathered, so that
+// they're only gathered once at a time, and mux is only held to read or set them, so
+// that getting them never waits on a slow or unreachable host.
+type gatheredFacts struct {
+	gathering sync.Mutex
+	mux       sync.Mutex
+	facts     *hostFacts
+}
+
+func (g *gatheredFacts) get() *hostFacts {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.facts
+}
+
+func newFactsStore() *factsStore {
+	return &factsStore{hosts: make(map[string]*gatheredFacts)}
+}
+
+func (s *factsStore) forHost(name string) *gatheredFacts {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	gathered, ok := s.hosts[name]
+	if !ok {
+		gathered = &gatheredFacts{}
+		s.hosts[name] = gathered
+	}
+	return gathered
+}
+
+// get returns the host's facts, or nil if they haven't been gathered.
+func (s *factsStore) get(name string) *hostFacts {
+	return s.forHost(name).get()
+}
+
+// gather gathers the host's facts if they haven't been gathered yet. Failures are
+// recorded and returned so that they can be retried by the next call.
+func (s *factsStore) gather(ctx context.Context, name string, id transport.Identity) (*hostFacts, error) {
+	gathered := s.forHost(name)
+	gathered.gathering.Lock()
+	defer gathered.gathering.Unlock()
+	if facts := gathered.get(); facts != nil {
+		return facts, nil
+	}
+
+	activity.Record(ctx, "Gathering facts about %v", name)
+	stdout, err := run(ctx, id, []string{"sh", "-c", factsScript}, plugin.ExecOptions{})
+	if err != nil {
+		activity.Record(ctx, "Unable to gather facts about %v: %v", name, err)
+		return nil, err
+	}
+	facts, err := parseFacts(stdout)
+	if err != nil {
+		activity.Record(ctx, "Unable to gather facts about %v: %v", name, err)
+		return nil, err
+	}
+	gathered.mux.Lock()
+	gathered.facts = facts
+	gathered.mux.Unlock()
+	return facts, nil
+}
+
+// parseFacts parses the output of factsScript.
+func parseFacts(output string) (*hostFacts, error) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("expected uname's output, not %q", output)
+	}
+	facts := &hostFacts{
+		Kernel:        lines[0],
+		Hostname:      lines[1],
+		KernelRelease: lines[2],
+		Architecture:  lines[3],
+	}
+	for _, line := range lines[4:] {
+		segments := strings.SplitN(line, "=", 2)
+		if len(segments) != 2 {
+			continue
+		}
+		// os-release values are quoted like shell variables.
+		words, err := shellquote.Split(segments[1])
+		if err != nil {
+			continue
+		}
+		if facts.OSRelease == nil {
+			facts.OSRelease = make(map[string]string)
+		}
+		facts.OSRelease[segments[0]] = strings.Join(words, " ")
+	}
+	return facts, nil
+}
+
+// run runs the command on the host and returns its stdout. It fails if the command exits
+// non-zero.
+func run(ctx context.Context, id transport.Identity, cmd []string, opts plugin.ExecOptions) (string, error) {
+	execCmd, err := transport.ExecSSH(ctx, id, cmd, opts)
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	for chunk := range execCmd.OutputCh() {
+		if chunk.Err != nil {
+			return "", chunk.Err
+		}
+		if chunk.StreamID == plugin.Stdout {
+			stdout.WriteString(chunk.Data)
+		} else {
+			stderr.WriteString(chunk.Data)
+		}
+	}
+	exitCode, err := execCmd.ExitCode()
+	if err != nil {
+		return "", err
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("%v exited %v: %v", shellquote.Join(cmd...), exitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,138 @@
+package ssh
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/plugin"
+)
+
+type hostsDir struct {
+	plugin.EntryBase
+	inv   *inventory
+	facts *factsStore
+}
+
+func newHostsDir(inv *inventory, facts *factsStore) *hostsDir {
+	d := &hostsDir{
+		EntryBase: plugin.NewEntry("hosts"),
+		inv:       inv,
+		facts:     facts,
+	}
+	d.DisableDefaultCaching()
+	return d
+}
+
+// List lists every host in the SSH config and the inventories.
+func (d *hostsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	return newHosts(d.inv, d.inv.hostNames(), d.facts), nil
+}
+
+func (d *hostsDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "hosts").
+		SetDescription(hostsDirDescription).
+		IsSingleton()
+}
+
+func (d *hostsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&host{}).Schema(),
+	}
+}
+
+type groupsDir struct {
+	plugin.EntryBase
+	inv   *inventory
+	facts *factsStore
+}
+
+func newGroupsDir(inv *inventory, facts *factsStore) *groupsDir {
+	d := &groupsDir{
+		EntryBase: plugin.NewEntry("groups"),
+		inv:       inv,
+		facts:     facts,
+	}
+	d.DisableDefaultCaching()
+	return d
+}
+
+// List lists the inventories' top-level groups.
+func (d *groupsDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	for _, g := range d.inv.topGroups() {
+		entries = append(entries, newGroup(g, d.inv, d.facts))
+	}
+	return entries, nil
+}
+
+func (d *groupsDir) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "groups").
+		SetDescription(groupsDirDescription).
+		IsSingleton()
+}
+
+func (d *groupsDir) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&group{}).Schema(),
+	}
+}
+
+type group struct {
+	plugin.EntryBase
+	conf  *groupConfig
+	inv   *inventory
+	facts *factsStore
+}
+
+type groupMetadata struct {
+	Vars map[string]interface{} `json:"vars,omitempty"`
+}
+
+func newGroup(conf *groupConfig, inv *inventory, facts *factsStore) *group {
+	g := &group{
+		EntryBase: plugin.NewEntry(conf.name),
+		conf:      conf,
+		inv:       inv,
+		facts:     facts,
+	}
+	g.DisableDefaultCaching()
+	g.SetPartialMetadata(groupMetadata{Vars: withoutPasswords(conf.vars)})
+	return g
+}
+
+// List lists the group's child groups, then its hosts.
+func (g *group) List(ctx context.Context) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	for _, child := range g.conf.children {
+		entries = append(entries, newGroup(g.inv.groups[child], g.inv, g.facts))
+	}
+	return append(entries, newHosts(g.inv, g.conf.hosts, g.facts)...), nil
+}
+
+func (g *group) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(g, "group").
+		SetDescription(groupDescription).
+		SetPartialMetadataSchema(groupMetadata{})
+}
+
+func (g *group) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&group{}).Schema(),
+		(&host{}).Schema(),
+	}
+}
+
+const hostsDirDescription = `
+This directory contains every host in your SSH config and inventories.
+`
+
+const groupsDirDescription = `
+This directory contains the top-level groups in your inventories.
+`
+
+const groupDescription = `
+This is a group from your inventories. It contains its child groups and its
+hosts. Its metadata includes its variables.
+`
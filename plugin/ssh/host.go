@@ -0,0 +1,172 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+	vol "github.com/puppetlabs/wash/volume"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+type host struct {
+	plugin.EntryBase
+	conf  *hostConfig
+	facts *factsStore
+}
+
+// hostMetadata is a host's metadata. Facts are only included once they've been gathered.
+type hostMetadata struct {
+	Address string                 `json:"address"`
+	Port    uint                   `json:"port,omitempty"`
+	User    string                 `json:"user,omitempty"`
+	Groups  []string               `json:"groups,omitempty"`
+	Vars    map[string]interface{} `json:"vars,omitempty"`
+	Facts   *hostFacts             `json:"facts,omitempty"`
+}
+
+func newHost(conf *hostConfig, facts *factsStore) *host {
+	h := &host{
+		EntryBase: plugin.NewEntry(conf.name),
+		conf:      conf,
+		facts:     facts,
+	}
+	h.
+		SetPartialMetadata(h.metadata(facts.get(conf.name))).
+		Attributes().
+		SetOS(plugin.OS{LoginShell: plugin.POSIXShell})
+	return h
+}
+
+func (h *host) metadata(facts *hostFacts) hostMetadata {
+	return hostMetadata{
+		Address: h.conf.address,
+		Port:    h.conf.port,
+		User:    h.conf.user,
+		Groups:  h.conf.groups,
+		Vars:    withoutPasswords(h.conf.vars),
+		Facts:   facts,
+	}
+}
+
+// withoutPasswords returns the variables without any passwords so that they don't end up
+// in metadata.json or find's output.
+func withoutPasswords(vars map[string]interface{}) map[string]interface{} {
+	var filtered map[string]interface{}
+	for k, v := range vars {
+		if containsString(passwordVars, k) || strings.Contains(k, "become_pass") {
+			continue
+		}
+		if filtered == nil {
+			filtered = make(map[string]interface{})
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
+
+// Metadata only includes the host's facts if they've already been gathered by an exec,
+// so that it doesn't connect to the host.
+func (h *host) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	return plugin.ToJSONObject(h.metadata(h.facts.get(h.Name()))), nil
+}
+
+func (h *host) identity() transport.Identity {
+	return transport.Identity{
+		Host:         h.conf.address,
+		Port:         h.conf.port,
+		User:         h.conf.user,
+		Password:     h.conf.password,
+		IdentityFile: h.conf.identityFile,
+		KnownHosts:   h.conf.knownHosts,
+		HostKeyAlias: h.conf.hostKeyAlias,
+	}
+}
+
+func (h *host) List(ctx context.Context) ([]plugin.Entry, error) {
+	metadataJSONFile, err := plugin.NewMetadataJSONFile(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	return []plugin.Entry{
+		metadataJSONFile,
+		// Use a small maxdepth because hosts can have lots of files and SSH is fast.
+		vol.NewFS(ctx, "fs", h, 3),
+	}, nil
+}
+
+// Exec runs the command over SSH. The host's facts are gathered first if this is its
+// first exec.
+func (h *host) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	id := h.identity()
+	// Facts are nice to have, so failing to gather them shouldn't fail the command.
+	_, _ = h.facts.gather(ctx, h.Name(), id)
+	return transport.ExecSSH(ctx, id, append([]string{cmd}, args...), opts)
+}
+
+func (h *host) Signal(ctx context.Context, signal string) error {
+	var cmd []string
+	switch signal {
+	case "reboot":
+		cmd = []string{"shutdown", "-r", "now"}
+	case "shutdown":
+		cmd = []string{"shutdown", "-h", "now"}
+	default:
+		return fmt.Errorf("unsupported signal %v", signal)
+	}
+
+	activity.Record(ctx, "Sending %v to %v", signal, h.Name())
+	_, err := run(ctx, h.identity(), cmd, plugin.ExecOptions{Elevate: true})
+	if _, ok := err.(*cryptossh.ExitMissingError); ok {
+		// The host usually closes the connection before shutdown exits.
+		activity.Record(ctx, "%v closed the connection before %v exited", h.Name(), strings.Join(cmd, " "))
+		return nil
+	}
+	return err
+}
+
+func (h *host) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(h, "host").
+		SetDescription(hostDescription).
+		SetPartialMetadataSchema(hostMetadata{}).
+		SetMetadataSchema(hostMetadata{}).
+		AddSignal("reboot", "Reboots the host with 'sudo shutdown -r now'").
+		AddSignal("shutdown", "Shuts down the host with 'sudo shutdown -h now'")
+}
+
+func (h *host) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+		(&vol.FS{}).Schema(),
+	}
+}
+
+func newHosts(inv *inventory, names []string, facts *factsStore) []plugin.Entry {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	entries := make([]plugin.Entry, len(sorted))
+	for i, name := range sorted {
+		entries[i] = newHost(inv.hosts[name], facts)
+	}
+	return entries
+}
+
+const hostDescription = `
+This is a host that's reached over SSH. Its address, port, user and identity
+file come from its inventory variables (ansible_host, ansible_port, ansible_user
+and ansible_ssh_private_key_file), then from your SSH config's HostName, Port,
+User and IdentityFile. UserKnownHostsFile and HostKeyAlias are also read from
+your SSH config.
+
+Facts about the host's system, like its kernel and /etc/os-release, are gathered
+the first time you exec on it, and are then included in its metadata. The fs
+directory is a view of its filesystem.
+
+The reboot and shutdown signals run shutdown with sudo, so the user must be able
+to sudo without a password.
+`
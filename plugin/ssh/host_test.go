@@ -0,0 +1,217 @@
+package ssh
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	gssh "github.com/gliderlabs/ssh"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+// testServer is an in-process SSH server that responds to the commands that the plugin
+// runs.
+type testServer struct {
+	gssh.Server
+	mux      sync.Mutex
+	commands []string
+}
+
+const testFacts = `Linux
+web1
+5.4.0-42-generic
+x86_64
+NAME="Ubuntu"
+VERSION_ID="20.04"
+PRETTY_NAME="Ubuntu 20.04.1 LTS"
+`
+
+func (s *testServer) handle(session gssh.Session) {
+	s.mux.Lock()
+	s.commands = append(s.commands, session.RawCommand())
+	s.mux.Unlock()
+
+	switch session.RawCommand() {
+	case "sh -c 'uname -s; uname -n; uname -r; uname -m; cat /etc/os-release 2>/dev/null || true'":
+		_, _ = io.WriteString(session, testFacts)
+	case "echo hello":
+		_, _ = io.WriteString(session, "hello\n")
+	case "sudo shutdown -r now":
+		// Close the connection without an exit status, like a rebooting host.
+		_ = session.Close()
+		return
+	case "sudo shutdown -h now":
+		_, _ = io.WriteString(session.Stderr(), "sudo: a password is required\n")
+		_ = session.Exit(1)
+		return
+	default:
+		_, _ = fmt.Fprintf(session.Stderr(), "unknown command %v\n", session.RawCommand())
+		_ = session.Exit(127)
+		return
+	}
+	_ = session.Exit(0)
+}
+
+func (s *testServer) executed() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+func startTestServer(t *testing.T) (*testServer, int) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := cryptossh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &testServer{}
+	s.Handle(s.handle)
+	s.AddHostKey(signer)
+	s.PublicKeyHandler = func(ctx gssh.Context, key gssh.PublicKey) bool {
+		return ctx.User() == "tester"
+	}
+	go func() { _ = s.Serve(listener) }()
+	return s, listener.Addr().(*net.TCPAddr).Port
+}
+
+func writeIdentityFile(t *testing.T, path string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestHosts(t *testing.T) {
+	server, port := startTestServer(t)
+	defer server.Close()
+
+	dir := writeFiles(t, map[string]string{"hosts": "[web]\nweb1\n"})
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config")
+	identityFile, knownHosts := filepath.Join(dir, "id_rsa"), filepath.Join(dir, "known_hosts")
+	writeIdentityFile(t, identityFile)
+	config := fmt.Sprintf("Host web1\n  HostName 127.0.0.1\n  Port %v\n  User tester\n  IdentityFile %v\n  UserKnownHostsFile %v\n", port, identityFile, knownHosts)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0600))
+
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	root := &Root{}
+	require.NoError(t, root.Init(map[string]interface{}{
+		"ssh_config":  configPath,
+		"inventories": []interface{}{filepath.Join(dir, "hosts")},
+	}))
+	entries, err := root.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	hosts, err := entries[0].(*hostsDir).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	web1 := hosts[0].(*host)
+	assert.Equal(t, "web1", web1.Name())
+
+	// Facts aren't included until the first exec, and getting the metadata doesn't
+	// connect to the host.
+	meta := web1.metadata(web1.facts.get("web1"))
+	assert.Nil(t, meta.Facts)
+	assert.Equal(t, "127.0.0.1", meta.Address)
+	assert.Equal(t, uint(port), meta.Port)
+	assert.Equal(t, []string{"web"}, meta.Groups)
+	fullMeta, err := web1.Metadata(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, fullMeta, "facts")
+	assert.Empty(t, server.executed())
+
+	cmd, err := web1.Exec(ctx, "echo", []string{"hello"}, plugin.ExecOptions{})
+	require.NoError(t, err)
+	var stdout string
+	for chunk := range cmd.OutputCh() {
+		require.NoError(t, chunk.Err)
+		stdout += chunk.Data
+	}
+	exitCode, err := cmd.ExitCode()
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "hello\n", stdout)
+
+	// The facts are gathered once, and are included in the partial metadata of new entries.
+	_, err = web1.Exec(ctx, "echo", []string{"hello"}, plugin.ExecOptions{})
+	require.NoError(t, err)
+	groups, err := entries[1].(*groupsDir).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	groupHosts, err := groups[0].(*group).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, groupHosts, 1)
+	partial := plugin.PartialMetadata(groupHosts[0])
+	require.Contains(t, partial, "facts")
+	assert.Equal(t, &hostFacts{
+		Kernel:        "Linux",
+		Hostname:      "web1",
+		KernelRelease: "5.4.0-42-generic",
+		Architecture:  "x86_64",
+		OSRelease: map[string]string{
+			"NAME":        "Ubuntu",
+			"VERSION_ID":  "20.04",
+			"PRETTY_NAME": "Ubuntu 20.04.1 LTS",
+		},
+	}, web1.facts.get("web1"))
+	fullMeta, err = web1.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, fullMeta, "facts")
+
+	// Reboot succeeds even though the host closes the connection, and shutdown's errors
+	// are returned.
+	assert.NoError(t, plugin.Signal(ctx, web1, "reboot"))
+	err = plugin.Signal(ctx, web1, "SHUTDOWN")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "sudo: a password is required")
+	}
+
+	commands := server.executed()
+	require.Len(t, commands, 5)
+	assert.Contains(t, commands[0], "uname -s")
+	assert.Equal(t, []string{"echo hello", "echo hello", "sudo shutdown -r now", "sudo shutdown -h now"}, commands[1:])
+}
+
+func TestHostMetadata_DoesNotWaitForFacts(t *testing.T) {
+	facts := newFactsStore()
+	web1 := newHost(&hostConfig{name: "web1", address: "10.0.0.1"}, facts)
+	// Simulate an exec that's gathering the host's facts from an unreachable host.
+	gathered := facts.forHost("web1")
+	gathered.gathering.Lock()
+	defer gathered.gathering.Unlock()
+
+	done := make(chan plugin.JSONObject)
+	go func() {
+		meta, err := web1.Metadata(context.Background())
+		assert.NoError(t, err)
+		done <- meta
+	}()
+	select {
+	case meta := <-done:
+		assert.Equal(t, "10.0.0.1", meta["address"])
+		assert.NotContains(t, meta, "facts")
+	case <-time.After(time.Second):
+		assert.Fail(t, "Metadata waited for the facts to be gathered")
+	}
+}
@@ -0,0 +1,486 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/kballard/go-shellquote"
+	"github.com/kevinburke/ssh_config"
+)
+
+// inventory is the set of hosts and groups that's read from the SSH config and the
+// inventory files.
+type inventory struct {
+	sshConfig *ssh_config.Config
+	hosts     map[string]*hostConfig
+	groups    map[string]*groupConfig
+}
+
+// hostConfig is a host's configuration. Its connection details are resolved from its
+// variables and the SSH config once the inventory's loaded.
+type hostConfig struct {
+	name string
+	// hostVars are the variables that were declared on the host itself. vars also
+	// includes the variables of its groups.
+	hostVars map[string]interface{}
+	vars     map[string]interface{}
+	groups   []string
+
+	address      string
+	port         uint
+	user         string
+	password     string
+	identityFile string
+	knownHosts   string
+	hostKeyAlias string
+}
+
+type groupConfig struct {
+	name     string
+	hosts    []string
+	children []string
+	vars     map[string]interface{}
+}
+
+// Inventory variables that are used to connect to a host. They take precedence over the
+// SSH config, like they do in Ansible.
+var (
+	addressVars      = []string{"ansible_host", "ansible_ssh_host"}
+	portVars         = []string{"ansible_port", "ansible_ssh_port"}
+	userVars         = []string{"ansible_user", "ansible_ssh_user"}
+	passwordVars     = []string{"ansible_password", "ansible_ssh_pass"}
+	identityFileVars = []string{"ansible_ssh_private_key_file"}
+)
+
+// loadInventory reads the hosts in the SSH config's Host blocks, then the hosts and groups
+// in the inventory files. A missing SSH config is treated like an empty one.
+func loadInventory(sshConfigPath string, inventoryPaths []string) (*inventory, error) {
+	inv := &inventory{
+		hosts:  make(map[string]*hostConfig),
+		groups: make(map[string]*groupConfig),
+	}
+
+	if sshConfigPath != "" {
+		if err := inv.addSSHConfig(sshConfigPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range inventoryPaths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			err = inv.addYAML(content)
+		default:
+			err = inv.addINI(content)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the inventory %v: %v", path, err)
+		}
+	}
+
+	if err := inv.resolveGroups(); err != nil {
+		return nil, err
+	}
+	for _, name := range inv.hostNames() {
+		if err := inv.resolveConnection(inv.hosts[name]); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+func (inv *inventory) host(name string) *hostConfig {
+	h, ok := inv.hosts[name]
+	if !ok {
+		h = &hostConfig{name: name, hostVars: make(map[string]interface{})}
+		inv.hosts[name] = h
+	}
+	return h
+}
+
+func (inv *inventory) group(name string) *groupConfig {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &groupConfig{name: name, vars: make(map[string]interface{})}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+func (g *groupConfig) addHost(name string) {
+	if !containsString(g.hosts, name) {
+		g.hosts = append(g.hosts, name)
+	}
+}
+
+func (g *groupConfig) addChild(name string) {
+	if !containsString(g.children, name) {
+		g.children = append(g.children, name)
+	}
+}
+
+func (inv *inventory) hostNames() []string {
+	names := make([]string, 0, len(inv.hosts))
+	for name := range inv.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// topGroups returns the groups that aren't a child of any other group. The "all" group
+// is replaced by its children because its hosts are already listed in hosts.
+func (inv *inventory) topGroups() []*groupConfig {
+	isChild := make(map[string]bool)
+	for _, g := range inv.groups {
+		for _, child := range g.children {
+			isChild[child] = true
+		}
+	}
+	var names []string
+	for name := range inv.groups {
+		if !isChild[name] && name != "all" {
+			names = append(names, name)
+		}
+	}
+	if all, ok := inv.groups["all"]; ok {
+		for _, child := range all.children {
+			if !containsString(names, child) {
+				names = append(names, child)
+			}
+		}
+	}
+	sort.Strings(names)
+	groups := make([]*groupConfig, len(names))
+	for i, name := range names {
+		groups[i] = inv.groups[name]
+	}
+	return groups
+}
+
+// addSSHConfig adds a host for each Host pattern in the SSH config that names a single
+// host. Wildcards and negated patterns only configure other hosts.
+func (inv *inventory) addSSHConfig(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if inv.sshConfig, err = ssh_config.Decode(f); err != nil {
+		return fmt.Errorf("could not parse the SSH config %v: %v", path, err)
+	}
+	for _, host := range inv.sshConfig.Hosts {
+		for _, pattern := range host.Patterns {
+			name := pattern.String()
+			// Negated patterns never match their own name.
+			if strings.ContainsAny(name, "*?") || !host.Matches(name) {
+				continue
+			}
+			inv.host(name)
+		}
+	}
+	return nil
+}
+
+// yamlGroup is a group in a YAML inventory, like
+//
+//	all:
+//	  hosts:
+//	    web1:
+//	      ansible_host: 10.0.0.1
+//	  children:
+//	    db:
+//	      hosts:
+//	        db1:
+//	      vars:
+//	        ansible_user: postgres
+type yamlGroup struct {
+	Hosts    map[string]map[string]interface{} `json:"hosts"`
+	Vars     map[string]interface{}            `json:"vars"`
+	Children map[string]*yamlGroup             `json:"children"`
+}
+
+func (inv *inventory) addYAML(content []byte) error {
+	var groups map[string]*yamlGroup
+	if err := yaml.Unmarshal(content, &groups); err != nil {
+		return err
+	}
+	var add func(name string, yg *yamlGroup)
+	add = func(name string, yg *yamlGroup) {
+		g := inv.group(name)
+		if yg == nil {
+			return
+		}
+		for hostName, vars := range yg.Hosts {
+			g.addHost(hostName)
+			h := inv.host(hostName)
+			for k, v := range vars {
+				h.hostVars[k] = v
+			}
+		}
+		for k, v := range yg.Vars {
+			g.vars[k] = v
+		}
+		for childName, child := range yg.Children {
+			g.addChild(childName)
+			add(childName, child)
+		}
+	}
+	for name, yg := range groups {
+		add(name, yg)
+	}
+	return nil
+}
+
+// addINI adds the hosts and groups of an INI inventory, like
+//
+//	bastion.example.com
+//
+//	[web]
+//	web1 ansible_host=10.0.0.1
+//
+//	[db:vars]
+//	ansible_user=postgres
+//
+//	[prod:children]
+//	web
+//	db
+//
+// Hosts that are listed before the first section are in the "ungrouped" group.
+func (inv *inventory) addINI(content []byte) error {
+	group, kind := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group, kind = line[1:len(line)-1], "hosts"
+			if i := strings.LastIndex(group, ":"); i >= 0 {
+				group, kind = group[:i], group[i+1:]
+			}
+			if kind != "hosts" && kind != "vars" && kind != "children" {
+				return fmt.Errorf("line %v: unknown section type %v", lineNum, kind)
+			}
+			inv.group(group)
+			continue
+		}
+
+		g := inv.group(group)
+		switch kind {
+		case "hosts":
+			fields, err := shellquote.Split(line)
+			if err != nil {
+				return fmt.Errorf("line %v: %v", lineNum, err)
+			}
+			name := fields[0]
+			if strings.ContainsAny(name, "[]") {
+				return fmt.Errorf("line %v: host ranges like %v are not supported", lineNum, name)
+			}
+			g.addHost(name)
+			h := inv.host(name)
+			for _, field := range fields[1:] {
+				if strings.HasPrefix(field, "#") {
+					break
+				}
+				segments := strings.SplitN(field, "=", 2)
+				if len(segments) != 2 {
+					return fmt.Errorf("line %v: expected a variable like key=value, not %v", lineNum, field)
+				}
+				h.hostVars[segments[0]] = segments[1]
+			}
+		case "vars":
+			segments := strings.SplitN(line, "=", 2)
+			if len(segments) != 2 {
+				return fmt.Errorf("line %v: expected a variable like key=value, not %v", lineNum, line)
+			}
+			g.vars[strings.TrimSpace(segments[0])] = unquote(strings.TrimSpace(segments[1]))
+		case "children":
+			g.addChild(line)
+			inv.group(line)
+		}
+	}
+	return scanner.Err()
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// resolveGroups walks the groups from the top down to find each host's groups and
+// variables. The "all" group's variables apply to every host. A child group's variables
+// override its parent's, and a host's own variables override its groups'.
+func (inv *inventory) resolveGroups() error {
+	groupVars := make(map[string]map[string]interface{})
+	visited := make(map[string]bool)
+	var visit func(g *groupConfig, path []string, vars map[string]interface{}) error
+	visit = func(g *groupConfig, path []string, vars map[string]interface{}) error {
+		if containsString(path, g.name) {
+			return fmt.Errorf("the group %v is its own descendant", g.name)
+		}
+		visited[g.name] = true
+		path = append(path[:len(path):len(path)], g.name)
+		if g.name != "all" {
+			vars = mergeVars(vars, g.vars)
+		}
+
+		sort.Strings(g.hosts)
+		for _, name := range g.hosts {
+			h := inv.hosts[name]
+			for _, group := range path {
+				if group != "all" && !containsString(h.groups, group) {
+					h.groups = append(h.groups, group)
+				}
+			}
+			groupVars[name] = mergeVars(groupVars[name], vars)
+		}
+		sort.Strings(g.children)
+		for _, child := range g.children {
+			if err := visit(inv.groups[child], path, vars); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	isChild := make(map[string]bool)
+	for _, g := range inv.groups {
+		for _, child := range g.children {
+			isChild[child] = true
+		}
+	}
+	var roots []string
+	for name := range inv.groups {
+		if !isChild[name] {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	for _, name := range roots {
+		if err := visit(inv.groups[name], nil, nil); err != nil {
+			return err
+		}
+	}
+	for name := range inv.groups {
+		if !visited[name] {
+			return fmt.Errorf("the group %v is its own descendant", name)
+		}
+	}
+
+	var allVars map[string]interface{}
+	if all, ok := inv.groups["all"]; ok {
+		allVars = all.vars
+	}
+	for name, h := range inv.hosts {
+		sort.Strings(h.groups)
+		h.vars = mergeVars(mergeVars(allVars, groupVars[name]), h.hostVars)
+	}
+	return nil
+}
+
+// resolveConnection sets the host's connection details from its variables, falling back
+// to the SSH config.
+func (inv *inventory) resolveConnection(h *hostConfig) error {
+	var err error
+	lookup := func(vars []string, key string) string {
+		for _, name := range vars {
+			if v, ok := h.vars[name]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+		}
+		if err != nil || key == "" {
+			return ""
+		}
+		var value string
+		value, err = inv.sshConfigValue(h.name, key)
+		return value
+	}
+
+	h.address = lookup(addressVars, "HostName")
+	if h.address == "" {
+		h.address = h.name
+	}
+	if port := lookup(portVars, "Port"); port != "" {
+		// YAML inventories decode numbers as floats, so parse the port as one.
+		p, parseErr := strconv.ParseFloat(port, 64)
+		if parseErr != nil || p <= 0 || p > 65535 || p != float64(int(p)) {
+			return fmt.Errorf("the host %v has an invalid port %v", h.name, port)
+		}
+		h.port = uint(p)
+	}
+	h.user = lookup(userVars, "User")
+	h.password = lookup(passwordVars, "")
+	h.identityFile = expandHome(lookup(identityFileVars, "IdentityFile"))
+	// UserKnownHostsFile can list several files, but transport only supports one.
+	if knownHosts := strings.Fields(lookup(nil, "UserKnownHostsFile")); len(knownHosts) > 0 {
+		h.knownHosts = expandHome(knownHosts[0])
+	}
+	h.hostKeyAlias = lookup(nil, "HostKeyAlias")
+	if err != nil {
+		return fmt.Errorf("could not read the SSH config of %v: %v", h.name, err)
+	}
+	return nil
+}
+
+// sshConfigValue returns the key's value for the host in the SSH config, or an empty
+// string if it isn't set.
+func (inv *inventory) sshConfigValue(host string, key string) (value string, err error) {
+	if inv.sshConfig == nil {
+		return "", nil
+	}
+	// ssh_config panics on Match directives, which it doesn't support.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return inv.sshConfig.Get(host, key)
+}
+
+func mergeVars(base map[string]interface{}, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
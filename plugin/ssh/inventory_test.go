@@ -0,0 +1,196 @@
+package ssh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the files to a new temporary directory, which is returned.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "wash_ssh_inventory")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+const testSSHConfig = `
+Host bastion
+  HostName bastion.example.com
+  User admin
+  Port 2200
+  IdentityFile ~/.ssh/bastion
+  UserKnownHostsFile /tmp/known_hosts /tmp/known_hosts2
+
+Host web1 !excluded
+  HostKeyAlias web1-key
+
+Host *.example.com
+  User wildcard
+`
+
+func TestLoadInventory_SSHConfig(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"config": testSSHConfig})
+	defer os.RemoveAll(dir)
+
+	inv, err := loadInventory(filepath.Join(dir, "config"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bastion", "web1"}, inv.hostNames())
+	assert.Empty(t, inv.topGroups())
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	bastion := inv.hosts["bastion"]
+	assert.Equal(t, "bastion.example.com", bastion.address)
+	assert.Equal(t, uint(2200), bastion.port)
+	assert.Equal(t, "admin", bastion.user)
+	assert.Equal(t, filepath.Join(home, ".ssh/bastion"), bastion.identityFile)
+	assert.Equal(t, "/tmp/known_hosts", bastion.knownHosts)
+
+	web1 := inv.hosts["web1"]
+	assert.Equal(t, "web1", web1.address)
+	assert.Equal(t, uint(0), web1.port)
+	assert.Equal(t, "web1-key", web1.hostKeyAlias)
+}
+
+func TestLoadInventory_MissingSSHConfig(t *testing.T) {
+	inv, err := loadInventory("/does/not/exist", nil)
+	require.NoError(t, err)
+	assert.Empty(t, inv.hosts)
+}
+
+func TestLoadInventory_YAML(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config": testSSHConfig,
+		"inventory.yaml": `
+all:
+  vars:
+    ansible_user: deploy
+    env: prod
+  hosts:
+    bastion:
+  children:
+    web:
+      hosts:
+        web1:
+          ansible_host: 10.0.0.1
+        web2.example.com:
+          ansible_port: 2222
+          ansible_password: hunter2
+      vars:
+        env: web
+    db:
+      hosts:
+        db1:
+      children:
+        replicas:
+          hosts:
+            db2:
+              env: replica
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	inv, err := loadInventory(filepath.Join(dir, "config"), []string{filepath.Join(dir, "inventory.yaml")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bastion", "db1", "db2", "web1", "web2.example.com"}, inv.hostNames())
+
+	var topGroups []string
+	for _, g := range inv.topGroups() {
+		topGroups = append(topGroups, g.name)
+	}
+	assert.Equal(t, []string{"db", "web"}, topGroups)
+	assert.Equal(t, []string{"replicas"}, inv.groups["db"].children)
+
+	// Inventory variables override the SSH config.
+	bastion := inv.hosts["bastion"]
+	assert.Equal(t, "bastion.example.com", bastion.address)
+	assert.Equal(t, "deploy", bastion.user)
+	assert.Empty(t, bastion.groups)
+
+	web1 := inv.hosts["web1"]
+	assert.Equal(t, "10.0.0.1", web1.address)
+	assert.Equal(t, []string{"web"}, web1.groups)
+	assert.Equal(t, "web", web1.vars["env"])
+
+	web2 := inv.hosts["web2.example.com"]
+	assert.Equal(t, uint(2222), web2.port)
+	assert.Equal(t, "hunter2", web2.password)
+	assert.NotContains(t, withoutPasswords(web2.vars), "ansible_password")
+
+	db2 := inv.hosts["db2"]
+	assert.Equal(t, []string{"db", "replicas"}, db2.groups)
+	assert.Equal(t, "replica", db2.vars["env"])
+	assert.Equal(t, "prod", inv.hosts["db1"].vars["env"])
+}
+
+func TestLoadInventory_INI(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"hosts": `
+# A comment
+bastion.example.com
+
+[web]
+web1 ansible_host=10.0.0.1 ansible_user="web user" # the first web server
+web2 ansible_port=2222
+
+[web:vars]
+env = 'web'
+
+[all:vars]
+env=prod
+
+[prod:children]
+web
+db
+
+[db]
+db1
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	inv, err := loadInventory("", []string{filepath.Join(dir, "hosts")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bastion.example.com", "db1", "web1", "web2"}, inv.hostNames())
+
+	var topGroups []string
+	for _, g := range inv.topGroups() {
+		topGroups = append(topGroups, g.name)
+	}
+	assert.Equal(t, []string{"prod", "ungrouped"}, topGroups)
+
+	web1 := inv.hosts["web1"]
+	assert.Equal(t, "10.0.0.1", web1.address)
+	assert.Equal(t, "web user", web1.user)
+	assert.Equal(t, []string{"prod", "web"}, web1.groups)
+	assert.Equal(t, "web", web1.vars["env"])
+	assert.Equal(t, uint(2222), inv.hosts["web2"].port)
+	assert.Equal(t, "prod", inv.hosts["db1"].vars["env"])
+	assert.Equal(t, []string{"ungrouped"}, inv.hosts["bastion.example.com"].groups)
+}
+
+func TestLoadInventory_Errors(t *testing.T) {
+	cases := map[string]string{
+		"[web]\nweb[01:10]\n":                                 "host ranges like web[01:10] are not supported",
+		"[web:other]\n":                                       "unknown section type other",
+		"[web]\nweb1 ansible_port\n":                          "expected a variable like key=value, not ansible_port",
+		"[web]\nweb1 ansible_port=http\n":                     "the host web1 has an invalid port http",
+		"[a:children]\nb\n[b:children]\na\n":                  "is its own descendant",
+		"[a:children]\nb\n[b:children]\nc\n[c:children]\nb\n": "the group b is its own descendant",
+	}
+	for content, expectedErr := range cases {
+		dir := writeFiles(t, map[string]string{"hosts": content})
+		_, err := loadInventory("", []string{filepath.Join(dir, "hosts")})
+		if assert.Error(t, err, content) {
+			assert.Contains(t, err.Error(), expectedErr)
+		}
+		os.RemoveAll(dir)
+	}
+}
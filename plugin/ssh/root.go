@@ -0,0 +1,94 @@
+// Package ssh presents a filesystem hierarchy for hosts that are reached over SSH.
+//
+// Hosts are read from the Host blocks in ~/.ssh/config, and from Ansible
+// inventory files (YAML or INI), which can also group them. Hosts can be exec'd
+// on, and include a view of their filesystem.
+package ssh
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Root of the SSH plugin
+type Root struct {
+	plugin.EntryBase
+	sshConfig   string
+	inventories []string
+	facts       *factsStore
+}
+
+type config struct {
+	SSHConfig   *string  `json:"ssh_config" jsonschema_description:"The SSH config whose Host blocks are listed as hosts. Defaults to ~/.ssh/config. Set it to an empty string to only list the inventories' hosts."`
+	Inventories []string `json:"inventories" jsonschema_description:"Ansible inventory files whose hosts and groups are listed. Files with a .yaml, .yml or .json extension are read as YAML, and other files are read as INI."`
+}
+
+// ConfigSchema returns the root's config schema
+func (r *Root) ConfigSchema() interface{} {
+	return config{}
+}
+
+// Init for root
+func (r *Root) Init(cfg map[string]interface{}) error {
+	var c config
+	if err := plugin.DecodeConfig(cfg, &c); err != nil {
+		return err
+	}
+
+	r.sshConfig = "~/.ssh/config"
+	if c.SSHConfig != nil {
+		r.sshConfig = *c.SSHConfig
+	}
+	r.sshConfig = expandHome(r.sshConfig)
+	r.inventories = nil
+	for _, path := range c.Inventories {
+		r.inventories = append(r.inventories, expandHome(path))
+	}
+	r.facts = newFactsStore()
+
+	// Load the inventory once to catch errors in it early.
+	if _, err := loadInventory(r.sshConfig, r.inventories); err != nil {
+		return err
+	}
+
+	r.EntryBase = plugin.NewEntry("ssh")
+	return nil
+}
+
+// Schema returns the root's schema
+func (r *Root) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(r, "ssh").
+		SetDescription(rootDescription).
+		IsSingleton()
+}
+
+// ChildSchemas returns the root's child schema
+func (r *Root) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&hostsDir{}).Schema(),
+		(&groupsDir{}).Schema(),
+	}
+}
+
+// List reloads the SSH config and the inventories, so that changes to them are
+// picked up once the root's list expires from the cache.
+func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
+	inv, err := loadInventory(r.sshConfig, r.inventories)
+	if err != nil {
+		return nil, err
+	}
+	return []plugin.Entry{
+		newHostsDir(inv, r.facts),
+		newGroupsDir(inv, r.facts),
+	}, nil
+}
+
+const rootDescription = `
+This is the SSH plugin root. It lists the hosts in your SSH config (the Host
+blocks in ~/.ssh/config, or the ssh.ssh_config config) and in your Ansible
+inventory files (the ssh.inventories config). Inventories can be YAML or INI,
+and their groups are listed in the groups directory. Host ranges, like
+web[01:10], and dynamic inventories aren't supported.
+`
@@ -0,0 +1,371 @@
+package plugintest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+// Suite is a testify suite that checks a plugin against the interface
+// contracts documented in plugin/types.go. It walks the plugin's entries
+// starting from its root, checking each entry against the root's schema
+// graph. Use it like
+//
+//	func TestConformance(t *testing.T) {
+//		suite.Run(t, &plugintest.Suite{
+//			NewRoot: func() plugin.Root { return &Root{client: newFakeClient()} },
+//		})
+//	}
+//
+// Core plugins should inject a fake API client. External plugins can be tested
+// by returning the root that's loaded from a test script's PluginSpec.
+type Suite struct {
+	suite.Suite
+	// NewRoot returns the (uninitialized) plugin root under test. It is called
+	// before each test so that the destructive tests start from a clean slate.
+	NewRoot func() plugin.Root
+	// Config is passed to the root's Init method
+	Config map[string]interface{}
+	// MaxDepth limits how deep the walk goes. It defaults to 10.
+	MaxDepth int
+	// Prune returns true if the walk should not list the given parent. Use it
+	// for parents whose children can't be listed against a fake API, like
+	// volume.FS entries that are listed by exec'ing a command.
+	Prune func(plugin.Entry) bool
+	// Destructive enables the tests that delete and signal entries. Only
+	// enable it when testing against a fake API.
+	Destructive bool
+
+	ctx   context.Context
+	root  plugin.Entry
+	graph *linkedhashmap.Map
+}
+
+// walkedEntry is an entry that was found by walking the plugin. ancestors
+// starts with the plugin root.
+type walkedEntry struct {
+	entry     plugin.Entry
+	ancestors []plugin.Entry
+}
+
+func (w walkedEntry) parent() plugin.Parent {
+	return w.ancestors[len(w.ancestors)-1].(plugin.Parent)
+}
+
+// externalEntry represents an entry whose supported methods are determined
+// by the plugin instead of by the implemented interfaces
+type externalEntry interface {
+	MethodSignature(string) plugin.MethodSignature
+}
+
+// SetupTest initializes and registers the plugin root
+func (s *Suite) SetupTest() {
+	s.Require().NotNil(s.NewRoot, "plugintest.Suite#NewRoot must be set")
+	s.ctx = plugin.SetTestCache(datastore.NewMemCache())
+
+	root := s.NewRoot()
+	registry := plugin.NewRegistry()
+	s.Require().NoError(registry.RegisterPlugin(root, s.Config))
+	// Listing the registry sets the root's ID
+	roots, err := plugin.List(s.ctx, registry)
+	s.Require().NoError(err)
+	s.root, _ = roots.Load(plugin.CName(root))
+	s.Require().NotNil(s.root)
+
+	s.graph, err = plugin.SchemaGraph(s.root)
+	s.Require().NoError(err)
+}
+
+// TearDownTest unsets the test cache
+func (s *Suite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+// TestSchemaGraph checks that the root's schema graph is well-formed
+func (s *Suite) TestSchemaGraph() {
+	if s.graph == nil {
+		s.T().Skip("the plugin does not provide a schema")
+	}
+	s.Equal(plugin.TypeID(s.root), s.graph.Keys()[0], "the root must be the first node in its schema graph")
+
+	s.graph.Each(func(key interface{}, value interface{}) {
+		typeID, node := key.(string), value.(plugin.EntrySchema)
+		s.NotEmpty(node.Label, "%v: the label must be set", typeID)
+		isParent := false
+		for _, action := range actionsOf(node) {
+			isParent = isParent || action == plugin.ListAction().Name
+		}
+		if !isParent {
+			s.Empty(node.Children, "%v: only parents can have children", typeID)
+		}
+		for _, child := range node.Children {
+			_, ok := s.graph.Get(child)
+			s.True(ok, "%v: the child %v is missing from the schema graph", typeID, child)
+		}
+		for _, signal := range node.Signals {
+			s.NotEmpty(signal.Name(), "%v: signals must have a name", typeID)
+			s.NotEmpty(signal.Description(), "%v: the %v signal must have a description", typeID, signal.Name())
+		}
+	})
+
+	rootNode, _ := s.graph.Get(plugin.TypeID(s.root))
+	s.True(rootNode.(plugin.EntrySchema).Singleton, "the root must be a singleton")
+}
+
+// TestEntries checks that each entry satisfies its schema and the contracts
+// of its implemented interfaces
+func (s *Suite) TestEntries() {
+	s.walk(func(w walkedEntry) {
+		e := w.entry
+		id := plugin.ID(e)
+		s.NotEmpty(plugin.CName(e), "%v: the name must be set", id)
+
+		s.checkSchema(w)
+		s.checkRead(e)
+		_, err := plugin.Metadata(s.ctx, e)
+		s.NoError(err, "%v: Metadata errored", id)
+
+		if plugin.IsPrefetched(e) {
+			s.checkPrefetched(w)
+		}
+	})
+}
+
+// TestDelete deletes every deletable entry, descendants first, checking that
+// deleted entries are no longer listed by their parent
+func (s *Suite) TestDelete() {
+	if !s.Destructive {
+		s.T().Skip("plugintest.Suite#Destructive is not set")
+	}
+	var deletables []walkedEntry
+	s.walk(func(w walkedEntry) {
+		if plugin.DeleteAction().IsSupportedOn(w.entry) {
+			deletables = append(deletables, w)
+		}
+	})
+	// walk visits parents before their children
+	for i := len(deletables) - 1; i >= 0; i-- {
+		w := deletables[i]
+		id := plugin.ID(w.entry)
+		deleted, err := plugin.Delete(s.ctx, w.entry.(plugin.Deletable))
+		if !s.NoError(err, "%v: Delete errored", id) || !deleted {
+			continue
+		}
+		parent := w.parent()
+		plugin.ClearCacheFor(plugin.ID(parent), false)
+		entries, err := plugin.List(s.ctx, parent)
+		if s.NoError(err, "%v: List errored after deleting %v", plugin.ID(parent), id) {
+			_, ok := entries.Load(plugin.CName(w.entry))
+			s.False(ok, "%v: Delete returned true but the entry is still listed", id)
+		}
+	}
+}
+
+// TestSignal sends every signal in each signalable entry's schema
+func (s *Suite) TestSignal() {
+	if !s.Destructive {
+		s.T().Skip("plugintest.Suite#Destructive is not set")
+	}
+	if s.graph == nil {
+		s.T().Skip("the plugin does not provide a schema")
+	}
+	s.walk(func(w walkedEntry) {
+		if !plugin.SignalAction().IsSupportedOn(w.entry) {
+			return
+		}
+		node, ok := s.schemaOf(w.entry)
+		if !ok {
+			return
+		}
+		for _, signal := range node.Signals {
+			if signal.IsGroup() {
+				continue
+			}
+			err := plugin.Signal(s.ctx, w.entry.(plugin.Signalable), signal.Name())
+			s.NoError(err, "%v: sending the %v signal errored", plugin.ID(w.entry), signal.Name())
+		}
+	})
+}
+
+// walk visits each of the plugin's entries (excluding the root) in pre-order
+func (s *Suite) walk(visit func(walkedEntry)) {
+	maxDepth := s.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = 10
+	}
+
+	var recurse func(p plugin.Parent, ancestors []plugin.Entry)
+	recurse = func(p plugin.Parent, ancestors []plugin.Entry) {
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], p)
+		entries, err := plugin.List(s.ctx, p)
+		if !s.NoError(err, "%v: List errored", plugin.ID(p)) {
+			return
+		}
+		children := entries.Map()
+		cnames := make([]string, 0, len(children))
+		for cname := range children {
+			cnames = append(cnames, cname)
+		}
+		sort.Strings(cnames)
+
+		s.checkSingletons(p, children)
+		for _, cname := range cnames {
+			child := children[cname]
+			visit(walkedEntry{entry: child, ancestors: ancestors})
+			if len(ancestors) >= maxDepth {
+				continue
+			}
+			if parent, ok := child.(plugin.Parent); ok && plugin.ListAction().IsSupportedOn(child) {
+				if s.Prune == nil || !s.Prune(child) {
+					recurse(parent, ancestors)
+				}
+			}
+		}
+	}
+	recurse(s.root.(plugin.Parent), nil)
+}
+
+// schemaOf returns e's node in the root's schema graph
+func (s *Suite) schemaOf(e plugin.Entry) (plugin.EntrySchema, bool) {
+	if s.graph == nil {
+		return plugin.EntrySchema{}, false
+	}
+	node, ok := s.graph.Get(plugin.TypeID(e))
+	if !s.True(ok, "%v: its type %v is missing from the root's schema graph", plugin.ID(e), plugin.TypeID(e)) {
+		return plugin.EntrySchema{}, false
+	}
+	return node.(plugin.EntrySchema), true
+}
+
+// actionsOf returns the Wash actions in node's actions. External plugin
+// schemas also include methods that aren't actions, like schema and metadata.
+func actionsOf(node plugin.EntrySchema) []string {
+	var actions []string
+	for _, action := range node.Actions {
+		if _, ok := plugin.Actions()[action]; ok {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func (s *Suite) checkSchema(w walkedEntry) {
+	e := w.entry
+	id := plugin.ID(e)
+	node, ok := s.schemaOf(e)
+	if !ok {
+		return
+	}
+	s.ElementsMatch(actionsOf(node), plugin.SupportedActionsOf(e), "%v: the supported actions don't match the schema's actions", id)
+	if parentNode, ok := s.schemaOf(w.parent()); ok {
+		s.Contains(parentNode.Children, plugin.TypeID(e), "%v: its type is not one of its parent's child types", id)
+	}
+
+	// A signalable entry without any signals cannot be signaled, while signals
+	// cannot be sent to a non-signalable entry.
+	signalable := plugin.SignalAction().IsSupportedOn(e)
+	if signalable {
+		s.NotEmpty(node.Signals, "%v: signalable entries must include their signals in the schema", id)
+	} else {
+		s.Empty(node.Signals, "%v: only signalable entries can have signals", id)
+	}
+	if signalable {
+		// plugin.Signal should reject unknown signals before they reach the plugin
+		invalidSignal := "plugintest-invalid-signal"
+		for _, signal := range node.Signals {
+			if signal.IsGroup() && signal.Regex().MatchString(invalidSignal) {
+				return
+			}
+		}
+		err := plugin.Signal(s.ctx, e.(plugin.Signalable), invalidSignal)
+		s.True(plugin.IsInvalidInputErr(err), "%v: expected an invalid signal to be rejected, got %v", id, err)
+	}
+}
+
+func (s *Suite) checkSingletons(p plugin.Parent, children map[string]plugin.Entry) {
+	counts := make(map[string][]string)
+	for cname, child := range children {
+		typeID := plugin.TypeID(child)
+		counts[typeID] = append(counts[typeID], cname)
+	}
+	for typeID, cnames := range counts {
+		if len(cnames) < 2 || s.graph == nil {
+			continue
+		}
+		if node, ok := s.graph.Get(typeID); ok && node.(plugin.EntrySchema).Singleton {
+			sort.Strings(cnames)
+			s.Fail(
+				fmt.Sprintf("%v: found multiple children of singleton type %v", plugin.ID(p), typeID),
+				strings.Join(cnames, ", "),
+			)
+		}
+	}
+}
+
+func (s *Suite) checkRead(e plugin.Entry) {
+	if !plugin.ReadAction().IsSupportedOn(e) {
+		return
+	}
+	id := plugin.ID(e)
+	attr := plugin.Attributes(e)
+
+	blockReadable := false
+	if ext, ok := e.(externalEntry); ok {
+		blockReadable = ext.MethodSignature(plugin.ReadAction().Name) == plugin.BlockReadableSignature
+	} else {
+		_, blockReadable = e.(plugin.BlockReadable)
+	}
+	if blockReadable && !s.True(attr.HasSize(), "%v: block-readable entries must set the size attribute", id) {
+		return
+	}
+
+	if r, ok := e.(plugin.Readable); ok && !blockReadable {
+		// Call Read directly because plugin.Read truncates the content to the
+		// size attribute
+		content, err := r.Read(s.ctx)
+		if s.NoError(err, "%v: Read errored", id) && attr.HasSize() {
+			s.Equal(attr.Size(), uint64(len(content)), "%v: the size attribute doesn't match the content's size", id)
+		}
+		return
+	}
+
+	size := int64(4096)
+	if attr.HasSize() {
+		size = int64(attr.Size())
+	}
+	content, err := plugin.Read(s.ctx, e, size, 0)
+	if err == io.EOF {
+		err = nil
+	}
+	if s.NoError(err, "%v: Read errored", id) && attr.HasSize() {
+		s.Equal(size, int64(len(content)), "%v: the size attribute doesn't match the content's size", id)
+	}
+}
+
+// checkPrefetched checks that a prefetched entry's attributes match the
+// attributes obtained by re-fetching it from its source ancestor, i.e. the
+// closest ancestor that isn't prefetched.
+func (s *Suite) checkPrefetched(w walkedEntry) {
+	id := plugin.ID(w.entry)
+	segments := []string{plugin.CName(w.entry)}
+	i := len(w.ancestors) - 1
+	for ; i > 0 && plugin.IsPrefetched(w.ancestors[i]); i-- {
+		segments = append([]string{plugin.CName(w.ancestors[i])}, segments...)
+	}
+	source := w.ancestors[i]
+
+	plugin.ClearCacheFor(plugin.ID(source), false)
+	fetched, err := plugin.FindEntry(s.ctx, source, segments)
+	if !s.NoError(err, "%v: re-fetching the prefetched entry errored", id) {
+		return
+	}
+	prefetchedAttr, fetchedAttr := plugin.Attributes(w.entry), plugin.Attributes(fetched)
+	s.Equal(fetchedAttr.ToMap(), prefetchedAttr.ToMap(), "%v: the prefetched attributes don't match the re-fetched attributes", id)
+}
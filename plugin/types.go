@@ -117,6 +117,20 @@ type HasWrappedTypes interface {
 	WrappedTypes() SchemaMap
 }
 
+// HasConfigSchema is an interface that's implemented by plugin roots that declare
+// their config's schema. ConfigSchema returns an empty struct that will be
+// marshalled into a JSON schema, similar to EntrySchema#SetPartialMetadataSchema.
+// Use the "json" tag to name each config option, the "jsonschema_description" tag
+// to document it, and `jsonschema:"required"` to mark it as required.
+//
+// The registry validates the plugin's config against the schema before calling
+// Init, so Init can decode the config via DecodeConfig without having to check it.
+// The schema is also included in the root's documentation (see 'wash docs').
+type HasConfigSchema interface {
+	Root
+	ConfigSchema() interface{}
+}
+
 // ExecOptions is a struct we can add new features to that must be serializable to JSON.
 // Examples of potential features: user, privileged, map of environment variables, timeout.
 type ExecOptions struct {
@@ -259,3 +273,11 @@ type externalPlugin interface {
 	// method.
 	BlockRead(ctx context.Context, size int64, offset int64) ([]byte, error)
 }
+
+// childMetadataPrefetcher is a parent that can fetch the full metadata of many
+// of its children at once. Only external plugins implement it (via their
+// metadata_batch method).
+type childMetadataPrefetcher interface {
+	Parent
+	PrefetchChildMetadata(ctx context.Context, children []Entry) error
+}
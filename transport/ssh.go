@@ -45,6 +45,7 @@ type sshConfig struct {
 	host, port, user, password string
 	identityFiles              []string
 	hostKeyCallback            ssh.HostKeyCallback
+	dial                       func(context.Context) (net.Conn, error)
 }
 
 func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
@@ -76,6 +77,7 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 	}
 
 	conf.password = id.Password
+	conf.dial = id.Dial
 
 	// Try the requested identity file first. Include any in SSH config as well just-in-case.
 	conf.identityFiles = make([]string, 0)
@@ -185,8 +187,22 @@ func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client,
 		var cli *ssh.Client
 		err := retry.Do(
 			func() (err error) {
-				cli, err = ssh.Dial("tcp", conf.host+":"+conf.port, sshConfig)
-				return
+				addr := conf.host + ":" + conf.port
+				if conf.dial == nil {
+					cli, err = ssh.Dial("tcp", addr, sshConfig)
+					return
+				}
+				conn, err := conf.dial(ctx)
+				if err != nil {
+					return err
+				}
+				c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+				if err != nil {
+					conn.Close()
+					return err
+				}
+				cli = ssh.NewClient(c, chans, reqs)
+				return nil
 			},
 			retry.Attempts(retries+1),
 			retry.Delay(500*time.Millisecond),
@@ -212,6 +228,9 @@ type Identity struct {
 	// Retries can be set to a non-zero value to retry every 500ms for that many times.
 	Retries uint `json:"retries"`
 	Port    uint `json:"port"`
+	// Dial can be set to connect through something other than TCP, like a tunnel. Host and
+	// Port are still used to look up SSH config and identify the connection.
+	Dial func(context.Context) (net.Conn, error) `json:"-"`
 }
 
 // ExecSSH executes against a target via SSH. It will look up port, user, and other configuration
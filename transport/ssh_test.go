@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"strconv"
 	"sync"
@@ -253,6 +254,28 @@ func (suite *SSHTestSuite) TestExec_HostKeyConflict() {
 	suite.EqualError(err, "Failed to connect: All attempts fail:\n#1: ssh: handshake failed: knownhosts: key mismatch")
 }
 
+func (suite *SSHTestSuite) TestExec_WithDial() {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	dialed := 0
+	identity := suite.Identity()
+	identity.Host = "tunneled"
+	identity.HostKeyAlias = host
+	identity.Dial = func(ctx context.Context) (net.Conn, error) {
+		dialed++
+		return net.Dial("tcp", host+":"+strconv.Itoa(port))
+	}
+	cmd, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+	if suite.NoError(err) {
+		<-cmd.OutputCh()
+		exit, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Zero(exit)
+		suite.Equal(1, dialed)
+	}
+}
+
 func (suite *SSHTestSuite) TestExec_ContextCancelled() {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -22,17 +22,24 @@ type FS struct {
 // NewFS creates a new FS entry with the given name, using the supplied executor to satisfy volume
 // operations.
 func NewFS(ctx context.Context, name string, executor plugin.Execable, maxdepth int) *FS {
+	fs := NewLazyFS(name, executor, maxdepth)
+	if _, err := plugin.List(ctx, fs); err != nil {
+		fs.MarkInaccessible(ctx, err)
+	}
+
+	return fs
+}
+
+// NewLazyFS is like NewFS, except that it doesn't list the filesystem until the FS
+// entry is listed. Use it when exec'ing on the executor is expensive or has side
+// effects, so that listing its parent doesn't exec anything.
+func NewLazyFS(name string, executor plugin.Execable, maxdepth int) *FS {
 	fs := &FS{
 		EntryBase: plugin.NewEntry(name),
 	}
 	fs.executor = executor
 	fs.maxdepth = maxdepth
 	fs.SetTTLOf(plugin.ListOp, ListTTL)
-
-	if _, err := plugin.List(ctx, fs); err != nil {
-		fs.MarkInaccessible(ctx, err)
-	}
-
 	return fs
 }
 
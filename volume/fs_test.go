@@ -180,6 +180,18 @@ func (suite *fsTestSuite) TestFSList() {
 	exec.AssertExpectations(suite.T())
 }
 
+func (suite *fsTestSuite) TestNewLazyFS() {
+	exec := suite.createExec()
+	fs := NewLazyFS("fs", exec, suite.outputDepth)
+	exec.AssertNotCalled(suite.T(), "Exec", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// The filesystem is listed when the FS entry is.
+	exec.onExec(suite.statCmd("/", suite.outputDepth), suite.createResult(suite.outputFixture))
+	entry := suite.find(fs, "var/log/path1/a file")
+	suite.Equal("a file", plugin.Name(entry))
+	exec.AssertExpectations(suite.T())
+}
+
 func (suite *fsTestSuite) TestFSListTwice() {
 	depth := 3
 	exec := suite.createExec()
@@ -0,0 +1,101 @@
+package volume
+
+import (
+	"archive/tar"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// ParseTar parses a tar archive of the 'start' directory, like the ones returned by Docker's
+// archive API, and maps each directory to a map of files in that directory and their attr
+// (attributes). Only the headers are used. Header names are relative to 'base', so a header
+// named 'etc/passwd' with a base of '/' describes '/etc/passwd'. Files that are more than
+// 'maxdepth' levels below 'start' are skipped, and directories at 'maxdepth' are marked as
+// unexplored.
+func ParseTar(archive io.Reader, base string, start string, maxdepth int) (DirMap, error) {
+	return ParseTarFiles(archive, base, start, maxdepth, nil)
+}
+
+// ParseTarFiles is like ParseTar, but it also calls visitFile with the content of each
+// regular file that's included in the DirMap. visitFile can be nil.
+//
+// If the archive can't be read to its end, ParseTarFiles returns the error with a DirMap
+// of what it parsed. Archives are depth-first, so that DirMap is missing the start
+// directory's later children, and the child that was being parsed is marked as
+// unexplored because its own listing may be incomplete.
+func ParseTarFiles(
+	archive io.Reader,
+	base string,
+	start string,
+	maxdepth int,
+	visitFile func(path string, size int64, content io.Reader) error,
+) (DirMap, error) {
+	if start == "/" {
+		start = RootPath
+	}
+	maxdepth += numPathSegments(start)
+
+	dirmap := DirMap{RootPath: make(Children)}
+	makeChildren(dirmap, start)
+	// current is the start directory's child that's being parsed.
+	var current string
+	partial := func(err error) (DirMap, error) {
+		if _, ok := dirmap[current]; ok && current != "" {
+			for dir := range dirmap {
+				if strings.HasPrefix(dir, current+"/") {
+					delete(dirmap, dir)
+				}
+			}
+			dirmap[current] = nil
+		}
+		return dirmap, err
+	}
+	tarReader := tar.NewReader(archive)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return partial(err)
+		}
+
+		fullpath := path.Join("/", base, path.Clean("/"+hdr.Name))
+		if fullpath == "/" {
+			fullpath = RootPath
+		}
+		// Skip the start directory and anything that's outside of it. The start
+		// directory's attributes are part of its parent's listing.
+		if fullpath == start || !strings.HasPrefix(fullpath, start+"/") {
+			continue
+		}
+		current = start + "/" + strings.SplitN(strings.TrimPrefix(fullpath, start+"/"), "/", 2)[0]
+		if numPathSegments(fullpath) > maxdepth {
+			continue
+		}
+		addAttributesForPath(dirmap, tarAttributes(hdr), RootPath, fullpath, maxdepth)
+		if visitFile != nil && hdr.Typeflag == tar.TypeReg {
+			if err := visitFile(fullpath, hdr.Size, tarReader); err != nil {
+				return partial(err)
+			}
+		}
+	}
+	return dirmap, nil
+}
+
+func tarAttributes(hdr *tar.Header) plugin.EntryAttributes {
+	var attr plugin.EntryAttributes
+	attr.
+		SetSize(uint64(hdr.Size)).
+		SetMtime(hdr.ModTime).
+		SetMode(hdr.FileInfo().Mode())
+	if !hdr.AccessTime.IsZero() {
+		attr.SetAtime(hdr.AccessTime)
+	}
+	if !hdr.ChangeTime.IsZero() {
+		attr.SetCtime(hdr.ChangeTime)
+	}
+	return attr
+}
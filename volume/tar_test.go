@@ -0,0 +1,146 @@
+package volume
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tarMtime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestTar(t *testing.T, headers ...tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range headers {
+		hdr.ModTime = tarMtime
+		require.NoError(t, tw.WriteHeader(&hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write(make([]byte, hdr.Size))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestParseTar_Root(t *testing.T) {
+	// Docker names the root directory '.' when archiving '/'.
+	archive := newTestTar(t,
+		tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 10},
+		tar.Header{Name: "etc/ssh/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/ssh/sshd_config", Typeflag: tar.TypeReg, Mode: 0600, Size: 5},
+		tar.Header{Name: "hello", Typeflag: tar.TypeReg, Mode: 0755, Size: 3},
+	)
+
+	dmap, err := ParseTar(archive, "/", "/", 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, len(dmap))
+		assert.Contains(t, dmap[RootPath], "etc")
+		assert.Contains(t, dmap[RootPath], "hello")
+		assert.Contains(t, dmap["/etc"], "passwd")
+		assert.Contains(t, dmap["/etc"], "ssh")
+		// /etc/ssh is at maxdepth, so it's unexplored.
+		assert.Contains(t, dmap, "/etc/ssh")
+		assert.Nil(t, dmap["/etc/ssh"])
+
+		expectedAttr := plugin.EntryAttributes{}
+		expectedAttr.
+			SetSize(10).
+			SetMtime(time.Unix(tarMtime.Unix(), 0)).
+			SetMode(0644)
+		assert.Equal(t, expectedAttr, dmap["/etc"]["passwd"])
+		etcAttr := dmap[RootPath]["etc"]
+		assert.Equal(t, os.ModeDir|0755, etcAttr.Mode())
+	}
+}
+
+func TestParseTar_Subdirectory(t *testing.T) {
+	// Docker names entries after the archived directory's basename.
+	archive := newTestTar(t,
+		tar.Header{Name: "local/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "local/bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "local/bin/tool", Typeflag: tar.TypeReg, Mode: 0755, Size: 1},
+		tar.Header{Name: "local/bin/more/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "local/bin/more/tool", Typeflag: tar.TypeReg, Mode: 0755, Size: 1},
+	)
+
+	dmap, err := ParseTar(archive, "/usr", "/usr/local", 2)
+	if assert.NoError(t, err) {
+		assert.Contains(t, dmap["/usr/local"], "bin")
+		assert.Contains(t, dmap["/usr/local/bin"], "tool")
+		assert.Contains(t, dmap["/usr/local/bin"], "more")
+		assert.Nil(t, dmap["/usr/local/bin/more"])
+		assert.NotContains(t, dmap, "/usr/local/bin/more/tool")
+		// The start directory's parents are created so the hierarchy is preserved.
+		assert.Contains(t, dmap[RootPath], "usr")
+		assert.Contains(t, dmap["/usr"], "local")
+	}
+}
+
+func TestParseTarFiles(t *testing.T) {
+	archive := newTestTar(t,
+		tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 10},
+		tar.Header{Name: "etc/ssh/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/ssh/sshd_config", Typeflag: tar.TypeReg, Mode: 0600, Size: 5},
+		tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "etc/passwd"},
+	)
+
+	// Only the regular files that are in the DirMap are visited.
+	sizes := make(map[string]int)
+	dmap, err := ParseTarFiles(archive, "/", "/", 2, func(path string, size int64, content io.Reader) error {
+		b, err := ioutil.ReadAll(content)
+		sizes[path] = len(b)
+		assert.EqualValues(t, size, len(b))
+		return err
+	})
+	if assert.NoError(t, err) {
+		assert.Contains(t, dmap[RootPath], "link")
+		assert.Equal(t, map[string]int{"/etc/passwd": 10}, sizes)
+	}
+}
+
+func TestParseTar_Error(t *testing.T) {
+	_, err := ParseTar(bytes.NewBufferString("not a tar archive"), "/", "/", 1)
+	assert.Error(t, err)
+}
+
+func TestParseTarFiles_Partial(t *testing.T) {
+	archive := newTestTar(t,
+		tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 10},
+		tar.Header{Name: "usr/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "usr/bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "usr/bin/tool", Typeflag: tar.TypeReg, Mode: 0755, Size: 1},
+		tar.Header{Name: "usr/lib/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "usr/lib/big", Typeflag: tar.TypeReg, Mode: 0644, Size: 4096},
+		tar.Header{Name: "var/", Typeflag: tar.TypeDir, Mode: 0755},
+	)
+	// Stop reading in the middle of usr/lib/big.
+	truncated := io.LimitReader(archive, int64(archive.Len()-2048))
+
+	dmap, err := ParseTarFiles(truncated, "/", "/", 3, func(path string, size int64, content io.Reader) error {
+		_, err := ioutil.ReadAll(content)
+		return err
+	})
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Contains(t, dmap["/etc"], "passwd")
+	// usr was being parsed, so it's listed again on its own.
+	assert.Contains(t, dmap[RootPath], "usr")
+	assert.Contains(t, dmap, "/usr")
+	assert.Nil(t, dmap["/usr"])
+	assert.NotContains(t, dmap, "/usr/bin")
+	assert.NotContains(t, dmap[RootPath], "var")
+}